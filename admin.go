@@ -0,0 +1,82 @@
+package main
+
+import "io"
+
+// CreateAPIKey creates a server API key scoped to the given database
+func (m *MenousDB) CreateAPIKey(database string) (_ string, err error) {
+	defer m.annotate(&err, "CreateAPIKey", "create-key", "")
+
+	headers := map[string]string{
+		"key": m.Key,
+	}
+
+	body := map[string]interface{}{
+		"database": database,
+	}
+
+	resp, err := m.makeRequest("POST", "create-key", headers, body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(responseBody), nil
+}
+
+// ListAPIKeys retrieves the server API keys and the databases they cover
+func (m *MenousDB) ListAPIKeys() (_ interface{}, err error) {
+	defer m.annotate(&err, "ListAPIKeys", "get-keys", "")
+
+	headers := map[string]string{
+		"key": m.Key,
+	}
+
+	resp, err := m.makeRequest("GET", "get-keys", headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result interface{}
+	if err := m.decode(resp.Body, &result); err != nil {
+		// If JSON decoding fails, return raw text
+		body, readErr := io.ReadAll(resp.Body)
+		if readErr != nil {
+			return nil, readErr
+		}
+		return string(body), nil
+	}
+
+	return result, nil
+}
+
+// RevokeAPIKey revokes a server API key
+func (m *MenousDB) RevokeAPIKey(apiKey string) (_ string, err error) {
+	defer m.annotate(&err, "RevokeAPIKey", "revoke-key", "")
+
+	headers := map[string]string{
+		"key": m.Key,
+	}
+
+	body := map[string]interface{}{
+		"api_key": apiKey,
+	}
+
+	resp, err := m.makeRequest("DELETE", "revoke-key", headers, body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(responseBody), nil
+}
@@ -0,0 +1,107 @@
+package main
+
+// Aggregator folds the rows of one group into a value
+type Aggregator interface {
+	Add(r Row)
+	Result() interface{}
+}
+
+// Aggregate creates a fresh Aggregator for each group
+type Aggregate func() Aggregator
+
+// aggregatorFunc adapts a pair of closures to Aggregator
+type aggregatorFunc struct {
+	add    func(Row)
+	result func() interface{}
+}
+
+func (a aggregatorFunc) Add(r Row)           { a.add(r) }
+func (a aggregatorFunc) Result() interface{} { return a.result() }
+
+// CountOf counts the rows in a group
+func CountOf() Aggregate {
+	return func() Aggregator {
+		n := 0
+		return aggregatorFunc{
+			add:    func(Row) { n++ },
+			result: func() interface{} { return n },
+		}
+	}
+}
+
+// SumOf adds up the numeric values of column, skipping others
+func SumOf(column string) Aggregate {
+	return func() Aggregator {
+		sum := 0.0
+		return aggregatorFunc{
+			add: func(r Row) {
+				if f, err := toFloat(r[column]); err == nil {
+					sum += f
+				}
+			},
+			result: func() interface{} { return sum },
+		}
+	}
+}
+
+// AvgOf averages the numeric values of column, nil when there are none
+func AvgOf(column string) Aggregate {
+	return func() Aggregator {
+		sum, n := 0.0, 0
+		return aggregatorFunc{
+			add: func(r Row) {
+				if f, err := toFloat(r[column]); err == nil {
+					sum += f
+					n++
+				}
+			},
+			result: func() interface{} {
+				if n == 0 {
+					return nil
+				}
+				return sum / float64(n)
+			},
+		}
+	}
+}
+
+// MinOf keeps the smallest non-null value of column
+func MinOf(column string) Aggregate {
+	return extremeOf(column, -1)
+}
+
+// MaxOf keeps the largest non-null value of column
+func MaxOf(column string) Aggregate {
+	return extremeOf(column, 1)
+}
+
+// extremeOf keeps the value of column furthest in the direction of sign
+func extremeOf(column string, sign int) Aggregate {
+	return func() Aggregator {
+		var best interface{}
+		return aggregatorFunc{
+			add: func(r Row) {
+				v := r[column]
+				if v != nil && (best == nil || compareValues(v, best)*sign > 0) {
+					best = v
+				}
+			},
+			result: func() interface{} { return best },
+		}
+	}
+}
+
+// CountDistinctOf counts the distinct non-null values of column
+func CountDistinctOf(column string) Aggregate {
+	return func() Aggregator {
+		seen := make(map[string]bool)
+		return aggregatorFunc{
+			add: func(r Row) {
+				if v := r[column]; v != nil {
+					seen[valueKey(v)] = true
+				}
+			},
+			result: func() interface{} { return len(seen) },
+		}
+	}
+}
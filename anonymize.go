@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+)
+
+// Anonymizer rewrites one column across a batch of rows in place
+type Anonymizer func(column string, rows []Row)
+
+// Anonymization maps table and column to the anonymizer applied to it on
+// export, so production data can be shared safely:
+//
+//	a := Anonymization{
+//		"users": {
+//			"email": FakeEmail(key),
+//			"name":  FakeName(key),
+//			"id":    Hash(key),
+//			"city":  Shuffle(time.Now().UnixNano()),
+//		},
+//	}
+//	db.DumpTable(w, "users", DumpOptions{Anonymize: a})
+type Anonymization map[string]map[string]Anonymizer
+
+// Apply anonymizes rows read from table in place. Nulls and missing values
+// are left as they are.
+func (a Anonymization) Apply(table string, rows []Row) {
+	for column, fn := range a[table] {
+		fn(column, rows)
+	}
+}
+
+// Chain applies anonymizers in order
+func Chain(as ...Anonymizer) Anonymizer {
+	return func(column string, rows []Row) {
+		for _, fn := range as {
+			fn(column, rows)
+		}
+	}
+}
+
+// Substitute replaces every non-null value with fn's result, the hook for
+// faker-style generators
+func Substitute(fn func(v interface{}) interface{}) Anonymizer {
+	return func(column string, rows []Row) {
+		for _, row := range rows {
+			if v, ok := row[column]; ok && v != nil {
+				row[column] = fn(v)
+			}
+		}
+	}
+}
+
+// Redact replaces every non-null value with replacement
+func Redact(replacement interface{}) Anonymizer {
+	return Substitute(func(interface{}) interface{} { return replacement })
+}
+
+// Hash replaces values with the hex HMAC-SHA256 of their JSON encoding under
+// key. Equal values hash equally, across tables too, so joins survive; a
+// secret key stops values from being recovered by hashing guesses.
+func Hash(key []byte) Anonymizer {
+	return Substitute(func(v interface{}) interface{} {
+		return hex.EncodeToString(anonDigest(key, v))
+	})
+}
+
+// FakeEmail replaces values with an address at example.com derived from
+// their hash under key, so equal inputs keep equal outputs
+func FakeEmail(key []byte) Anonymizer {
+	return Substitute(func(v interface{}) interface{} {
+		return fmt.Sprintf("user_%s@example.com", hex.EncodeToString(anonDigest(key, v)[:5]))
+	})
+}
+
+var (
+	fakeFirstNames = []string{"Alex", "Sam", "Jordan", "Taylor", "Morgan", "Casey", "Riley", "Jamie", "Avery", "Quinn", "Robin", "Drew"}
+	fakeLastNames  = []string{"Smith", "Garcia", "Chen", "Patel", "Kim", "Nguyen", "Silva", "Müller", "Rossi", "Okafor", "Haddad", "Novak"}
+)
+
+// FakeName replaces values with a plausible full name chosen by their hash
+// under key
+func FakeName(key []byte) Anonymizer {
+	return Substitute(func(v interface{}) interface{} {
+		d := anonDigest(key, v)
+		first := binary.BigEndian.Uint32(d[0:4]) % uint32(len(fakeFirstNames))
+		last := binary.BigEndian.Uint32(d[4:8]) % uint32(len(fakeLastNames))
+		return fakeFirstNames[first] + " " + fakeLastNames[last]
+	})
+}
+
+// Shuffle permutes the column's values among the rows that have one, keeping
+// the column's distribution while unlinking it from the rest of each row.
+// The same seed gives the same permutation of the same batch. Sharded dumps
+// shuffle within each shard.
+func Shuffle(seed int64) Anonymizer {
+	return func(column string, rows []Row) {
+		var holders []Row
+		var values []interface{}
+		for _, row := range rows {
+			if v, ok := row[column]; ok && v != nil {
+				holders = append(holders, row)
+				values = append(values, v)
+			}
+		}
+		rand.New(rand.NewSource(seed)).Shuffle(len(values), func(i, j int) {
+			values[i], values[j] = values[j], values[i]
+		})
+		for i, row := range holders {
+			row[column] = values[i]
+		}
+	}
+}
+
+// anonDigest returns the HMAC-SHA256 of v's JSON encoding under key
+func anonDigest(key []byte, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data = []byte(fmt.Sprint(v))
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// BackupAnonymized writes a snapshot like Backup, with the records of each
+// table passed through a first
+func (m *MenousDB) BackupAnonymized(w io.Writer, a Anonymization) error {
+	data, err := m.ReadDB()
+	if err != nil {
+		return err
+	}
+	for table, entry := range data {
+		if len(a[table]) == 0 {
+			continue
+		}
+		a.Apply(table, snapshotRecords(entry))
+	}
+	snapshot := Snapshot{
+		Database: m.Database,
+		Created:  time.Now().UTC(),
+		Data:     data,
+	}
+	return json.NewEncoder(w).Encode(snapshot)
+}
+
+// snapshotRecords returns the records in a table's database contents: the
+// objects among its elements, whether it holds an array or an object keyed
+// by row id
+func snapshotRecords(entry interface{}) []Row {
+	var rows []Row
+	switch v := entry.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if row, ok := item.(map[string]interface{}); ok {
+				rows = append(rows, row)
+			}
+		}
+	case map[string]interface{}:
+		ids := make([]string, 0, len(v))
+		for id := range v {
+			ids = append(ids, id)
+		}
+		sortRowIDs(ids)
+		for _, id := range ids {
+			if row, ok := v[id].(map[string]interface{}); ok {
+				rows = append(rows, row)
+			}
+		}
+	}
+	return rows
+}
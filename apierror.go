@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxErrorBody bounds how much of an error response is read
+const maxErrorBody = 64 << 10
+
+// APIError is returned when the server answers with a non-2xx status
+type APIError struct {
+	HTTPStatus    int
+	ServerCode    string
+	ServerMessage string
+	Endpoint      string
+	Retryable     bool
+	RequestID     string
+
+	// RetryAfter is the delay the server asked for, if any
+	RetryAfter time.Duration
+}
+
+// Error formats the status and server message
+func (e *APIError) Error() string {
+	s := fmt.Sprintf("server returned %d %s", e.HTTPStatus, http.StatusText(e.HTTPStatus))
+	if e.ServerCode != "" {
+		s += " [" + e.ServerCode + "]"
+	}
+	if e.ServerMessage != "" {
+		s += ": " + e.ServerMessage
+	}
+	if e.RequestID != "" {
+		s += " (request " + e.RequestID + ")"
+	}
+	return s
+}
+
+// retryableStatus lists statuses worth retrying: timeouts, throttling and
+// transient server or gateway failures
+var retryableStatus = map[int]bool{
+	http.StatusRequestTimeout:      true,
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// newAPIError builds an APIError from a failed response, consuming its body
+func newAPIError(endpoint string, resp *http.Response) *APIError {
+	e := &APIError{
+		HTTPStatus: resp.StatusCode,
+		Endpoint:   endpoint,
+		Retryable:  retryableStatus[resp.StatusCode],
+		RequestID:  resp.Header.Get("X-Request-Id"),
+		RetryAfter: parseRetryAfter(resp.Header, time.Now()),
+	}
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get("X-Correlation-Id")
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	text := strings.TrimSpace(string(body))
+
+	// The server reports errors either as JSON objects or as bare text
+	var fields map[string]interface{}
+	if json.Unmarshal(body, &fields) == nil {
+		for _, k := range []string{"error", "message", "msg", "detail"} {
+			if s, ok := fields[k].(string); ok {
+				e.ServerMessage = s
+				break
+			}
+		}
+		for _, k := range []string{"code", "error_code"} {
+			switch v := fields[k].(type) {
+			case string:
+				e.ServerCode = v
+			case float64:
+				e.ServerCode = fmt.Sprint(v)
+			}
+			if e.ServerCode != "" {
+				break
+			}
+		}
+		if e.ServerMessage == "" {
+			e.ServerMessage = text
+		}
+	} else {
+		e.ServerMessage = text
+	}
+	return e
+}
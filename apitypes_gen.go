@@ -0,0 +1,61 @@
+// Code generated by "menousdb openapi gen" from MenousDB 1.0.0; DO NOT EDIT.
+
+package main
+
+// ColumnDefinition is a typed column declaration
+type ColumnDefinition struct {
+	Default  interface{} `json:"default,omitempty"`
+	Name     string      `json:"name"`
+	Nullable bool        `json:"nullable,omitempty"`
+	Required bool        `json:"required,omitempty"`
+	Type     string      `json:"type,omitempty"`
+	Validate string      `json:"validate,omitempty"`
+}
+
+// CreateKeyRequest is the body of create-key
+type CreateKeyRequest struct {
+	Database string `json:"database"`
+}
+
+// CreateTableRequest is the body of create-table
+type CreateTableRequest struct {
+	Attributes []string           `json:"attributes"`
+	Columns    []ColumnDefinition `json:"columns,omitempty"`
+}
+
+// DeleteWhereRequest is the body of delete-where
+type DeleteWhereRequest struct {
+	Conditions map[string]interface{} `json:"conditions"`
+}
+
+// InsertIntoTableRequest is the body of insert-into-table
+type InsertIntoTableRequest struct {
+	Values map[string]interface{} `json:"values"`
+}
+
+// RevokeKeyRequest is the body of revoke-key
+type RevokeKeyRequest struct {
+	APIKey string `json:"api_key"`
+}
+
+// SelectColumnsRequest is the body of select-columns
+type SelectColumnsRequest struct {
+	Columns []string `json:"columns"`
+}
+
+// SelectColumnsWhereRequest is the body of select-columns-where
+type SelectColumnsWhereRequest struct {
+	Columns    []string               `json:"columns"`
+	Conditions map[string]interface{} `json:"conditions"`
+}
+
+// SelectWhereRequest is the body of select-where
+type SelectWhereRequest struct {
+	Conditions map[string]interface{} `json:"conditions"`
+}
+
+// UpdateTableRequest is the body of update-table
+type UpdateTableRequest struct {
+	Conditions map[string]interface{} `json:"conditions"`
+	Values     map[string]interface{} `json:"values"`
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// APIVersion selects the server's endpoint namespace
+type APIVersion string
+
+const (
+	// APIVersionNone speaks to unversioned servers, with endpoints at the root
+	APIVersionNone APIVersion = ""
+	// APIVersion1 prefixes endpoints with v1/
+	APIVersion1 APIVersion = "v1"
+	// APIVersion2 prefixes endpoints with v2/
+	APIVersion2 APIVersion = "v2"
+)
+
+// VersionAdapter translates between the client's payload shapes and those of
+// one API version. Either function may be nil to leave that side unchanged.
+type VersionAdapter struct {
+	// Request rewrites the headers and body sent to endpoint
+	Request func(endpoint string, headers map[string]string, body interface{}) (map[string]string, interface{})
+
+	// Response rewrites a successful response body from endpoint into the
+	// shape the client decodes
+	Response func(endpoint string, body io.ReadCloser) (io.ReadCloser, error)
+}
+
+// versionAdapters holds the adapters registered per version
+var (
+	versionMu       sync.RWMutex
+	versionAdapters = map[APIVersion]VersionAdapter{}
+)
+
+// RegisterAPIVersion sets the adapter used for v. Versions without one get
+// only the endpoint prefix.
+func RegisterAPIVersion(v APIVersion, a VersionAdapter) {
+	versionMu.Lock()
+	defer versionMu.Unlock()
+	versionAdapters[v] = a
+}
+
+// WithAPIVersion sends requests to v's namespace and adapts payloads with
+// the adapter registered for it, so one program can talk to old and new
+// servers during an upgrade
+func WithAPIVersion(v APIVersion) Option {
+	return func(m *MenousDB) {
+		m.apiVersion = APIVersion(strings.Trim(string(v), "/"))
+	}
+}
+
+// versionPrefix returns the path prefix for the client's API version
+func (m *MenousDB) versionPrefix() string {
+	if m.apiVersion == APIVersionNone {
+		return ""
+	}
+	return string(m.apiVersion) + "/"
+}
+
+// versionAdapter returns the adapter for the client's API version
+func (m *MenousDB) versionAdapter() VersionAdapter {
+	if m.apiVersion == APIVersionNone {
+		return VersionAdapter{}
+	}
+	versionMu.RLock()
+	defer versionMu.RUnlock()
+	return versionAdapters[m.apiVersion]
+}
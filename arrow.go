@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// ArrowType is the logical type of an Arrow column
+type ArrowType int
+
+const (
+	// ArrowInt64 columns hold signed 64-bit integers
+	ArrowInt64 ArrowType = iota
+	// ArrowFloat64 columns hold doubles
+	ArrowFloat64
+	// ArrowBool columns hold bit-packed booleans
+	ArrowBool
+	// ArrowString columns hold UTF-8 strings (nested values as JSON)
+	ArrowString
+)
+
+// ArrowField names and types one column
+type ArrowField struct {
+	Name string
+	Type ArrowType
+}
+
+// ArrowColumn holds one column in the Arrow columnar layout, so the buffers
+// can be handed to an Arrow implementation without copying (for arrow-go:
+// memory.NewBufferBytes on each buffer, then array.NewData).
+type ArrowColumn struct {
+	Field ArrowField
+	Len   int
+
+	// NullCount is the number of null slots
+	NullCount int
+
+	// Validity is the LSB-numbered bitmap of non-null slots
+	Validity []byte
+
+	// Offsets holds Len+1 little-endian int32 offsets into Data for strings
+	Offsets []byte
+
+	// Data holds little-endian values for fixed-width types, the value
+	// bitmap for booleans, or concatenated UTF-8 bytes for strings
+	Data []byte
+}
+
+// RecordBatch is a set of equal-length Arrow columns
+type RecordBatch struct {
+	Schema  []ArrowField
+	Columns []*ArrowColumn
+	NumRows int
+}
+
+// Column returns the column named name, or nil
+func (b *RecordBatch) Column(name string) *ArrowColumn {
+	for _, c := range b.Columns {
+		if c.Field.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// IsNull reports whether slot i is null
+func (c *ArrowColumn) IsNull(i int) bool {
+	return c.Validity[i/8]&(1<<(i%8)) == 0
+}
+
+// Int64 returns slot i of an ArrowInt64 column
+func (c *ArrowColumn) Int64(i int) int64 {
+	return int64(binary.LittleEndian.Uint64(c.Data[i*8:]))
+}
+
+// Float64 returns slot i of an ArrowFloat64 column
+func (c *ArrowColumn) Float64(i int) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(c.Data[i*8:]))
+}
+
+// Bool returns slot i of an ArrowBool column
+func (c *ArrowColumn) Bool(i int) bool {
+	return c.Data[i/8]&(1<<(i%8)) != 0
+}
+
+// String returns slot i of an ArrowString column
+func (c *ArrowColumn) String(i int) string {
+	start := binary.LittleEndian.Uint32(c.Offsets[i*4:])
+	end := binary.LittleEndian.Uint32(c.Offsets[(i+1)*4:])
+	return string(c.Data[start:end])
+}
+
+// setBit sets bit i of bitmap, growing it as needed
+func setBit(bitmap []byte, i int, v bool) []byte {
+	for len(bitmap) <= i/8 {
+		bitmap = append(bitmap, 0)
+	}
+	if v {
+		bitmap[i/8] |= 1 << (i % 8)
+	}
+	return bitmap
+}
+
+// append adds one value (nil for null) to the column
+func (c *ArrowColumn) append(v interface{}) error {
+	i := c.Len
+	valid := v != nil
+	c.Validity = setBit(c.Validity, i, valid)
+	if !valid {
+		c.NullCount++
+	}
+
+	switch c.Field.Type {
+	case ArrowInt64:
+		var n int64
+		if valid {
+			var err error
+			if n, err = arrowInt(v); err != nil {
+				return err
+			}
+		}
+		c.Data = binary.LittleEndian.AppendUint64(c.Data, uint64(n))
+	case ArrowFloat64:
+		var f float64
+		if valid {
+			var err error
+			if f, err = arrowFloat(v); err != nil {
+				return err
+			}
+		}
+		c.Data = binary.LittleEndian.AppendUint64(c.Data, math.Float64bits(f))
+	case ArrowBool:
+		b := false
+		if valid {
+			var ok bool
+			if b, ok = v.(bool); !ok {
+				return fmt.Errorf("cannot store %T in a bool column", v)
+			}
+		}
+		c.Data = setBit(c.Data, i, b)
+	case ArrowString:
+		if valid {
+			s, err := arrowString(v)
+			if err != nil {
+				return err
+			}
+			c.Data = append(c.Data, s...)
+		}
+		c.Offsets = binary.LittleEndian.AppendUint32(c.Offsets, uint32(len(c.Data)))
+	}
+	c.Len++
+	return nil
+}
+
+// arrowInt converts a decoded value to int64
+func arrowInt(v interface{}) (int64, error) {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := n.Float64()
+		if err != nil || !isInt64(f) {
+			return 0, fmt.Errorf("cannot store %v in an int64 column", v)
+		}
+		return int64(f), nil
+	}
+	return 0, fmt.Errorf("cannot store %T in an int64 column", v)
+}
+
+// arrowFloat converts a decoded value to float64
+func arrowFloat(v interface{}) (float64, error) {
+	if n, ok := v.(json.Number); ok {
+		return n.Float64()
+	}
+	return 0, fmt.Errorf("cannot store %T in a float64 column", v)
+}
+
+// arrowString converts a decoded value to its string form
+func arrowString(v interface{}) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case json.Number:
+		return x.String(), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	}
+	raw, err := json.Marshal(v)
+	return string(raw), err
+}
+
+// InferArrowSchema derives a schema from sample rows, for callers that do
+// not know their column types up front
+func InferArrowSchema(rows []Row) []ArrowField {
+	names := inferColumns(rows)
+	schema := make([]ArrowField, len(names))
+	for i, name := range names {
+		t := ArrowString
+		switch inferParquetType(rows, name) {
+		case parquetInt64:
+			t = ArrowInt64
+		case parquetDouble:
+			t = ArrowFloat64
+		case parquetBoolean:
+			t = ArrowBool
+		}
+		schema[i] = ArrowField{Name: name, Type: t}
+	}
+	return schema
+}
+
+// DecodeArrow decodes a select response (an array of records or an object
+// keyed by row id) from r straight into columns, without building a map per
+// row. Attributes outside schema are skipped; missing ones are null. Records
+// keep the order the server sent them in.
+func DecodeArrow(r io.Reader, schema []ArrowField) (*RecordBatch, error) {
+	batch := &RecordBatch{Schema: schema, Columns: make([]*ArrowColumn, len(schema))}
+	index := make(map[string]int, len(schema))
+	for i, f := range schema {
+		batch.Columns[i] = &ArrowColumn{Field: f}
+		if f.Type == ArrowString {
+			batch.Columns[i].Offsets = make([]byte, 4)
+		}
+		index[f.Name] = i
+	}
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	open, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	keyed := open == json.Delim('{')
+	if !keyed && open != json.Delim('[') {
+		return nil, fmt.Errorf("%w: %v", ErrUnexpectedResponse, open)
+	}
+
+	seen := make([]bool, len(schema))
+	for dec.More() {
+		if keyed {
+			// Row id, not part of the record
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+		}
+		if t, err := dec.Token(); err != nil {
+			return nil, err
+		} else if t != json.Delim('{') {
+			return nil, fmt.Errorf("%w: record is %v, not an object", ErrUnexpectedResponse, t)
+		}
+
+		for i := range seen {
+			seen[i] = false
+		}
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			name := t.(string)
+			col, ok := index[name]
+			if !ok || seen[col] {
+				var skip json.RawMessage
+				if err := dec.Decode(&skip); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return nil, err
+			}
+			if err := batch.Columns[col].append(decompressValue(v)); err != nil {
+				return nil, fmt.Errorf("row %d, column %q: %w", batch.NumRows, name, err)
+			}
+			seen[col] = true
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+
+		for i, ok := range seen {
+			if !ok {
+				batch.Columns[i].append(nil)
+			}
+		}
+		batch.NumRows++
+	}
+	return batch, nil
+}
+
+// GetTableArrow retrieves a table's contents as an Arrow record batch
+func (m *MenousDB) GetTableArrow(table string, schema []ArrowField) (_ *RecordBatch, err error) {
+	defer m.annotate(&err, "GetTableArrow", "get-table", table)
+
+	if err := m.validateDatabase(); err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{
+		"key":      m.Key,
+		"database": m.Database,
+		"table":    table,
+	}
+
+	resp, err := m.makeRequest("GET", "get-table", headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return DecodeArrow(resp.Body, schema)
+}
+
+// SelectWhereArrow retrieves records matching conditions as an Arrow record
+// batch
+func (m *MenousDB) SelectWhereArrow(table string, conditions map[string]interface{}, schema []ArrowField) (_ *RecordBatch, err error) {
+	defer m.annotate(&err, "SelectWhereArrow", "select-where", table)
+
+	if err := m.validateDatabase(); err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{
+		"key":      m.Key,
+		"database": m.Database,
+		"table":    table,
+	}
+
+	body := map[string]interface{}{
+		"conditions": conditions,
+	}
+
+	resp, err := m.doQuery("select-where", headers, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return DecodeArrow(resp.Body, schema)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"time"
+)
+
+// attachmentMeta is the chunk position of an attachment's metadata row
+const attachmentMeta = -1
+
+// AttachmentAttributes are the attributes of an attachments table. Each file
+// has one metadata row, with chunk -1, and one row per chunk of content.
+var AttachmentAttributes = []string{
+	"attachment", "chunk", "data",
+	"filename", "content_type", "size", "chunks", "sha256", "created",
+}
+
+// AttachmentInfo describes a stored file
+type AttachmentInfo struct {
+	ID          string
+	Filename    string
+	ContentType string
+	Size        int64
+	Chunks      int
+	SHA256      string
+	Created     time.Time
+}
+
+// Attachments stores files as chunked rows in one table
+type Attachments struct {
+	// ChunkSize is the number of file bytes per row; defaults to 256 KiB
+	ChunkSize int
+
+	client *MenousDB
+	table  string
+}
+
+// Attachments returns the file store kept in table
+func (m *MenousDB) Attachments(table string) *Attachments {
+	return &Attachments{client: m, table: table}
+}
+
+// Ensure creates the attachments table if it does not exist
+func (a *Attachments) Ensure() (bool, error) {
+	return a.client.EnsureTable(TableSpec{Name: a.table, Attributes: AttachmentAttributes})
+}
+
+// Put stores the contents of r under id, replacing any file stored there.
+// Content is read and written a chunk at a time, so files need not fit in
+// memory. The metadata row is written last: until it is, Stat and Open
+// report the file as missing.
+func (a *Attachments) Put(id, filename, contentType string, r io.Reader) (AttachmentInfo, error) {
+	if err := a.Delete(id); err != nil {
+		return AttachmentInfo{}, err
+	}
+
+	size := a.ChunkSize
+	if size <= 0 {
+		size = 256 << 10
+	}
+	buf := make([]byte, size)
+	sum := sha256.New()
+	info := AttachmentInfo{ID: id, Filename: filename, ContentType: contentType}
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			sum.Write(buf[:n])
+			row := Row{
+				"attachment": id,
+				"chunk":      info.Chunks,
+				"data":       base64.StdEncoding.EncodeToString(buf[:n]),
+			}
+			if _, err := a.client.InsertIntoTable(a.table, row); err != nil {
+				return info, fmt.Errorf("writing chunk %d of %s: %w", info.Chunks, id, err)
+			}
+			info.Chunks++
+			info.Size += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return info, fmt.Errorf("reading %s: %w", id, err)
+		}
+	}
+
+	info.SHA256 = hex.EncodeToString(sum.Sum(nil))
+	info.Created = time.Now().UTC()
+	meta := Row{
+		"attachment":   id,
+		"chunk":        attachmentMeta,
+		"filename":     info.Filename,
+		"content_type": info.ContentType,
+		"size":         info.Size,
+		"chunks":       info.Chunks,
+		"sha256":       info.SHA256,
+		"created":      info.Created.Format(time.RFC3339Nano),
+	}
+	if _, err := a.client.InsertIntoTable(a.table, meta); err != nil {
+		return info, fmt.Errorf("writing metadata of %s: %w", id, err)
+	}
+	return info, nil
+}
+
+// Stat returns the metadata of the file stored under id, or ErrBlobNotFound
+func (a *Attachments) Stat(id string) (AttachmentInfo, error) {
+	rows, err := a.client.SelectWhereRows(a.table, map[string]interface{}{"attachment": id, "chunk": attachmentMeta})
+	if err != nil {
+		return AttachmentInfo{}, err
+	}
+	if len(rows) == 0 {
+		return AttachmentInfo{}, fmt.Errorf("%w: attachment %q", ErrBlobNotFound, id)
+	}
+	return attachmentInfo(rows[0]), nil
+}
+
+// List returns the metadata of every stored file
+func (a *Attachments) List() ([]AttachmentInfo, error) {
+	rows, err := a.client.SelectWhereRows(a.table, map[string]interface{}{"chunk": attachmentMeta})
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]AttachmentInfo, len(rows))
+	for i, row := range rows {
+		infos[i] = attachmentInfo(row)
+	}
+	return infos, nil
+}
+
+// Open streams the file stored under id, fetching one chunk at a time. The
+// reader returns ErrBlobCorrupt in place of io.EOF when a chunk is missing or
+// the content does not match its checksum.
+func (a *Attachments) Open(id string) (io.ReadCloser, error) {
+	info, err := a.Stat(id)
+	if err != nil {
+		return nil, err
+	}
+	return &attachmentReader{store: a, info: info, sum: sha256.New()}, nil
+}
+
+// Delete removes the file stored under id, metadata first so readers stop
+// seeing it before its chunks go
+func (a *Attachments) Delete(id string) error {
+	if _, err := a.client.DeleteWhere(a.table, map[string]interface{}{"attachment": id, "chunk": attachmentMeta}); err != nil {
+		return err
+	}
+	_, err := a.client.DeleteWhere(a.table, map[string]interface{}{"attachment": id})
+	return err
+}
+
+// attachmentInfo reads a metadata row
+func attachmentInfo(row Row) AttachmentInfo {
+	info := AttachmentInfo{}
+	info.ID, _ = row["attachment"].(string)
+	info.Filename, _ = row["filename"].(string)
+	info.ContentType, _ = row["content_type"].(string)
+	info.SHA256, _ = row["sha256"].(string)
+	if f, err := toFloat(row["size"]); err == nil {
+		info.Size = int64(f)
+	}
+	if f, err := toFloat(row["chunks"]); err == nil {
+		info.Chunks = int(f)
+	}
+	if t, err := toTime(row["created"]); err == nil {
+		info.Created = t
+	}
+	return info
+}
+
+// attachmentReader streams an attachment's chunks in order
+type attachmentReader struct {
+	store *Attachments
+	info  AttachmentInfo
+	next  int
+	buf   bytes.Reader
+	sum   hash.Hash
+	err   error
+}
+
+// Read returns content from the current chunk, fetching the next when it
+// runs out
+func (r *attachmentReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.fetch()
+	}
+	return r.buf.Read(p)
+}
+
+// fetch loads the next chunk into buf, or returns the error ending the
+// stream
+func (r *attachmentReader) fetch() error {
+	id := r.info.ID
+	if r.next == r.info.Chunks {
+		if hex.EncodeToString(r.sum.Sum(nil)) != r.info.SHA256 {
+			return fmt.Errorf("%w: attachment %q checksum mismatch", ErrBlobCorrupt, id)
+		}
+		return io.EOF
+	}
+	rows, err := r.store.client.SelectWhereRows(r.store.table, map[string]interface{}{"attachment": id, "chunk": r.next})
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("%w: attachment %q is missing chunk %d", ErrBlobCorrupt, id, r.next)
+	}
+	text, _ := rows[0]["data"].(string)
+	data, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return fmt.Errorf("%w: attachment %q chunk %d: %v", ErrBlobCorrupt, id, r.next, err)
+	}
+	r.sum.Write(data)
+	r.buf.Reset(data)
+	r.next++
+	return nil
+}
+
+// Close stops the stream
+func (r *attachmentReader) Close() error {
+	r.err = os.ErrClosed
+	r.buf.Reset(nil)
+	return nil
+}
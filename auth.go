@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// AuthScheme selects how credentials are attached to requests
+type AuthScheme int
+
+const (
+	// AuthKeyHeader sends the key in the bare "key" header only
+	AuthKeyHeader AuthScheme = iota
+	// AuthBearer also sends the key as an Authorization: Bearer token
+	AuthBearer
+	// AuthBasic also sends HTTP Basic credentials
+	AuthBasic
+)
+
+// WithBearerAuth sends the client key as a bearer token
+func WithBearerAuth() Option {
+	return func(m *MenousDB) {
+		m.authScheme = AuthBearer
+	}
+}
+
+// WithBasicAuth sends the given username and password using HTTP Basic auth
+func WithBasicAuth(username, password string) Option {
+	return func(m *MenousDB) {
+		m.authScheme = AuthBasic
+		m.basicUser = username
+		m.basicPass = password
+	}
+}
+
+// applyAuth sets the Authorization header for the configured scheme
+func (m *MenousDB) applyAuth(req *http.Request, key string) error {
+	if m.tokenSource != nil {
+		token, err := m.tokenSource.Token()
+		if err != nil {
+			return fmt.Errorf("fetching oauth2 token: %w", err)
+		}
+		token.SetAuthHeader(req)
+		return nil
+	}
+
+	switch m.authScheme {
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+key)
+	case AuthBasic:
+		req.SetBasicAuth(m.basicUser, m.basicPass)
+	}
+	return nil
+}
+
+// WithTokenSource attaches a bearer token from ts to every request. Tokens are
+// cached and refreshed through oauth2.ReuseTokenSource, so short-lived tokens
+// are renewed automatically when they expire.
+func WithTokenSource(ts oauth2.TokenSource) Option {
+	return func(m *MenousDB) {
+		m.tokenSource = oauth2.ReuseTokenSource(nil, ts)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Tabler lets a model choose its table name in AutoMigrate
+type Tabler interface {
+	TableName() string
+}
+
+// AutoMigrate creates the tables for models, which are structs or pointers
+// to structs, in the client's database and declares their columns
+// client-side. It reconciles the same way ApplySchema does: missing tables
+// are created, and fields missing from an existing table, as DescribeTable
+// sees it, are reported as unapplied add-attribute changes.
+//
+//	changes, err := db.AutoMigrate(&User{}, &Order{})
+//
+// A model's table name is its TableName method, or its type name in
+// snake_case and pluralised. Fields are named as encoding/json names them
+// unless a menousdb tag names them, in which case inserts of the struct send
+// the field under the tag's name:
+//
+//	Email string  `menousdb:"email,required"`
+//	Bio   string  `menousdb:",nullable"`
+//	Note  *string // pointers are nullable
+//	Skip  string  `menousdb:"-"`
+//
+// Other tag options are validation rules, kept in the column's Validate.
+func (m *MenousDB) AutoMigrate(models ...interface{}) ([]SchemaChange, error) {
+	db := DatabaseSpec{Name: m.Database}
+	for _, model := range models {
+		t, err := tableSpecOf(model)
+		if err != nil {
+			return nil, err
+		}
+		db.Tables = append(db.Tables, t)
+	}
+	return m.ApplySchema(&SchemaSpec{Databases: []DatabaseSpec{db}}, false)
+}
+
+// tableSpecOf derives a table declaration from a model struct
+func tableSpecOf(model interface{}) (TableSpec, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return TableSpec{}, fmt.Errorf("menousdb: AutoMigrate model must be a struct, got %T", model)
+	}
+
+	name := tableNameOf(t)
+	if tabler, ok := model.(Tabler); ok {
+		name = tabler.TableName()
+	}
+	spec := TableSpec{Name: name, Columns: structColumns(t)}
+	if len(spec.Columns) == 0 {
+		return TableSpec{}, fmt.Errorf("menousdb: model %s has no exported fields", t.Name())
+	}
+	return spec, nil
+}
+
+// structColumns returns a column definition for every encoded field of t,
+// flattening embedded structs as encoding/json does
+func structColumns(t reflect.Type) []ColumnDef {
+	var columns []ColumnDef
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, skip := fieldColumn(f)
+		if skip {
+			continue
+		}
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				columns = append(columns, structColumns(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		c := ColumnDef{Name: name, Type: columnTypeOf(ft)}
+		switch ft.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+			c.Nullable = true
+		}
+		var rules []string
+		for _, o := range opts {
+			switch o {
+			case "required":
+				c.Required = true
+			case "nullable":
+				c.Nullable = true
+			case "":
+			default:
+				rules = append(rules, o)
+			}
+		}
+		c.Validate = strings.Join(rules, ",")
+		columns = append(columns, c)
+	}
+	return columns
+}
+
+// fieldColumn splits a field's menousdb tag into name and options, taking
+// the name from its json tag when the menousdb tag leaves it empty
+func fieldColumn(f reflect.StructField) (name string, opts []string, skip bool) {
+	jsonName := f.Tag.Get("json")
+	if i := strings.IndexByte(jsonName, ','); i >= 0 {
+		jsonName = jsonName[:i]
+	}
+	parts := strings.Split(f.Tag.Get("menousdb"), ",")
+	if parts[0] == "-" || parts[0] == "" && jsonName == "-" {
+		return "", nil, true
+	}
+	name = parts[0]
+	if name == "" {
+		name = jsonName
+	}
+	return name, parts[1:], false
+}
+
+// columnTypeOf maps a Go field type to the column type its JSON encoding has
+func columnTypeOf(t reflect.Type) ColumnType {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return ColumnTime
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return ColumnString
+	case reflect.Bool:
+		return ColumnBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return ColumnInt
+	case reflect.Float32, reflect.Float64:
+		return ColumnFloat
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return ColumnString
+		}
+		return ColumnJSON
+	case reflect.Array, reflect.Map, reflect.Struct:
+		return ColumnJSON
+	}
+	return ColumnAny
+}
+
+// tableNameOf returns the snake_case plural of t's name: User becomes users,
+// OrderItem becomes order_items
+func tableNameOf(t reflect.Type) string {
+	var b strings.Builder
+	runes := []rune(t.Name())
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	name := b.String()
+	switch {
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "z"),
+		strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsRune("aeiou", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+	}
+	return name + "s"
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"io"
+	"time"
+)
+
+// Snapshot is the backup format written by Backup
+type Snapshot struct {
+	Database string                 `json:"database"`
+	Created  time.Time              `json:"created"`
+	Data     map[string]interface{} `json:"data"`
+}
+
+// Backup writes a JSON snapshot of the whole database to w
+func (m *MenousDB) Backup(w io.Writer) error {
+	return m.BackupAnonymized(w, nil)
+}
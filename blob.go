@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// BlobAttributes are the attributes of a table holding blobs: the blob key,
+// the chunk's position and the chunk count, the chunk's base64 data, and the
+// hex SHA-256 of the whole blob
+var BlobAttributes = []string{"blob", "chunk", "chunks", "data", "sha256"}
+
+// BlobOptions configures PutBlob
+type BlobOptions struct {
+	// ChunkSize splits blobs larger than it across rows of at most ChunkSize
+	// bytes each, before base64; zero stores every blob in one row
+	ChunkSize int
+}
+
+// EnsureBlobTable creates table with BlobAttributes if it does not exist
+func (m *MenousDB) EnsureBlobTable(table string) (bool, error) {
+	return m.EnsureTable(TableSpec{Name: table, Attributes: BlobAttributes})
+}
+
+// PutBlob stores data under key in table, replacing any blob stored there.
+// Rows are written one chunk at a time, so a failure part way leaves a blob
+// that GetBlob reports as corrupt until PutBlob succeeds.
+func (m *MenousDB) PutBlob(table, key string, data []byte, opts BlobOptions) error {
+	if err := m.DeleteBlob(table, key); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	size := opts.ChunkSize
+	if size <= 0 || size > len(data) {
+		size = len(data)
+	}
+	chunks := 1
+	if size > 0 {
+		chunks = (len(data) + size - 1) / size
+	}
+
+	for i := 0; i < chunks; i++ {
+		end := (i + 1) * size
+		if end > len(data) {
+			end = len(data)
+		}
+		row := Row{
+			"blob":   key,
+			"chunk":  i,
+			"chunks": chunks,
+			"data":   base64.StdEncoding.EncodeToString(data[i*size : end]),
+			"sha256": checksum,
+		}
+		if _, err := m.InsertIntoTable(table, row); err != nil {
+			return fmt.Errorf("writing chunk %d of %d: %w", i+1, chunks, err)
+		}
+	}
+	return nil
+}
+
+// GetBlob reassembles the blob stored under key in table and verifies its
+// checksum. It returns ErrBlobNotFound when nothing is stored under key and
+// ErrBlobCorrupt when chunks are missing or the checksum does not match.
+func (m *MenousDB) GetBlob(table, key string) ([]byte, error) {
+	rows, err := m.SelectWhereRows(table, map[string]interface{}{"blob": key})
+	if err != nil {
+		return nil, err
+	}
+	return assembleBlob(key, rows)
+}
+
+// DeleteBlob removes the blob stored under key in table
+func (m *MenousDB) DeleteBlob(table, key string) error {
+	_, err := m.DeleteWhere(table, map[string]interface{}{"blob": key})
+	return err
+}
+
+// blobChunk is one decoded chunk row
+type blobChunk struct {
+	index int
+	data  []byte
+}
+
+// assembleBlob joins the chunk rows of one blob in order and checks them
+func assembleBlob(key string, rows []Row) ([]byte, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrBlobNotFound, key)
+	}
+
+	want, err := toFloat(rows[0]["chunks"])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q has no chunk count", ErrBlobCorrupt, key)
+	}
+	checksum, _ := rows[0]["sha256"].(string)
+
+	chunks := make([]blobChunk, 0, len(rows))
+	seen := make(map[int]bool, len(rows))
+	for _, row := range rows {
+		index, err := toFloat(row["chunk"])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q has a chunk without a position", ErrBlobCorrupt, key)
+		}
+		if seen[int(index)] {
+			continue
+		}
+		seen[int(index)] = true
+		text, _ := row["data"].(string)
+		data, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q chunk %d: %v", ErrBlobCorrupt, key, int(index), err)
+		}
+		chunks = append(chunks, blobChunk{index: int(index), data: data})
+	}
+	if len(chunks) != int(want) {
+		return nil, fmt.Errorf("%w: %q has %d of %d chunks", ErrBlobCorrupt, key, len(chunks), int(want))
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].index < chunks[j].index })
+
+	var out []byte
+	for _, c := range chunks {
+		out = append(out, c.data...)
+	}
+	sum := sha256.Sum256(out)
+	if hex.EncodeToString(sum[:]) != checksum {
+		return nil, fmt.Errorf("%w: %q checksum mismatch", ErrBlobCorrupt, key)
+	}
+	return out, nil
+}
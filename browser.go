@@ -0,0 +1,642 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// browserView is the screen a Browser shows
+type browserView int
+
+const (
+	viewDatabases browserView = iota
+	viewTables
+	viewRows
+)
+
+// Browser is a terminal UI for browsing databases and tables, paging
+// through rows, filtering them and editing cells. It follows the
+// model-update-view shape: Update applies a key press to the state and View
+// renders the state to a screen of text, so the UI can be driven without a
+// terminal; Run connects the two to one.
+//
+// Filters use the shell's where syntax, such as age > 30 and city = 'Lyon'.
+// Edits and deletes match the row by every scalar value it holds, so rows
+// that are exact duplicates change together.
+type Browser struct {
+	Client *MenousDB
+
+	// MaxRows caps the rows loaded for a table; defaults to 10000
+	MaxRows int
+
+	view    browserView
+	db      string
+	table   string
+	items   []string
+	matches []string
+	rows    []Row
+	columns []string
+	filter  string
+	search  string
+
+	cursor, top int // selected list item or row, and the first one shown
+	col, left   int // selected column, and the first one shown
+	pageH       int
+	input       *browserInput
+	status      string
+	quit        bool
+}
+
+// browserInput is a line being typed at the bottom of the screen
+type browserInput struct {
+	label  string
+	text   string
+	submit func(text string)
+}
+
+// NewBrowser returns a browser opened on the client's database, or on the
+// list of databases when the client has none
+func NewBrowser(client *MenousDB) *Browser {
+	b := &Browser{Client: client, pageH: 20}
+	if client.Database != "" {
+		b.openDatabase(client.Database)
+	} else {
+		b.loadDatabases()
+	}
+	return b
+}
+
+// Quit reports whether the user asked to leave
+func (b *Browser) Quit() bool {
+	return b.quit
+}
+
+// maxRows returns the configured row cap or the default
+func (b *Browser) maxRows() int {
+	if b.MaxRows <= 0 {
+		return 10000
+	}
+	return b.MaxRows
+}
+
+// client returns the client for the open database
+func (b *Browser) client() *MenousDB {
+	return b.Client.ForDatabase(b.db)
+}
+
+func (b *Browser) loadDatabases() {
+	b.view, b.db, b.table = viewDatabases, "", ""
+	result, err := b.Client.GetDatabases()
+	if err != nil {
+		b.setList(nil)
+		b.status = "error: " + err.Error()
+		return
+	}
+	b.setList(databaseNames(result))
+}
+
+func (b *Browser) openDatabase(db string) {
+	b.view, b.db, b.table = viewTables, db, ""
+	tables, err := b.client().ListTables()
+	b.setList(tables)
+	if err != nil {
+		b.status = "error: " + err.Error()
+	}
+}
+
+func (b *Browser) openTable(table string) {
+	b.view, b.table, b.filter = viewRows, table, ""
+	b.cursor, b.top, b.col, b.left = 0, 0, 0, 0
+	b.loadRows()
+}
+
+// setList shows names in a list view, clearing its search
+func (b *Browser) setList(names []string) {
+	b.items, b.matches, b.search = names, names, ""
+	b.cursor, b.top = 0, 0
+	b.status = ""
+}
+
+// applySearch narrows the list to names containing the search text
+func (b *Browser) applySearch(search string) {
+	b.search = search
+	b.matches = nil
+	for _, n := range b.items {
+		if strings.Contains(strings.ToLower(n), strings.ToLower(search)) {
+			b.matches = append(b.matches, n)
+		}
+	}
+	b.cursor, b.top = 0, 0
+}
+
+// loadRows fetches the open table's rows under the current filter
+func (b *Browser) loadRows() {
+	b.rows, b.columns = nil, nil
+	q := b.client().Select(b.table).Limit(b.maxRows())
+	if b.filter != "" {
+		st, err := parseShell("select * from t where " + b.filter)
+		if err != nil {
+			b.status = "filter: " + err.Error()
+			return
+		}
+		conds, exact := st.where.equalities()
+		q.Where(conds)
+		if !exact {
+			q.Filter(st.where.predicate())
+		}
+	}
+	rows, err := q.Rows()
+	if err != nil {
+		b.status = "error: " + err.Error()
+		return
+	}
+	b.rows, b.columns = rows, inferColumns(rows)
+	b.clamp()
+	b.status = fmt.Sprintf("%d rows", len(rows))
+	if len(rows) == b.maxRows() {
+		b.status = fmt.Sprintf("first %d rows; filter to narrow", len(rows))
+	}
+}
+
+// Update applies one key press. Keys are single characters or the names
+// readKeys gives special keys, such as up, pgdown, enter and esc.
+func (b *Browser) Update(key string) {
+	if key == "ctrl+c" {
+		b.quit = true
+		return
+	}
+	if b.input != nil {
+		b.updateInput(key)
+		return
+	}
+	if strings.HasPrefix(b.status, "error: ") {
+		b.status = ""
+	}
+	if b.view == viewRows {
+		b.updateRows(key)
+	} else {
+		b.updateList(key)
+	}
+	b.clamp()
+}
+
+// updateInput edits the line being typed
+func (b *Browser) updateInput(key string) {
+	in := b.input
+	switch key {
+	case "enter":
+		b.input = nil
+		in.submit(in.text)
+	case "esc":
+		b.input = nil
+	case "backspace":
+		if _, size := utf8.DecodeLastRuneInString(in.text); size > 0 {
+			in.text = in.text[:len(in.text)-size]
+		}
+	default:
+		if utf8.RuneCountInString(key) == 1 {
+			in.text += key
+		}
+	}
+	b.clamp()
+}
+
+// prompt starts reading a line
+func (b *Browser) prompt(label, text string, submit func(string)) {
+	b.input = &browserInput{label: label, text: text, submit: submit}
+}
+
+func (b *Browser) updateList(key string) {
+	switch key {
+	case "q":
+		b.quit = true
+	case "up", "k":
+		b.cursor--
+	case "down", "j":
+		b.cursor++
+	case "pgup":
+		b.cursor -= b.pageH
+	case "pgdown", " ":
+		b.cursor += b.pageH
+	case "home", "g":
+		b.cursor = 0
+	case "end", "G":
+		b.cursor = len(b.matches) - 1
+	case "/":
+		b.prompt("search", b.search, b.applySearch)
+	case "r":
+		if b.view == viewDatabases {
+			b.loadDatabases()
+		} else {
+			b.openDatabase(b.db)
+		}
+	case "enter", "right", "l":
+		if b.cursor < 0 || b.cursor >= len(b.matches) {
+			return
+		}
+		name := b.matches[b.cursor]
+		if b.view == viewDatabases {
+			b.openDatabase(name)
+		} else {
+			b.openTable(name)
+		}
+	case "esc", "backspace", "left", "h":
+		if b.view == viewTables && b.Client.Database == "" {
+			b.loadDatabases()
+		}
+	}
+}
+
+func (b *Browser) updateRows(key string) {
+	switch key {
+	case "q":
+		b.quit = true
+	case "up", "k":
+		b.cursor--
+	case "down", "j":
+		b.cursor++
+	case "left", "h":
+		b.col--
+	case "right", "l", "tab":
+		b.col++
+	case "pgup":
+		b.cursor -= b.pageH
+	case "pgdown", " ":
+		b.cursor += b.pageH
+	case "home", "g":
+		b.cursor = 0
+	case "end", "G":
+		b.cursor = len(b.rows) - 1
+	case "0":
+		b.col = 0
+	case "$":
+		b.col = len(b.columns) - 1
+	case "/", "f":
+		b.prompt("where", b.filter, func(text string) {
+			b.filter = strings.TrimSpace(text)
+			b.cursor, b.top = 0, 0
+			b.loadRows()
+		})
+	case "r":
+		b.loadRows()
+	case "enter", "e":
+		r, c, ok := b.selected()
+		if !ok {
+			return
+		}
+		conds, n, err := b.matchRow(r)
+		if err != nil {
+			b.status = "error: " + err.Error()
+			return
+		}
+		text := ""
+		if v, ok := r[c]; ok {
+			text = literalText(v)
+		}
+		label := "set " + c
+		if n > 1 {
+			label = fmt.Sprintf("set %s in all %d identical rows", c, n)
+		}
+		b.prompt(label, text, func(text string) { b.editCell(r, conds, n, c, text) })
+	case "d", "delete":
+		r, _, ok := b.selected()
+		if !ok {
+			return
+		}
+		conds, n, err := b.matchRow(r)
+		if err != nil {
+			b.status = "error: " + err.Error()
+			return
+		}
+		i := b.cursor
+		label := "delete this row? (y/n)"
+		if n > 1 {
+			label = fmt.Sprintf("delete all %d identical rows? (y/n)", n)
+		}
+		b.prompt(label, "", func(text string) {
+			if strings.EqualFold(strings.TrimSpace(text), "y") {
+				b.deleteRow(i, conds, n)
+			}
+		})
+	case "esc", "backspace":
+		b.openDatabase(b.db)
+	}
+}
+
+// selected returns the row and column under the cursor
+func (b *Browser) selected() (Row, string, bool) {
+	if b.cursor < 0 || b.cursor >= len(b.rows) || b.col < 0 || b.col >= len(b.columns) {
+		return nil, "", false
+	}
+	return b.rows[b.cursor], b.columns[b.col], true
+}
+
+// matchRow returns the conditions identifying row r and how many rows of
+// the table they match. A row with no scalar values cannot be identified:
+// empty conditions would match the whole table.
+func (b *Browser) matchRow(r Row) (map[string]interface{}, int, error) {
+	conds := rowConditions(r)
+	if len(conds) == 0 {
+		return nil, 0, fmt.Errorf("row has no scalar values to identify it by")
+	}
+	rows, err := b.client().SelectWhereRows(b.table, conds)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(rows) == 0 {
+		return nil, 0, fmt.Errorf("row no longer exists; press r to reload")
+	}
+	return conds, len(rows), nil
+}
+
+// editCell sets column c of row r, and of the n rows conds match, to the
+// value text spells
+func (b *Browser) editCell(r Row, conds map[string]interface{}, n int, c, text string) {
+	v := parseLiteral(text)
+	if _, err := b.client().UpdateWhere(b.table, conds, map[string]interface{}{c: v}); err != nil {
+		b.status = "error: " + err.Error()
+		return
+	}
+	r[c] = v
+	b.status = fmt.Sprintf("updated %s", c)
+	if n > 1 {
+		b.status = fmt.Sprintf("updated %s in %d identical rows; press r to reload", c, n)
+	}
+}
+
+// deleteRow deletes the i'th row along with the rest of the n rows conds
+// match
+func (b *Browser) deleteRow(i int, conds map[string]interface{}, n int) {
+	if _, err := b.client().DeleteWhere(b.table, conds); err != nil {
+		b.status = "error: " + err.Error()
+		return
+	}
+	b.rows = append(b.rows[:i], b.rows[i+1:]...)
+	b.clamp()
+	b.status = "deleted row"
+	if n > 1 {
+		b.status = fmt.Sprintf("deleted %d identical rows; press r to reload", n)
+	}
+}
+
+// rowConditions matches r by its scalar values, which the server can compare
+func rowConditions(r Row) map[string]interface{} {
+	conds := make(map[string]interface{}, len(r))
+	for k, v := range r {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			continue
+		}
+		conds[k] = v
+	}
+	return conds
+}
+
+// parseLiteral reads text as a shell literal, such as 42, 'text', true or
+// null, and otherwise as the string itself
+func parseLiteral(text string) interface{} {
+	toks, err := lexShell(text)
+	if err == nil && len(toks) == 2 {
+		p := &shellParser{toks: toks}
+		if v, err := p.value(); err == nil {
+			return v
+		}
+	}
+	return text
+}
+
+// literalText spells v so that parseLiteral reads it back
+func literalText(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		if _, isString := parseLiteral(v).(string); isString && !strings.ContainsAny(v, "'\"") {
+			return v
+		}
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	}
+	return shellCell(v)
+}
+
+// clamp keeps the cursors in range and on screen
+func (b *Browser) clamp() {
+	n := len(b.matches)
+	if b.view == viewRows {
+		n = len(b.rows)
+		b.col = clampInt(b.col, 0, len(b.columns)-1)
+		if b.col < b.left {
+			b.left = b.col
+		}
+	}
+	b.cursor = clampInt(b.cursor, 0, n-1)
+	if b.cursor < b.top {
+		b.top = b.cursor
+	}
+	if b.cursor >= b.top+b.pageH {
+		b.top = b.cursor - b.pageH + 1
+	}
+}
+
+// clampInt bounds v to [lo, hi], preferring lo when the range is empty
+func clampInt(v, lo, hi int) int {
+	if v > hi {
+		v = hi
+	}
+	if v < lo {
+		v = lo
+	}
+	return v
+}
+
+// Terminal escape sequences used by View
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiReverse = "\x1b[7m"
+)
+
+// View renders the browser on a width by height screen, one string per line
+func (b *Browser) View(width, height int) []string {
+	b.pageH = height - 4
+	if b.view == viewRows {
+		b.pageH-- // column header
+	}
+	if b.pageH < 1 {
+		b.pageH = 1
+	}
+	b.clamp()
+
+	title := " menousdb"
+	if b.db != "" {
+		title += " › " + b.db
+	}
+	if b.table != "" {
+		title += " › " + b.table
+	}
+	if b.filter != "" && b.view == viewRows {
+		title += "  where " + b.filter
+	}
+	if b.search != "" && b.view != viewRows {
+		title += "  /" + b.search
+	}
+	lines := []string{ansiReverse + padRight(title, width) + ansiReset}
+
+	if b.view == viewRows {
+		lines = append(lines, b.viewRows(width)...)
+	} else {
+		lines = append(lines, b.viewList(width)...)
+	}
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+
+	status := b.status
+	if b.input != nil {
+		status = b.input.label + ": " + b.input.text + "█"
+	}
+	lines = append(lines, padRight(status, width))
+	help := "↑↓ move  enter open  / search  r reload  esc back  q quit"
+	if b.view == viewRows {
+		help = "↑↓←→ move  pgup/pgdn page  enter edit  d delete  / filter  r reload  esc back  q quit"
+	}
+	return append(lines, ansiDim+padRight(help, width)+ansiReset)
+}
+
+// viewList renders the database or table list
+func (b *Browser) viewList(width int) []string {
+	var lines []string
+	if len(b.matches) == 0 {
+		return []string{ansiDim + " (none)" + ansiReset}
+	}
+	for i := b.top; i < len(b.matches) && i < b.top+b.pageH; i++ {
+		line := padRight("  "+b.matches[i], width)
+		if i == b.cursor {
+			line = ansiReverse + line + ansiReset
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// maxCellWidth caps how wide a column is drawn
+const maxCellWidth = 32
+
+// viewRows renders the page of rows around the cursor
+func (b *Browser) viewRows(width int) []string {
+	if len(b.columns) == 0 {
+		return []string{ansiDim + " (no rows)" + ansiReset}
+	}
+	end := b.top + b.pageH
+	if end > len(b.rows) {
+		end = len(b.rows)
+	}
+	page := b.rows[b.top:end]
+
+	widths := make([]int, len(b.columns))
+	for i, c := range b.columns {
+		widths[i] = utf8.RuneCountInString(c)
+		for _, r := range page {
+			if w := utf8.RuneCountInString(shellCell(r[c])); w > widths[i] {
+				widths[i] = w
+			}
+		}
+		if widths[i] > maxCellWidth {
+			widths[i] = maxCellWidth
+		}
+	}
+
+	// Scroll right until the selected column fits
+	for b.left < b.col {
+		used := 0
+		for i := b.left; i <= b.col; i++ {
+			used += widths[i] + 2
+		}
+		if used <= width {
+			break
+		}
+		b.left++
+	}
+
+	render := func(cell func(i int, c string) string) string {
+		var sb strings.Builder
+		used := 0
+		for i := b.left; i < len(b.columns); i++ {
+			if used+widths[i]+2 > width && i > b.left {
+				break
+			}
+			sb.WriteString(cell(i, b.columns[i]))
+			used += widths[i] + 2
+		}
+		return sb.String()
+	}
+	lines := []string{render(func(i int, c string) string {
+		return ansiBold + " " + padRight(c, widths[i]) + " " + ansiReset
+	})}
+	for n, r := range page {
+		rowIndex := b.top + n
+		lines = append(lines, render(func(i int, c string) string {
+			cell := " " + padRight(shellCell(r[c]), widths[i]) + " "
+			switch {
+			case rowIndex == b.cursor && i == b.col:
+				return ansiReverse + ansiBold + cell + ansiReset
+			case rowIndex == b.cursor:
+				return ansiReverse + cell + ansiReset
+			}
+			return cell
+		}))
+	}
+	return lines
+}
+
+// padRight truncates or pads s to exactly w runes, marking truncation with
+// an ellipsis
+func padRight(s string, w int) string {
+	n := utf8.RuneCountInString(s)
+	if n > w {
+		if w <= 0 {
+			return ""
+		}
+		runes := []rune(s)
+		return string(runes[:w-1]) + "…"
+	}
+	return s + strings.Repeat(" ", w-n)
+}
+
+// Run shows the browser full screen on the terminal in until the user
+// quits
+func (b *Browser) Run(in *os.File, out io.Writer) error {
+	restore, err := makeRaw(in)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	// Alternate screen with the cursor hidden, put back on exit
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan string)
+	errc := make(chan error, 1)
+	go func() { errc <- readKeys(in, keys) }()
+	for {
+		width, height := terminalSize(in)
+		screen := "\x1b[H\x1b[2J" + strings.Join(b.View(width, height), "\r\n")
+		if _, err := io.WriteString(out, screen); err != nil {
+			return err
+		}
+		select {
+		case key := <-keys:
+			b.Update(key)
+		case err := <-errc:
+			return err
+		}
+		if b.quit {
+			return nil
+		}
+	}
+}
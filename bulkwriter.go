@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BulkWriterOptions configures a BulkWriter. A flush starts when any limit
+// is reached.
+type BulkWriterOptions struct {
+	// MaxRows flushes once this many rows are buffered; defaults to 500
+	MaxRows int
+
+	// MaxBytes flushes once the buffered rows' JSON reaches this size; zero
+	// means no size limit
+	MaxBytes int
+
+	// Interval flushes buffered rows at least this often; zero flushes only
+	// on the other limits, Flush and Close
+	Interval time.Duration
+
+	// Concurrency bounds the inserts in flight; defaults to 4. Write blocks
+	// while every slot is busy, so producers cannot outrun the server.
+	Concurrency int
+
+	// OnError, if set, is called for each row that failed to insert
+	OnError func(row Row, err error)
+}
+
+// BulkWriter buffers rows for one table and inserts them in the background,
+// the usual shape for high-throughput ingestion. It is safe for concurrent
+// use. The client's Close flushes it.
+type BulkWriter struct {
+	client *MenousDB
+	table  string
+	opts   BulkWriterOptions
+	sem    chan struct{}
+	stop   chan struct{}
+
+	mu      sync.Mutex
+	buf     []Row
+	bytes   int
+	closed  bool
+	failed  int
+	lastErr error
+}
+
+// NewBulkWriter starts a writer inserting into table
+func (m *MenousDB) NewBulkWriter(table string, opts BulkWriterOptions) *BulkWriter {
+	if opts.MaxRows <= 0 {
+		opts.MaxRows = 500
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	w := &BulkWriter{
+		client: m,
+		table:  table,
+		opts:   opts,
+		sem:    make(chan struct{}, opts.Concurrency),
+		stop:   make(chan struct{}),
+	}
+	if opts.Interval > 0 {
+		go w.tick()
+	}
+	m.RegisterFlusher(w)
+	return w
+}
+
+// Write buffers row, starting a flush when a limit is reached
+func (w *BulkWriter) Write(row Row) error {
+	size := 0
+	if w.opts.MaxBytes > 0 {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		size = len(data)
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrWriterClosed
+	}
+	w.buf = append(w.buf, row)
+	w.bytes += size
+	var batch []Row
+	if len(w.buf) >= w.opts.MaxRows || w.opts.MaxBytes > 0 && w.bytes >= w.opts.MaxBytes {
+		batch = w.take()
+	}
+	w.mu.Unlock()
+
+	w.dispatch(batch)
+	return nil
+}
+
+// Flush starts inserting the buffered rows and waits for every insert in
+// flight to finish or ctx to end. It reports how many rows failed since the
+// previous Flush, with the last failure.
+func (w *BulkWriter) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	batch := w.take()
+	w.mu.Unlock()
+	w.dispatch(batch)
+
+	// Holding every slot at once means no insert is still running
+	for held := 0; held < cap(w.sem); held++ {
+		select {
+		case w.sem <- struct{}{}:
+		case <-ctx.Done():
+			for ; held > 0; held-- {
+				<-w.sem
+			}
+			return ctx.Err()
+		}
+	}
+	for held := cap(w.sem); held > 0; held-- {
+		<-w.sem
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failed == 0 {
+		return nil
+	}
+	err := fmt.Errorf("menousdb: %d rows failed to insert into %s: %w", w.failed, w.table, w.lastErr)
+	w.failed, w.lastErr = 0, nil
+	return err
+}
+
+// Close flushes the writer and stops it; later writes return
+// ErrWriterClosed
+func (w *BulkWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.stop)
+	}
+	w.mu.Unlock()
+	return w.Flush(ctx)
+}
+
+// take empties the buffer; w.mu must be held
+func (w *BulkWriter) take() []Row {
+	batch := w.buf
+	w.buf = nil
+	w.bytes = 0
+	return batch
+}
+
+// dispatch inserts batch's rows concurrently, blocking while every slot is
+// busy
+func (w *BulkWriter) dispatch(batch []Row) {
+	for _, row := range batch {
+		w.sem <- struct{}{}
+		go func(row Row) {
+			defer func() { <-w.sem }()
+			if _, err := w.client.InsertIntoTable(w.table, row); err != nil {
+				w.mu.Lock()
+				w.failed++
+				w.lastErr = err
+				w.mu.Unlock()
+				if w.opts.OnError != nil {
+					w.opts.OnError(row, err)
+				}
+			}
+		}(row)
+	}
+}
+
+// tick flushes on the interval until the writer is closed
+func (w *BulkWriter) tick() {
+	t := time.NewTicker(w.opts.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			w.mu.Lock()
+			batch := w.take()
+			w.mu.Unlock()
+			w.dispatch(batch)
+		case <-w.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for the read cache
+const (
+	DefaultCacheTTL           = 30 * time.Second
+	DefaultExistsTTL          = 5 * time.Second
+	DefaultCacheMaxEntries    = 1024
+	DefaultCacheMaxEntryBytes = 1 << 20
+)
+
+// readEndpoints lists the read endpoints whose responses are cached
+var readEndpoints = []string{
+	"read-db",
+	"get-databases",
+	"get-table",
+	"select-where",
+	"select-columns",
+	"select-columns-where",
+}
+
+// existsEndpoints lists the existence checks, cached on their own TTL
+var existsEndpoints = []string{
+	"check-db-exists",
+	"check-table-exists",
+}
+
+// cacheRule says how long an endpoint's responses stay fresh and for how
+// much longer they may be served stale
+type cacheRule struct {
+	ttl   time.Duration
+	stale time.Duration
+}
+
+// CacheOptions configures the read cache
+type CacheOptions struct {
+	// TTL is how long a response is served without asking the server;
+	// defaults to DefaultCacheTTL
+	TTL time.Duration
+
+	// StaleTTL extends each entry past TTL with a stale-while-revalidate
+	// window: the cached response is still returned at once while a
+	// background request refreshes it. Zero disables the window.
+	StaleTTL time.Duration
+
+	// OnRefresh is called after a background refresh brings back a response
+	// that differs from the cached one, so UIs can re-render
+	OnRefresh func(CacheRefresh)
+
+	// ExistsTTL is how long existence checks, positive or negative, are
+	// cached, with no stale window; defaults to DefaultExistsTTL. A
+	// negative value leaves them uncached.
+	ExistsTTL time.Duration
+
+	// MaxEntries bounds the cache, evicting the least recently used entry;
+	// defaults to DefaultCacheMaxEntries
+	MaxEntries int
+
+	// MaxEntryBytes skips caching larger responses; defaults to
+	// DefaultCacheMaxEntryBytes
+	MaxEntryBytes int
+
+	// WriteThrough applies successful inserts and updates to the cached
+	// reads of their table instead of invalidating them, so hot queries
+	// stay warm. The server does not echo the rows it stores, so patches
+	// use the values the client sent and are best-effort approximations:
+	// anything the server adds or changes on write, such as generated ids,
+	// its own defaults or type coercion, is missing from cached reads until
+	// the entry expires or is invalidated. Leave it off where reads must
+	// match the stored data exactly. Entries a write cannot be applied to
+	// at all, such as those filtering on a column an update changes, are
+	// still dropped. A shared cache tier is invalidated either way.
+	WriteThrough bool
+}
+
+// CacheRefresh describes a cached response replaced by fresher data
+type CacheRefresh struct {
+	Endpoint string
+	Database string
+	Table    string
+	Body     json.RawMessage
+}
+
+// cacheEntry is one cached response
+type cacheEntry struct {
+	key        string
+	endpoint   string
+	request    interface{}
+	database   string
+	table      string
+	status     int
+	header     http.Header
+	body       []byte
+	rule       cacheRule
+	freshUntil time.Time
+	staleUntil time.Time
+	refreshing bool
+	elem       *list.Element
+}
+
+// readCache holds cached read responses, shared by clones. Writes through
+// any client sharing it invalidate the entries they could affect.
+type readCache struct {
+	opts   CacheOptions
+	rules  map[string]cacheRule
+	shared *SharedCache
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	lru     *list.List
+	gen     uint64
+}
+
+// WithCache caches read responses and existence checks in memory.
+// Successful writes invalidate cached reads of the table they touched, and
+// of the whole database for database and table level changes.
+func WithCache(opts CacheOptions) Option {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultCacheTTL
+	}
+	if opts.ExistsTTL == 0 {
+		opts.ExistsTTL = DefaultExistsTTL
+	}
+	return func(m *MenousDB) {
+		c := newReadCache(opts)
+		for _, e := range readEndpoints {
+			c.rules[e] = cacheRule{ttl: opts.TTL, stale: opts.StaleTTL}
+		}
+		if opts.ExistsTTL > 0 {
+			for _, e := range existsEndpoints {
+				c.rules[e] = cacheRule{ttl: opts.ExistsTTL}
+			}
+		}
+		m.cache = c
+	}
+}
+
+// WithExistsCache caches only existence checks, positive and negative, for
+// ttl, so code that guards every write with CheckTableExists or TableExists
+// does not double its request volume. Creating or deleting the database or
+// table invalidates the cached answer. Combined with WithCache, whichever
+// option comes last sets the existence TTL.
+func WithExistsCache(ttl time.Duration) Option {
+	return func(m *MenousDB) {
+		var c *readCache
+		if m.cache != nil {
+			c = newReadCache(m.cache.opts)
+			for e, r := range m.cache.rules {
+				c.rules[e] = r
+			}
+		} else {
+			c = newReadCache(CacheOptions{})
+		}
+		for _, e := range existsEndpoints {
+			c.rules[e] = cacheRule{ttl: ttl}
+		}
+		m.cache = c
+	}
+}
+
+// newReadCache creates an empty cache, filling in size defaults
+func newReadCache(opts CacheOptions) *readCache {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultCacheMaxEntries
+	}
+	if opts.MaxEntryBytes <= 0 {
+		opts.MaxEntryBytes = DefaultCacheMaxEntryBytes
+	}
+	return &readCache{
+		opts:    opts,
+		rules:   make(map[string]cacheRule),
+		entries: make(map[string]*cacheEntry),
+		lru:     list.New(),
+	}
+}
+
+// rule returns the caching rule for endpoint, ignoring any query string
+func (c *readCache) rule(endpoint string) (cacheRule, bool) {
+	r, ok := c.rules[strings.SplitN(endpoint, "?", 2)[0]]
+	return r, ok
+}
+
+// PurgeCache drops every cached response
+func (m *MenousDB) PurgeCache() {
+	if m.cache == nil {
+		return
+	}
+	m.cache.mu.Lock()
+	defer m.cache.mu.Unlock()
+	m.cache.entries = make(map[string]*cacheEntry)
+	m.cache.lru.Init()
+	m.cache.gen++
+}
+
+// uncached returns a copy of the client that bypasses the cache
+func (m *MenousDB) uncached() *MenousDB {
+	if m.cache == nil {
+		return m
+	}
+	c := *m
+	c.cache = nil
+	return &c
+}
+
+// cacheKey identifies a request by method, endpoint, scope and payload.
+// scope is the client's cacheScope, so clients sharing a cache with
+// different credentials or API versions never see each other's entries.
+func cacheKey(method, endpoint, scope string, headers map[string]string, body interface{}) string {
+	payload, _ := json.Marshal(body)
+	sum := sha256.Sum256(payload)
+	return strings.Join([]string{scope, method, endpoint, headers["database"], headers["table"], hex.EncodeToString(sum[:8])}, "|")
+}
+
+// cacheScope hashes what decides which responses the client may see: the
+// key resolved for database, the other credentials and the API version
+func (m *MenousDB) cacheScope(database string) (string, error) {
+	key, err := m.resolveKey(context.Background(), database)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%p", key, m.apiVersion, m.basicUser, m.basicPass, m.tokenSource)
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// cachedRequest serves a read from the cache when possible, otherwise
+// performs it and caches the response
+func (m *MenousDB) cachedRequest(rule cacheRule, method, endpoint string, headers map[string]string, body interface{}, params bool) (*http.Response, error) {
+	scope, err := m.cacheScope(headers["database"])
+	if err != nil {
+		return nil, err
+	}
+	c := m.cache
+	key := cacheKey(method, endpoint, scope, headers, body)
+	now := time.Now()
+
+	c.mu.Lock()
+	e := c.entries[key]
+	if e != nil && now.Before(e.staleUntil) {
+		c.lru.MoveToFront(e.elem)
+		if !now.Before(e.freshUntil) && !e.refreshing {
+			e.refreshing = true
+			go m.revalidate(rule, key, method, endpoint, headers, body, params)
+		}
+		resp := e.response()
+		c.mu.Unlock()
+		return resp, nil
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	if c.shared != nil {
+		if e := c.shared.load(key, rule, endpoint, headers, body); e != nil {
+			c.store(gen, e)
+			return e.response(), nil
+		}
+	}
+
+	resp, err := m.roundTrip(method, endpoint, headers, body, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read enough to know whether the response fits in the cache
+	limit := int64(c.opts.MaxEntryBytes)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		resp.Body = readCloser{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	e = &cacheEntry{
+		key:      key,
+		rule:     rule,
+		endpoint: endpoint,
+		request:  body,
+		database: headers["database"],
+		table:    headers["table"],
+		status:   resp.StatusCode,
+		header:   resp.Header.Clone(),
+		body:     data,
+	}
+	if c.store(gen, e) && c.shared != nil {
+		c.shared.save(e)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+// revalidate refreshes a stale entry in the background
+func (m *MenousDB) revalidate(rule cacheRule, key, method, endpoint string, headers map[string]string, body interface{}, params bool) {
+	c := m.cache
+	c.mu.Lock()
+	gen := c.gen
+	c.mu.Unlock()
+
+	var data []byte
+	resp, err := m.roundTrip(method, endpoint, headers, body, params)
+	if err == nil {
+		data, err = io.ReadAll(io.LimitReader(resp.Body, int64(c.opts.MaxEntryBytes)+1))
+		resp.Body.Close()
+	}
+
+	c.mu.Lock()
+	old := c.entries[key]
+	if old != nil {
+		old.refreshing = false
+	}
+	c.mu.Unlock()
+	if err != nil || len(data) > c.opts.MaxEntryBytes {
+		// Keep serving the stale entry until its window closes
+		return
+	}
+
+	changed := old == nil || !bytes.Equal(old.body, data)
+	e := &cacheEntry{
+		key:      key,
+		rule:     rule,
+		endpoint: endpoint,
+		request:  body,
+		database: headers["database"],
+		table:    headers["table"],
+		status:   resp.StatusCode,
+		header:   resp.Header.Clone(),
+		body:     data,
+	}
+	stored := c.store(gen, e)
+	if stored && c.shared != nil {
+		c.shared.save(e)
+	}
+	if stored && changed && c.opts.OnRefresh != nil {
+		c.opts.OnRefresh(CacheRefresh{
+			Endpoint: endpoint,
+			Database: headers["database"],
+			Table:    headers["table"],
+			Body:     data,
+		})
+	}
+}
+
+// store inserts e unless the cache was invalidated since gen was read, which
+// would mean e may predate a write. It reports whether e was stored.
+func (c *readCache) store(gen uint64, e *cacheEntry) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != gen {
+		return false
+	}
+
+	now := time.Now()
+	e.freshUntil = now.Add(e.rule.ttl)
+	e.staleUntil = e.freshUntil.Add(e.rule.stale)
+	if old := c.entries[e.key]; old != nil {
+		c.lru.Remove(old.elem)
+	}
+	e.elem = c.lru.PushFront(e)
+	c.entries[e.key] = e
+
+	for c.lru.Len() > c.opts.MaxEntries {
+		oldest := c.lru.Remove(c.lru.Back()).(*cacheEntry)
+		delete(c.entries, oldest.key)
+	}
+	return true
+}
+
+// invalidate drops entries a successful write to endpoint could affect
+func (c *readCache) invalidate(endpoint, database, table string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+
+	// Row writes affect their table; structural changes the whole database
+	// and, for database changes, the server-wide listing
+	tableOnly := table != "" && (endpoint == "insert-into-table" || endpoint == "update-table" || endpoint == "delete-where")
+	for key, e := range c.entries {
+		var hit bool
+		switch {
+		case tableOnly:
+			hit = e.database == database && e.table == table
+		case database != "":
+			hit = e.database == database || e.database == ""
+		default:
+			hit = true
+		}
+		if hit {
+			c.lru.Remove(e.elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// response builds a response serving the cached body
+func (e *cacheEntry) response() *http.Response {
+	return &http.Response{
+		StatusCode: e.status,
+		Status:     http.StatusText(e.status),
+		Header:     e.header.Clone(),
+		Body:       io.NopCloser(bytes.NewReader(e.body)),
+	}
+}
+
+// readCloser pairs a reader with the closer of the stream behind it
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
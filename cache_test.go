@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// keyedServer serves get-table only to the admin key, counting requests
+func keyedServer(t *testing.T, requests *int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.Header.Get("key") != "admin" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"1": map[string]interface{}{"secret": "s3"}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCacheScopedByKey(t *testing.T) {
+	var requests int
+	srv := keyedServer(t, &requests)
+	admin := NewMenousDB(srv.URL, "admin", "db", WithCache(CacheOptions{}))
+
+	if _, err := admin.GetTable("t"); err != nil {
+		t.Fatalf("admin GetTable: %v", err)
+	}
+	if _, err := admin.GetTable("t"); err != nil {
+		t.Fatalf("admin GetTable again: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("admin reads made %d requests, want 1 cached", requests)
+	}
+
+	guest := admin.Clone(WithKey("guest"))
+	if rows, err := guest.GetTable("t"); err == nil {
+		t.Fatalf("guest GetTable served %v from the admin's cache", rows)
+	}
+	if requests != 2 {
+		t.Fatalf("guest read made %d requests in total, want 2", requests)
+	}
+}
+
+func TestCacheScopedByAPIVersion(t *testing.T) {
+	var requests int
+	srv := keyedServer(t, &requests)
+	m := NewMenousDB(srv.URL, "admin", "db", WithCache(CacheOptions{}))
+
+	m.GetTable("t")
+	m.Clone(WithAPIVersion(APIVersion1)).GetTable("t")
+	if requests != 2 {
+		t.Fatalf("reads under two API versions made %d requests, want 2", requests)
+	}
+}
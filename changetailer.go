@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ChangeEvent is one row change found by tailing a table. Offset numbers a
+// table's changes from 1 in the order they were found.
+type ChangeEvent struct {
+	Database string                 `json:"database"`
+	Table    string                 `json:"table"`
+	Kind     string                 `json:"kind"`
+	Key      map[string]interface{} `json:"key"`
+	Old      Row                    `json:"old,omitempty"`
+	New      Row                    `json:"new,omitempty"`
+	Offset   int64                  `json:"offset"`
+	Time     time.Time              `json:"time"`
+}
+
+// SinkTable selects a table to tail
+type SinkTable struct {
+	Name       string
+	KeyColumns []string
+
+	// Topic receives the table's changes in a KafkaSink; defaults to the
+	// sink's prefix followed by the table name
+	Topic string
+}
+
+// TailPosition is how far a table has been tailed
+type TailPosition struct {
+	// Offset is the offset of the last change handled
+	Offset int64
+
+	// Hash identifies the table contents the next poll is compared with
+	Hash string
+}
+
+// ChangeTailer turns tables into ordered streams of ChangeEvents. MenousDB
+// has no change log, so each poll reads a table and diffs it against the
+// snapshot kept from the last poll; a table whose content hash is unchanged
+// costs one read. The changes a poll finds are numbered and saved, with the
+// new snapshot, before the handler sees any of them. A table's position
+// advances past each change the handler accepts; changes it has not accepted
+// are redelivered unchanged, same offsets included, before the table is
+// read again. The state persists to CheckpointPath, so after a restart the
+// tailer resumes where it stopped. Changes are therefore emitted at least
+// once and in offset order, and an offset always names the same change:
+// consumers can deduplicate on database, table and offset.
+type ChangeTailer struct {
+	Client *MenousDB
+	Tables []SinkTable
+
+	// CheckpointPath, if set, persists each table's last snapshot, hash,
+	// offset and undelivered changes. The checkpoint holds full table
+	// contents.
+	CheckpointPath string
+
+	// SkipSnapshot starts tables without a checkpoint from their current
+	// contents instead of emitting every row as added
+	SkipSnapshot bool
+
+	mu    sync.Mutex
+	state map[string]*tailState
+}
+
+// tailState is a table's position and the snapshot behind it. Offset is
+// the last offset assigned; Pending holds the changes up to it that the
+// handler has yet to accept.
+type tailState struct {
+	Offset  int64         `json:"offset"`
+	Hash    string        `json:"hash"`
+	Rows    []Row         `json:"rows"`
+	Pending []ChangeEvent `json:"pending,omitempty"`
+}
+
+// tailCheckpoint is the on-disk checkpoint format
+type tailCheckpoint struct {
+	Tables map[string]*tailState `json:"tables"`
+}
+
+// Run calls Poll immediately and then every interval until ctx is done,
+// passing poll errors to onError when it is set
+func (t *ChangeTailer) Run(ctx context.Context, interval time.Duration, fn func(context.Context, ChangeEvent) error, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := t.Poll(ctx, fn); err != nil && ctx.Err() == nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll calls fn with every table's changes since its position, in order, and
+// returns the number handled. A table with undelivered changes from an
+// earlier poll delivers those instead of being read. When fn fails the
+// table keeps the change, so the next poll emits it again, and Poll stops.
+func (t *ChangeTailer) Poll(ctx context.Context, fn func(context.Context, ChangeEvent) error) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return 0, err
+	}
+
+	handled := 0
+	for _, table := range t.Tables {
+		st := t.state[table.Name]
+		if st == nil || len(st.Pending) == 0 {
+			events, next, err := tailTable(t.Client, table.Name, table.KeyColumns, st, t.SkipSnapshot)
+			if err != nil {
+				return handled, fmt.Errorf("tailing %s: %w", table.Name, err)
+			}
+			if next == st {
+				continue
+			}
+			// Fix the batch's offsets before anyone sees them
+			next.Pending = events
+			t.state[table.Name] = next
+			if err := t.save(); err != nil {
+				return handled, err
+			}
+			st = next
+		}
+
+		for len(st.Pending) > 0 {
+			e := st.Pending[0]
+			err := ctx.Err()
+			if err == nil {
+				if err = fn(ctx, e); err != nil {
+					err = fmt.Errorf("handling %s offset %d: %w", e.Table, e.Offset, err)
+				}
+			}
+			if err != nil {
+				if saveErr := t.save(); saveErr != nil {
+					return handled, errors.Join(err, saveErr)
+				}
+				return handled, err
+			}
+			st.Pending = st.Pending[1:]
+			handled++
+		}
+		if err := t.save(); err != nil {
+			return handled, err
+		}
+	}
+	return handled, nil
+}
+
+// Position returns how far table has been tailed, reporting false before
+// its first poll
+func (t *ChangeTailer) Position(table string) (TailPosition, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return TailPosition{}, false, err
+	}
+	st, ok := t.state[table]
+	if !ok {
+		return TailPosition{}, false, nil
+	}
+	return TailPosition{Offset: st.Offset - int64(len(st.Pending)), Hash: st.Hash}, true, nil
+}
+
+// Reset forgets table's position, so the next poll starts it afresh
+func (t *ChangeTailer) Reset(table string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.load(); err != nil {
+		return err
+	}
+	delete(t.state, table)
+	return t.save()
+}
+
+// load reads the checkpoint on first use; t.mu must be held
+func (t *ChangeTailer) load() error {
+	if t.state != nil {
+		return nil
+	}
+	cp := tailCheckpoint{Tables: make(map[string]*tailState)}
+	if t.CheckpointPath != "" {
+		data, err := os.ReadFile(t.CheckpointPath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return err
+		case !decodeCached(data, &cp):
+			return fmt.Errorf("reading checkpoint %s: invalid JSON", t.CheckpointPath)
+		}
+		if cp.Tables == nil {
+			cp.Tables = make(map[string]*tailState)
+		}
+	}
+	t.state = cp.Tables
+	return nil
+}
+
+// save persists the checkpoint, if configured; t.mu must be held
+func (t *ChangeTailer) save() error {
+	if t.CheckpointPath == "" {
+		return nil
+	}
+	data, err := json.Marshal(tailCheckpoint{Tables: t.state})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(t.CheckpointPath, data)
+}
+
+// tailTable diffs a table's current contents against its last state,
+// returning the changes in order and the state to resume from once they are
+// handled. It returns last itself when the contents are unchanged. A nil
+// state emits every row as added unless skip is set.
+func tailTable(client *MenousDB, table string, keyColumns []string, last *tailState, skip bool) ([]ChangeEvent, *tailState, error) {
+	if len(keyColumns) == 0 {
+		return nil, nil, fmt.Errorf("tailing requires at least one key column")
+	}
+	current, err := client.uncached().GetTableRows(table)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Compare in JSON form, as rows read back from a checkpoint are
+	var rows []Row
+	if !remarshal(current, &rows) {
+		return nil, nil, fmt.Errorf("%w: rows are not JSON", ErrUnexpectedResponse)
+	}
+	hash, err := hashRows(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	if last != nil && last.Hash == hash {
+		return nil, last, nil
+	}
+
+	next := &tailState{Hash: hash, Rows: rows}
+	if last == nil {
+		if skip {
+			return nil, next, nil
+		}
+		last = &tailState{}
+	}
+	next.Offset = last.Offset
+
+	var events []ChangeEvent
+	now := time.Now().UTC()
+	_, err = diffRows(last.Rows, keyColumns, func(emit func(Row) error) error {
+		for _, row := range rows {
+			if err := emit(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(c RowChange) error {
+		next.Offset++
+		events = append(events, ChangeEvent{
+			Database: client.Database,
+			Table:    table,
+			Kind:     c.Kind.String(),
+			Key:      c.Key,
+			Old:      c.Old,
+			New:      c.New,
+			Offset:   next.Offset,
+			Time:     now,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return events, next, nil
+}
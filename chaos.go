@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ChaosFault names a failure ChaosTransport injects
+type ChaosFault string
+
+const (
+	// ChaosTimeout hangs the request until its context ends, or for
+	// ChaosTransport.Timeout, and fails it with a timeout error
+	ChaosTimeout ChaosFault = "timeout"
+	// ChaosReset fails the request with a connection reset before it is sent
+	ChaosReset ChaosFault = "reset"
+	// ChaosServerError answers with a 5xx status without reaching the server
+	ChaosServerError ChaosFault = "server-error"
+	// ChaosPartialWrite sends the request, so the server applies it, then
+	// loses the response to a connection reset
+	ChaosPartialWrite ChaosFault = "partial-write"
+	// ChaosSlowBody trickles the response body a few bytes at a time
+	ChaosSlowBody ChaosFault = "slow-body"
+	// ChaosTruncated cuts the response body short with io.ErrUnexpectedEOF
+	ChaosTruncated ChaosFault = "truncated"
+	// ChaosMalformed corrupts the response body while keeping a 200 status
+	ChaosMalformed ChaosFault = "malformed"
+)
+
+// ChaosTransport is an http.RoundTripper that injects failures at
+// configurable rates, so applications can check how they cope with a
+// misbehaving server or network:
+//
+//	chaos := &ChaosTransport{ResetRate: 0.05, MalformedRate: 0.02, Seed: 1}
+//	db := NewMenousDB(url, key, "app", WithHTTPClient(&http.Client{Transport: chaos}))
+//
+// Each rate is the probability, per request, of that fault. At most one
+// fault hits a request; the rates add up, so they should sum to at most 1.
+type ChaosTransport struct {
+	// Base sends the requests that get through; http.DefaultTransport when
+	// nil
+	Base http.RoundTripper
+
+	TimeoutRate      float64
+	ResetRate        float64
+	ServerErrorRate  float64
+	PartialWriteRate float64
+	SlowBodyRate     float64
+	TruncateRate     float64
+	MalformedRate    float64
+
+	// Timeout is how long a timed-out request hangs when its context has no
+	// deadline; 30s when zero
+	Timeout time.Duration
+
+	// SlowBodyDelay is the pause before each chunk of a slow body; 50ms
+	// when zero
+	SlowBodyDelay time.Duration
+
+	// Endpoints limits injection to the named endpoints; all when empty
+	Endpoints []string
+
+	// Seed makes the fault sequence reproducible; a time-based seed is used
+	// when zero
+	Seed int64
+
+	// OnFault, if set, is called for each injected fault
+	OnFault func(req *http.Request, fault ChaosFault)
+
+	mu     sync.Mutex
+	rand   *rand.Rand
+	counts map[ChaosFault]int
+}
+
+// chaosChunk is how many bytes a slow body returns per read
+const chaosChunk = 16
+
+// Faults returns how many times each fault has been injected
+func (c *ChaosTransport) Faults() map[ChaosFault]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[ChaosFault]int, len(c.counts))
+	for f, n := range c.counts {
+		out[f] = n
+	}
+	return out
+}
+
+// pick draws the fault for a request, or "" for none
+func (c *ChaosTransport) pick(req *http.Request) (ChaosFault, *rand.Rand) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rand == nil {
+		seed := c.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		c.rand = rand.New(rand.NewSource(seed))
+		c.counts = make(map[ChaosFault]int)
+	}
+	if len(c.Endpoints) > 0 && !containsString(c.Endpoints, path.Base(req.URL.Path)) {
+		return "", nil
+	}
+
+	draw := c.rand.Float64()
+	for _, f := range []struct {
+		fault ChaosFault
+		rate  float64
+	}{
+		{ChaosTimeout, c.TimeoutRate},
+		{ChaosReset, c.ResetRate},
+		{ChaosServerError, c.ServerErrorRate},
+		{ChaosPartialWrite, c.PartialWriteRate},
+		{ChaosSlowBody, c.SlowBodyRate},
+		{ChaosTruncated, c.TruncateRate},
+		{ChaosMalformed, c.MalformedRate},
+	} {
+		if draw < f.rate {
+			c.counts[f.fault]++
+			// Give the fault its own generator so its details stay
+			// reproducible however requests interleave
+			return f.fault, rand.New(rand.NewSource(c.rand.Int63()))
+		}
+		draw -= f.rate
+	}
+	return "", nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (c *ChaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := c.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	fault, r := c.pick(req)
+	if fault == "" {
+		return base.RoundTrip(req)
+	}
+	if c.OnFault != nil {
+		c.OnFault(req, fault)
+	}
+
+	switch fault {
+	case ChaosTimeout:
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		timeout := c.Timeout
+		if timeout == 0 {
+			timeout = 30 * time.Second
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-timer.C:
+			return nil, chaosTimeoutError{}
+		}
+
+	case ChaosReset:
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, chaosResetError("write")
+
+	case ChaosServerError:
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		statuses := []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+		status := statuses[r.Intn(len(statuses))]
+		body := fmt.Sprintf(`{"error":"chaos: injected %d"}`, status)
+		return &http.Response{
+			Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+			StatusCode:    status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"application/json"}},
+			Body:          io.NopCloser(bytes.NewBufferString(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	switch fault {
+	case ChaosPartialWrite:
+		resp.Body.Close()
+		return nil, chaosResetError("read")
+	case ChaosSlowBody:
+		delay := c.SlowBodyDelay
+		if delay == 0 {
+			delay = 50 * time.Millisecond
+		}
+		resp.Body = &chaosSlowBody{ReadCloser: resp.Body, ctx: req.Context(), delay: delay}
+		return resp, nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if fault == ChaosTruncated {
+		resp.Body = &chaosTruncatedBody{data: data[:r.Intn(len(data)+1)]}
+	} else {
+		data = corruptJSON(data, r)
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		resp.StatusCode, resp.Status = http.StatusOK, "200 OK"
+	}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// corruptJSON damages a response body the ways misbehaving servers and
+// proxies do
+func corruptJSON(data []byte, r *rand.Rand) []byte {
+	switch r.Intn(5) {
+	case 0:
+		// Cut short without an error
+		return data[:r.Intn(len(data)+1)]
+	case 1:
+		// A proxy's error page
+		return []byte("<html><body><h1>502 Bad Gateway</h1></body></html>")
+	case 2:
+		// Trailing garbage after the value
+		return append(data, []byte(`}]"garbage`)...)
+	case 3:
+		// Invalid UTF-8 and a stray NUL somewhere inside
+		if len(data) == 0 {
+			return []byte{0xff, 0x00}
+		}
+		i := r.Intn(len(data))
+		out := append([]byte(nil), data[:i]...)
+		out = append(out, 0xff, 0x00)
+		return append(out, data[i:]...)
+	}
+	// A structural byte flipped
+	out := append([]byte(nil), data...)
+	for i := 0; i < len(out); i++ {
+		j := r.Intn(len(out))
+		switch out[j] {
+		case '{', '}', '[', ']', ':', ',', '"':
+			out[j] = "{}[]:,\""[r.Intn(7)]
+			return out
+		}
+	}
+	return append(out, '{')
+}
+
+// chaosTimeoutError is the error of a timed-out request
+type chaosTimeoutError struct{}
+
+func (chaosTimeoutError) Error() string   { return "chaos: i/o timeout" }
+func (chaosTimeoutError) Timeout() bool   { return true }
+func (chaosTimeoutError) Temporary() bool { return true }
+
+// chaosResetError returns a connection reset as the net package reports it
+func chaosResetError(op string) error {
+	return &net.OpError{Op: op, Net: "tcp", Err: os.NewSyscallError(op, syscall.ECONNRESET)}
+}
+
+// chaosSlowBody returns a body a chunk at a time with a pause before each
+type chaosSlowBody struct {
+	io.ReadCloser
+	ctx   context.Context
+	delay time.Duration
+}
+
+// Read implements io.Reader
+func (b *chaosSlowBody) Read(p []byte) (int, error) {
+	timer := time.NewTimer(b.delay)
+	defer timer.Stop()
+	select {
+	case <-b.ctx.Done():
+		return 0, b.ctx.Err()
+	case <-timer.C:
+	}
+	if len(p) > chaosChunk {
+		p = p[:chaosChunk]
+	}
+	return b.ReadCloser.Read(p)
+}
+
+// chaosTruncatedBody returns data and then io.ErrUnexpectedEOF
+type chaosTruncatedBody struct {
+	data []byte
+}
+
+// Read implements io.Reader
+func (b *chaosTruncatedBody) Read(p []byte) (int, error) {
+	if len(b.data) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, b.data)
+	b.data = b.data[n:]
+	return n, nil
+}
+
+// Close implements io.Closer
+func (b *chaosTruncatedBody) Close() error { return nil }
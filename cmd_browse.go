@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"os"
+)
+
+// runBrowse implements "menousdb browse"
+func runBrowse(args []string) error {
+	fs := flag.NewFlagSet("browse", flag.ExitOnError)
+	conn := newConnFlags(fs, "", "server")
+	maxRows := fs.Int("max-rows", 10000, "rows loaded per table")
+	fs.Parse(args)
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	b := NewBrowser(client)
+	b.MaxRows = *maxRows
+	return b.Run(os.Stdin, os.Stdout)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+)
+
+// runConformance implements "menousdb conformance"
+func runConformance(args []string) error {
+	fs := flag.NewFlagSet("conformance", flag.ExitOnError)
+	conn := newConnFlags(fs, "", "server")
+	scratch := fs.String("scratch-db", "", "scratch database to create and delete; a random name when empty")
+	admin := fs.Bool("admin", false, "also check the API key endpoints, creating and revoking a key")
+	keep := fs.Bool("keep", false, "leave the scratch database in place")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	timeout := fs.Duration("timeout", 10*time.Second, "request timeout")
+	fs.Parse(args)
+
+	client, err := conn.client(WithTimeout(*timeout))
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c := &Conformance{Client: client, Database: *scratch, Admin: *admin, Keep: *keep}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if !*asJSON {
+		c.OnResult = func(r ConformanceResult) {
+			required := "optional"
+			if r.Required {
+				required = "required"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Status, r.Check, r.Endpoint, required, r.Detail)
+		}
+	}
+	report, runErr := c.Run(ctx)
+	if report == nil {
+		return runErr
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		tw.Flush()
+	}
+	if runErr != nil {
+		return runErr
+	}
+	if !report.OK() {
+		return fmt.Errorf("server at %s fails required conformance checks", report.URL)
+	}
+	return nil
+}
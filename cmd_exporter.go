@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// runExporter implements "menousdb exporter"
+func runExporter(args []string) error {
+	fs := flag.NewFlagSet("exporter", flag.ExitOnError)
+	conn := newConnFlags(fs, "", "server")
+	listen := fs.String("listen", ":9187", "address to serve /metrics on")
+	interval := fs.Duration("interval", 30*time.Second, "collection interval")
+	databases := fs.String("databases", "", "comma-separated databases to count tables in (default all)")
+	skipRows := fs.Bool("skip-row-counts", false, "don't report table row counts, which read every table on each collection")
+	fs.Parse(args)
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	e := &Exporter{Client: client, SkipRowCounts: *skipRows}
+	if *databases != "" {
+		e.Databases = strings.Split(*databases, ",")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go e.Run(ctx, *interval)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	srv := &http.Server{Addr: *listen, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
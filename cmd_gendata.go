@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runGenData implements "menousdb gen-data"
+func runGenData(args []string) error {
+	fs := flag.NewFlagSet("gen-data", flag.ExitOnError)
+	conn := newConnFlags(fs, "", "server")
+	table := fs.String("table", "", "table to fill; every table in -spec when empty")
+	rowsFlag := fs.String("rows", "", "rows to generate, such as 5000, 10k or 1M; defaults to the spec's rows or 1000")
+	specPath := fs.String("spec", "", "data spec or schema spec file describing the columns")
+	seed := fs.Int64("seed", 0, "random seed; 0 picks one from the clock")
+	concurrency := fs.Int("concurrency", 4, "inserts in flight")
+	batch := fs.Int("batch", 500, "rows buffered before a flush; the server takes one row per insert, so each row is still its own request")
+	create := fs.Bool("create", true, "create missing tables")
+	out := fs.String("out", "", "write newline-delimited JSON to this file, - for stdout, instead of loading")
+	fs.Parse(args)
+
+	rows := 0
+	if *rowsFlag != "" {
+		n, err := parseCount(*rowsFlag)
+		if err != nil {
+			return fmt.Errorf("-rows: %w", err)
+		}
+		rows = n
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	var tables []DataTableSpec
+	if *specPath != "" {
+		spec, err := LoadDataSpec(*specPath)
+		if err != nil {
+			return err
+		}
+		tables = spec.Tables
+		if *table != "" {
+			t, ok := spec.Table(*table)
+			if !ok {
+				return fmt.Errorf("%s does not describe table %q", *specPath, *table)
+			}
+			tables = []DataTableSpec{t}
+		}
+		if len(tables) == 0 {
+			return fmt.Errorf("%s describes no tables", *specPath)
+		}
+	} else if *table == "" {
+		return fmt.Errorf("-table or -spec is required")
+	}
+
+	var client *MenousDB
+	var sink *bufio.Writer
+	switch *out {
+	case "":
+		var err error
+		if client, err = conn.client(); err != nil {
+			return err
+		}
+	case "-":
+		sink = bufio.NewWriter(os.Stdout)
+		defer sink.Flush()
+	default:
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		sink = bufio.NewWriter(f)
+		defer sink.Flush()
+	}
+	if tables == nil {
+		// Without a spec, fill an existing table's attributes
+		t := DataTableSpec{Name: *table, Columns: defaultDataColumns}
+		if client != nil {
+			if existing, err := client.DescribeTable(*table); err == nil {
+				t = dataTableOf(*existing)
+			}
+		}
+		tables = []DataTableSpec{t}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	for i, t := range tables {
+		n := rows
+		if n == 0 {
+			n = t.Rows
+		}
+		if n == 0 {
+			n = 1000
+		}
+		g, err := NewDataGenerator(t, *seed+int64(i))
+		if err != nil {
+			return err
+		}
+		if sink != nil {
+			if err := writeFakeData(sink, g, n); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if *create {
+			created, err := client.EnsureTable(g.Spec().TableSpec())
+			if err != nil {
+				return fmt.Errorf("%s: %w", t.Name, err)
+			}
+			if created {
+				fmt.Fprintf(os.Stderr, "created %s\n", t.Name)
+			}
+		}
+		start := time.Now()
+		progress := func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\r%s: %d/%d rows", t.Name, done, total)
+		}
+		done, err := client.LoadFakeData(ctx, g, n, BulkWriterOptions{MaxRows: *batch, Concurrency: *concurrency}, progress)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+		elapsed := time.Since(start)
+		fmt.Fprintf(os.Stderr, "%s: loaded %d rows in %s (%.0f rows/s)\n", t.Name, done, elapsed.Round(time.Millisecond), float64(done)/elapsed.Seconds())
+	}
+	if sink != nil {
+		return sink.Flush()
+	}
+	return nil
+}
+
+// writeFakeData writes n generated rows as newline-delimited JSON, the
+// format ImportTable reads
+func writeFakeData(w io.Writer, g *DataGenerator, n int) error {
+	enc := json.NewEncoder(w)
+	for i := 0; i < n; i++ {
+		if err := enc.Encode(g.Row()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseCount parses a row count with an optional k, M or B suffix, such as
+// 10k or 1.5M; underscores and commas group digits
+func parseCount(arg string) (int, error) {
+	s := strings.NewReplacer("_", "", ",", "").Replace(strings.TrimSpace(arg))
+	mult := 1.0
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k', 'K':
+			mult = 1e3
+		case 'm', 'M':
+			mult = 1e6
+		case 'b', 'B', 'g', 'G':
+			mult = 1e9
+		}
+		if mult != 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 || f*mult > 1e12 {
+		return 0, fmt.Errorf("invalid count %q", arg)
+	}
+	return int(f * mult), nil
+}
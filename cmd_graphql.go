@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+)
+
+// runGraphQL implements "menousdb graphql"
+func runGraphQL(args []string) error {
+	fs := flag.NewFlagSet("graphql", flag.ExitOnError)
+	conn := newConnFlags(fs, "", "server")
+	serve := newServeFlags(fs, "8080", "GraphQL API")
+	path := fs.String("path", "/graphql", "path to serve the API at")
+	schemaPath := fs.String("schema", "", "schema spec declaring the tables; the server's tables when empty")
+	printSDL := fs.Bool("sdl", false, "print the generated schema and exit")
+	fs.Parse(args)
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	var spec *SchemaSpec
+	if *schemaPath != "" {
+		spec, err = LoadSchemaSpec(*schemaPath)
+	} else {
+		spec, err = client.DescribeSchema(client.Database)
+	}
+	if err != nil {
+		return err
+	}
+	var tables []TableSpec
+	for _, db := range spec.Databases {
+		if db.Name == client.Database {
+			tables = db.Tables
+		}
+	}
+	if len(tables) == 0 {
+		return fmt.Errorf("no tables found for database %q", client.Database)
+	}
+	api, err := NewGraphQL(client, tables...)
+	if err != nil {
+		return err
+	}
+	if *printSDL {
+		fmt.Print(api.SDL())
+		return nil
+	}
+	if api.Authorize, err = serve.authorize(); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(*path, api)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	srv := &http.Server{Addr: *serve.listen, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+)
+
+// runGRPC implements "menousdb grpc"
+func runGRPC(args []string) error {
+	fs := flag.NewFlagSet("grpc", flag.ExitOnError)
+	conn := newConnFlags(fs, "", "server")
+	serve := newServeFlags(fs, "8443", "gRPC API")
+	cert := fs.String("cert", "", "TLS certificate file (gRPC needs HTTP/2, served over TLS)")
+	key := fs.String("tls-key", "", "TLS private key file")
+	readOnly := fs.Bool("read-only", false, "reject writes")
+	fs.Parse(args)
+
+	if *cert == "" || *key == "" {
+		return errors.New("-cert and -tls-key are required")
+	}
+	authorize, err := serve.authorize()
+	if err != nil {
+		return err
+	}
+	var opts []Option
+	if *readOnly {
+		opts = append(opts, WithReadOnly())
+	}
+	client, err := conn.client(opts...)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	srv := &http.Server{Addr: *serve.listen, Handler: &GRPCServer{Client: client, Authorize: authorize}}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServeTLS(*cert, *key); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
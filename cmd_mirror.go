@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// runMirror implements "menousdb mirror"
+func runMirror(args []string) error {
+	fs := flag.NewFlagSet("mirror", flag.ExitOnError)
+	source := newConnFlags(fs, "source-", "source")
+	target := newConnFlags(fs, "target-", "target")
+	tables := fs.String("tables", "", "comma-separated table:key1+key2 list to mirror")
+	interval := fs.Duration("interval", 10*time.Second, "polling interval")
+	deletes := fs.Bool("delete", false, "delete target rows missing from the source")
+	checkpoint := fs.String("checkpoint", "", "checkpoint file for resuming")
+	fs.Parse(args)
+
+	src, err := source.client()
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	dst, err := target.client()
+	if err != nil {
+		return fmt.Errorf("target: %w", err)
+	}
+	syncTables, err := parseMirrorTables(*tables, *deletes)
+	if err != nil {
+		return err
+	}
+
+	s := &Syncer{
+		Source:         src,
+		Target:         dst,
+		Tables:         syncTables,
+		CheckpointPath: *checkpoint,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	logger := log.New(os.Stderr, "mirror: ", log.LstdFlags)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	// Lag is the age of the oldest change a cycle can apply: changes made
+	// just after the previous cycle read the source wait until this one ends
+	lastStart := time.Now()
+	for {
+		start := time.Now()
+		reports, err := s.SyncOnce()
+		end := time.Now()
+		if err != nil {
+			logger.Printf("error: %v", err)
+		}
+		for _, r := range reports {
+			if r.Skipped {
+				continue
+			}
+			logger.Printf("%s inserted=%d updated=%d deleted=%d kept=%d", r.Table, r.Inserted, r.Updated, r.Deleted, r.Kept)
+		}
+		if err == nil {
+			logger.Printf("cycle=%s lag=%s", end.Sub(start).Round(time.Millisecond), end.Sub(lastStart).Round(time.Millisecond))
+			lastStart = start
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// parseMirrorTables parses "users:id,orders:id+line" into SyncTables
+func parseMirrorTables(spec string, deletes bool) ([]SyncTable, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("no tables given (use -tables table:key)")
+	}
+	var tables []SyncTable
+	for _, item := range strings.Split(spec, ",") {
+		name, keys, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok || name == "" || keys == "" {
+			return nil, fmt.Errorf("table %q must be written as table:key", item)
+		}
+		tables = append(tables, SyncTable{
+			Name:       name,
+			KeyColumns: strings.Split(keys, "+"),
+			Delete:     deletes,
+		})
+	}
+	return tables, nil
+}
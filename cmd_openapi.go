@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runOpenAPI implements "menousdb openapi", whose subcommands print the
+// client's description, check a server's for drift and generate bindings
+func runOpenAPI(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: menousdb openapi spec|check|gen [flags]")
+	}
+	fs := flag.NewFlagSet("openapi "+args[0], flag.ExitOnError)
+	switch args[0] {
+	case "spec":
+		out := fs.String("o", "", "file to write the description to; stdout when empty")
+		fs.Parse(args[1:])
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(ClientOpenAPI()); err != nil {
+			return err
+		}
+		return writeOutput(*out, buf.Bytes())
+
+	case "check":
+		specPath := fs.String("spec", "", "file or URL of the server's OpenAPI description")
+		all := fs.Bool("all", false, "also report drift that breaks nothing")
+		fs.Parse(args[1:])
+		if *specPath == "" {
+			return fmt.Errorf("missing -spec")
+		}
+		doc, err := LoadOpenAPI(*specPath)
+		if err != nil {
+			return err
+		}
+		breaking := 0
+		for _, d := range OpenAPIDrift(doc) {
+			if d.Breaking {
+				breaking++
+			} else if !*all {
+				continue
+			}
+			fmt.Fprintln(os.Stdout, d)
+		}
+		if breaking > 0 {
+			return fmt.Errorf("%d breaking differences", breaking)
+		}
+		return nil
+
+	case "gen":
+		specPath := fs.String("spec", "", "file or URL of the OpenAPI description")
+		out := fs.String("o", "", "file to write the Go source to; stdout when empty")
+		pkg := fs.String("package", "main", "package name of the generated source")
+		fs.Parse(args[1:])
+		if *specPath == "" {
+			return fmt.Errorf("missing -spec")
+		}
+		doc, err := LoadOpenAPI(*specPath)
+		if err != nil {
+			return err
+		}
+		src, err := GenerateBindings(doc, *pkg)
+		if err != nil {
+			return err
+		}
+		return writeOutput(*out, src)
+	}
+	return fmt.Errorf("unknown openapi subcommand %q", args[0])
+}
+
+// writeOutput writes data to path, or to stdout when path is empty
+func writeOutput(path string, data []byte) error {
+	if path == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// runProbe implements "menousdb probe", for container health checks
+func runProbe(args []string) error {
+	fs := flag.NewFlagSet("probe", flag.ExitOnError)
+	conn := newConnFlags(fs, "", "server")
+	mode := fs.String("mode", "live", "live: server reachable; ready: also the database exists")
+	timeout := fs.Duration("timeout", 5*time.Second, "request timeout")
+	fs.Parse(args)
+
+	client, err := conn.client(WithHTTPClient(&http.Client{Timeout: *timeout}))
+	if err != nil {
+		return err
+	}
+
+	switch *mode {
+	case "live":
+		_, err := client.ForDatabase("").Ping()
+		if err != nil {
+			return fmt.Errorf("server unreachable: %w", err)
+		}
+		return nil
+	case "ready":
+		if client.Database == "" {
+			return fmt.Errorf("ready mode needs a database (-db or MENOUSDB_DATABASE)")
+		}
+		exists, err := client.DatabaseExists()
+		if err != nil {
+			return fmt.Errorf("server unreachable: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("database %q does not exist", client.Database)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown mode %q (want live or ready)", *mode)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// runProfile implements "menousdb profile", whose subcommands manage the
+// connection profiles in the profiles file
+func runProfile(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: menousdb profile list|show|use|add|remove [flags] [name]")
+	}
+	path := DefaultProfilesPath()
+	if path == "" {
+		return fmt.Errorf("no home directory; set MENOUSDB_CONFIG")
+	}
+	profiles, err := LoadProfiles(path)
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("profile "+args[0], flag.ExitOnError)
+	switch args[0] {
+	case "list":
+		fs.Parse(args[1:])
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, name := range profiles.Names() {
+			mark := " "
+			if name == profiles.Current {
+				mark = "*"
+			}
+			p := profiles.Profiles[name]
+			fmt.Fprintf(tw, "%s %s\t%s\t%s\n", mark, name, p.URL, p.Database)
+		}
+		return tw.Flush()
+
+	case "show":
+		fs.Parse(args[1:])
+		name := fs.Arg(0)
+		p, err := profiles.Lookup(name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			if profiles.Current == "" {
+				return fmt.Errorf("no current profile")
+			}
+			name = profiles.Current
+		}
+		fmt.Printf("name:     %s\nurl:      %s\ndatabase: %s\n", name, p.URL, p.Database)
+		switch {
+		case p.KeyFile != "":
+			fmt.Printf("key_file: %s\n", p.KeyFile)
+		case p.Key != "":
+			fmt.Printf("key:      %s\n", maskKey(p.Key))
+		}
+		if p.Timeout > 0 {
+			fmt.Printf("timeout:  %s\n", p.Timeout)
+		}
+		return nil
+
+	case "use":
+		fs.Parse(args[1:])
+		name := fs.Arg(0)
+		if _, ok := profiles.Profiles[name]; !ok {
+			return fmt.Errorf("no profile %q", name)
+		}
+		profiles.Current = name
+		return profiles.Save(path)
+
+	case "add":
+		url := fs.String("url", "", "server URL")
+		key := fs.String("key", "", "API key")
+		keyFile := fs.String("key-file", "", "file holding the API key, read on each run")
+		database := fs.String("db", "", "database")
+		timeout := fs.Duration("timeout", 0, "request timeout")
+		use := fs.Bool("use", false, "make it the current profile")
+		name, rest := splitName(args[1:])
+		fs.Parse(rest)
+		if name == "" {
+			name = fs.Arg(0)
+		}
+		if name == "" {
+			return fmt.Errorf("usage: menousdb profile add NAME -url URL [flags]")
+		}
+		// Adding an existing profile updates the settings given
+		p := profiles.Profiles[name]
+		set := map[string]bool{}
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if set["url"] {
+			p.URL = *url
+		}
+		if set["key"] {
+			p.Key, p.KeyFile = *key, ""
+		}
+		if set["key-file"] {
+			p.Key, p.KeyFile = "", *keyFile
+		}
+		if set["db"] {
+			p.Database = *database
+		}
+		if set["timeout"] {
+			p.Timeout = *timeout
+		}
+		if p.URL == "" {
+			return fmt.Errorf("profile %s needs -url", name)
+		}
+		if profiles.Profiles == nil {
+			profiles.Profiles = map[string]Profile{}
+		}
+		profiles.Profiles[name] = p
+		if *use || profiles.Current == "" {
+			profiles.Current = name
+		}
+		return profiles.Save(path)
+
+	case "remove":
+		fs.Parse(args[1:])
+		name := fs.Arg(0)
+		if _, ok := profiles.Profiles[name]; !ok {
+			return fmt.Errorf("no profile %q", name)
+		}
+		delete(profiles.Profiles, name)
+		if profiles.Current == name {
+			profiles.Current = ""
+		}
+		return profiles.Save(path)
+	}
+	return fmt.Errorf("unknown profile subcommand %q", args[0])
+}
+
+// splitName takes a leading non-flag argument, so both "add NAME -url U"
+// and "add -url U NAME" work
+func splitName(args []string) (string, []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
+	return "", args
+}
+
+// maskKey shows only the end of a key
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", 8) + key[len(key)-4:]
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+)
+
+// runProxy implements "menousdb proxy"
+func runProxy(args []string) error {
+	fs := flag.NewFlagSet("proxy", flag.ExitOnError)
+	conn := newConnFlags(fs, "", "server")
+	serve := newServeFlags(fs, "8080", "REST API")
+	prefix := fs.String("prefix", "", "path prefix to serve the API under, such as /api")
+	readOnly := fs.Bool("read-only", false, "reject writes")
+	fs.Parse(args)
+
+	authorize, err := serve.authorize()
+	if err != nil {
+		return err
+	}
+	var opts []Option
+	if *readOnly {
+		opts = append(opts, WithReadOnly())
+	}
+	client, err := conn.client(opts...)
+	if err != nil {
+		return err
+	}
+
+	var handler http.Handler = &RESTProxy{Client: client, Authorize: authorize}
+	if *prefix != "" {
+		handler = http.StripPrefix(*prefix, handler)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	srv := &http.Server{Addr: *serve.listen, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
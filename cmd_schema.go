@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runSchemaDiff implements "menousdb schema-diff"
+func runSchemaDiff(args []string) error {
+	fs := flag.NewFlagSet("schema-diff", flag.ExitOnError)
+	conn := newConnFlags(fs, "", "server")
+	specPath := fs.String("spec", "", "YAML or JSON schema spec with the desired databases and tables")
+	apply := fs.Bool("apply", false, "create the missing databases and tables")
+	fs.Parse(args)
+
+	if *specPath == "" {
+		return fmt.Errorf("missing -spec")
+	}
+	desired, err := LoadSchemaSpec(*specPath)
+	if err != nil {
+		return err
+	}
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(desired.Databases))
+	for i, db := range desired.Databases {
+		names[i] = db.Name
+	}
+	actual, err := client.DescribeSchema(names...)
+	if err != nil {
+		return err
+	}
+
+	changes := SchemaDiff(desired, actual)
+	var applyErr error
+	if *apply {
+		changes, applyErr = client.ApplySchemaChanges(desired, changes)
+	}
+	for _, c := range changes {
+		switch {
+		case c.Applied:
+			fmt.Fprintln(os.Stdout, "applied", c)
+		case *apply:
+			fmt.Fprintln(os.Stdout, "skipped", c)
+		default:
+			fmt.Fprintln(os.Stdout, c)
+		}
+	}
+	return applyErr
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runShell implements "menousdb shell"
+func runShell(args []string) error {
+	fs := flag.NewFlagSet("shell", flag.ExitOnError)
+	conn := newConnFlags(fs, "", "server")
+	command := fs.String("c", "", "run these statements and exit")
+	file := fs.String("f", "", "run the statements in this file and exit")
+	history := fs.String("history", defaultHistoryPath(), "history file; empty disables history")
+	asJSON := fs.Bool("json", false, "print rows as JSON lines")
+	fs.Parse(args)
+
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	sh := &Shell{Client: client, In: os.Stdin, Out: os.Stdout, JSON: *asJSON}
+	switch {
+	case *command != "":
+		sh.In = strings.NewReader(*command)
+	case *file != "":
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		sh.In = f
+	default:
+		sh.Interactive = isTerminal(os.Stdin)
+		sh.HistoryPath = *history
+	}
+	return sh.Run()
+}
+
+// defaultHistoryPath returns ~/.menousdb_history, or nothing without a home
+// directory
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".menousdb_history")
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithUpdateCoalescing merges UpdateWhere calls on the same table and
+// conditions made within window of the first into one request, for chatty
+// services that update the same records in bursts. Later calls' values win
+// where they overlap. Every merged caller waits for the shared request and
+// gets its result, so each call takes up to window longer. Each call's
+// values are validated against the table's schema before they are merged,
+// so an invalid call fails on its own without joining the batch. Only calls
+// through the same client, with the same key and database, are merged;
+// clones batch separately. Updates with conditions that cannot be encoded
+// are sent on their own.
+func WithUpdateCoalescing(window time.Duration) Option {
+	return func(m *MenousDB) {
+		if window <= 0 {
+			m.coalescer = nil
+			return
+		}
+		m.coalescer = &updateCoalescer{window: window, pending: make(map[string]*pendingUpdate)}
+	}
+}
+
+// updateCoalescer holds the updates waiting for their window to close
+type updateCoalescer struct {
+	window  time.Duration
+	mu      sync.Mutex
+	pending map[string]*pendingUpdate
+}
+
+// pendingUpdate is a merged update and the outcome its callers wait for
+type pendingUpdate struct {
+	values map[string]interface{}
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+// update merges the call into a pending update for the same client and
+// target and waits for it to be sent
+func (c *updateCoalescer) update(m *MenousDB, table string, conditions, values map[string]interface{}) (_ interface{}, err error) {
+	cond, err := json.Marshal(conditions)
+	if err != nil {
+		return m.updateWhere(table, conditions, values)
+	}
+	defer m.annotate(&err, "UpdateWhere", "update-table", table)
+
+	// Fail this caller alone rather than the merged batch
+	headers := map[string]string{"database": m.Database, "table": table}
+	if _, err := m.checkWrite("update-table", headers, map[string]interface{}{"conditions": conditions, "values": values}); err != nil {
+		return nil, err
+	}
+
+	// The client pointer stands for its whole configuration: credentials,
+	// endpoints, policy and schemas
+	key := fmt.Sprintf("%p\x00%s\x00%s\x00%s\x00%s", m, m.Key, m.Database, table, cond)
+
+	c.mu.Lock()
+	p := c.pending[key]
+	if p == nil {
+		p = &pendingUpdate{values: make(map[string]interface{}, len(values)), done: make(chan struct{})}
+		c.pending[key] = p
+		time.AfterFunc(c.window, func() {
+			c.mu.Lock()
+			delete(c.pending, key)
+			c.mu.Unlock()
+			p.result, p.err = m.updateWhere(table, conditions, p.values)
+			close(p.done)
+		})
+	}
+	for k, v := range values {
+		p.values[k] = v
+	}
+	c.mu.Unlock()
+
+	<-p.done
+	return p.result, p.err
+}
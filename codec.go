@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// Codec is a json-compatible encoder/decoder. jsoniter and sonic configs
+// satisfy it directly; go-json needs a two-line adapter around its package
+// functions.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// WithCodec replaces encoding/json for request bodies and responses. Strict
+// decoding (WithStrictDecoding) always uses encoding/json, since it relies on
+// DisallowUnknownFields.
+func WithCodec(c Codec) Option {
+	return func(m *MenousDB) {
+		m.codec = c
+	}
+}
+
+// encode writes v to buf as JSON using the configured codec
+func (m *MenousDB) encode(buf *bytes.Buffer, v interface{}) error {
+	if m.codec == nil {
+		return json.NewEncoder(buf).Encode(v)
+	}
+	data, err := m.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = buf.Write(data)
+	return err
+}
+
+// decode reads one JSON value from r into v using the configured codec
+func (m *MenousDB) decode(r io.Reader, v interface{}) error {
+	if m.codec == nil {
+		return json.NewDecoder(r).Decode(v)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return m.codec.Unmarshal(data, v)
+}
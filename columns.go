@@ -0,0 +1,381 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ColumnType is the declared type of a column
+type ColumnType string
+
+const (
+	// ColumnAny accepts any value
+	ColumnAny ColumnType = ""
+	// ColumnString holds strings
+	ColumnString ColumnType = "string"
+	// ColumnInt holds integral numbers, decoded as int64
+	ColumnInt ColumnType = "int"
+	// ColumnFloat holds numbers, decoded as float64
+	ColumnFloat ColumnType = "float"
+	// ColumnBool holds booleans
+	ColumnBool ColumnType = "bool"
+	// ColumnTime holds RFC 3339 timestamps, decoded as time.Time
+	ColumnTime ColumnType = "time"
+	// ColumnJSON holds arbitrary JSON values
+	ColumnJSON ColumnType = "json"
+)
+
+// ColumnDef declares one column of a table. Columns are NOT NULL unless
+// Nullable is set: an explicit null is rejected, an omitted value is not.
+// Required columns must also be present, after defaults, on every insert.
+// Validate lists further rules as menousdb struct tags write them, such as
+// "max=255,format=email".
+type ColumnDef struct {
+	Name     string      `yaml:"name" json:"name"`
+	Type     ColumnType  `yaml:"type,omitempty" json:"type,omitempty"`
+	Nullable bool        `yaml:"nullable,omitempty" json:"nullable,omitempty"`
+	Required bool        `yaml:"required,omitempty" json:"required,omitempty"`
+	Default  interface{} `yaml:"default,omitempty" json:"default,omitempty"`
+	Validate string      `yaml:"validate,omitempty" json:"validate,omitempty"`
+}
+
+// FieldError is one rule a row broke
+type FieldError struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+// ValidationError lists every problem found in a row before it was sent
+type ValidationError struct {
+	Table  string
+	Fields []FieldError
+}
+
+// Error joins the field problems into one message
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + ": " + f.Message
+	}
+	return fmt.Sprintf("invalid row for table %s: %s", e.Table, strings.Join(parts, "; "))
+}
+
+// CheckConstraint is a rule every written row must satisfy. Like a SQL
+// CHECK, it only runs when all of its columns hold a non-null value, so a
+// partial update or an omitted nullable column passes.
+type CheckConstraint struct {
+	Name      string
+	Columns   []string
+	Predicate Predicate
+}
+
+// tableSchema holds the client-side declarations for one table
+type tableSchema struct {
+	mu       sync.RWMutex
+	columns  []ColumnDef
+	checks   []CheckConstraint
+	compress map[string]columnCompression
+}
+
+// schemaRegistry maps database and table to their declarations, shared by
+// clones
+type schemaRegistry struct {
+	mu     sync.Mutex
+	tables map[string]*tableSchema
+}
+
+// table returns the declarations for database and table, creating them if
+// create is set
+func (r *schemaRegistry) table(database, table string, create bool) *tableSchema {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := database + "\x00" + table
+	s := r.tables[key]
+	if s == nil && create {
+		if r.tables == nil {
+			r.tables = make(map[string]*tableSchema)
+		}
+		s = &tableSchema{}
+		r.tables[key] = s
+	}
+	return s
+}
+
+// CreateTableWithColumns creates a table from typed column definitions. The
+// definitions are sent to the server next to the plain attribute list, which
+// older servers use alone, and remembered client-side as DeclareColumns does.
+func (m *MenousDB) CreateTableWithColumns(table string, columns []ColumnDef) (_ string, err error) {
+	defer m.annotate(&err, "CreateTableWithColumns", "create-table", table)
+
+	if err := m.validateDatabase(); err != nil {
+		return "", err
+	}
+
+	headers := map[string]string{
+		"key":      m.Key,
+		"database": m.Database,
+		"table":    table,
+	}
+
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name
+	}
+	body := map[string]interface{}{
+		"attributes": names,
+		"columns":    columns,
+	}
+
+	resp, err := m.makeRequest("POST", "create-table", headers, body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	m.DeclareColumns(table, columns)
+	return string(responseBody), nil
+}
+
+// DeclareColumns records column definitions for an existing table in the
+// client's database. Inserts and updates are then checked against them
+// before they are sent, and rows read through Select and SelectFiltered are
+// decoded to the declared types. The plain row methods keep the server's
+// JSON types, which exporters and sync rely on.
+func (m *MenousDB) DeclareColumns(table string, columns []ColumnDef) {
+	m.Table(table).Declare(columns...)
+}
+
+// checkWrite validates the row in an insert or update body against the
+// table's declarations and, for struct values, their menousdb tag rules,
+// returning the body to send
+func (m *MenousDB) checkWrite(endpoint string, headers map[string]string, body interface{}) (interface{}, error) {
+	if endpoint != "insert-into-table" && endpoint != "update-table" {
+		return body, nil
+	}
+	payload, ok := body.(map[string]interface{})
+	if !ok {
+		return body, nil
+	}
+	s := m.schemas.table(headers["database"], headers["table"], false)
+	tagged := taggedColumns(payload["values"])
+	if s == nil && tagged == nil {
+		return body, nil
+	}
+	values, err := toRowValues(payload["values"])
+	if err != nil {
+		return nil, err
+	}
+	values = tagged.rename(values)
+
+	var columns []ColumnDef
+	var checks []CheckConstraint
+	var compress map[string]columnCompression
+	if s != nil {
+		s.mu.RLock()
+		columns = append(columns, s.columns...)
+		checks = append(checks, s.checks...)
+		compress = make(map[string]columnCompression, len(s.compress))
+		for k, v := range s.compress {
+			compress[k] = v
+		}
+		s.mu.RUnlock()
+	}
+	columns = mergeTagged(columns, tagged)
+
+	// Fill omitted columns on insert, without touching the caller's map
+	if endpoint == "insert-into-table" {
+		filled := make(Row, len(values))
+		for k, v := range values {
+			filled[k] = v
+		}
+		for _, c := range columns {
+			if _, present := filled[c.Name]; !present && c.Default != nil {
+				filled[c.Name] = c.Default
+			}
+		}
+		values = filled
+	}
+
+	var fields []FieldError
+	for _, c := range columns {
+		v, present := values[c.Name]
+		if !present {
+			if c.Required && endpoint == "insert-into-table" {
+				fields = append(fields, FieldError{Field: c.Name, Rule: "required", Message: "is required"})
+			}
+			continue
+		}
+		if v == nil {
+			if c.Required {
+				fields = append(fields, FieldError{Field: c.Name, Rule: "required", Message: "is required"})
+			} else if !c.Nullable {
+				fields = append(fields, FieldError{Field: c.Name, Rule: "nullable", Message: "must not be null"})
+			}
+			continue
+		}
+		if !c.Type.accepts(v) {
+			fields = append(fields, FieldError{Field: c.Name, Rule: "type", Message: fmt.Sprintf("must be %s, got %T", c.Type, v)})
+			continue
+		}
+		fields = append(fields, c.checkRules(v)...)
+	}
+	for _, c := range checks {
+		if c.applies(values) && !c.Predicate(values) {
+			fields = append(fields, FieldError{Field: strings.Join(c.Columns, ","), Rule: "check", Message: "violates check " + c.Name})
+		}
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Table: headers["table"], Fields: fields}
+	}
+	if values, err = compressValues(values, compress); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]interface{}, len(payload))
+	for k, v := range payload {
+		out[k] = v
+	}
+	out["values"] = values
+	return out, nil
+}
+
+// applies reports whether every column of c holds a non-null value in row
+func (c CheckConstraint) applies(row Row) bool {
+	for _, col := range c.Columns {
+		if row[col] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// toRowValues returns the fields of an insert or update value, which may be
+// a map or any struct encoding to a JSON object
+func toRowValues(v interface{}) (Row, error) {
+	if row, ok := v.(map[string]interface{}); ok {
+		return row, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var row Row
+	if err := json.Unmarshal(raw, &row); err != nil {
+		return nil, fmt.Errorf("values must encode to a JSON object: %w", err)
+	}
+	return row, nil
+}
+
+// accepts reports whether v is a valid value of type t
+func (t ColumnType) accepts(v interface{}) bool {
+	switch t {
+	case ColumnString:
+		_, ok := v.(string)
+		return ok
+	case ColumnInt:
+		switch n := v.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return true
+		case float64:
+			return isInt64(n)
+		case json.Number:
+			_, err := n.Int64()
+			return err == nil
+		}
+		return false
+	case ColumnFloat:
+		switch v.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+			return true
+		}
+		return false
+	case ColumnBool:
+		_, ok := v.(bool)
+		return ok
+	case ColumnTime:
+		switch x := v.(type) {
+		case time.Time:
+			return true
+		case string:
+			_, err := time.Parse(time.RFC3339Nano, x)
+			return err == nil
+		}
+		return false
+	}
+	return true
+}
+
+// decodeColumns decompresses rows read from table and converts them to the
+// declared column types. Values that do not convert are left as decoded.
+func (m *MenousDB) decodeColumns(table string, rows []Row) {
+	s := m.schemas.table(m.Database, table, false)
+	if s == nil {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, row := range rows {
+		for column := range s.compress {
+			if v, ok := row[column]; ok {
+				row[column] = decompressValue(v)
+			}
+		}
+		for _, c := range s.columns {
+			if v, ok := row[c.Name]; ok && v != nil {
+				row[c.Name] = c.Type.decode(v)
+			}
+		}
+	}
+}
+
+// isInt64 reports whether f is a whole number that int64 holds
+func isInt64(f float64) bool {
+	return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64
+}
+
+// decode converts a decoded JSON value to t's Go representation
+func (t ColumnType) decode(v interface{}) interface{} {
+	switch t {
+	case ColumnInt:
+		if f, err := toFloat(v); err == nil && isInt64(f) {
+			return int64(f)
+		}
+	case ColumnFloat:
+		if f, err := toFloat(v); err == nil {
+			return f
+		}
+	case ColumnTime:
+		if s, ok := v.(string); ok {
+			if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return ts
+			}
+		}
+	}
+	return v
+}
+
+// columnDecoder wraps emit so records streamed from table are decoded to the
+// declared column types first
+func (m *MenousDB) columnDecoder(table string, emit func(Row) error) func(Row) error {
+	if m.schemas.table(m.Database, table, false) == nil {
+		return emit
+	}
+	return func(r Row) error {
+		m.decodeColumns(table, []Row{r})
+		return emit(r)
+	}
+}
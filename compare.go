@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// rowKey builds a comparable identity for row from the given columns; it
+// returns "" if the values cannot be encoded
+func rowKey(row Row, columns []string) string {
+	values := make([]interface{}, len(columns))
+	for i, c := range columns {
+		values[i] = row[c]
+	}
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+// compareValues orders two decoded JSON values, returning -1, 0 or 1. Nulls
+// sort first, numbers compare numerically, strings that both parse as
+// RFC 3339 timestamps compare chronologically and other strings lexically.
+// Values of different kinds are ordered null < bool < number < string.
+func compareValues(a, b interface{}) int {
+	ra, rb := kindRank(a), kindRank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+
+	switch x := a.(type) {
+	case nil:
+		return 0
+	case bool:
+		y := b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		}
+		return 1
+	case string:
+		y := b.(string)
+		if tx, err := time.Parse(time.RFC3339Nano, x); err == nil {
+			if ty, err := time.Parse(time.RFC3339Nano, y); err == nil {
+				return tx.Compare(ty)
+			}
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+
+	if fa, err := toFloat(a); err == nil {
+		if fb, err := toFloat(b); err == nil {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	// Objects and arrays fall back to their encoded form
+	sa, sb := fmt.Sprint(a), fmt.Sprint(b)
+	switch {
+	case sa < sb:
+		return -1
+	case sa > sb:
+		return 1
+	}
+	return 0
+}
+
+// kindRank orders value kinds for compareValues
+func kindRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64, float32, int, int64, int32, json.Number:
+		return 2
+	case string:
+		return 3
+	}
+	return 4
+}
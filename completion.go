@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Shell completion works by the scripts below calling "menousdb __complete"
+// with the words typed so far; it prints one candidate per line. Flags come
+// from the subcommand's own -h output, so they never go stale, and table,
+// database and profile names are looked up live using the connection flags
+// already typed.
+
+// __complete reads commands, so it joins them at init to avoid an
+// initialization cycle
+func init() {
+	commands["__complete"] = command{"", runComplete}
+}
+
+// completionScripts are the scripts "menousdb completion" prints, by shell
+var completionScripts = map[string]string{
+	"bash": `# menousdb completion for bash; add to ~/.bashrc:
+#   source <(menousdb completion bash)
+_menousdb() {
+    local IFS=$'\n'
+    COMPREPLY=($(menousdb __complete "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _menousdb menousdb
+`,
+	"zsh": `#compdef menousdb
+# menousdb completion for zsh; add to ~/.zshrc:
+#   source <(menousdb completion zsh)
+_menousdb() {
+    local -a candidates
+    candidates=("${(@f)$(menousdb __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if (( ${#candidates} )) && [[ -n ${candidates[1]} ]]; then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+if [[ ${funcstack[1]} == _menousdb ]]; then
+    _menousdb "$@"
+else
+    compdef _menousdb menousdb
+fi
+`,
+	"fish": `# menousdb completion for fish; save as
+#   ~/.config/fish/completions/menousdb.fish
+function __menousdb_complete
+    set -l args (commandline -opc)[2..-1] (commandline -ct)
+    menousdb __complete $args 2>/dev/null
+end
+complete -c menousdb -f -a '(__menousdb_complete)'
+`,
+}
+
+// runCompletion implements "menousdb completion"
+func runCompletion(args []string) error {
+	if len(args) != 1 || completionScripts[args[0]] == "" {
+		return fmt.Errorf("usage: menousdb completion bash|zsh|fish")
+	}
+	_, err := fmt.Print(completionScripts[args[0]])
+	return err
+}
+
+// runComplete implements the hidden "menousdb __complete", printing the
+// candidates for the last of args
+func runComplete(args []string) error {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	for _, c := range completeWords(args) {
+		fmt.Println(c)
+	}
+	return nil
+}
+
+// subcommandWords lists the first arguments of commands that take one
+var subcommandWords = map[string][]string{
+	"completion": {"bash", "fish", "zsh"},
+	"openapi":    {"check", "gen", "spec"},
+	"profile":    {"add", "list", "remove", "show", "use"},
+}
+
+// completeWords returns the candidates for the last word, given the words
+// after the program name
+func completeWords(words []string) []string {
+	cur := words[len(words)-1]
+	if len(words) == 1 {
+		var names []string
+		for name, c := range commands {
+			if c.summary != "" {
+				names = append(names, name)
+			}
+		}
+		return withPrefix(names, cur)
+	}
+
+	cmd, prev := words[0], words[len(words)-2]
+	if name, ok := flagName(prev); ok && !strings.Contains(prev, "=") {
+		if takes, known := commandFlags(words)[name]; known && takes {
+			return withPrefix(flagValues(name, words), cur)
+		}
+	}
+	if name, value, ok := strings.Cut(cur, "="); ok && strings.HasPrefix(name, "-") {
+		// -flag=value completes the value and keeps the flag
+		var out []string
+		for _, v := range withPrefix(flagValues(strings.TrimLeft(name, "-"), words), value) {
+			out = append(out, name+"="+v)
+		}
+		return out
+	}
+	if strings.HasPrefix(cur, "-") {
+		var flags []string
+		for name := range commandFlags(words) {
+			flags = append(flags, "-"+name)
+		}
+		return withPrefix(flags, cur)
+	}
+
+	if len(words) == 2 {
+		return withPrefix(subcommandWords[cmd], cur)
+	}
+	if cmd == "profile" && len(words) == 3 {
+		switch words[1] {
+		case "use", "remove", "show":
+			return withPrefix(profileNames(), cur)
+		}
+	}
+	return nil
+}
+
+// flagName returns the name of a flag word such as -table or --db=x
+func flagName(word string) (string, bool) {
+	if !strings.HasPrefix(word, "-") || word == "-" || word == "--" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(strings.TrimLeft(word, "-"), "=")
+	return name, true
+}
+
+// commandFlags returns the flags of the command words start, and whether
+// each takes a value, from the command's -h output
+func commandFlags(words []string) map[string]bool {
+	args := []string{words[0]}
+	if _, ok := subcommandWords[words[0]]; ok && len(words) > 2 {
+		args = append(args, words[1])
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil
+	}
+	// -h exits non-zero for commands with subcommands; the usage is all
+	// that matters
+	out, _ := exec.Command(exe, append(args, "-h")...).CombinedOutput()
+	flags := map[string]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if !strings.HasPrefix(line, "  -") {
+			continue
+		}
+		fields := strings.Fields(line)
+		flags[strings.TrimPrefix(fields[0], "-")] = len(fields) > 1
+	}
+	return flags
+}
+
+// flagValues returns the known values of flag name: table, database and
+// profile names
+func flagValues(name string, words []string) []string {
+	switch {
+	case name == "profile" || strings.HasSuffix(name, "-profile"):
+		return profileNames()
+	case name == "table":
+		client, err := completionClient(words)
+		if err != nil || client.Database == "" {
+			return nil
+		}
+		tables, _ := client.ListTables()
+		return tables
+	case name == "db" || strings.HasSuffix(name, "-db"):
+		client, err := completionClient(words)
+		if err != nil {
+			return nil
+		}
+		result, err := client.GetDatabases()
+		if err != nil {
+			return nil
+		}
+		return databaseNames(result)
+	}
+	return nil
+}
+
+// completionClient connects with the url, key, db and profile flags among
+// words, falling back as commands do to the environment and profiles
+func completionClient(words []string) (*MenousDB, error) {
+	values := map[string]string{
+		"url":     os.Getenv("MENOUSDB_URL"),
+		"key":     os.Getenv("MENOUSDB_KEY"),
+		"db":      os.Getenv("MENOUSDB_DATABASE"),
+		"profile": os.Getenv("MENOUSDB_PROFILE"),
+	}
+	// The last word is being typed, so it is not a value yet
+	for i := 1; i < len(words)-1; i++ {
+		name, ok := flagName(words[i])
+		if _, conn := values[name]; !ok || !conn {
+			continue
+		}
+		if _, v, inline := strings.Cut(words[i], "="); inline {
+			values[name] = v
+		} else if i+1 < len(words)-1 {
+			values[name] = words[i+1]
+			i++
+		}
+	}
+	url, key, db, profile := values["url"], values["key"], values["db"], values["profile"]
+	conn := &connFlags{url: &url, key: &key, database: &db, profile: &profile}
+	return conn.client(WithTimeout(2 * time.Second))
+}
+
+// profileNames returns the profiles in the profiles file
+func profileNames() []string {
+	profiles, err := LoadProfiles(DefaultProfilesPath())
+	if err != nil {
+		return nil
+	}
+	return profiles.Names()
+}
+
+// withPrefix returns the sorted candidates starting with prefix
+func withPrefix(candidates []string, prefix string) []string {
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Compressor is a compression codec for column values. gzip is built in;
+// others such as zstd can be registered with RegisterCompressor.
+type Compressor interface {
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// compressedPrefix marks a stored value as compressed: the prefix, the codec
+// name, a colon and the base64 of the compressed text
+const compressedPrefix = "menousdb-z:"
+
+// compressors maps codec names to their implementations
+var compressors = struct {
+	sync.RWMutex
+	m map[string]Compressor
+}{m: map[string]Compressor{"gzip": gzipCompressor{}}}
+
+// RegisterCompressor makes c available to Table.Compress under c.Name(). It
+// panics if the name is empty, contains ":", or is already registered.
+func RegisterCompressor(c Compressor) {
+	name := c.Name()
+	if name == "" || strings.Contains(name, ":") {
+		panic("menousdb: invalid compressor name " + name)
+	}
+	compressors.Lock()
+	defer compressors.Unlock()
+	if _, dup := compressors.m[name]; dup {
+		panic("menousdb: RegisterCompressor called twice for " + name)
+	}
+	compressors.m[name] = c
+}
+
+// compressor returns the codec registered under name
+func compressor(name string) (Compressor, bool) {
+	compressors.RLock()
+	defer compressors.RUnlock()
+	c, ok := compressors.m[name]
+	return c, ok
+}
+
+// columnCompression is the compression declared for one column
+type columnCompression struct {
+	codec   Compressor
+	minSize int
+}
+
+// Compress stores string values of column compressed with the named codec
+// once they are at least minSize bytes, and only when that makes them
+// smaller. Values are compressed on insert and update, after validation,
+// and decompressed wherever records are read: GetTable and the Select
+// methods, the Rows methods, Arrow batches and everything built on them.
+// The Raw and Into methods return them as stored. The server compares
+// conditions against the stored form, so a condition on a compressed
+// column cannot match a value that was stored compressed.
+// It panics if codec is not registered.
+func (t *Table) Compress(column, codec string, minSize int) *Table {
+	c, ok := compressor(codec)
+	if !ok {
+		panic("menousdb: unknown compressor " + codec)
+	}
+	t.schema.mu.Lock()
+	defer t.schema.mu.Unlock()
+	if t.schema.compress == nil {
+		t.schema.compress = make(map[string]columnCompression)
+	}
+	t.schema.compress[column] = columnCompression{codec: c, minSize: minSize}
+	return t
+}
+
+// compressValue returns s in stored form, compressed when it is worth it
+func (cc columnCompression) compressValue(s string) (string, error) {
+	if len(s) < cc.minSize {
+		return s, nil
+	}
+	z, err := cc.codec.Compress([]byte(s))
+	if err != nil {
+		return "", err
+	}
+	out := compressedPrefix + cc.codec.Name() + ":" + base64.StdEncoding.EncodeToString(z)
+	if len(out) >= len(s) {
+		return s, nil
+	}
+	return out, nil
+}
+
+// compressValues returns values with the string values of compressed
+// columns in stored form, copying values before changing it
+func compressValues(values Row, compress map[string]columnCompression) (Row, error) {
+	var out Row
+	for column, cc := range compress {
+		s, ok := values[column].(string)
+		if !ok {
+			continue
+		}
+		stored, err := cc.compressValue(s)
+		if err != nil {
+			return nil, fmt.Errorf("compressing %s: %w", column, err)
+		}
+		if stored == s {
+			continue
+		}
+		if out == nil {
+			out = make(Row, len(values))
+			for k, v := range values {
+				out[k] = v
+			}
+		}
+		out[column] = stored
+	}
+	if out == nil {
+		return values, nil
+	}
+	return out, nil
+}
+
+// decompressValue reverses compressValue, leaving values that are not
+// compressed, or do not decompress, as they are
+func decompressValue(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok || !strings.HasPrefix(s, compressedPrefix) {
+		return v
+	}
+	name, encoded, ok := strings.Cut(s[len(compressedPrefix):], ":")
+	if !ok {
+		return v
+	}
+	c, ok := compressor(name)
+	if !ok {
+		return v
+	}
+	z, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return v
+	}
+	data, err := c.Decompress(z)
+	if err != nil {
+		return v
+	}
+	return string(data)
+}
+
+// decompressRecords decompresses the values of a select result's records,
+// an array of objects or an object of them keyed by row id, in place.
+// Compressed values are tagged with their codec, so this needs no schema.
+func decompressRecords(result interface{}) interface{} {
+	var records []interface{}
+	switch v := result.(type) {
+	case []interface{}:
+		records = v
+	case map[string]interface{}:
+		for _, r := range v {
+			records = append(records, r)
+		}
+	}
+	for _, r := range records {
+		if row, ok := r.(map[string]interface{}); ok {
+			for k, v := range row {
+				row[k] = decompressValue(v)
+			}
+		}
+	}
+	return result
+}
+
+// gzipCompressor is the built-in gzip codec
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string { return "gzip" }
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ClientConfig holds the settings a ConfigWatcher can change at runtime
+type ClientConfig struct {
+	URL      string        `yaml:"url" json:"url"`
+	Key      string        `yaml:"key" json:"key"`
+	Database string        `yaml:"database" json:"database"`
+	Timeout  time.Duration `yaml:"timeout" json:"timeout"`
+}
+
+// ConfigSource returns the current client configuration
+type ConfigSource func(ctx context.Context) (ClientConfig, error)
+
+// FileConfig reads the configuration from a YAML or JSON file, with the
+// timeout written as a duration string such as "5s"
+func FileConfig(path string) ConfigSource {
+	return func(ctx context.Context) (ClientConfig, error) {
+		var cfg ClientConfig
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		return cfg, nil
+	}
+}
+
+// ConfigWatcher keeps a client in step with a changing configuration. Each
+// change builds a new client and swaps it in atomically, so requests already
+// running on the previous client finish undisturbed while new callers of
+// Client get the new settings. Clients share one HTTP client, and with it the
+// connection pool.
+type ConfigWatcher struct {
+	// OnReload is called with each newly swapped-in client
+	OnReload func(*MenousDB)
+
+	// OnError is called when Run fails to load the configuration; the
+	// current client stays in place
+	OnError func(error)
+
+	source ConfigSource
+	opts   []Option
+	http   *http.Client
+
+	mu      sync.Mutex
+	current ClientConfig
+	client  atomic.Pointer[MenousDB]
+}
+
+// NewConfigWatcher loads the configuration from source and builds the first
+// client with opts applied. The same opts are applied to every reloaded
+// client.
+func NewConfigWatcher(ctx context.Context, source ConfigSource, opts ...Option) (*ConfigWatcher, error) {
+	w := &ConfigWatcher{source: source, opts: opts, http: &http.Client{}}
+	if _, err := w.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Client returns the client for the current configuration. Callers should
+// fetch it per operation rather than holding on to it.
+func (w *ConfigWatcher) Client() *MenousDB {
+	return w.client.Load()
+}
+
+// Reload reads the configuration once and swaps in a new client if it
+// changed, reporting whether it did
+func (w *ConfigWatcher) Reload(ctx context.Context) (bool, error) {
+	cfg, err := w.source(ctx)
+	if err != nil {
+		return false, err
+	}
+	if cfg.URL == "" {
+		return false, errors.New("config has no url")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.client.Load() != nil && cfg == w.current {
+		return false, nil
+	}
+
+	opts := append([]Option{WithHTTPClient(w.http)}, w.opts...)
+	if cfg.Timeout > 0 {
+		opts = append(opts, WithTimeout(cfg.Timeout))
+	}
+	m := NewMenousDB(cfg.URL, cfg.Key, cfg.Database, opts...)
+	w.current = cfg
+	w.client.Store(m)
+
+	if w.OnReload != nil {
+		w.OnReload(m)
+	}
+	return true, nil
+}
+
+// Run reloads the configuration every interval until ctx is done
+func (w *ConfigWatcher) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if _, err := w.Reload(ctx); err != nil && w.OnError != nil {
+			w.OnError(err)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConformanceStatus is the outcome of one conformance check
+type ConformanceStatus string
+
+const (
+	ConformancePass ConformanceStatus = "pass"
+	ConformanceFail ConformanceStatus = "fail"
+	// ConformanceSkip marks checks that were not run, because a check they
+	// depend on failed or because they were not enabled
+	ConformanceSkip ConformanceStatus = "skip"
+)
+
+// ConformanceResult is one check's outcome
+type ConformanceResult struct {
+	Check    string
+	Endpoint string
+	// Required checks cover behaviour the client relies on; the others
+	// probe optional protocol variants and features
+	Required bool
+	Status   ConformanceStatus
+	Detail   string
+	Elapsed  time.Duration
+}
+
+// ConformanceReport holds the results of a conformance run in check order
+type ConformanceReport struct {
+	URL      string
+	Database string
+	Results  []ConformanceResult
+}
+
+// OK reports whether every required check passed
+func (r *ConformanceReport) OK() bool {
+	for _, res := range r.Results {
+		if res.Required && res.Status != ConformancePass {
+			return false
+		}
+	}
+	return true
+}
+
+// Endpoints summarises the results per endpoint: pass when every check of
+// the endpoint that ran passed, fail when any failed, skip when none ran
+func (r *ConformanceReport) Endpoints() map[string]ConformanceStatus {
+	out := make(map[string]ConformanceStatus)
+	for _, res := range r.Results {
+		switch {
+		case res.Status == ConformanceFail:
+			out[res.Endpoint] = ConformanceFail
+		case res.Status == ConformancePass && out[res.Endpoint] != ConformanceFail:
+			out[res.Endpoint] = ConformancePass
+		case out[res.Endpoint] == "":
+			out[res.Endpoint] = ConformanceSkip
+		}
+	}
+	return out
+}
+
+// Conformance runs a battery of operations against a server and reports
+// which endpoints and behaviours it supports, to validate a server version
+// or custom deployment before relying on it. It works in a scratch database
+// that it creates and, unless Keep is set, deletes again, so the key must be
+// allowed to create databases.
+//
+//	report, err := (&Conformance{Client: db}).Run(ctx)
+//	if !report.OK() { ... }
+type Conformance struct {
+	Client *MenousDB
+
+	// Database names the scratch database; a random conformance_ name is
+	// used when empty. It must not exist yet.
+	Database string
+
+	// Admin also runs the API key checks, which create and revoke a key
+	Admin bool
+
+	// Keep leaves the scratch database in place for inspection
+	Keep bool
+
+	// OnResult, if set, sees each result as its check finishes
+	OnResult func(ConformanceResult)
+}
+
+// conformanceTable is the scratch table most checks use
+const conformanceTable = "conformance"
+
+// conformanceRows are the rows inserted into the scratch table
+var conformanceRows = []Row{
+	{"id": "1", "name": "alpha", "qty": 1, "tags": "a"},
+	{"id": "2", "name": "bravo", "qty": 2, "tags": "b"},
+	{"id": "3", "name": "charlie", "qty": 3, "tags": "c"},
+}
+
+// errConformanceSkip marks a check that chose not to run
+var errConformanceSkip = errors.New("skipped")
+
+// conformanceCheck is one check: the endpoint it exercises, the checks that
+// must pass first, and the test itself, which returns a detail on success
+type conformanceCheck struct {
+	name     string
+	endpoint string
+	required bool
+	needs    []string
+	run      func(r *conformanceRun) (string, error)
+}
+
+// conformanceRun is the state of one run
+type conformanceRun struct {
+	c      *Conformance
+	db     *MenousDB
+	passed map[string]bool
+	apiKey string
+}
+
+// Run executes the checks in order and returns the report. It stops early,
+// after cleaning up, when ctx is done.
+func (c *Conformance) Run(ctx context.Context) (*ConformanceReport, error) {
+	name := c.Database
+	if name == "" {
+		var b [4]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		name = "conformance_" + hex.EncodeToString(b[:])
+	}
+	r := &conformanceRun{c: c, db: c.Client.ForDatabase(name).uncached(), passed: make(map[string]bool)}
+	report := &ConformanceReport{URL: c.Client.URL, Database: name}
+
+	var ctxErr error
+	for _, check := range conformanceChecks {
+		cleanup := check.endpoint == "delete-table" || check.endpoint == "del-database"
+		if ctxErr = ctx.Err(); ctxErr != nil && !cleanup {
+			continue
+		}
+		res := ConformanceResult{Check: check.name, Endpoint: check.endpoint, Required: check.required}
+		for _, dep := range check.needs {
+			if !r.passed[dep] {
+				res.Status = ConformanceSkip
+				res.Detail = "needs " + dep
+			}
+		}
+		if res.Status == "" && cleanup && c.Keep {
+			res.Status = ConformanceSkip
+			res.Detail = "keeping the scratch database"
+		}
+		if res.Status == "" {
+			start := time.Now()
+			detail, err := check.run(r)
+			res.Elapsed = time.Since(start)
+			switch {
+			case errors.Is(err, errConformanceSkip):
+				res.Status, res.Detail = ConformanceSkip, detail
+			case err != nil:
+				res.Status, res.Detail = ConformanceFail, err.Error()
+			default:
+				res.Status, res.Detail = ConformancePass, detail
+				r.passed[check.name] = true
+			}
+		}
+		report.Results = append(report.Results, res)
+		if c.OnResult != nil {
+			c.OnResult(res)
+		}
+	}
+	return report, ctxErr
+}
+
+// conformanceChecks lists the checks in the order they run. Later checks
+// rely on the rows earlier ones leave behind.
+var conformanceChecks = []conformanceCheck{
+	{name: "list databases", endpoint: "get-databases", required: true, run: func(r *conformanceRun) (string, error) {
+		result, err := r.db.GetDatabases()
+		if err != nil {
+			return "", err
+		}
+		switch result.(type) {
+		case []interface{}, map[string]interface{}:
+			return fmt.Sprintf("%d databases", len(databaseNames(result))), nil
+		}
+		return "", fmt.Errorf("%w: %T", ErrUnexpectedResponse, result)
+	}},
+	{name: "create database", endpoint: "create-db", required: true, run: func(r *conformanceRun) (string, error) {
+		if exists, err := r.db.DatabaseExists(); err == nil && exists {
+			return "", fmt.Errorf("database %s already exists", r.db.Database)
+		}
+		_, err := r.db.CreateDB()
+		return r.db.Database, err
+	}},
+	{name: "database exists", endpoint: "check-db-exists", required: true, needs: []string{"create database"},
+		run: func(r *conformanceRun) (string, error) {
+			return "", expectExists(r.db.DatabaseExists, true)
+		}},
+	{name: "database missing", endpoint: "check-db-exists", required: true, run: func(r *conformanceRun) (string, error) {
+		return "", expectExists(r.db.ForDatabase(r.db.Database+"_missing").DatabaseExists, false)
+	}},
+	{name: "create table", endpoint: "create-table", required: true, needs: []string{"create database"},
+		run: func(r *conformanceRun) (string, error) {
+			_, err := r.db.CreateTable(conformanceTable, []string{"id", "name", "qty", "tags"})
+			return "", err
+		}},
+	{name: "table exists", endpoint: "check-table-exists", required: true, needs: []string{"create table"},
+		run: func(r *conformanceRun) (string, error) {
+			return "", expectExists(func() (bool, error) { return r.db.TableExists(conformanceTable) }, true)
+		}},
+	{name: "table missing", endpoint: "check-table-exists", required: true, needs: []string{"create database"},
+		run: func(r *conformanceRun) (string, error) {
+			return "", expectExists(func() (bool, error) { return r.db.TableExists(conformanceTable + "_missing") }, false)
+		}},
+	{name: "read database", endpoint: "read-db", required: true, needs: []string{"create table"},
+		run: func(r *conformanceRun) (string, error) {
+			tables, err := r.db.ListTables()
+			if err != nil {
+				return "", err
+			}
+			for _, t := range tables {
+				if t == conformanceTable {
+					return "", nil
+				}
+			}
+			return "", fmt.Errorf("tables %v do not include %s", tables, conformanceTable)
+		}},
+	{name: "insert rows", endpoint: "insert-into-table", required: true, needs: []string{"create table"},
+		run: func(r *conformanceRun) (string, error) {
+			for _, row := range conformanceRows {
+				if _, err := r.db.InsertIntoTable(conformanceTable, row); err != nil {
+					return "", err
+				}
+			}
+			return "", nil
+		}},
+	{name: "read table", endpoint: "get-table", required: true, needs: []string{"insert rows"},
+		run: func(r *conformanceRun) (string, error) {
+			rows, err := r.db.GetTableRows(conformanceTable)
+			if err != nil {
+				return "", err
+			}
+			return "", expectRows(rows, conformanceRows, nil)
+		}},
+	{name: "select where", endpoint: "select-where", required: true, needs: []string{"insert rows"},
+		run: func(r *conformanceRun) (string, error) {
+			rows, err := r.db.SelectWhereRows(conformanceTable, map[string]interface{}{"name": "bravo"})
+			if err != nil {
+				return "", err
+			}
+			return "", expectRows(rows, conformanceRows[1:2], nil)
+		}},
+	{name: "select where without matches", endpoint: "select-where", required: true, needs: []string{"insert rows"},
+		run: func(r *conformanceRun) (string, error) {
+			rows, err := r.db.SelectWhereRows(conformanceTable, map[string]interface{}{"name": "nobody"})
+			if err != nil {
+				return "", err
+			}
+			return "", expectRows(rows, nil, nil)
+		}},
+	{name: "select numeric condition", endpoint: "select-where", required: true, needs: []string{"insert rows"},
+		run: func(r *conformanceRun) (string, error) {
+			rows, err := r.db.SelectWhereRows(conformanceTable, map[string]interface{}{"qty": 3})
+			if err != nil {
+				return "", err
+			}
+			return "", expectRows(rows, conformanceRows[2:], nil)
+		}},
+	{name: "select columns", endpoint: "select-columns", required: true, needs: []string{"insert rows"},
+		run: func(r *conformanceRun) (string, error) {
+			rows, err := r.db.SelectColumnsRows(conformanceTable, []string{"id", "name"})
+			if err != nil {
+				return "", err
+			}
+			return "", expectRows(rows, conformanceRows, []string{"id", "name"})
+		}},
+	{name: "select columns where", endpoint: "select-columns-where", required: true, needs: []string{"insert rows"},
+		run: func(r *conformanceRun) (string, error) {
+			rows, err := r.db.SelectColumnsWhereRows(conformanceTable, []string{"name"}, map[string]interface{}{"id": "1"})
+			if err != nil {
+				return "", err
+			}
+			return "", expectRows(rows, conformanceRows[:1], []string{"name"})
+		}},
+	{name: "select via POST", endpoint: "select-where", needs: []string{"insert rows"},
+		run: func(r *conformanceRun) (string, error) {
+			rows, err := r.db.Clone(WithQueryMode(QueryModePost)).SelectWhereRows(conformanceTable, map[string]interface{}{"name": "alpha"})
+			if err != nil {
+				return "", err
+			}
+			return "", expectRows(rows, conformanceRows[:1], nil)
+		}},
+	{name: "select via query parameters", endpoint: "select-where", needs: []string{"insert rows"},
+		run: func(r *conformanceRun) (string, error) {
+			rows, err := r.db.Clone(WithQueryMode(QueryModeParams)).SelectWhereRows(conformanceTable, map[string]interface{}{"name": "alpha"})
+			if err != nil {
+				return "", err
+			}
+			return "", expectRows(rows, conformanceRows[:1], nil)
+		}},
+	{name: "select from missing table fails", endpoint: "select-where", needs: []string{"create database"},
+		run: func(r *conformanceRun) (string, error) {
+			rows, err := r.db.SelectWhereRows(conformanceTable+"_missing", map[string]interface{}{"id": "1"})
+			var apiErr *APIError
+			switch {
+			case errors.As(err, &apiErr):
+				return fmt.Sprintf("status %d", apiErr.HTTPStatus), nil
+			case err != nil:
+				return "", err
+			}
+			return "", fmt.Errorf("got %d rows and no error", len(rows))
+		}},
+	{name: "update where", endpoint: "update-table", required: true, needs: []string{"select where"},
+		run: func(r *conformanceRun) (string, error) {
+			if _, err := r.db.UpdateWhere(conformanceTable, map[string]interface{}{"name": "alpha"}, map[string]interface{}{"qty": 10}); err != nil {
+				return "", err
+			}
+			rows, err := r.db.GetTableRows(conformanceTable)
+			if err != nil {
+				return "", err
+			}
+			want := []Row{{"id": "1", "name": "alpha", "qty": 10, "tags": "a"}, conformanceRows[1], conformanceRows[2]}
+			return "", expectRows(rows, want, nil)
+		}},
+	{name: "delete where", endpoint: "delete-where", required: true, needs: []string{"select where"},
+		run: func(r *conformanceRun) (string, error) {
+			if _, err := r.db.DeleteWhere(conformanceTable, map[string]interface{}{"name": "charlie"}); err != nil {
+				return "", err
+			}
+			rows, err := r.db.SelectWhereRows(conformanceTable, map[string]interface{}{"name": "charlie"})
+			if err != nil {
+				return "", err
+			}
+			return "", expectRows(rows, nil, nil)
+		}},
+	{name: "value types round-trip", endpoint: "insert-into-table", needs: []string{"select where"},
+		run: func(r *conformanceRun) (string, error) {
+			row := Row{"id": "types", "name": "ünïcødé ✓ \"quoted\"", "qty": 2.5,
+				"tags": map[string]interface{}{"list": []interface{}{1, true, nil, "x"}, "nested": map[string]interface{}{"n": -7}}}
+			if _, err := r.db.InsertIntoTable(conformanceTable, row); err != nil {
+				return "", err
+			}
+			rows, err := r.db.SelectWhereRows(conformanceTable, map[string]interface{}{"id": "types"})
+			if err != nil {
+				return "", err
+			}
+			return "", expectRows(rows, []Row{row}, nil)
+		}},
+	{name: "typed columns", endpoint: "create-table", needs: []string{"create database"},
+		run: func(r *conformanceRun) (string, error) {
+			table := conformanceTable + "_typed"
+			columns := []ColumnDef{{Name: "id", Type: ColumnString, Required: true}, {Name: "n", Type: ColumnInt}}
+			if _, err := r.db.CreateTableWithColumns(table, columns); err != nil {
+				return "", err
+			}
+			_, err := r.db.DeleteTable(table)
+			return "", err
+		}},
+	{name: "reject invalid key", endpoint: "get-databases", run: func(r *conformanceRun) (string, error) {
+		_, err := r.db.Clone(WithKey("conformance-invalid-key")).GetDatabases()
+		var apiErr *APIError
+		switch {
+		case errors.As(err, &apiErr):
+			return fmt.Sprintf("status %d", apiErr.HTTPStatus), nil
+		case err != nil:
+			return "", err
+		}
+		return "", errors.New("an invalid key was accepted")
+	}},
+	{name: "create API key", endpoint: "create-key", run: func(r *conformanceRun) (string, error) {
+		if !r.c.Admin {
+			return "not enabled", errConformanceSkip
+		}
+		key, err := r.db.CreateAPIKey(r.db.Database)
+		if err != nil {
+			return "", err
+		}
+		r.apiKey = conformanceKey(key)
+		if r.apiKey == "" {
+			return "", fmt.Errorf("%w: no key in %q", ErrUnexpectedResponse, key)
+		}
+		return "", nil
+	}},
+	{name: "list API keys", endpoint: "get-keys", needs: []string{"create API key"}, run: func(r *conformanceRun) (string, error) {
+		keys, err := r.db.ListAPIKeys()
+		if err != nil {
+			return "", err
+		}
+		data, _ := json.Marshal(keys)
+		if !bytes.Contains(data, []byte(r.apiKey)) {
+			return "", fmt.Errorf("the created key is not listed")
+		}
+		return "", nil
+	}},
+	{name: "revoke API key", endpoint: "revoke-key", needs: []string{"create API key"}, run: func(r *conformanceRun) (string, error) {
+		_, err := r.db.RevokeAPIKey(r.apiKey)
+		return "", err
+	}},
+	{name: "delete table", endpoint: "delete-table", required: true, needs: []string{"create table"},
+		run: func(r *conformanceRun) (string, error) {
+			if _, err := r.db.DeleteTable(conformanceTable); err != nil {
+				return "", err
+			}
+			return "", expectExists(func() (bool, error) { return r.db.TableExists(conformanceTable) }, false)
+		}},
+	{name: "delete database", endpoint: "del-database", required: true, needs: []string{"create database"},
+		run: func(r *conformanceRun) (string, error) {
+			if _, err := r.db.DeleteDB(); err != nil {
+				return "", err
+			}
+			return "", expectExists(r.db.DatabaseExists, false)
+		}},
+}
+
+// expectExists checks an existence call's answer
+func expectExists(check func() (bool, error), want bool) error {
+	got, err := check()
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("reported %v, want %v", got, want)
+	}
+	return nil
+}
+
+// expectRows checks that got holds the want rows, projected to columns when
+// given, in any order. Values compare by their JSON encoding, so 1 and 1.0
+// are equal.
+func expectRows(got, want []Row, columns []string) error {
+	encode := func(rows []Row, columns []string) ([]string, error) {
+		out := make([]string, len(rows))
+		for i, row := range rows {
+			data, err := json.Marshal(project(row, columns))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = string(data)
+		}
+		sort.Strings(out)
+		return out, nil
+	}
+	g, err := encode(got, nil)
+	if err != nil {
+		return err
+	}
+	w, err := encode(want, columns)
+	if err != nil {
+		return err
+	}
+	if fmt.Sprint(g) != fmt.Sprint(w) {
+		return fmt.Errorf("got rows %v, want %v", g, w)
+	}
+	return nil
+}
+
+// conformanceKey extracts the key from a create-key response, which may be
+// the key itself, a JSON string or an object holding it
+func conformanceKey(body string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return strings.TrimSpace(body)
+	}
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		for _, field := range []string{"api_key", "key"} {
+			if s, ok := v[field].(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// CopyRows streams the rows of srcTable in src matching conditions (all rows
+// when conditions is empty) into dstTable in dst and returns the number
+// copied. Each record is inserted as soon as it is decoded from the
+// response, so the source result is never held in memory as a whole.
+// Numbers are copied verbatim, without a round trip through float64.
+func CopyRows(src *MenousDB, srcTable string, conditions map[string]interface{}, dst *MenousDB, dstTable string) (int, error) {
+	copied := 0
+	err := src.streamSelect("CopyRows", srcTable, nil, conditions, true, func(row Row) error {
+		if _, err := dst.InsertIntoTable(dstTable, row); err != nil {
+			return fmt.Errorf("inserting row %d: %w", copied, err)
+		}
+		copied++
+		return nil
+	})
+	return copied, err
+}
+
+// streamSelect runs a select of columns (all when empty) from records
+// matching conditions (all when empty) and calls emit for each record as it
+// is decoded, keeping numbers as json.Number when exactNumbers is set
+func (m *MenousDB) streamSelect(op, table string, columns []string, conditions map[string]interface{}, exactNumbers bool, emit func(Row) error) (err error) {
+	var endpoint string
+	body := map[string]interface{}{}
+	switch {
+	case len(columns) > 0 && len(conditions) > 0:
+		endpoint = "select-columns-where"
+		body["columns"] = columns
+		body["conditions"] = conditions
+	case len(columns) > 0:
+		endpoint = "select-columns"
+		body["columns"] = columns
+	case len(conditions) > 0:
+		endpoint = "select-where"
+		body["conditions"] = conditions
+	default:
+		endpoint = "get-table"
+	}
+	defer m.annotate(&err, op, endpoint, table)
+
+	if err := m.validateDatabase(); err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"key":      m.Key,
+		"database": m.Database,
+		"table":    table,
+	}
+
+	var resp *http.Response
+	if endpoint == "get-table" {
+		resp, err = m.makeRequest("GET", endpoint, headers, nil)
+	} else {
+		resp, err = m.doQuery(endpoint, headers, body)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return streamRecords(resp.Body, exactNumbers, emit)
+}
+
+// streamRecords walks a select response (an array of records or an object
+// keyed by row id) and calls emit for each record in the order sent
+func streamRecords(r io.Reader, exactNumbers bool, emit func(Row) error) error {
+	dec := json.NewDecoder(r)
+	if exactNumbers {
+		dec.UseNumber()
+	}
+	open, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	keyed := open == json.Delim('{')
+	if !keyed && open != json.Delim('[') {
+		return fmt.Errorf("%w: %v", ErrUnexpectedResponse, open)
+	}
+
+	for dec.More() {
+		if keyed {
+			// Row id, not part of the record
+			if _, err := dec.Token(); err != nil {
+				return err
+			}
+		}
+		var row Row
+		if err := dec.Decode(&row); err != nil {
+			return err
+		}
+		if row == nil {
+			return fmt.Errorf("%w: record is not an object", ErrUnexpectedResponse)
+		}
+		for k, v := range row {
+			row[k] = decompressValue(v)
+		}
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Cursor is the pagination state of a query over a table: which records it
+// selects and how far a client has read
+type Cursor struct {
+	Database   string                 `json:"d,omitempty"`
+	Table      string                 `json:"t"`
+	Columns    []string               `json:"s,omitempty"`
+	Conditions map[string]interface{} `json:"c,omitempty"`
+	Offset     int                    `json:"o,omitempty"`
+	PageSize   int                    `json:"n,omitempty"`
+
+	// Expires, if set, is when DecodeCursor starts rejecting the cursor
+	Expires time.Time `json:"-"`
+}
+
+// cursorPayload is the signed form of a Cursor
+type cursorPayload struct {
+	Cursor
+	Expires int64 `json:"e,omitempty"`
+}
+
+// EncodeCursor returns c as an opaque URL-safe token signed with key, safe
+// to hand to browsers: DecodeCursor rejects tokens that were changed. The
+// token is signed, not encrypted, so conditions can be read by whoever holds
+// it.
+func EncodeCursor(c Cursor, key []byte) (string, error) {
+	p := cursorPayload{Cursor: c}
+	if !c.Expires.IsZero() {
+		p.Expires = c.Expires.Unix()
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(data)
+	return body + "." + base64.RawURLEncoding.EncodeToString(cursorMAC(key, body)), nil
+}
+
+// DecodeCursor verifies a token from EncodeCursor against key and returns
+// its cursor. Invalid, tampered and expired tokens return ErrInvalidCursor.
+func DecodeCursor(token string, key []byte) (Cursor, error) {
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return Cursor{}, fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, cursorMAC(key, body)) {
+		return Cursor{}, fmt.Errorf("%w: bad signature", ErrInvalidCursor)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+	var p cursorPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Cursor{}, fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+	c := p.Cursor
+	if p.Expires != 0 {
+		c.Expires = time.Unix(p.Expires, 0).UTC()
+		if time.Now().After(c.Expires) {
+			return Cursor{}, fmt.Errorf("%w: expired", ErrInvalidCursor)
+		}
+	}
+	return c, nil
+}
+
+// cursorMAC signs a cursor body
+func cursorMAC(key []byte, body string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
+
+// FetchPage returns the page of rows c points at and the cursor of the page
+// after it, nil at the end. As with FetchAll, the matching records are
+// fetched whole and sliced client-side, so offsets shift when records are
+// inserted or deleted between pages.
+func (m *MenousDB) FetchPage(c Cursor) ([]Row, *Cursor, error) {
+	if c.PageSize <= 0 {
+		return nil, nil, fmt.Errorf("page size must be positive, got %d", c.PageSize)
+	}
+	var rows []Row
+	var err error
+	if c.Conditions == nil {
+		rows, err = m.GetTableRows(c.Table)
+	} else {
+		rows, err = m.SelectWhereRows(c.Table, c.Conditions)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := c.Offset
+	if start < 0 {
+		start = 0
+	}
+	if start > len(rows) {
+		start = len(rows)
+	}
+	end := start + c.PageSize
+	if end >= len(rows) {
+		return rows[start:], nil, nil
+	}
+	next := c
+	next.Offset = end
+	return rows[start:end], &next, nil
+}
@@ -0,0 +1,613 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DataSpec describes the synthetic tables gen-data produces
+//
+//	tables:
+//	  - name: users
+//	    rows: 10000
+//	    columns:
+//	      - name: id
+//	      - name: name
+//	      - name: email            # derived from name
+//	      - name: plan
+//	        fake: choice
+//	        values: [free, team, enterprise]
+//	        weights: [8, 3, 1]
+//	      - name: created_at
+//	      - name: last_login
+//	        fake: timestamp
+//	        from: created_at       # always after created_at
+//	        within: 2160h
+type DataSpec struct {
+	Tables []DataTableSpec `yaml:"tables" json:"tables"`
+}
+
+// DataTableSpec describes one synthetic table
+type DataTableSpec struct {
+	Name    string           `yaml:"name" json:"name"`
+	Rows    int              `yaml:"rows,omitempty" json:"rows,omitempty"`
+	Columns []DataColumnSpec `yaml:"columns" json:"columns"`
+}
+
+// DataColumnSpec describes how one column's values are generated. Fake
+// names the generator; when empty it is inferred from the column's name and
+// type, so id, name, email, city, created_at and the like need no more than
+// a name.
+type DataColumnSpec struct {
+	Name string     `yaml:"name" json:"name"`
+	Fake string     `yaml:"fake,omitempty" json:"fake,omitempty"`
+	Type ColumnType `yaml:"type,omitempty" json:"type,omitempty"`
+
+	// From names the column this one follows: an email or username is
+	// built from a name, a timestamp falls after another
+	From string `yaml:"from,omitempty" json:"from,omitempty"`
+
+	// Min and Max bound numbers, and timestamps as RFC 3339 or dates
+	Min interface{} `yaml:"min,omitempty" json:"min,omitempty"`
+	Max interface{} `yaml:"max,omitempty" json:"max,omitempty"`
+
+	// Within bounds how long after its From column a timestamp falls, as a
+	// Go duration; 720h when empty
+	Within string `yaml:"within,omitempty" json:"within,omitempty"`
+
+	// Values and Weights are a choice column's options and their relative
+	// frequencies, equal when Weights is empty
+	Values  []interface{} `yaml:"values,omitempty" json:"values,omitempty"`
+	Weights []float64     `yaml:"weights,omitempty" json:"weights,omitempty"`
+
+	// Nulls is the fraction of rows left null
+	Nulls float64 `yaml:"nulls,omitempty" json:"nulls,omitempty"`
+}
+
+// LoadDataSpec reads a data spec from a YAML or JSON file. A schema spec,
+// as schema-diff takes, is accepted too: its tables' columns get inferred
+// generators.
+func LoadDataSpec(path string) (*DataSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec struct {
+		DataSpec   `yaml:",inline"`
+		SchemaSpec `yaml:",inline"`
+	}
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	out := spec.DataSpec
+	for _, db := range spec.Databases {
+		for _, t := range db.Tables {
+			out.Tables = append(out.Tables, dataTableOf(t))
+		}
+	}
+	return &out, nil
+}
+
+// dataTableOf derives a data spec from a table declaration
+func dataTableOf(t TableSpec) DataTableSpec {
+	out := DataTableSpec{Name: t.Name}
+	for _, c := range t.columnDefs() {
+		col := DataColumnSpec{Name: c.Name, Type: c.Type}
+		if c.Nullable && !c.Required {
+			col.Nulls = 0.05
+		}
+		out.Columns = append(out.Columns, col)
+	}
+	return out
+}
+
+// Table returns the spec of the named table
+func (s *DataSpec) Table(name string) (DataTableSpec, bool) {
+	for _, t := range s.Tables {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return DataTableSpec{}, false
+}
+
+// TableSpec returns the table declaration matching the generated rows
+func (t DataTableSpec) TableSpec() TableSpec {
+	spec := TableSpec{Name: t.Name}
+	for _, c := range t.Columns {
+		typ := c.Type
+		if typ == ColumnAny {
+			typ = fakeTypes[c.Fake]
+		}
+		spec.Columns = append(spec.Columns, ColumnDef{Name: c.Name, Type: typ, Nullable: c.Nulls > 0})
+	}
+	return spec
+}
+
+// fakeTypes are the column types generators produce
+var fakeTypes = map[string]ColumnType{
+	"sequence": ColumnInt, "uuid": ColumnString, "first_name": ColumnString, "last_name": ColumnString,
+	"name": ColumnString, "email": ColumnString, "username": ColumnString, "phone": ColumnString,
+	"company": ColumnString, "city": ColumnString, "country": ColumnString, "address": ColumnString,
+	"url": ColumnString, "ip": ColumnString, "word": ColumnString, "sentence": ColumnString,
+	"timestamp": ColumnTime, "int": ColumnInt, "float": ColumnFloat, "price": ColumnFloat,
+	"bool": ColumnBool, "choice": ColumnAny, "json": ColumnJSON,
+}
+
+// DataGenerator produces rows for one table. Values within a row are
+// correlated: name, email and username columns describe the same person,
+// city and country the same place, and timestamps with From follow their
+// column.
+type DataGenerator struct {
+	spec  DataTableSpec
+	rand  *rand.Rand
+	order []int
+	seq   int64
+
+	// Per-row state shared by correlated columns
+	person *fakePerson
+	place  *fakePlace
+}
+
+// fakePerson is the person a row describes
+type fakePerson struct {
+	first, last string
+	n           int
+}
+
+// fakePlace is the place a row describes
+type fakePlace struct {
+	city, country string
+}
+
+// NewDataGenerator checks spec, inferring missing generators, and returns a
+// generator seeded with seed
+func NewDataGenerator(spec DataTableSpec, seed int64) (*DataGenerator, error) {
+	if len(spec.Columns) == 0 {
+		return nil, fmt.Errorf("table %s has no columns", spec.Name)
+	}
+	cols := append([]DataColumnSpec(nil), spec.Columns...)
+	index := make(map[string]int, len(cols))
+	for i, c := range cols {
+		if _, dup := index[c.Name]; dup {
+			return nil, fmt.Errorf("%s.%s: declared twice", spec.Name, c.Name)
+		}
+		index[c.Name] = i
+	}
+	for i := range cols {
+		c := &cols[i]
+		if c.Fake == "" {
+			fake, from := inferFake(c.Name, c.Type, index)
+			c.Fake = fake
+			if c.From == "" {
+				c.From = from
+			}
+		}
+		if _, ok := fakeTypes[c.Fake]; !ok {
+			return nil, fmt.Errorf("%s.%s: unknown fake %q", spec.Name, c.Name, c.Fake)
+		}
+		if c.From != "" {
+			if _, ok := index[c.From]; !ok {
+				return nil, fmt.Errorf("%s.%s: from names unknown column %q", spec.Name, c.Name, c.From)
+			}
+		}
+		if c.Fake == "choice" && len(c.Values) == 0 {
+			return nil, fmt.Errorf("%s.%s: choice needs values", spec.Name, c.Name)
+		}
+		if len(c.Weights) > 0 && len(c.Weights) != len(c.Values) {
+			return nil, fmt.Errorf("%s.%s: %d weights for %d values", spec.Name, c.Name, len(c.Weights), len(c.Values))
+		}
+		if c.Within != "" {
+			if _, err := time.ParseDuration(c.Within); err != nil {
+				return nil, fmt.Errorf("%s.%s: within: %w", spec.Name, c.Name, err)
+			}
+		}
+	}
+	spec.Columns = cols
+
+	// Generate columns after the ones they follow
+	g := &DataGenerator{spec: spec, rand: rand.New(rand.NewSource(seed))}
+	state := make([]int, len(cols))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case 1:
+			return fmt.Errorf("%s.%s: from columns form a cycle", spec.Name, cols[i].Name)
+		case 2:
+			return nil
+		}
+		state[i] = 1
+		if cols[i].From != "" {
+			if err := visit(index[cols[i].From]); err != nil {
+				return err
+			}
+		}
+		state[i] = 2
+		g.order = append(g.order, i)
+		return nil
+	}
+	for i := range cols {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// Spec returns the table spec with inferred generators filled in
+func (g *DataGenerator) Spec() DataTableSpec {
+	return g.spec
+}
+
+// inferFake picks a generator from a column's name and type, and the column
+// it follows when the table has an obvious one
+func inferFake(name string, typ ColumnType, columns map[string]int) (fake, from string) {
+	n := strings.ToLower(name)
+	has := func(s string) bool { return strings.Contains(n, s) }
+	source := func(names ...string) string {
+		for _, s := range names {
+			if _, ok := columns[s]; ok && s != name {
+				return s
+			}
+		}
+		return ""
+	}
+	switch {
+	case n == "id":
+		if typ == ColumnInt {
+			return "sequence", ""
+		}
+		return "uuid", ""
+	case has("uuid"), has("guid"):
+		return "uuid", ""
+	case has("first_name"), has("firstname"), n == "given_name":
+		return "first_name", ""
+	case has("last_name"), has("lastname"), has("surname"), n == "family_name":
+		return "last_name", ""
+	case has("email"):
+		return "email", source("name", "full_name", "username")
+	case has("username"), n == "login", n == "handle":
+		return "username", source("name", "full_name")
+	case n == "name", has("full_name"), n == "customer", n == "author":
+		return "name", ""
+	case has("phone"), has("mobile"):
+		return "phone", ""
+	case has("company"), has("employer"), has("organization"):
+		return "company", ""
+	case has("city"), has("town"):
+		return "city", ""
+	case has("country"):
+		return "country", ""
+	case has("address"), has("street"):
+		return "address", ""
+	case has("url"), has("website"), has("homepage"):
+		return "url", ""
+	case n == "ip", has("ip_address"), has("ipaddr"):
+		return "ip", ""
+	case has("price"), has("amount"), has("total"), has("cost"), has("balance"), has("spend"), has("revenue"), has("salary"), has("fee"):
+		return "price", ""
+	case typ == ColumnTime, strings.HasSuffix(n, "_at"), has("date"), has("time"):
+		if n != "created_at" && strings.HasSuffix(n, "_at") {
+			return "timestamp", source("created_at")
+		}
+		return "timestamp", ""
+	case typ == ColumnBool, strings.HasPrefix(n, "is_"), strings.HasPrefix(n, "has_"), n == "active", n == "enabled", n == "verified":
+		return "bool", ""
+	case has("description"), has("bio"), has("comment"), has("notes"), has("text"), has("body"), has("summary"):
+		return "sentence", ""
+	case typ == ColumnInt, n == "age", has("count"), has("qty"), has("quantity"):
+		return "int", ""
+	case typ == ColumnFloat:
+		return "float", ""
+	case typ == ColumnJSON:
+		return "json", ""
+	}
+	return "word", ""
+}
+
+// Row returns the next row
+func (g *DataGenerator) Row() Row {
+	g.seq++
+	g.person, g.place = nil, nil
+	row := make(Row, len(g.spec.Columns))
+	for _, i := range g.order {
+		c := &g.spec.Columns[i]
+		if c.Nulls > 0 && g.rand.Float64() < c.Nulls {
+			row[c.Name] = nil
+			continue
+		}
+		row[c.Name] = g.value(c, row)
+	}
+	return row
+}
+
+// value generates one column's value; row holds the columns generated so far
+func (g *DataGenerator) value(c *DataColumnSpec, row Row) interface{} {
+	r := g.rand
+	switch c.Fake {
+	case "sequence":
+		return g.seq
+	case "uuid":
+		b := make([]byte, 16)
+		r.Read(b)
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	case "first_name":
+		return g.personFor().first
+	case "last_name":
+		return g.personFor().last
+	case "name":
+		p := g.personFor()
+		return p.first + " " + p.last
+	case "email", "username":
+		p := g.personFor()
+		if s, ok := row[c.From].(string); ok && c.From != "" {
+			p = personFromName(s, p.n)
+		}
+		first, last := mailbox(p.first), mailbox(p.last)
+		user := first + "." + last
+		if c.Fake == "username" && first != "" {
+			user = first[:1] + last
+		}
+		if p.n > 0 {
+			user += fmt.Sprint(p.n)
+		}
+		if c.Fake == "username" {
+			return user
+		}
+		return user + "@" + dataDomains[r.Intn(len(dataDomains))]
+	case "phone":
+		return fmt.Sprintf("+1-555-%03d-%04d", r.Intn(1000), r.Intn(10000))
+	case "company":
+		return dataCompanyWords[r.Intn(len(dataCompanyWords))] + " " + dataCompanySuffixes[r.Intn(len(dataCompanySuffixes))]
+	case "city":
+		return g.placeFor().city
+	case "country":
+		return g.placeFor().country
+	case "address":
+		return fmt.Sprintf("%d %s %s", 1+r.Intn(9999), dataStreets[r.Intn(len(dataStreets))], []string{"St", "Ave", "Rd", "Ln", "Blvd"}[r.Intn(5)])
+	case "url":
+		return "https://" + strings.ToLower(dataCompanyWords[r.Intn(len(dataCompanyWords))]) + "." + dataDomains[r.Intn(len(dataDomains))] + "/"
+	case "ip":
+		// Documentation ranges only, so generated data never names a real host
+		nets := []string{"192.0.2", "198.51.100", "203.0.113"}
+		return fmt.Sprintf("%s.%d", nets[r.Intn(len(nets))], 1+r.Intn(254))
+	case "word":
+		return dataWords[r.Intn(len(dataWords))]
+	case "sentence":
+		words := make([]string, 4+r.Intn(12))
+		for i := range words {
+			words[i] = dataWords[r.Intn(len(dataWords))]
+		}
+		s := strings.Join(words, " ")
+		return strings.ToUpper(s[:1]) + s[1:] + "."
+	case "timestamp":
+		return g.timestamp(c, row).Format(time.RFC3339)
+	case "int":
+		lo, hi := numberRange(c, 0, 1000)
+		if strings.ToLower(c.Name) == "age" && c.Min == nil && c.Max == nil {
+			lo, hi = 18, 90
+		}
+		return int64(lo) + r.Int63n(int64(hi-lo)+1)
+	case "float":
+		lo, hi := numberRange(c, 0, 1000)
+		return lo + r.Float64()*(hi-lo)
+	case "price":
+		// Log-uniform, so cheap items are common and expensive ones rare
+		lo, hi := numberRange(c, 1, 1000)
+		v := math.Exp(math.Log(math.Max(lo, 0.01)) + r.Float64()*(math.Log(hi)-math.Log(math.Max(lo, 0.01))))
+		return math.Round(v*100) / 100
+	case "bool":
+		return r.Intn(2) == 0
+	case "choice":
+		return c.Values[weightedIndex(r, c.Weights, len(c.Values))]
+	case "json":
+		return map[string]interface{}{
+			"tag":   dataWords[r.Intn(len(dataWords))],
+			"score": r.Intn(100),
+		}
+	}
+	return nil
+}
+
+// personFor returns the row's person, drawing one on first use
+func (g *DataGenerator) personFor() *fakePerson {
+	if g.person == nil {
+		g.person = &fakePerson{
+			first: dataFirstNames[g.rand.Intn(len(dataFirstNames))],
+			last:  dataLastNames[g.rand.Intn(len(dataLastNames))],
+		}
+		// Keep usernames and emails mostly unique across rows
+		if g.rand.Intn(4) > 0 {
+			g.person.n = g.rand.Intn(1000)
+		}
+	}
+	return g.person
+}
+
+// personFromName splits a generated or given full name into a person
+func personFromName(name string, n int) *fakePerson {
+	parts := strings.Fields(name)
+	switch len(parts) {
+	case 0:
+		return &fakePerson{first: "user", last: "unknown", n: n}
+	case 1:
+		return &fakePerson{first: parts[0], last: "x", n: n}
+	}
+	return &fakePerson{first: parts[0], last: parts[len(parts)-1], n: n}
+}
+
+// mailbox lowercases s and keeps only ASCII letters and digits, so names
+// like Van Dijk or Müller make valid addresses
+func mailbox(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// placeFor returns the row's place, drawing one on first use
+func (g *DataGenerator) placeFor() *fakePlace {
+	if g.place == nil {
+		p := dataPlaces[g.rand.Intn(len(dataPlaces))]
+		g.place = &p
+	}
+	return g.place
+}
+
+// timestamp draws a time between Min and Max, by default the last two
+// years, or within Within after the From column
+func (g *DataGenerator) timestamp(c *DataColumnSpec, row Row) time.Time {
+	if c.From != "" {
+		if after, err := toTime(row[c.From]); err == nil {
+			within := 720 * time.Hour
+			if c.Within != "" {
+				within, _ = time.ParseDuration(c.Within)
+			}
+			return after.Add(time.Duration(g.rand.Int63n(int64(within) + 1))).Truncate(time.Second)
+		}
+	}
+	now := time.Now().UTC()
+	lo, hi := now.AddDate(-2, 0, 0), now
+	if t, ok := specTime(c.Min); ok {
+		lo = t
+	}
+	if t, ok := specTime(c.Max); ok {
+		hi = t
+	}
+	if !hi.After(lo) {
+		return lo
+	}
+	return lo.Add(time.Duration(g.rand.Int63n(int64(hi.Sub(lo))))).Truncate(time.Second)
+}
+
+// specTime reads a spec bound given as RFC 3339 or a date
+func specTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		if ts, err := time.Parse(time.RFC3339, t); err == nil {
+			return ts, true
+		}
+		if ts, err := time.Parse("2006-01-02", t); err == nil {
+			return ts, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// numberRange returns a column's numeric bounds, or the defaults
+func numberRange(c *DataColumnSpec, lo, hi float64) (float64, float64) {
+	if f, err := toFloat(c.Min); err == nil && c.Min != nil {
+		lo = f
+	}
+	if f, err := toFloat(c.Max); err == nil && c.Max != nil {
+		hi = f
+	}
+	if hi < lo {
+		hi = lo
+	}
+	return lo, hi
+}
+
+// weightedIndex picks an index in [0, n) by weight, uniformly without weights
+func weightedIndex(r *rand.Rand, weights []float64, n int) int {
+	if len(weights) == 0 {
+		return r.Intn(n)
+	}
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	x := r.Float64() * total
+	for i, w := range weights {
+		if x < w {
+			return i
+		}
+		x -= w
+	}
+	return n - 1
+}
+
+// Word lists for the generators. Domains are reserved for documentation, so
+// generated emails and URLs never reach real people.
+var (
+	dataFirstNames = []string{
+		"Ada", "Alan", "Amara", "Ana", "Arjun", "Beatriz", "Chen", "Dmitri", "Elena", "Emeka",
+		"Farah", "Grace", "Hiro", "Ingrid", "Isaac", "Jamal", "Julia", "Kwame", "Lars", "Leila",
+		"Mateo", "Mei", "Nadia", "Noah", "Olga", "Omar", "Priya", "Rafael", "Sakura", "Sofia",
+		"Tariq", "Thandiwe", "Uma", "Victor", "Wei", "Yara", "Yusuf", "Zoe",
+	}
+	dataLastNames = []string{
+		"Abara", "Andersen", "Bianchi", "Costa", "Dubois", "Eriksson", "Fernandez", "Garcia", "Haddad", "Ivanova",
+		"Jensen", "Kim", "Kowalski", "Lee", "Mensah", "Müller", "Nakamura", "Nguyen", "Okafor", "Patel",
+		"Quispe", "Rossi", "Santos", "Schmidt", "Singh", "Smith", "Tanaka", "Van Dijk", "Wang", "Yilmaz",
+	}
+	dataPlaces = []fakePlace{
+		{"Accra", "Ghana"}, {"Amsterdam", "Netherlands"}, {"Austin", "United States"}, {"Bangalore", "India"},
+		{"Berlin", "Germany"}, {"Bogotá", "Colombia"}, {"Cape Town", "South Africa"}, {"Chicago", "United States"},
+		{"Istanbul", "Turkey"}, {"Lagos", "Nigeria"}, {"Lima", "Peru"}, {"Lisbon", "Portugal"},
+		{"London", "United Kingdom"}, {"Lyon", "France"}, {"Melbourne", "Australia"}, {"Mexico City", "Mexico"},
+		{"Milan", "Italy"}, {"Mumbai", "India"}, {"Osaka", "Japan"}, {"São Paulo", "Brazil"},
+		{"Seoul", "South Korea"}, {"Stockholm", "Sweden"}, {"Tokyo", "Japan"}, {"Toronto", "Canada"},
+		{"Warsaw", "Poland"},
+	}
+	dataDomains         = []string{"example.com", "example.org", "example.net"}
+	dataCompanyWords    = []string{"Acme", "Blue", "Bright", "Cedar", "Delta", "Harbor", "Iron", "Lumen", "Nimbus", "North", "Orbit", "Pine", "Quartz", "Summit", "Vertex"}
+	dataCompanySuffixes = []string{"Labs", "Systems", "Group", "Industries", "Partners", "Works", "Analytics", "Logistics"}
+	dataStreets         = []string{"Maple", "Oak", "Cedar", "Elm", "Park", "Lake", "Hill", "River", "Station", "Market", "Church", "Mill"}
+	dataWords           = []string{
+		"alpha", "amber", "anchor", "apex", "arc", "atlas", "beacon", "birch", "bolt", "breeze",
+		"canyon", "cobalt", "comet", "coral", "crest", "dawn", "drift", "echo", "ember", "fable",
+		"fern", "flint", "forge", "glade", "grove", "harbor", "haze", "iris", "jade", "keystone",
+		"lagoon", "lark", "lotus", "maple", "meadow", "mesa", "mist", "nova", "oasis", "onyx",
+		"orbit", "pebble", "pine", "prism", "quill", "raven", "reef", "ridge", "sage", "shore",
+		"sierra", "slate", "spruce", "summit", "tide", "timber", "vale", "willow", "zephyr", "zinc",
+	}
+)
+
+// defaultDataColumns are generated when neither a spec nor an existing table
+// gives the columns
+var defaultDataColumns = []DataColumnSpec{
+	{Name: "id"}, {Name: "name"}, {Name: "email"}, {Name: "city"}, {Name: "country"},
+	{Name: "created_at"}, {Name: "updated_at"},
+}
+
+// LoadFakeData inserts rows generated by g through a BulkWriter and returns
+// the number written. progress, if set, is called every batch.
+func (m *MenousDB) LoadFakeData(ctx context.Context, g *DataGenerator, rows int, opts BulkWriterOptions, progress ProgressFunc) (int, error) {
+	w := m.NewBulkWriter(g.spec.Name, opts)
+	batch := opts.MaxRows
+	if batch <= 0 {
+		batch = 500
+	}
+	done := 0
+	for ; done < rows; done++ {
+		if done%batch == 0 {
+			if err := ctx.Err(); err != nil {
+				w.Close(context.Background())
+				return done, err
+			}
+			progress.report(done, rows)
+		}
+		if err := w.Write(g.Row()); err != nil {
+			w.Close(context.Background())
+			return done, err
+		}
+	}
+	err := w.Close(ctx)
+	progress.report(done, rows)
+	return done, err
+}
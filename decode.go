@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// WithStrictDecoding makes the *Into methods reject fields missing from the
+// destination struct and any data after the first JSON value, so drift
+// between client models and server data surfaces as an error
+func WithStrictDecoding() Option {
+	return func(m *MenousDB) {
+		m.strictDecode = true
+	}
+}
+
+// decodeInto decodes a JSON response into dst, honoring strict mode
+func (m *MenousDB) decodeInto(r io.Reader, dst interface{}) error {
+	if !m.strictDecode {
+		return m.decode(r, dst)
+	}
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return ErrTrailingData
+	}
+	return nil
+}
+
+// GetTableInto decodes a table's contents into dst
+func (m *MenousDB) GetTableInto(table string, dst interface{}) (err error) {
+	defer m.annotate(&err, "GetTableInto", "get-table", table)
+
+	if err := m.validateDatabase(); err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"key":      m.Key,
+		"database": m.Database,
+		"table":    table,
+	}
+
+	resp, err := m.makeRequest("GET", "get-table", headers, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return m.decodeInto(resp.Body, dst)
+}
+
+// SelectWhereInto decodes records matching conditions into dst
+func (m *MenousDB) SelectWhereInto(table string, conditions map[string]interface{}, dst interface{}) (err error) {
+	defer m.annotate(&err, "SelectWhereInto", "select-where", table)
+
+	if err := m.validateDatabase(); err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"key":      m.Key,
+		"database": m.Database,
+		"table":    table,
+	}
+
+	body := map[string]interface{}{
+		"conditions": conditions,
+	}
+
+	resp, err := m.doQuery("select-where", headers, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return m.decodeInto(resp.Body, dst)
+}
+
+// SelectColumnsWhereInto decodes specific columns matching conditions into dst
+func (m *MenousDB) SelectColumnsWhereInto(table string, columns []string, conditions map[string]interface{}, dst interface{}) (err error) {
+	defer m.annotate(&err, "SelectColumnsWhereInto", "select-columns-where", table)
+
+	if err := m.validateDatabase(); err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"key":      m.Key,
+		"database": m.Database,
+		"table":    table,
+	}
+
+	body := map[string]interface{}{
+		"columns":    columns,
+		"conditions": conditions,
+	}
+
+	resp, err := m.doQuery("select-columns-where", headers, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return m.decodeInto(resp.Body, dst)
+}
@@ -0,0 +1,59 @@
+package main
+
+// deduper remembers recently seen row keys. With a zero window it only
+// remembers keys until reset, which callers do at every batch boundary.
+type deduper struct {
+	columns []string
+	window  int
+	seen    map[string]struct{}
+	order   []string
+	next    int
+}
+
+// newDeduper returns a deduper keyed on columns, or nil if columns is empty
+func newDeduper(columns []string, window int) *deduper {
+	if len(columns) == 0 {
+		return nil
+	}
+	return &deduper{
+		columns: columns,
+		window:  window,
+		seen:    make(map[string]struct{}),
+	}
+}
+
+// duplicate reports whether row was seen recently, and records it if not
+func (d *deduper) duplicate(row Row) bool {
+	if d == nil {
+		return false
+	}
+	k := rowKey(row, d.columns)
+	if k == "" {
+		// Unencodable rows are never treated as duplicates
+		return false
+	}
+	if _, ok := d.seen[k]; ok {
+		return true
+	}
+	d.seen[k] = struct{}{}
+
+	if d.window > 0 {
+		// Ring buffer evicts the oldest key once the window is full
+		if len(d.order) < d.window {
+			d.order = append(d.order, k)
+		} else {
+			delete(d.seen, d.order[d.next])
+			d.order[d.next] = k
+			d.next = (d.next + 1) % d.window
+		}
+	}
+	return false
+}
+
+// batchDone forgets batch-scoped keys when no window is configured
+func (d *deduper) batchDone() {
+	if d == nil || d.window > 0 {
+		return
+	}
+	d.seen = make(map[string]struct{})
+}
@@ -0,0 +1,179 @@
+package main
+
+import "sync/atomic"
+
+// defaultClient is the client used by the package-level functions
+var defaultClient atomic.Pointer[MenousDB]
+
+// SetDefault sets the client used by the package-level functions, so small
+// scripts can call menousdb.SelectWhere(...) without passing a client around
+func SetDefault(m *MenousDB) {
+	defaultClient.Store(m)
+}
+
+// Default returns the client set with SetDefault, or nil
+func Default() *MenousDB {
+	return defaultClient.Load()
+}
+
+// ReadDB calls ReadDB on the default client
+func ReadDB() (map[string]interface{}, error) {
+	m := Default()
+	if m == nil {
+		return nil, ErrNoDefaultClient
+	}
+	return m.ReadDB()
+}
+
+// CreateDB calls CreateDB on the default client
+func CreateDB() (string, error) {
+	m := Default()
+	if m == nil {
+		return "", ErrNoDefaultClient
+	}
+	return m.CreateDB()
+}
+
+// DeleteDB calls DeleteDB on the default client
+func DeleteDB() (string, error) {
+	m := Default()
+	if m == nil {
+		return "", ErrNoDefaultClient
+	}
+	return m.DeleteDB()
+}
+
+// CheckDBExists calls CheckDBExists on the default client
+func CheckDBExists() (string, error) {
+	m := Default()
+	if m == nil {
+		return "", ErrNoDefaultClient
+	}
+	return m.CheckDBExists()
+}
+
+// CreateTable calls CreateTable on the default client
+func CreateTable(table string, attributes []string) (string, error) {
+	m := Default()
+	if m == nil {
+		return "", ErrNoDefaultClient
+	}
+	return m.CreateTable(table, attributes)
+}
+
+// CheckTableExists calls CheckTableExists on the default client
+func CheckTableExists(table string) (string, error) {
+	m := Default()
+	if m == nil {
+		return "", ErrNoDefaultClient
+	}
+	return m.CheckTableExists(table)
+}
+
+// InsertIntoTable calls InsertIntoTable on the default client
+func InsertIntoTable(table string, values interface{}) (string, error) {
+	m := Default()
+	if m == nil {
+		return "", ErrNoDefaultClient
+	}
+	return m.InsertIntoTable(table, values)
+}
+
+// GetTable calls GetTable on the default client
+func GetTable(table string) (interface{}, error) {
+	m := Default()
+	if m == nil {
+		return nil, ErrNoDefaultClient
+	}
+	return m.GetTable(table)
+}
+
+// GetTableRows calls GetTableRows on the default client
+func GetTableRows(table string) ([]Row, error) {
+	m := Default()
+	if m == nil {
+		return nil, ErrNoDefaultClient
+	}
+	return m.GetTableRows(table)
+}
+
+// SelectWhere calls SelectWhere on the default client
+func SelectWhere(table string, conditions map[string]interface{}) (interface{}, error) {
+	m := Default()
+	if m == nil {
+		return nil, ErrNoDefaultClient
+	}
+	return m.SelectWhere(table, conditions)
+}
+
+// SelectWhereRows calls SelectWhereRows on the default client
+func SelectWhereRows(table string, conditions map[string]interface{}) ([]Row, error) {
+	m := Default()
+	if m == nil {
+		return nil, ErrNoDefaultClient
+	}
+	return m.SelectWhereRows(table, conditions)
+}
+
+// SelectColumns calls SelectColumns on the default client
+func SelectColumns(table string, columns []string) (interface{}, error) {
+	m := Default()
+	if m == nil {
+		return nil, ErrNoDefaultClient
+	}
+	return m.SelectColumns(table, columns)
+}
+
+// SelectColumnsWhere calls SelectColumnsWhere on the default client
+func SelectColumnsWhere(table string, columns []string, conditions map[string]interface{}) (interface{}, error) {
+	m := Default()
+	if m == nil {
+		return nil, ErrNoDefaultClient
+	}
+	return m.SelectColumnsWhere(table, columns, conditions)
+}
+
+// Query calls Query on the default client
+func Query(table string, columns []string, conditions map[string]interface{}) (*Rows, error) {
+	m := Default()
+	if m == nil {
+		return nil, ErrNoDefaultClient
+	}
+	return m.Query(table, columns, conditions)
+}
+
+// DeleteWhere calls DeleteWhere on the default client
+func DeleteWhere(table string, conditions map[string]interface{}) (interface{}, error) {
+	m := Default()
+	if m == nil {
+		return nil, ErrNoDefaultClient
+	}
+	return m.DeleteWhere(table, conditions)
+}
+
+// DeleteTable calls DeleteTable on the default client
+func DeleteTable(table string) (interface{}, error) {
+	m := Default()
+	if m == nil {
+		return nil, ErrNoDefaultClient
+	}
+	return m.DeleteTable(table)
+}
+
+// UpdateWhere calls UpdateWhere on the default client
+func UpdateWhere(table string, conditions, values map[string]interface{}) (interface{}, error) {
+	m := Default()
+	if m == nil {
+		return nil, ErrNoDefaultClient
+	}
+	return m.UpdateWhere(table, conditions, values)
+}
+
+// GetDatabases calls GetDatabases on the default client
+func GetDatabases() (interface{}, error) {
+	m := Default()
+	if m == nil {
+		return nil, ErrNoDefaultClient
+	}
+	return m.GetDatabases()
+}
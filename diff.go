@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ChangeKind classifies a row difference
+type ChangeKind int
+
+const (
+	// RowAdded rows exist only in the target table
+	RowAdded ChangeKind = iota
+	// RowRemoved rows exist only in the source table
+	RowRemoved
+	// RowChanged rows exist in both tables with different values
+	RowChanged
+)
+
+// String returns the change kind name
+func (k ChangeKind) String() string {
+	switch k {
+	case RowAdded:
+		return "added"
+	case RowRemoved:
+		return "removed"
+	case RowChanged:
+		return "changed"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// RowChange is one difference between two tables. Old is nil for added rows
+// and New is nil for removed rows.
+type RowChange struct {
+	Kind ChangeKind
+	Key  map[string]interface{}
+	Old  Row
+	New  Row
+}
+
+// DiffSummary counts the differences found by DiffTables
+type DiffSummary struct {
+	Added     int
+	Removed   int
+	Changed   int
+	Unchanged int
+}
+
+// DiffTables compares table a (through client ca) with table b (through
+// client cb), matching rows on keyColumns, and calls fn for every
+// difference. The clients may point at different databases or servers. Only
+// table a is held in memory; b is decoded from its response as it arrives
+// and compared row by row against a's index, so run the smaller table as a
+// when sizes differ. Both tables are decoded the same way, as the server
+// sends them, without either client's declared column types, so the same
+// stored value compares equal on both sides.
+func DiffTables(ca *MenousDB, a string, cb *MenousDB, b string, keyColumns []string, fn func(RowChange) error) (DiffSummary, error) {
+	var summary DiffSummary
+	if len(keyColumns) == 0 {
+		return summary, fmt.Errorf("diff requires at least one key column")
+	}
+
+	var aRows []Row
+	err := ca.streamSelect("DiffTables", a, nil, nil, false, func(r Row) error {
+		aRows = append(aRows, r)
+		return nil
+	})
+	if err != nil {
+		return summary, fmt.Errorf("reading %s: %w", a, err)
+	}
+
+	// fn's errors are returned as they are, not as errors reading b
+	var fnErr error
+	report := func(c RowChange) error {
+		fnErr = fn(c)
+		return fnErr
+	}
+	summary, err = diffRows(aRows, keyColumns, func(emit func(Row) error) error {
+		return cb.streamSelect("DiffTables", b, nil, nil, false, emit)
+	}, report)
+	if fnErr != nil {
+		return summary, fnErr
+	}
+	if err != nil {
+		return summary, fmt.Errorf("reading %s: %w", b, err)
+	}
+	return summary, nil
+}
+
+// diffRows compares indexed old rows against new rows produced by stream
+func diffRows(oldRows []Row, keyColumns []string, stream func(emit func(Row) error) error, fn func(RowChange) error) (DiffSummary, error) {
+	var summary DiffSummary
+
+	index := make(map[string]Row, len(oldRows))
+	for _, row := range oldRows {
+		index[rowKey(row, keyColumns)] = row
+	}
+	matched := make(map[string]bool, len(oldRows))
+
+	err := stream(func(row Row) error {
+		key := rowKey(row, keyColumns)
+		old, ok := index[key]
+		switch {
+		case !ok:
+			summary.Added++
+			return fn(RowChange{Kind: RowAdded, Key: keyConditions(row, keyColumns), New: row})
+		case matched[key]:
+			// Duplicate keys on the new side can't be paired; treat as additions
+			summary.Added++
+			return fn(RowChange{Kind: RowAdded, Key: keyConditions(row, keyColumns), New: row})
+		}
+		matched[key] = true
+		if reflect.DeepEqual(old, row) {
+			summary.Unchanged++
+			return nil
+		}
+		summary.Changed++
+		return fn(RowChange{Kind: RowChanged, Key: keyConditions(row, keyColumns), Old: old, New: row})
+	})
+	if err != nil {
+		return summary, err
+	}
+
+	// Whatever the stream never matched has been removed, reported in the
+	// original row order
+	for _, row := range oldRows {
+		key := rowKey(row, keyColumns)
+		if matched[key] {
+			continue
+		}
+		matched[key] = true
+		summary.Removed++
+		if err := fn(RowChange{Kind: RowRemoved, Key: keyConditions(row, keyColumns), Old: row}); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
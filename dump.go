@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// DumpOptions configures DumpTable
+type DumpOptions struct {
+	// Shards partitions the table into disjoint condition sets downloaded
+	// concurrently, e.g. one per region value. When Shards and ShardBy are
+	// both empty the whole table is streamed from a single request.
+	Shards []map[string]interface{}
+
+	// ShardBy, if set and Shards is empty, derives the shards from a column:
+	// one per distinct value, found by first reading that column alone. Every
+	// row must have a value in the column, or the dump fails, since rows
+	// without one would match no shard.
+	ShardBy string
+
+	// Concurrency bounds the number of shards fetched at once; defaults to 4
+	Concurrency int
+
+	// Progress, if set, is called after each shard, or every 1000 rows of
+	// an unsharded dump, with the rows written so far. The total is reported
+	// only once an unsharded dump has finished.
+	Progress ProgressFunc
+
+	// Anonymize, if set, rewrites the table's columns before rows are
+	// written
+	Anonymize Anonymization
+}
+
+// shardResult holds one downloaded shard
+type shardResult struct {
+	rows []Row
+	err  error
+}
+
+// DumpTable writes every row of table to w as newline-delimited JSON and
+// returns the number of rows written. An unsharded dump streams rows as they
+// arrive. Shards are fetched concurrently, each held in memory until its
+// turn, and written in the order given (for ShardBy, the order of their
+// values' JSON), so output is deterministic.
+func (m *MenousDB) DumpTable(w io.Writer, table string, opts DumpOptions) (int, error) {
+	if len(opts.Shards) == 0 && opts.ShardBy != "" {
+		shards, err := m.dumpShards(table, opts.ShardBy)
+		if err != nil {
+			return 0, err
+		}
+		opts.Shards = shards
+	}
+	if len(opts.Shards) == 0 {
+		enc := json.NewEncoder(w)
+		n := 0
+		err := m.streamSelect("DumpTable", table, nil, nil, false, func(r Row) error {
+			opts.Anonymize.Apply(table, []Row{r})
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+			n++
+			if n%1000 == 0 {
+				opts.Progress.report(n, -1)
+			}
+			return nil
+		})
+		if err == nil {
+			opts.Progress.report(n, n)
+		}
+		return n, err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	// Each shard gets its own channel so results can be written in order
+	results := make([]chan shardResult, len(opts.Shards))
+	for i := range results {
+		results[i] = make(chan shardResult, 1)
+	}
+
+	sem := make(chan struct{}, concurrency)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i, conditions := range opts.Shards {
+			select {
+			case sem <- struct{}{}:
+			case <-done:
+				return
+			}
+			wg.Add(1)
+			go func(i int, conditions map[string]interface{}) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				rows, err := m.SelectWhereRows(table, conditions)
+				results[i] <- shardResult{rows: rows, err: err}
+			}(i, conditions)
+		}
+	}()
+
+	total := 0
+	for _, ch := range results {
+		res := <-ch
+		if res.err != nil {
+			return total, res.err
+		}
+		opts.Anonymize.Apply(table, res.rows)
+		n, err := writeRows(w, res.rows)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		opts.Progress.report(total, -1)
+	}
+	return total, nil
+}
+
+// dumpShards returns one shard per distinct value of column in table
+func (m *MenousDB) dumpShards(table, column string) ([]map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	err := m.streamSelect("DumpTable", table, []string{column}, nil, false, func(r Row) error {
+		v, ok := r[column]
+		if !ok || v == nil {
+			return fmt.Errorf("sharding %s by %s: a row has no value", table, column)
+		}
+		key, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		values[string(key)] = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	shards := make([]map[string]interface{}, len(keys))
+	for i, k := range keys {
+		shards[i] = map[string]interface{}{column: values[k]}
+	}
+	return shards, nil
+}
+
+// writeRows encodes rows to w as newline-delimited JSON
+func writeRows(w io.Writer, rows []Row) (int, error) {
+	enc := json.NewEncoder(w)
+	for i, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return i, err
+		}
+	}
+	return len(rows), nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tuning for latency-aware endpoint selection
+const (
+	// endpointDecay weights the newest sample in the rolling averages
+	endpointDecay = 0.2
+
+	// endpointMaxErrorRate marks an endpoint unhealthy once its rolling
+	// error rate passes it
+	endpointMaxErrorRate = 0.5
+
+	// endpointCooldown is how long an unhealthy endpoint is skipped before
+	// it is tried again
+	endpointCooldown = 10 * time.Second
+)
+
+// EndpointStatus reports the rolling statistics of one configured server URL
+type EndpointStatus struct {
+	URL       string
+	Latency   time.Duration
+	ErrorRate float64
+	Healthy   bool
+}
+
+// endpointStat tracks one server URL
+type endpointStat struct {
+	url       string
+	latency   float64
+	errorRate float64
+	samples   int
+	downUntil time.Time
+}
+
+// endpointSet holds the server URLs a client can route reads to. It is
+// shared by clones so they learn from each other's requests.
+type endpointSet struct {
+	mu      sync.Mutex
+	primary string
+	stats   []*endpointStat
+}
+
+// WithEndpoints adds replica URLs next to the client's primary URL. Reads go
+// to whichever healthy URL has the lowest rolling latency; writes always go
+// to the primary. Ignored for unix socket clients.
+func WithEndpoints(urls ...string) Option {
+	return func(m *MenousDB) {
+		m.replicaURLs = append(m.replicaURLs[:len(m.replicaURLs):len(m.replicaURLs)], urls...)
+	}
+}
+
+// newEndpointSet builds the set for primary and replicas
+func newEndpointSet(primary string, replicas []string) *endpointSet {
+	s := &endpointSet{}
+	s.replace(append([]string{primary}, replicas...))
+	return s
+}
+
+// replace swaps in a new list of URLs, the first being the primary, keeping
+// the statistics of URLs already known. It reports whether the list changed.
+func (s *endpointSet) replace(urls []string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	known := make(map[string]*endpointStat, len(s.stats))
+	for _, st := range s.stats {
+		known[st.url] = st
+	}
+
+	var stats []*endpointStat
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		if !strings.HasSuffix(u, "/") {
+			u += "/"
+		}
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		st := known[u]
+		if st == nil {
+			st = &endpointStat{url: u}
+		}
+		stats = append(stats, st)
+	}
+
+	changed := len(stats) != len(s.stats) || stats[0].url != s.primary
+	for i := 0; !changed && i < len(stats); i++ {
+		changed = stats[i] != s.stats[i]
+	}
+	s.primary = stats[0].url
+	s.stats = stats
+	return changed
+}
+
+// primaryURL returns the URL that receives writes
+func (s *endpointSet) primaryURL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.primary
+}
+
+// pick returns the base URL to send a read to. Unmeasured URLs are tried
+// first so every one gets a latency sample; if none is healthy the one that
+// recovers soonest is used.
+func (s *endpointSet) pick(now time.Time) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var best, fallback *endpointStat
+	for _, st := range s.stats {
+		if now.Before(st.downUntil) {
+			if fallback == nil || st.downUntil.Before(fallback.downUntil) {
+				fallback = st
+			}
+			continue
+		}
+		if st.samples == 0 {
+			return st.url
+		}
+		if best == nil || st.latency < best.latency {
+			best = st
+		}
+	}
+	if best == nil {
+		best = fallback
+	}
+	return best.url
+}
+
+// observe records the outcome of a request sent to url
+func (s *endpointSet) observe(url string, elapsed time.Duration, failed bool, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, st := range s.stats {
+		if !strings.HasPrefix(url, st.url) {
+			continue
+		}
+		errSample := 0.0
+		if failed {
+			errSample = 1
+		}
+		if st.samples == 0 {
+			st.latency = float64(elapsed)
+			st.errorRate = errSample
+		} else {
+			st.latency += endpointDecay * (float64(elapsed) - st.latency)
+			st.errorRate += endpointDecay * (errSample - st.errorRate)
+		}
+		st.samples++
+
+		if st.errorRate > endpointMaxErrorRate {
+			st.downUntil = now.Add(endpointCooldown)
+			// Start the error rate over so one more failure after the
+			// cooldown does not immediately exclude it again
+			st.errorRate = endpointMaxErrorRate
+		}
+		return
+	}
+}
+
+// Endpoints reports the statistics of each configured server URL, healthy
+// ones first and fastest first. It returns nil when no replicas are configured.
+func (m *MenousDB) Endpoints() []EndpointStatus {
+	if m.endpoints == nil {
+		return nil
+	}
+	m.endpoints.mu.Lock()
+	defer m.endpoints.mu.Unlock()
+
+	now := time.Now()
+	out := make([]EndpointStatus, len(m.endpoints.stats))
+	for i, st := range m.endpoints.stats {
+		out[i] = EndpointStatus{
+			URL:       st.url,
+			Latency:   time.Duration(st.latency),
+			ErrorRate: st.errorRate,
+			Healthy:   !now.Before(st.downUntil),
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Healthy != out[j].Healthy {
+			return out[i].Healthy
+		}
+		return out[i].Latency < out[j].Latency
+	})
+	return out
+}
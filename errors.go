@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/url"
+)
+
+// ErrReadOnly is returned when a read-only client attempts a mutation
+var ErrReadOnly = errors.New("menousdb: client is read-only")
+
+// ErrAccessDenied is returned when the configured AccessPolicy rejects a call
+var ErrAccessDenied = errors.New("menousdb: access denied")
+
+// ErrInvalidHeaderValue is returned when a header value cannot be sent as-is
+var ErrInvalidHeaderValue = errors.New("menousdb: invalid header value")
+
+// ErrInvalidIdentifier is returned for database, table or column names
+// outside the allowed character set or length
+var ErrInvalidIdentifier = errors.New("menousdb: invalid identifier")
+
+// ErrTrailingData is returned in strict decode mode when a response holds
+// more than one JSON value
+var ErrTrailingData = errors.New("menousdb: trailing data after JSON value")
+
+// ErrUnexpectedResponse is returned when a response does not have the shape
+// a typed method expects
+var ErrUnexpectedResponse = errors.New("menousdb: unexpected response shape")
+
+// ErrNoHealthyClient is returned by Pool.Get when every client is idle and
+// marked unhealthy
+var ErrNoHealthyClient = errors.New("menousdb: no healthy client in pool")
+
+// ErrNotCheckedOut is returned by Pool.Put for a client that did not come
+// from the pool's Get, or was already put back
+var ErrNotCheckedOut = errors.New("menousdb: client not checked out of pool")
+
+// isTransportError reports whether err came from reaching the server rather
+// than from the server's answer
+func isTransportError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
+
+// ErrNoDefaultClient is returned by the package-level functions before
+// SetDefault is called
+var ErrNoDefaultClient = errors.New("menousdb: no default client set")
+
+// ErrClientClosed is returned by requests made after Close
+var ErrClientClosed = errors.New("menousdb: client is closed")
+
+// ErrFeatureDisabled is returned for endpoints of a feature the client has
+// disabled
+var ErrFeatureDisabled = errors.New("menousdb: feature disabled")
+
+// ErrFeatureUnsupported is returned for endpoints of a feature the server has
+// rejected before
+var ErrFeatureUnsupported = errors.New("menousdb: feature not supported by server")
+
+// OpError records the call that failed: the client method, the endpoint it
+// hit, and the database and table involved. It wraps the underlying error,
+// so errors.Is and errors.As see through it.
+type OpError struct {
+	Op       string
+	Endpoint string
+	Database string
+	Table    string
+	Err      error
+}
+
+// Error formats the failing call and its cause
+func (e *OpError) Error() string {
+	s := "menousdb: " + e.Op + " (" + e.Endpoint
+	if e.Database != "" {
+		s += ", database " + e.Database
+	}
+	if e.Table != "" {
+		s += ", table " + e.Table
+	}
+	return s + "): " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// annotate wraps *err in an OpError unless it is nil or already annotated
+func (m *MenousDB) annotate(err *error, op, endpoint, table string) {
+	if *err == nil {
+		return
+	}
+	var opErr *OpError
+	if errors.As(*err, &opErr) {
+		return
+	}
+	*err = &OpError{Op: op, Endpoint: endpoint, Database: m.Database, Table: table, Err: *err}
+}
+
+// ErrBlobNotFound is returned by GetBlob and Attachments when nothing is
+// stored under the key
+var ErrBlobNotFound = errors.New("menousdb: blob not found")
+
+// ErrBlobCorrupt is returned by GetBlob and attachment readers when chunks
+// are missing or the reassembled data does not match its checksum
+var ErrBlobCorrupt = errors.New("menousdb: blob corrupt")
+
+// ErrInvalidCursor is returned by DecodeCursor for cursors that are
+// malformed, tampered with, signed with another key or expired
+var ErrInvalidCursor = errors.New("menousdb: invalid cursor")
+
+// ErrWriterClosed is returned by writes to a BulkWriter after Close
+var ErrWriterClosed = errors.New("menousdb: bulk writer is closed")
+
+// ErrNATSQueueFull is reported to NATSOptions.OnError for each event dropped
+// because the publish queue was full
+var ErrNATSQueueFull = errors.New("menousdb: nats publish queue full")
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Event is a change made by a successful write: RowInserted, RowsUpdated,
+// RowsDeleted or TableCreated
+type Event interface {
+	// Scope returns the database and table the write changed
+	Scope() (database, table string)
+}
+
+// RowInserted reports a row inserted into a table
+type RowInserted struct {
+	Database string
+	Table    string
+	Values   Row
+}
+
+// RowsUpdated reports an update of the rows matching Conditions
+type RowsUpdated struct {
+	Database   string
+	Table      string
+	Conditions map[string]interface{}
+	Values     Row
+}
+
+// RowsDeleted reports a delete of the rows matching Conditions
+type RowsDeleted struct {
+	Database   string
+	Table      string
+	Conditions map[string]interface{}
+}
+
+// TableCreated reports a table created with Attributes
+type TableCreated struct {
+	Database   string
+	Table      string
+	Attributes []string
+}
+
+// Scope returns the database and table the event concerns
+func (e RowInserted) Scope() (string, string) { return e.Database, e.Table }
+
+// Scope returns the database and table the event concerns
+func (e RowsUpdated) Scope() (string, string) { return e.Database, e.Table }
+
+// Scope returns the database and table the event concerns
+func (e RowsDeleted) Scope() (string, string) { return e.Database, e.Table }
+
+// Scope returns the database and table the event concerns
+func (e TableCreated) Scope() (string, string) { return e.Database, e.Table }
+
+// EventBus delivers events for successful writes to subscribed handlers.
+// Handlers run on the goroutine that made the write, in subscription order,
+// before the write returns; slow work belongs on a goroutine of its own.
+type EventBus struct {
+	mu       sync.Mutex
+	next     int
+	handlers []eventHandler
+}
+
+// eventHandler is one subscription
+type eventHandler struct {
+	id int
+	fn func(Event)
+}
+
+// NewEventBus creates an event bus with no subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// WithEventBus emits events for the client's successful writes on b.
+// Clients made from it share the bus.
+func WithEventBus(b *EventBus) Option {
+	return func(m *MenousDB) {
+		m.events = b
+	}
+}
+
+// Subscribe calls fn with every event until the returned function is called.
+// Handlers pick out the events they want with a type switch:
+//
+//	unsubscribe := bus.Subscribe(func(e Event) {
+//		if ins, ok := e.(RowInserted); ok {
+//			notify(ins.Table, ins.Values)
+//		}
+//	})
+func (b *EventBus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.next++
+	id := b.next
+	// Copy on write, so Publish can range over a snapshot without the lock
+	handlers := make([]eventHandler, len(b.handlers), len(b.handlers)+1)
+	copy(handlers, b.handlers)
+	b.handlers = append(handlers, eventHandler{id: id, fn: fn})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			handlers := make([]eventHandler, 0, len(b.handlers))
+			for _, h := range b.handlers {
+				if h.id != id {
+					handlers = append(handlers, h)
+				}
+			}
+			b.handlers = handlers
+		})
+	}
+}
+
+// Publish delivers e to every current subscriber
+func (b *EventBus) Publish(e Event) {
+	b.mu.Lock()
+	handlers := b.handlers
+	b.mu.Unlock()
+	for _, h := range handlers {
+		h.fn(e)
+	}
+}
+
+// emitEvent publishes the event for a successful write, if it has one, on
+// the client's event bus and NATS bridge. Values are reported as the
+// application wrote them, after defaults and before compression.
+func (m *MenousDB) emitEvent(endpoint string, headers map[string]string, body interface{}) {
+	database, table := headers["database"], headers["table"]
+	var req struct {
+		Values     Row                    `json:"values"`
+		Conditions map[string]interface{} `json:"conditions"`
+		Attributes []string               `json:"attributes"`
+	}
+	if body != nil && !remarshal(body, &req) {
+		return
+	}
+	if req.Values != nil {
+		m.decodeColumns(table, []Row{req.Values})
+	}
+
+	var e Event
+	switch endpoint {
+	case "insert-into-table":
+		e = RowInserted{Database: database, Table: table, Values: req.Values}
+	case "update-table":
+		e = RowsUpdated{Database: database, Table: table, Conditions: req.Conditions, Values: req.Values}
+	case "delete-where":
+		e = RowsDeleted{Database: database, Table: table, Conditions: req.Conditions}
+	case "create-table":
+		e = TableCreated{Database: database, Table: table, Attributes: req.Attributes}
+	default:
+		return
+	}
+	if m.events != nil {
+		m.events.Publish(e)
+	}
+	if m.nats != nil {
+		m.nats.publish(e)
+	}
+}
+
+// marshalEvent encodes e as a JSON object with its type name under "type"
+// and its fields in lower case
+func marshalEvent(e Event) ([]byte, error) {
+	database, table := e.Scope()
+	msg := map[string]interface{}{
+		"database": database,
+		"table":    table,
+	}
+	switch e := e.(type) {
+	case RowInserted:
+		msg["type"] = "RowInserted"
+		msg["values"] = e.Values
+	case RowsUpdated:
+		msg["type"] = "RowsUpdated"
+		msg["conditions"] = e.Conditions
+		msg["values"] = e.Values
+	case RowsDeleted:
+		msg["type"] = "RowsDeleted"
+		msg["conditions"] = e.Conditions
+	case TableCreated:
+		msg["type"] = "TableCreated"
+		msg["attributes"] = e.Attributes
+	}
+	return json.Marshal(msg)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// parseExists interprets an existence-check response, which the server
+// sends as a JSON boolean or as the text True/False
+func parseExists(body string) (bool, error) {
+	text := strings.TrimSpace(body)
+	var v interface{}
+	if err := json.Unmarshal([]byte(text), &v); err == nil {
+		if s, ok := v.(string); ok {
+			text = s
+		} else if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "true", "exists", "yes", "1":
+		return true, nil
+	case "false", "does not exist", "no", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("%w: cannot interpret %q as an existence check", ErrUnexpectedResponse, text)
+}
+
+// DatabaseExists reports whether the client's database exists
+func (m *MenousDB) DatabaseExists() (bool, error) {
+	body, err := m.CheckDBExists()
+	if err != nil {
+		return false, err
+	}
+	return parseExists(body)
+}
+
+// TableExists reports whether table exists in the client's database
+func (m *MenousDB) TableExists(table string) (bool, error) {
+	body, err := m.CheckTableExists(table)
+	if err != nil {
+		return false, err
+	}
+	return parseExists(body)
+}
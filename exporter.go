@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exporter periodically collects health metrics from a MenousDB server and
+// serves them in the Prometheus text exposition format.
+//
+// The server has no count endpoint, so menousdb_table_rows is measured by
+// reading every table in full on each collection: the cost in server load
+// and transfer grows with the data, once per interval. Rows are counted as
+// they stream in rather than held, but on large databases restrict
+// Databases, lengthen the interval or set SkipRowCounts.
+type Exporter struct {
+	Client *MenousDB
+
+	// Databases lists the databases whose tables are counted. Empty means
+	// every database returned by GetDatabases.
+	Databases []string
+
+	// SkipRowCounts leaves out menousdb_table_rows, so collections never
+	// read table contents
+	SkipRowCounts bool
+
+	mu      sync.RWMutex
+	metrics string
+	errors  int
+}
+
+// databaseNames extracts names from a GetDatabases response
+func databaseNames(result interface{}) []string {
+	var names []string
+	switch v := result.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				names = append(names, s)
+			}
+		}
+	case map[string]interface{}:
+		for name := range v {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Collect gathers one set of metrics
+func (e *Exporter) Collect() {
+	var b strings.Builder
+	now := time.Now()
+	failed := false
+
+	latency, err := e.Client.ForDatabase("").Ping()
+	up := 1
+	if err != nil {
+		up = 0
+		failed = true
+	}
+	writeMetric(&b, "menousdb_up", "gauge", "Whether the server answered the last ping.", nil, float64(up))
+	if err == nil {
+		writeMetric(&b, "menousdb_ping_seconds", "gauge", "Round-trip time of the last ping.", nil, latency.Seconds())
+	}
+
+	var databases []string
+	if result, err := e.Client.GetDatabases(); err == nil {
+		databases = databaseNames(result)
+		writeMetric(&b, "menousdb_databases", "gauge", "Number of databases on the server.", nil, float64(len(databases)))
+	} else {
+		failed = true
+	}
+	if len(e.Databases) > 0 {
+		databases = e.Databases
+	}
+	if e.SkipRowCounts {
+		databases = nil
+	}
+
+	header := true
+	for _, db := range databases {
+		client := e.Client.ForDatabase(db)
+		tables, err := client.ListTables()
+		if err != nil {
+			failed = true
+			continue
+		}
+		for _, table := range tables {
+			rows := 0
+			err := client.streamSelect("Collect", table, nil, nil, false, func(Row) error {
+				rows++
+				return nil
+			})
+			if err != nil {
+				failed = true
+				continue
+			}
+			help := ""
+			if header {
+				help = "Number of rows in each table."
+				header = false
+			}
+			writeMetric(&b, "menousdb_table_rows", "gauge", help,
+				[][2]string{{"database", db}, {"table", table}}, float64(rows))
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if failed {
+		e.errors++
+	}
+	writeMetric(&b, "menousdb_scrape_errors_total", "counter", "Collections that hit at least one error.", nil, float64(e.errors))
+	writeMetric(&b, "menousdb_last_collect_timestamp_seconds", "gauge", "Unix time of the last collection.", nil, float64(now.Unix()))
+	e.metrics = b.String()
+}
+
+// Run collects immediately and then every interval until ctx is done
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		e.Collect()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ServeHTTP serves the most recently collected metrics
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprint(w, e.metrics)
+}
+
+// labelEscaper escapes label values as the exposition format requires:
+// only backslash, double quote and newline
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeMetric appends one sample, preceded by HELP and TYPE lines when help
+// is non-empty
+func writeMetric(b *strings.Builder, name, typ, help string, labels [][2]string, value float64) {
+	if help != "" {
+		fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=\"%s\"", l[0], labelEscaper.Replace(l[1]))
+		}
+		b.WriteByte('}')
+	}
+	fmt.Fprintf(b, " %g\n", value)
+}
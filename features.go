@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Feature names an optional server capability whose endpoints the client can
+// adopt gradually
+type Feature string
+
+const (
+	// FeatureAPIKeys covers the API key management endpoints used by
+	// CreateAPIKey, ListAPIKeys and RevokeAPIKey
+	FeatureAPIKeys Feature = "api-keys"
+)
+
+// featureInfo describes a feature: the endpoints it gates and whether it is
+// on unless disabled
+type featureInfo struct {
+	endpoints []string
+	enabled   bool
+}
+
+// features lists the known features. Endpoints not listed are always allowed.
+var features = map[Feature]featureInfo{
+	FeatureAPIKeys: {endpoints: []string{"create-key", "get-keys", "revoke-key"}, enabled: true},
+}
+
+// featureEndpoints maps each gated endpoint to its feature
+var featureEndpoints = func() map[string]Feature {
+	out := make(map[string]Feature)
+	for f, info := range features {
+		for _, e := range info.endpoints {
+			out[e] = f
+		}
+	}
+	return out
+}()
+
+// featureSet records which features a client has enabled and which the
+// server turned out not to support
+type featureSet struct {
+	mu          sync.RWMutex
+	enabled     map[Feature]bool
+	unsupported map[Feature]bool
+}
+
+// newFeatureSet starts from each feature's default
+func newFeatureSet() *featureSet {
+	s := &featureSet{enabled: make(map[Feature]bool), unsupported: make(map[Feature]bool)}
+	for f, info := range features {
+		s.enabled[f] = info.enabled
+	}
+	return s
+}
+
+// clone copies the set so a cloned client can toggle features on its own
+func (s *featureSet) clone() *featureSet {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c := &featureSet{enabled: make(map[Feature]bool, len(s.enabled)), unsupported: make(map[Feature]bool, len(s.unsupported))}
+	for f, v := range s.enabled {
+		c.enabled[f] = v
+	}
+	for f, v := range s.unsupported {
+		c.unsupported[f] = v
+	}
+	return c
+}
+
+// WithFeatures enables fs on the new client
+func WithFeatures(fs ...Feature) Option {
+	return func(m *MenousDB) {
+		m.Enable(fs...)
+	}
+}
+
+// Enable turns features on. It also forgets that the server rejected them,
+// so enabling again re-probes a server that has since been upgraded.
+func (m *MenousDB) Enable(fs ...Feature) {
+	m.setFeatures(true, fs)
+}
+
+// Disable turns features off; their endpoints then fail with
+// ErrFeatureDisabled without reaching the server
+func (m *MenousDB) Disable(fs ...Feature) {
+	m.setFeatures(false, fs)
+}
+
+// setFeatures records fs as enabled or disabled
+func (m *MenousDB) setFeatures(on bool, fs []Feature) {
+	if m.features == nil {
+		m.features = newFeatureSet()
+	}
+	m.features.mu.Lock()
+	defer m.features.mu.Unlock()
+	for _, f := range fs {
+		m.features.enabled[f] = on
+		if on {
+			delete(m.features.unsupported, f)
+		}
+	}
+}
+
+// Supports reports whether f is enabled and has not been rejected by the
+// server. A feature counts as rejected once one of its endpoints answers
+// 404, 405 or 501.
+func (m *MenousDB) Supports(f Feature) bool {
+	s := m.features
+	if s == nil {
+		return features[f].enabled
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.enabled[f] && !s.unsupported[f]
+}
+
+// checkFeature fails requests to endpoints of disabled or unsupported features
+func (m *MenousDB) checkFeature(endpoint string) error {
+	f, ok := featureEndpoints[endpoint]
+	if !ok || m.Supports(f) {
+		return nil
+	}
+	if m.features != nil {
+		m.features.mu.RLock()
+		defer m.features.mu.RUnlock()
+		if m.features.unsupported[f] {
+			return fmt.Errorf("%w: %s", ErrFeatureUnsupported, f)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrFeatureDisabled, f)
+}
+
+// observeFeature marks an endpoint's feature unsupported when err shows the
+// server does not have the endpoint
+func (m *MenousDB) observeFeature(endpoint string, err error) {
+	f, ok := featureEndpoints[endpoint]
+	if !ok || m.features == nil {
+		return
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return
+	}
+	switch apiErr.HTTPStatus {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		m.features.mu.Lock()
+		m.features.unsupported[f] = true
+		m.features.mu.Unlock()
+	}
+}
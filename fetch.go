@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrStopFetch can be returned from a FetchAll callback to stop early
+// without FetchAll reporting an error
+var ErrStopFetch = errors.New("menousdb: stop fetch")
+
+// FetchAll walks every record in table matching conditions (nil for all) and
+// calls fn with consecutive chunks of at most pageSize rows. It is not
+// server-side pagination: the server has no paging endpoint, so one request
+// selects every matching record and the server builds and sends the whole
+// result, however large, and returning ErrStopFetch only abandons the rest
+// of the download. What FetchAll bounds is the client's memory: the response
+// is decoded as it streams in and each page is handed to fn as soon as it
+// fills, so only one page is held at a time (plus up to the response
+// cache's MaxEntryBytes when caching is on). fn may keep the pages it is
+// given.
+func (m *MenousDB) FetchAll(table string, conditions map[string]interface{}, pageSize int, fn func(page []Row) error) error {
+	if pageSize <= 0 {
+		return fmt.Errorf("page size must be positive, got %d", pageSize)
+	}
+
+	// fn's errors are returned as they are, not annotated as request errors
+	var fnErr error
+	page := make([]Row, 0, pageSize)
+	flush := func() error {
+		if len(page) == 0 {
+			return nil
+		}
+		fnErr = fn(page)
+		page = make([]Row, 0, pageSize)
+		return fnErr
+	}
+	err := m.streamSelect("FetchAll", table, nil, conditions, false, m.columnDecoder(table, func(r Row) error {
+		page = append(page, r)
+		if len(page) < pageSize {
+			return nil
+		}
+		return flush()
+	}))
+	if err == nil {
+		err = flush()
+	}
+	if fnErr != nil {
+		if errors.Is(fnErr, ErrStopFetch) {
+			return nil
+		}
+		return fnErr
+	}
+	return err
+}
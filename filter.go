@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// Predicate reports whether a row matches
+type Predicate func(Row) bool
+
+// Eq matches rows whose column equals v; numbers compare by value
+func Eq(column string, v interface{}) Predicate {
+	return func(r Row) bool { return compareValues(r[column], v) == 0 }
+}
+
+// Ne matches rows whose column differs from v
+func Ne(column string, v interface{}) Predicate {
+	return func(r Row) bool { return compareValues(r[column], v) != 0 }
+}
+
+// Gt matches rows whose column is greater than v
+func Gt(column string, v interface{}) Predicate {
+	return func(r Row) bool { return orderable(r[column], v) && compareValues(r[column], v) > 0 }
+}
+
+// Gte matches rows whose column is at least v
+func Gte(column string, v interface{}) Predicate {
+	return func(r Row) bool { return orderable(r[column], v) && compareValues(r[column], v) >= 0 }
+}
+
+// Lt matches rows whose column is less than v
+func Lt(column string, v interface{}) Predicate {
+	return func(r Row) bool { return orderable(r[column], v) && compareValues(r[column], v) < 0 }
+}
+
+// Lte matches rows whose column is at most v
+func Lte(column string, v interface{}) Predicate {
+	return func(r Row) bool { return orderable(r[column], v) && compareValues(r[column], v) <= 0 }
+}
+
+// In matches rows whose column equals any of values
+func In(column string, values ...interface{}) Predicate {
+	return func(r Row) bool {
+		for _, v := range values {
+			if compareValues(r[column], v) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Contains matches rows whose string column contains substr
+func Contains(column, substr string) Predicate {
+	return func(r Row) bool {
+		s, ok := r[column].(string)
+		return ok && strings.Contains(s, substr)
+	}
+}
+
+// HasPrefix matches rows whose string column starts with prefix
+func HasPrefix(column, prefix string) Predicate {
+	return func(r Row) bool {
+		s, ok := r[column].(string)
+		return ok && strings.HasPrefix(s, prefix)
+	}
+}
+
+// IsNull matches rows where column is null or missing
+func IsNull(column string) Predicate {
+	return func(r Row) bool { return r[column] == nil }
+}
+
+// Matches matches rows equal to every condition, the same semantics as the
+// server's select-where conditions
+func Matches(conditions map[string]interface{}) Predicate {
+	return func(r Row) bool {
+		for c, v := range conditions {
+			if compareValues(r[c], v) != 0 {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// And matches rows matching every predicate
+func And(ps ...Predicate) Predicate {
+	return func(r Row) bool {
+		for _, p := range ps {
+			if !p(r) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches rows matching any predicate
+func Or(ps ...Predicate) Predicate {
+	return func(r Row) bool {
+		for _, p := range ps {
+			if p(r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not inverts p
+func Not(p Predicate) Predicate {
+	return func(r Row) bool { return !p(r) }
+}
+
+// orderable reports whether ordering a and b is meaningful: both present
+// and of the same kind
+func orderable(a, b interface{}) bool {
+	return a != nil && b != nil && kindRank(a) == kindRank(b)
+}
+
+// Filter is a pipeline of predicates, transforms and projections applied to
+// rows one at a time, for conditions the server cannot express. Stages run
+// in the order they were added. A Filter holds no per-run state and can be
+// reused.
+type Filter struct {
+	stages []func(Row) (Row, bool)
+	limit  int
+}
+
+// NewFilter creates an empty filter that passes every row
+func NewFilter() *Filter {
+	return &Filter{}
+}
+
+// Where keeps rows matching every predicate
+func (f *Filter) Where(ps ...Predicate) *Filter {
+	p := And(ps...)
+	f.stages = append(f.stages, func(r Row) (Row, bool) { return r, p(r) })
+	return f
+}
+
+// Map replaces each row with fn's result
+func (f *Filter) Map(fn func(Row) Row) *Filter {
+	f.stages = append(f.stages, func(r Row) (Row, bool) { return fn(r), true })
+	return f
+}
+
+// Project keeps only columns, dropping the rest
+func (f *Filter) Project(columns ...string) *Filter {
+	f.stages = append(f.stages, func(r Row) (Row, bool) {
+		out := make(Row, len(columns))
+		for _, c := range columns {
+			if v, ok := r[c]; ok {
+				out[c] = v
+			}
+		}
+		return out, true
+	})
+	return f
+}
+
+// Rename moves each row's column to key, leaving rows without it unchanged
+func (f *Filter) Rename(column, key string) *Filter {
+	f.stages = append(f.stages, func(r Row) (Row, bool) {
+		if v, ok := r[column]; ok {
+			delete(r, column)
+			r[key] = v
+		}
+		return r, true
+	})
+	return f
+}
+
+// Limit stops after n rows have passed; zero means no limit
+func (f *Filter) Limit(n int) *Filter {
+	f.limit = n
+	return f
+}
+
+// pass runs row through the stages
+func (f *Filter) pass(r Row) (Row, bool) {
+	for _, stage := range f.stages {
+		var ok bool
+		if r, ok = stage(r); !ok {
+			return nil, false
+		}
+	}
+	return r, true
+}
+
+// Apply filters rows in memory
+func (f *Filter) Apply(rows []Row) []Row {
+	var out []Row
+	for _, r := range rows {
+		if f.limit > 0 && len(out) == f.limit {
+			break
+		}
+		if r, ok := f.pass(r); ok {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Stream wraps emit so only rows passing the filter reach it, transformed.
+// Once the limit is reached the returned function reports ErrStopFetch.
+func (f *Filter) Stream(emit func(Row) error) func(Row) error {
+	passed := 0
+	return func(r Row) error {
+		if f.limit > 0 && passed == f.limit {
+			return ErrStopFetch
+		}
+		r, ok := f.pass(r)
+		if !ok {
+			return nil
+		}
+		passed++
+		return emit(r)
+	}
+}
+
+// SelectFiltered fetches records of table matching conditions (all records
+// when empty) and runs them through f as they are decoded, so rows the
+// filter drops are never accumulated. Put whatever the server can express
+// in conditions and the rest in f.
+func (m *MenousDB) SelectFiltered(table string, conditions map[string]interface{}, f *Filter) ([]Row, error) {
+	var out []Row
+	emit := f.Stream(func(r Row) error {
+		out = append(out, r)
+		return nil
+	})
+	err := m.streamSelect("SelectFiltered", table, nil, conditions, false, m.columnDecoder(table, emit))
+	if err != nil && !errors.Is(err, ErrStopFetch) {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// The Fuzz functions are native fuzz targets for the client's decoders; run
+// one with, for example:
+//
+//	go test -run '^$' -fuzz FuzzGraphQL -fuzztime 30s
+//
+// Without -fuzz, go test runs each against its seed corpus.
+
+// fuzzCursorKey signs cursors in FuzzDecodeCursor
+var fuzzCursorKey = []byte("fuzz")
+
+// FuzzDecodeRows feeds data to the select response decoder. Rows it accepts
+// must re-encode and decode to themselves, and scanning them into every kind
+// of destination must fail cleanly rather than panic.
+func FuzzDecodeRows(f *testing.F) {
+	f.Add([]byte(`[{"id":1,"name":"a"}]`))
+	f.Add([]byte(`{"1":{"n":1.5,"ok":true,"at":"2024-01-02T03:04:05Z"}}`))
+	f.Add([]byte(`{"1":{"big":12345678901234567890,"nil":null,"x":[1,"2"]}}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m := &MenousDB{}
+		var result interface{}
+		if err := m.decode(bytes.NewReader(data), &result); err != nil {
+			return
+		}
+		rows, err := toRows(result)
+		if err != nil {
+			return
+		}
+		if err := CheckDecodeRoundTrip(m, rows); err != nil {
+			t.Fatal(err)
+		}
+
+		r := NewRows(rows)
+		for r.Next() {
+			for _, column := range r.Columns() {
+				src := r.Row()[column]
+				for _, dest := range []interface{}{
+					new(string), new(uint8), new(float32), new(bool),
+					new(time.Time), new([]byte), new(*string), new(interface{}),
+				} {
+					convertAssign(dest, src)
+				}
+				// Whole numbers must scan into int64 exactly or not at all
+				var n int64
+				if convertAssign(&n, src) == nil {
+					if f, err := toFloat(src); err == nil && float64(n) != f {
+						t.Fatalf("scanned %#v into int64 as %d", src, n)
+					}
+				}
+			}
+		}
+	})
+}
+
+// FuzzColumnValues runs a JSON value through every column type's decoding
+// and validation. Values a type accepts must decode to the same value:
+// numbers to the same number, and anything but time strings unchanged.
+func FuzzColumnValues(f *testing.F) {
+	for _, seed := range []string{`1`, `-2.5`, `"text"`, `true`, `null`, `"2024-01-02T03:04:05Z"`, `[1,2]`, `{"a":1}`} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return
+		}
+		for _, typ := range generatedTypes {
+			decoded := typ.decode(v)
+			if !typ.accepts(v) {
+				continue
+			}
+			switch typ {
+			case ColumnInt, ColumnFloat:
+				want, _ := toFloat(v)
+				if got, err := toFloat(decoded); err != nil || got != want {
+					t.Fatalf("%s column decoded %#v to %#v", typ, v, decoded)
+				}
+			case ColumnTime:
+			default:
+				if !sameJSON(decoded, v) {
+					t.Fatalf("%s column decoded %#v to %#v", typ, v, decoded)
+				}
+			}
+		}
+		decompressValue(v)
+	})
+}
+
+// FuzzDecompress feeds gzip-tagged data to column decompression
+func FuzzDecompress(f *testing.F) {
+	f.Add([]byte("H4sIAAAAAAAA/8tIzcnJBwCGphA2BQAAAA=="))
+	f.Add([]byte("not base64"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decompressValue(compressedPrefix + "gzip:" + string(data))
+	})
+}
+
+// FuzzParseExists feeds data to the existence check parser
+func FuzzParseExists(f *testing.F) {
+	for _, seed := range []string{"true", "false", `"Table exists"`, `{"exists":true}`, ""} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parseExists(string(data))
+	})
+}
+
+// FuzzAPIError feeds data to error response parsing as the body of every
+// status the client may see
+func FuzzAPIError(f *testing.F) {
+	f.Add([]byte(`{"error":"no such table"}`))
+	f.Add([]byte(`Too many requests`))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, status := range []int{400, 401, 404, 429, 500, 503} {
+			resp := &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(data))}
+			if e := newAPIError("fuzz", resp); e.Error() == "" {
+				t.Fatalf("empty APIError message for status %d", status)
+			}
+		}
+	})
+}
+
+// FuzzDecodeCursor feeds data to cursor decoding. Tokens it accepts must
+// re-encode to tokens that decode to the same cursor.
+func FuzzDecodeCursor(f *testing.F) {
+	for _, c := range []Cursor{
+		{Table: "users"},
+		{Table: "users", Conditions: map[string]interface{}{"age": 30}, Offset: 20, PageSize: 10},
+	} {
+		if token, err := EncodeCursor(c, fuzzCursorKey); err == nil {
+			f.Add([]byte(token))
+		}
+	}
+	f.Add([]byte("garbage"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c, err := DecodeCursor(string(data), fuzzCursorKey)
+		if err != nil {
+			return
+		}
+		token, err := EncodeCursor(c, fuzzCursorKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := DecodeCursor(token, fuzzCursorKey)
+		if err != nil || !sameJSON(again, c) || !again.Expires.Equal(c.Expires) {
+			t.Fatalf("cursor %+v does not round-trip: %+v, %v", c, again, err)
+		}
+	})
+}
+
+// FuzzGraphQL feeds data to the GraphQL document parser
+func FuzzGraphQL(f *testing.F) {
+	for _, seed := range []string{
+		`{ users { id name } }`,
+		`query Q($id: Int = 1) { users(where: {id: $id}, limit: 10) { ...F } } fragment F on users { name }`,
+		`mutation { insert_users(objects: [{name: "a\"b", bio: """block"""}]) { affected_rows } }`,
+		`{A(A:"\`,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parseGraphQL(string(data))
+	})
+}
+
+// FuzzProtoStruct feeds data to the protobuf Struct decoder. Structs it
+// accepts must re-encode and decode to themselves.
+func FuzzProtoStruct(f *testing.F) {
+	f.Add(encodeProtoStruct(map[string]interface{}{"id": 1.0, "name": "a", "tags": []interface{}{"x", true, nil}}))
+	f.Add([]byte{0x0a, 0x00})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s, err := decodeProtoStruct(data)
+		if err != nil {
+			return
+		}
+		again, err := decodeProtoStruct(encodeProtoStruct(s))
+		if err != nil || fmt.Sprint(again) != fmt.Sprint(s) {
+			t.Fatalf("struct %v does not round-trip: %v, %v", s, again, err)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// FuzzyMatch is one FuzzySearch result
+type FuzzyMatch struct {
+	Row Row
+	// Distance is the Levenshtein distance between the query and the value
+	Distance int
+	// Similarity is the share of trigrams the two have in common, from 0 to 1
+	Similarity float64
+}
+
+// FuzzySearch returns the records of table whose column is within
+// maxDistance edits of query, ignoring case, for typo-tolerant lookups.
+// Values are compared as they stream in and matches are ranked by distance,
+// then by trigram similarity, then in the order they were read.
+func (m *MenousDB) FuzzySearch(table, column, query string, maxDistance int) ([]FuzzyMatch, error) {
+	q := []rune(strings.ToLower(query))
+	qGrams := trigrams(string(q))
+
+	var matches []FuzzyMatch
+	err := m.streamSelect("FuzzySearch", table, nil, nil, false, m.columnDecoder(table, func(r Row) error {
+		s, ok := r[column].(string)
+		if !ok {
+			return nil
+		}
+		v := strings.ToLower(s)
+		d, ok := levenshtein(q, []rune(v), maxDistance)
+		if !ok {
+			return nil
+		}
+		matches = append(matches, FuzzyMatch{Row: r, Distance: d, Similarity: trigramSimilarity(qGrams, trigrams(v))})
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].Similarity > matches[j].Similarity
+	})
+	return matches, nil
+}
+
+// levenshtein returns the edit distance between a and b, or false once it
+// is certain to exceed limit
+func levenshtein(a, b []rune, limit int) (int, bool) {
+	if d := len(a) - len(b); d > limit || -d > limit {
+		return 0, false
+	}
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return 0, false
+		}
+		prev, cur = cur, prev
+	}
+	d := prev[len(b)]
+	return d, d <= limit
+}
+
+// trigrams returns the set of three-rune windows of s, padded so short
+// strings and word edges count
+func trigrams(s string) map[string]bool {
+	r := []rune("  " + s + " ")
+	grams := make(map[string]bool, len(r))
+	for i := 0; i+3 <= len(r); i++ {
+		grams[string(r[i:i+3])] = true
+	}
+	return grams
+}
+
+// trigramSimilarity is the Jaccard index of two trigram sets
+func trigramSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for g := range a {
+		if b[g] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// earthRadius is the mean Earth radius in meters used by Haversine
+const earthRadius = 6371008.8
+
+// Point is a position in decimal degrees
+type Point struct {
+	Lat, Lon float64
+}
+
+// BoundingBox is the area between two latitudes and two longitudes. A box
+// whose MinLon is greater than its MaxLon crosses the antimeridian.
+type BoundingBox struct {
+	MinLat, MinLon, MaxLat, MaxLon float64
+}
+
+// Contains reports whether p lies in the box, edges included
+func (b BoundingBox) Contains(p Point) bool {
+	if p.Lat < b.MinLat || p.Lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+	}
+	return p.Lon >= b.MinLon || p.Lon <= b.MaxLon
+}
+
+// Haversine returns the great-circle distance between a and b in meters
+func Haversine(a, b Point) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// rowPoint reads a position from a row's latitude and longitude columns
+func rowPoint(r Row, latColumn, lonColumn string) (Point, bool) {
+	lat, err := toFloat(r[latColumn])
+	if err != nil {
+		return Point{}, false
+	}
+	lon, err := toFloat(r[lonColumn])
+	if err != nil {
+		return Point{}, false
+	}
+	return Point{Lat: lat, Lon: lon}, true
+}
+
+// InBox matches rows whose position lies in box. Rows without a numeric
+// position do not match.
+func InBox(latColumn, lonColumn string, box BoundingBox) Predicate {
+	return func(r Row) bool {
+		p, ok := rowPoint(r, latColumn, lonColumn)
+		return ok && box.Contains(p)
+	}
+}
+
+// WithinRadius matches rows whose position is at most meters from center.
+// Rows without a numeric position do not match.
+func WithinRadius(latColumn, lonColumn string, center Point, meters float64) Predicate {
+	// A latitude band rejects most far rows before the trigonometry
+	band := meters / earthRadius * 180 / math.Pi
+	return func(r Row) bool {
+		p, ok := rowPoint(r, latColumn, lonColumn)
+		if !ok || math.Abs(p.Lat-center.Lat) > band {
+			return false
+		}
+		return Haversine(center, p) <= meters
+	}
+}
+
+// SelectInBox returns the records of table matching conditions whose
+// position lies in box. The server matches only equality conditions, so
+// those are sent with the request and the box is applied to records as they
+// stream in.
+func (m *MenousDB) SelectInBox(table, latColumn, lonColumn string, box BoundingBox, conditions map[string]interface{}) ([]Row, error) {
+	return m.SelectFiltered(table, conditions, NewFilter().Where(InBox(latColumn, lonColumn, box)))
+}
+
+// SelectWithinRadius returns the records of table matching conditions that
+// lie at most meters from center, nearest first. Conditions are sent with
+// the request and the radius is applied as records stream in.
+func (m *MenousDB) SelectWithinRadius(table, latColumn, lonColumn string, center Point, meters float64, conditions map[string]interface{}) ([]Row, error) {
+	rows, err := m.SelectFiltered(table, conditions, NewFilter().Where(WithinRadius(latColumn, lonColumn, center, meters)))
+	if err != nil {
+		return nil, err
+	}
+	distances := make([]float64, len(rows))
+	index := make([]int, len(rows))
+	for i, r := range rows {
+		p, _ := rowPoint(r, latColumn, lonColumn)
+		distances[i] = Haversine(center, p)
+		index[i] = i
+	}
+	sort.SliceStable(index, func(a, b int) bool { return distances[index[a]] < distances[index[b]] })
+	out := make([]Row, len(rows))
+	for i, j := range index {
+		out[i] = rows[j]
+	}
+	return out, nil
+}
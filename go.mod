@@ -1,3 +1,7 @@
 module menousdb
 
 go 1.23.4
+
+require golang.org/x/oauth2 v0.30.0
+
+require gopkg.in/yaml.v3 v3.0.1
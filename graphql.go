@@ -0,0 +1,990 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// GraphQL serves a GraphQL API generated from table declarations and
+// resolved through a client. Each table, such as order_items, gets an object
+// type with a field per column and:
+//
+//	query    { order_items(where: OrderItemsFilter, limit: Int, offset: Int): [OrderItems!]! }
+//	mutation { insertOrderItems(values: OrderItemsInput!): String
+//	           updateOrderItems(where: OrderItemsFilter!, set: OrderItemsInput!): String
+//	           deleteOrderItems(where: OrderItemsFilter!): String }
+//
+// where filters on column equality, as select-where does, and must hold a
+// condition for updates and deletes; the mutations return the server's
+// response. Column types map to Int, Float, Boolean and
+// String, and untyped columns to a JSON scalar. The executor covers
+// operations, variables, aliases and __typename; fragments, directives and
+// introspection are not supported, so tools should be given SDL instead.
+type GraphQL struct {
+	// Authorize, if set, vets each request before it is executed; an error
+	// answers 403 with its message. write is set for mutations.
+	Authorize func(r *http.Request, database string, write bool) error
+
+	client *MenousDB
+	tables map[string]*gqlTable
+	order  []*gqlTable
+	fields map[string]gqlRoot
+}
+
+// gqlTable is one table's generated types
+type gqlTable struct {
+	name     string
+	typeName string
+	columns  []ColumnDef
+	byName   map[string]ColumnDef
+}
+
+// gqlRoot is a root field: the table it reads or writes and how
+type gqlRoot struct {
+	table *gqlTable
+	kind  string
+}
+
+// NewGraphQL generates the API for tables, such as those of a schema spec
+// from LoadSchemaSpec or DescribeSchema
+func NewGraphQL(client *MenousDB, tables ...TableSpec) (*GraphQL, error) {
+	g := &GraphQL{client: client, tables: make(map[string]*gqlTable), fields: make(map[string]gqlRoot)}
+	for _, spec := range tables {
+		if !isGraphQLName(spec.Name) {
+			return nil, fmt.Errorf("graphql: table name %q is not a valid GraphQL name", spec.Name)
+		}
+		t := &gqlTable{name: spec.Name, typeName: graphQLTypeName(spec.Name), byName: make(map[string]ColumnDef)}
+		for _, c := range spec.columnDefs() {
+			if !isGraphQLName(c.Name) {
+				return nil, fmt.Errorf("graphql: column %s.%s is not a valid GraphQL name", spec.Name, c.Name)
+			}
+			t.columns = append(t.columns, c)
+			t.byName[c.Name] = c
+		}
+		g.tables[spec.Name] = t
+		g.order = append(g.order, t)
+		for name, kind := range map[string]string{
+			spec.Name:             "query",
+			"insert" + t.typeName: "insert",
+			"update" + t.typeName: "update",
+			"delete" + t.typeName: "delete",
+		} {
+			if _, dup := g.fields[name]; dup {
+				return nil, fmt.Errorf("graphql: field %s is generated twice", name)
+			}
+			g.fields[name] = gqlRoot{table: t, kind: kind}
+		}
+	}
+	return g, nil
+}
+
+// SDL returns the generated schema in the GraphQL schema language
+func (g *GraphQL) SDL() string {
+	var b strings.Builder
+	b.WriteString("scalar JSON\n")
+	for _, t := range g.order {
+		for _, decl := range []string{"type " + t.typeName, "input " + t.typeName + "Filter", "input " + t.typeName + "Input"} {
+			fmt.Fprintf(&b, "\n%s {\n", decl)
+			for _, c := range t.columns {
+				fmt.Fprintf(&b, "  %s: %s\n", c.Name, graphQLScalar(c.Type))
+			}
+			b.WriteString("}\n")
+		}
+	}
+	b.WriteString("\ntype Query {\n")
+	for _, t := range g.order {
+		fmt.Fprintf(&b, "  %s(where: %sFilter, limit: Int, offset: Int): [%s!]!\n", t.name, t.typeName, t.typeName)
+	}
+	b.WriteString("}\n\ntype Mutation {\n")
+	for _, t := range g.order {
+		fmt.Fprintf(&b, "  insert%s(values: %sInput!): String\n", t.typeName, t.typeName)
+		fmt.Fprintf(&b, "  update%s(where: %sFilter!, set: %sInput!): String\n", t.typeName, t.typeName, t.typeName)
+		fmt.Fprintf(&b, "  delete%s(where: %sFilter!): String\n", t.typeName, t.typeName)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// GraphQLError is one error in a GraphQL response
+type GraphQLError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// GraphQLResult is a GraphQL response: data is nil when the request could
+// not be executed at all
+type GraphQLResult struct {
+	Data   interface{}    `json:"data"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+// Execute runs one operation of a GraphQL document. operationName picks the
+// operation when the document has several.
+func (g *GraphQL) Execute(query string, variables map[string]interface{}, operationName string) GraphQLResult {
+	ops, err := parseGraphQL(query)
+	if err != nil {
+		return GraphQLResult{Errors: []GraphQLError{{Message: err.Error()}}}
+	}
+	op, err := pickOperation(ops, operationName)
+	if err != nil {
+		return GraphQLResult{Errors: []GraphQLError{{Message: err.Error()}}}
+	}
+	vars, err := op.bindVariables(variables)
+	if err != nil {
+		return GraphQLResult{Errors: []GraphQLError{{Message: err.Error()}}}
+	}
+
+	data := &gqlObject{}
+	var errs []GraphQLError
+	// Mutations run in order, as the spec requires; queries do too, for
+	// simplicity
+	for _, f := range op.selection {
+		key := f.responseKey()
+		if f.name == "__typename" {
+			typ := "Query"
+			if op.kind == "mutation" {
+				typ = "Mutation"
+			}
+			data.set(key, typ)
+			continue
+		}
+		value, err := g.resolveRoot(op.kind, f, vars)
+		if err != nil {
+			errs = append(errs, GraphQLError{Message: err.Error(), Path: []interface{}{key}})
+			data.set(key, nil)
+			continue
+		}
+		data.set(key, value)
+	}
+	return GraphQLResult{Data: data, Errors: errs}
+}
+
+// ServeHTTP executes GraphQL requests sent as POSTed JSON
+// {"query", "variables", "operationName"} or as GET query parameters, which
+// may only run queries. A GET without a query returns the SDL.
+func (g *GraphQL) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query         string                 `json:"query"`
+		Variables     map[string]interface{} `json:"variables"`
+		OperationName string                 `json:"operationName"`
+	}
+	switch r.Method {
+	case "GET":
+		params := r.URL.Query()
+		req.Query = params.Get("query")
+		req.OperationName = params.Get("operationName")
+		if req.Query == "" {
+			if g.Authorize != nil {
+				if err := g.Authorize(r, g.client.Database, false); err != nil {
+					http.Error(w, err.Error(), http.StatusForbidden)
+					return
+				}
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte(g.SDL()))
+			return
+		}
+		if v := params.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, "invalid variables: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if ops, err := parseGraphQL(req.Query); err == nil {
+			if op, err := pickOperation(ops, req.OperationName); err == nil && op.kind == "mutation" {
+				http.Error(w, "mutations must be POSTed", http.StatusMethodNotAllowed)
+				return
+			}
+		}
+	case "POST":
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "GraphQL requests are GET or POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if g.Authorize != nil {
+		// Documents that fail to parse are vetted as reads; Execute then
+		// rejects them without running anything
+		write := false
+		if ops, err := parseGraphQL(req.Query); err == nil {
+			if op, err := pickOperation(ops, req.OperationName); err == nil {
+				write = op.kind == "mutation"
+			}
+		}
+		if err := g.Authorize(r, g.client.Database, write); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(g.Execute(req.Query, req.Variables, req.OperationName))
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
+
+// resolveRoot resolves one root field
+func (g *GraphQL) resolveRoot(kind string, f gqlField, vars map[string]interface{}) (interface{}, error) {
+	root, ok := g.fields[f.name]
+	if !ok || (root.kind == "query") != (kind == "query") {
+		typ := "Query"
+		if kind == "mutation" {
+			typ = "Mutation"
+		}
+		return nil, fmt.Errorf("%s has no field %s", typ, f.name)
+	}
+	t := root.table
+	args, err := f.resolveArgs(vars)
+	if err != nil {
+		return nil, err
+	}
+	allowed := map[string][]string{
+		"query":  {"where", "limit", "offset"},
+		"insert": {"values"},
+		"update": {"where", "set"},
+		"delete": {"where"},
+	}[root.kind]
+	for name := range args {
+		if !containsString(allowed, name) {
+			return nil, fmt.Errorf("%s has no argument %s", f.name, name)
+		}
+	}
+	where, err := t.columnArg(args, "where", root.kind == "update" || root.kind == "delete")
+	if err != nil {
+		return nil, err
+	}
+	if where == nil && (root.kind == "update" || root.kind == "delete") {
+		return nil, fmt.Errorf("%s needs at least one where condition", f.name)
+	}
+
+	switch root.kind {
+	case "query":
+		return g.query(t, f, where, args)
+	case "insert", "update", "delete":
+		if len(f.selection) > 0 {
+			return nil, fmt.Errorf("%s returns String, which has no fields", f.name)
+		}
+	}
+	var result interface{}
+	switch root.kind {
+	case "insert":
+		values, err := t.columnArg(args, "values", true)
+		if err != nil {
+			return nil, err
+		}
+		return g.client.InsertIntoTable(t.name, values)
+	case "update":
+		set, err := t.columnArg(args, "set", true)
+		if err != nil {
+			return nil, err
+		}
+		result, err = g.client.UpdateWhere(t.name, where, set)
+		if err != nil {
+			return nil, err
+		}
+	case "delete":
+		result, err = g.client.DeleteWhere(t.name, where)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s, ok := result.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(result)
+	return string(data), err
+}
+
+// query resolves a table's query field
+func (g *GraphQL) query(t *gqlTable, f gqlField, where map[string]interface{}, args map[string]interface{}) (interface{}, error) {
+	if len(f.selection) == 0 {
+		return nil, fmt.Errorf("%s needs a selection of fields", f.name)
+	}
+	limit, err := intArg(args, "limit")
+	if err != nil {
+		return nil, err
+	}
+	offset, err := intArg(args, "offset")
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	for _, sf := range f.selection {
+		if sf.name == "__typename" {
+			continue
+		}
+		if _, ok := t.byName[sf.name]; !ok {
+			return nil, fmt.Errorf("%s has no field %s", t.typeName, sf.name)
+		}
+		if len(sf.selection) > 0 {
+			return nil, fmt.Errorf("%s.%s is a scalar and has no fields", t.typeName, sf.name)
+		}
+		if !containsString(columns, sf.name) {
+			columns = append(columns, sf.name)
+		}
+	}
+	rows, err := g.client.Select(t.name, columns...).Where(where).Rows()
+	if err != nil {
+		return nil, err
+	}
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	rows = rows[offset:]
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+
+	out := make([]interface{}, len(rows))
+	for i, row := range rows {
+		obj := &gqlObject{}
+		for _, sf := range f.selection {
+			if sf.name == "__typename" {
+				obj.set(sf.responseKey(), t.typeName)
+			} else {
+				obj.set(sf.responseKey(), row[sf.name])
+			}
+		}
+		out[i] = obj
+	}
+	return out, nil
+}
+
+// columnArg returns an input object argument, checking its fields are
+// columns
+func (t *gqlTable) columnArg(args map[string]interface{}, name string, required bool) (map[string]interface{}, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		if required {
+			return nil, fmt.Errorf("argument %s is required", name)
+		}
+		return nil, nil
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("argument %s must be an input object", name)
+	}
+	if len(obj) == 0 {
+		return nil, nil
+	}
+	for k := range obj {
+		if _, ok := t.byName[k]; !ok {
+			return nil, fmt.Errorf("argument %s: %s has no column %s", name, t.name, k)
+		}
+	}
+	return obj, nil
+}
+
+// intArg returns a non-negative integer argument, zero when absent
+func intArg(args map[string]interface{}, name string) (int, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		return 0, nil
+	}
+	f, err := toFloat(v)
+	if err != nil || f < 0 || f != float64(int(f)) {
+		return 0, fmt.Errorf("argument %s must be a non-negative Int", name)
+	}
+	return int(f), nil
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// graphQLScalar maps a column type to a GraphQL type
+func graphQLScalar(t ColumnType) string {
+	switch t {
+	case ColumnInt:
+		return "Int"
+	case ColumnFloat:
+		return "Float"
+	case ColumnBool:
+		return "Boolean"
+	case ColumnString, ColumnTime:
+		return "String"
+	}
+	return "JSON"
+}
+
+// graphQLTypeName returns a table's type name in PascalCase: order_items
+// becomes OrderItems
+func graphQLTypeName(table string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range table {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 || unicode.IsDigit([]rune(b.String())[0]) {
+		return "T" + b.String()
+	}
+	return b.String()
+}
+
+// isGraphQLName reports whether s is a valid GraphQL name
+func isGraphQLName(s string) bool {
+	if s == "" || strings.HasPrefix(s, "__") {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// gqlObject is a response object that keeps its fields in selection order
+type gqlObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// set adds or replaces a field
+func (o *gqlObject) set(key string, v interface{}) {
+	if o.values == nil {
+		o.values = make(map[string]interface{})
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+// MarshalJSON encodes the fields in order
+func (o *gqlObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		v, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// gqlOperation is a parsed query or mutation
+type gqlOperation struct {
+	kind      string
+	name      string
+	vars      []gqlVarDef
+	selection []gqlField
+}
+
+// gqlVarDef is a variable definition
+type gqlVarDef struct {
+	name       string
+	nonNull    bool
+	def        interface{}
+	hasDefault bool
+}
+
+// gqlField is a selected field
+type gqlField struct {
+	alias     string
+	name      string
+	args      map[string]interface{}
+	selection []gqlField
+}
+
+// gqlVar is a variable reference in an argument value
+type gqlVar string
+
+// responseKey returns the field's alias, or its name
+func (f gqlField) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// resolveArgs substitutes variables into the field's arguments
+func (f gqlField) resolveArgs(vars map[string]interface{}) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(f.args))
+	for k, v := range f.args {
+		r, err := substituteVars(v, vars)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = r
+	}
+	return out, nil
+}
+
+// substituteVars replaces variable references in a value
+func substituteVars(v interface{}, vars map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case gqlVar:
+		value, ok := vars[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", string(v))
+		}
+		return value, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			r, err := substituteVars(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			r, err := substituteVars(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// bindVariables checks supplied variables against the operation's
+// definitions, filling defaults
+func (op *gqlOperation) bindVariables(supplied map[string]interface{}) (map[string]interface{}, error) {
+	vars := make(map[string]interface{}, len(op.vars))
+	for _, d := range op.vars {
+		v, ok := supplied[d.name]
+		switch {
+		case ok:
+			vars[d.name] = v
+		case d.hasDefault:
+			vars[d.name] = d.def
+		case d.nonNull:
+			return nil, fmt.Errorf("variable $%s is required", d.name)
+		default:
+			vars[d.name] = nil
+		}
+		if d.nonNull && vars[d.name] == nil {
+			return nil, fmt.Errorf("variable $%s must not be null", d.name)
+		}
+	}
+	return vars, nil
+}
+
+// pickOperation selects the operation to run
+func pickOperation(ops []*gqlOperation, name string) (*gqlOperation, error) {
+	if name == "" {
+		if len(ops) != 1 {
+			return nil, errors.New("operationName is required for documents with several operations")
+		}
+		return ops[0], nil
+	}
+	for _, op := range ops {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// gqlParser parses the executable subset of GraphQL documents
+type gqlParser struct {
+	src string
+	pos int
+}
+
+// parseGraphQL parses a document into its operations
+func parseGraphQL(src string) ([]*gqlOperation, error) {
+	p := &gqlParser{src: src}
+	var ops []*gqlOperation
+	names := make(map[string]bool)
+	for {
+		p.skip()
+		if p.pos >= len(p.src) {
+			break
+		}
+		op, err := p.operation()
+		if err != nil {
+			return nil, err
+		}
+		if op.name != "" && names[op.name] {
+			return nil, fmt.Errorf("operation %s is defined twice", op.name)
+		}
+		names[op.name] = true
+		ops = append(ops, op)
+	}
+	if len(ops) == 0 {
+		return nil, errors.New("document has no operations")
+	}
+	return ops, nil
+}
+
+// errorf reports a syntax error at the current position
+func (p *gqlParser) errorf(format string, args ...interface{}) error {
+	line, col := 1, 1
+	for _, r := range p.src[:p.pos] {
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return fmt.Errorf("syntax error at %d:%d: %s", line, col, fmt.Sprintf(format, args...))
+}
+
+// skip passes whitespace, commas and comments
+func (p *gqlParser) skip() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ', c == '\t', c == '\n', c == '\r', c == ',', c == 0xEF, c == 0xBB, c == 0xBF:
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next significant byte, or 0 at the end
+func (p *gqlParser) peek() byte {
+	p.skip()
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// expect consumes punctuator c
+func (p *gqlParser) expect(c byte) error {
+	if p.peek() != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+// name reads a name
+func (p *gqlParser) name() (string, error) {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' && p.pos > start {
+			p.pos++
+			continue
+		}
+		break
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a name")
+	}
+	return p.src[start:p.pos], nil
+}
+
+// operation reads an operation definition
+func (p *gqlParser) operation() (*gqlOperation, error) {
+	op := &gqlOperation{kind: "query"}
+	if p.peek() != '{' {
+		kind, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case "query", "mutation":
+			op.kind = kind
+		case "fragment":
+			return nil, p.errorf("fragments are not supported")
+		default:
+			return nil, p.errorf("unsupported operation %q", kind)
+		}
+		if c := p.peek(); c != '{' && c != '(' && c != '@' {
+			if op.name, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek() == '(' {
+			if op.vars, err = p.varDefs(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if p.peek() == '@' {
+		return nil, p.errorf("directives are not supported")
+	}
+	sel, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selection = sel
+	return op, nil
+}
+
+// varDefs reads variable definitions
+func (p *gqlParser) varDefs() ([]gqlVarDef, error) {
+	p.pos++
+	var defs []gqlVarDef
+	for p.peek() != ')' {
+		if err := p.expect('$'); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+		d := gqlVarDef{name: name}
+		if d.nonNull, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if p.peek() == '=' {
+			p.pos++
+			if d.def, err = p.value(true); err != nil {
+				return nil, err
+			}
+			d.hasDefault = true
+		}
+		defs = append(defs, d)
+		if p.peek() == 0 {
+			return nil, p.errorf("unterminated variable definitions")
+		}
+	}
+	p.pos++
+	return defs, nil
+}
+
+// typeRef reads a type reference, reporting whether it is non-null
+func (p *gqlParser) typeRef() (bool, error) {
+	if p.peek() == '[' {
+		p.pos++
+		if _, err := p.typeRef(); err != nil {
+			return false, err
+		}
+		if err := p.expect(']'); err != nil {
+			return false, err
+		}
+	} else if _, err := p.name(); err != nil {
+		return false, err
+	}
+	if p.peek() == '!' {
+		p.pos++
+		return true, nil
+	}
+	return false, nil
+}
+
+// selectionSet reads { field ... }
+func (p *gqlParser) selectionSet() ([]gqlField, error) {
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	var fields []gqlField
+	for p.peek() != '}' {
+		switch p.peek() {
+		case 0:
+			return nil, p.errorf("unterminated selection set")
+		case '.':
+			return nil, p.errorf("fragments are not supported")
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	p.pos++
+	if len(fields) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+	return fields, nil
+}
+
+// field reads a field with its alias, arguments and selection
+func (p *gqlParser) field() (gqlField, error) {
+	var f gqlField
+	name, err := p.name()
+	if err != nil {
+		return f, err
+	}
+	if p.peek() == ':' {
+		p.pos++
+		f.alias = name
+		if name, err = p.name(); err != nil {
+			return f, err
+		}
+	}
+	f.name = name
+	if p.peek() == '(' {
+		p.pos++
+		f.args = make(map[string]interface{})
+		for p.peek() != ')' {
+			arg, err := p.name()
+			if err != nil {
+				return f, err
+			}
+			if err := p.expect(':'); err != nil {
+				return f, err
+			}
+			if f.args[arg], err = p.value(false); err != nil {
+				return f, err
+			}
+		}
+		p.pos++
+	}
+	if p.peek() == '@' {
+		return f, p.errorf("directives are not supported")
+	}
+	if p.peek() == '{' {
+		if f.selection, err = p.selectionSet(); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// value reads an input value; constant values may not use variables
+func (p *gqlParser) value(constant bool) (interface{}, error) {
+	switch c := p.peek(); {
+	case c == '$':
+		if constant {
+			return nil, p.errorf("variables are not allowed here")
+		}
+		p.pos++
+		name, err := p.name()
+		return gqlVar(name), err
+	case c == '"':
+		return p.stringValue()
+	case c == '[':
+		p.pos++
+		list := []interface{}{}
+		for p.peek() != ']' {
+			if p.peek() == 0 {
+				return nil, p.errorf("unterminated list")
+			}
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		p.pos++
+		return list, nil
+	case c == '{':
+		p.pos++
+		obj := make(map[string]interface{})
+		for p.peek() != '}' {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(':'); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		p.pos++
+		return obj, nil
+	case c == '-' || c >= '0' && c <= '9':
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		var n json.Number
+		if err := json.Unmarshal([]byte(p.src[start:p.pos]), &n); err != nil {
+			return nil, p.errorf("invalid number %q", p.src[start:p.pos])
+		}
+		return n, nil
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	// Enum values pass through as strings
+	return name, nil
+}
+
+// stringValue reads a string or block string
+func (p *gqlParser) stringValue() (string, error) {
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		end := strings.Index(p.src[p.pos+3:], `"""`)
+		if end < 0 {
+			return "", p.errorf("unterminated block string")
+		}
+		s := p.src[p.pos+3 : p.pos+3+end]
+		p.pos += end + 6
+		return strings.TrimSpace(s), nil
+	}
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			if p.pos+1 >= len(p.src) {
+				return "", p.errorf("unterminated string")
+			}
+			p.pos += 2
+			continue
+		case '\n':
+			return "", p.errorf("unterminated string")
+		case '"':
+			p.pos++
+			var s string
+			if err := json.Unmarshal([]byte(p.src[start:p.pos]), &s); err != nil {
+				return "", p.errorf("invalid string")
+			}
+			return s, nil
+		}
+		p.pos++
+	}
+	return "", p.errorf("unterminated string")
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGraphQLUnterminatedString(t *testing.T) {
+	for _, src := range []string{
+		`{A(A:"\`,
+		`{A(A:"abc\`,
+		`{A(A:"abc`,
+		"{A(A:\"abc\n\")}",
+	} {
+		_, err := parseGraphQL(src)
+		if err == nil || !strings.Contains(err.Error(), "unterminated string") {
+			t.Errorf("parseGraphQL(%q) = %v, want unterminated string", src, err)
+		}
+	}
+}
+
+func TestParseGraphQLStringEscapes(t *testing.T) {
+	if _, err := parseGraphQL(`{A(A:"a\"b\\")}`); err != nil {
+		t.Fatalf("parseGraphQL: %v", err)
+	}
+}
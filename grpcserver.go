@@ -0,0 +1,393 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gRPC status codes
+const (
+	grpcOK               = 0
+	grpcInvalidArgument  = 3
+	grpcNotFound         = 5
+	grpcPermissionDenied = 7
+	grpcUnimplemented    = 12
+	grpcInternal         = 13
+	grpcUnavailable      = 14
+)
+
+// GRPCServer serves the menousdb.v1.MenousDB service defined in
+// proto/menousdb.proto and forwards each call through Client, giving other
+// languages a typed API generated from the proto file. It implements the
+// gRPC protocol over net/http directly, with a hand-written codec for the
+// service's messages, so the module needs no gRPC dependency. gRPC runs over
+// HTTP/2, which net/http serves on TLS listeners:
+//
+//	srv := &http.Server{Addr: ":8443", Handler: &GRPCServer{Client: db}}
+//	srv.ListenAndServeTLS("cert.pem", "key.pem")
+//
+// Only uncompressed messages are accepted.
+type GRPCServer struct {
+	Client *MenousDB
+
+	// Authorize, if set, vets each call before it is forwarded; an error
+	// fails the call with PERMISSION_DENIED
+	Authorize func(r *http.Request, database string, write bool) error
+
+	// CursorKey signs page tokens; defaults to a random key, so tokens do
+	// not survive a restart
+	CursorKey []byte
+
+	// MaxMessageBytes bounds request messages; defaults to 4 MiB
+	MaxMessageBytes int
+
+	once sync.Once
+	key  []byte
+}
+
+// grpcStatus is an error carrying a gRPC status code
+type grpcStatus struct {
+	code int
+	msg  string
+}
+
+func (e *grpcStatus) Error() string { return e.msg }
+
+// grpcMethod handles one decoded request message
+type grpcMethod struct {
+	write  bool
+	handle func(s *GRPCServer, client *MenousDB, req []byte) ([]byte, error)
+}
+
+// grpcMethods maps method names to their handlers
+var grpcMethods = map[string]grpcMethod{
+	"ListTables": {false, (*GRPCServer).listTables},
+	"Select":     {false, (*GRPCServer).selectRows},
+	"Insert":     {true, (*GRPCServer).insertRows},
+	"Update":     {true, (*GRPCServer).updateRows},
+	"Delete":     {true, (*GRPCServer).deleteRows},
+}
+
+// ServeHTTP handles one unary gRPC call
+func (s *GRPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.once.Do(func() {
+		s.key = s.CursorKey
+		if len(s.key) == 0 {
+			s.key = make([]byte, 32)
+			rand.Read(s.key)
+		}
+	})
+
+	if r.Method != "POST" || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+
+	name, ok := strings.CutPrefix(r.URL.Path, "/menousdb.v1.MenousDB/")
+	method, known := grpcMethods[name]
+	if !ok || !known {
+		writeGRPCStatus(w, &grpcStatus{grpcUnimplemented, "unknown method " + r.URL.Path})
+		return
+	}
+	req, err := s.readMessage(r)
+	if err != nil {
+		writeGRPCStatus(w, err)
+		return
+	}
+
+	// Every request message has the database in field 1
+	var database string
+	decodeProto(req, func(f protoField) error {
+		if f.num == 1 && f.wireType == protoBytes {
+			database = string(f.data)
+		}
+		return nil
+	})
+	if s.Authorize != nil {
+		if err := s.Authorize(r, database, method.write); err != nil {
+			writeGRPCStatus(w, &grpcStatus{grpcPermissionDenied, err.Error()})
+			return
+		}
+	}
+
+	client := s.Client
+	if database != "" {
+		client = client.ForDatabase(database)
+	}
+	resp, err := method.handle(s, client, req)
+	if err != nil {
+		writeGRPCStatus(w, err)
+		return
+	}
+
+	frame := make([]byte, 5, 5+len(resp))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(resp)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(append(frame, resp...))
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(grpcOK))
+}
+
+// readMessage reads the single length-prefixed message of a unary call
+func (s *GRPCServer) readMessage(r *http.Request) ([]byte, error) {
+	limit := s.MaxMessageBytes
+	if limit <= 0 {
+		limit = 4 << 20
+	}
+	var prefix [5]byte
+	if _, err := io.ReadFull(r.Body, prefix[:]); err != nil {
+		return nil, &grpcStatus{grpcInvalidArgument, "reading message: " + err.Error()}
+	}
+	if prefix[0] != 0 {
+		return nil, &grpcStatus{grpcUnimplemented, "compressed messages are not supported"}
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > uint32(limit) {
+		return nil, &grpcStatus{grpcInvalidArgument, fmt.Sprintf("message of %d bytes exceeds %d", size, limit)}
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r.Body, msg); err != nil {
+		return nil, &grpcStatus{grpcInvalidArgument, "reading message: " + err.Error()}
+	}
+	return msg, nil
+}
+
+func (s *GRPCServer) listTables(client *MenousDB, req []byte) ([]byte, error) {
+	tables, err := client.ListTables()
+	if err != nil {
+		return nil, err
+	}
+	var e protoEncoder
+	for _, t := range tables {
+		e.bytes(1, []byte(t))
+	}
+	return e.buf, nil
+}
+
+func (s *GRPCServer) selectRows(client *MenousDB, req []byte) ([]byte, error) {
+	var table, token string
+	var columns []string
+	var conditions map[string]interface{}
+	pageSize := 100
+	err := decodeProto(req, func(f protoField) error {
+		var err error
+		switch f.num {
+		case 2:
+			table = string(f.data)
+		case 3:
+			columns = append(columns, string(f.data))
+		case 4:
+			conditions, err = decodeProtoStruct(f.data)
+		case 5:
+			if n := int(int32(f.varint)); n > 0 {
+				pageSize = n
+			}
+		case 6:
+			token = string(f.data)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, &grpcStatus{grpcInvalidArgument, err.Error()}
+	}
+	if len(conditions) == 0 {
+		conditions = nil
+	}
+
+	offset := 0
+	if token != "" {
+		c, err := DecodeCursor(token, s.key)
+		if err != nil {
+			return nil, &grpcStatus{grpcInvalidArgument, err.Error()}
+		}
+		if c.Database != client.Database || c.Table != table ||
+			!slices.Equal(c.Columns, columns) || !sameConditions(c.Conditions, conditions) {
+			return nil, &grpcStatus{grpcInvalidArgument, "page token belongs to another query"}
+		}
+		offset = c.Offset
+	}
+
+	rows, err := client.Select(table, columns...).Where(conditions).Rows()
+	if err != nil {
+		return nil, err
+	}
+	var e protoEncoder
+	end := offset + pageSize
+	if end > len(rows) {
+		end = len(rows)
+	}
+	for i := offset; i < end; i++ {
+		e.structValue(1, rows[i])
+	}
+	if end < len(rows) {
+		next, err := EncodeCursor(Cursor{Database: client.Database, Table: table, Columns: columns, Conditions: conditions, Offset: end, PageSize: pageSize}, s.key)
+		if err != nil {
+			return nil, err
+		}
+		e.string(2, next)
+	}
+	e.varint(3, uint64(len(rows)))
+	return e.buf, nil
+}
+
+func (s *GRPCServer) insertRows(client *MenousDB, req []byte) ([]byte, error) {
+	var table string
+	var rows []map[string]interface{}
+	err := decodeProto(req, func(f protoField) error {
+		switch f.num {
+		case 2:
+			table = string(f.data)
+		case 3:
+			row, err := decodeProtoStruct(f.data)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, row)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, &grpcStatus{grpcInvalidArgument, err.Error()}
+	}
+	for i, row := range rows {
+		if _, err := client.InsertIntoTable(table, row); err != nil {
+			if i > 0 {
+				return nil, fmt.Errorf("after inserting %d of %d rows: %w", i, len(rows), err)
+			}
+			return nil, err
+		}
+	}
+	var e protoEncoder
+	e.varint(1, uint64(len(rows)))
+	return e.buf, nil
+}
+
+func (s *GRPCServer) updateRows(client *MenousDB, req []byte) ([]byte, error) {
+	var table string
+	var conditions, values map[string]interface{}
+	var all bool
+	err := decodeProto(req, func(f protoField) error {
+		var err error
+		switch f.num {
+		case 2:
+			table = string(f.data)
+		case 3:
+			conditions, err = decodeProtoStruct(f.data)
+		case 4:
+			values, err = decodeProtoStruct(f.data)
+		case 5:
+			all = f.varint != 0
+		}
+		return err
+	})
+	if err != nil {
+		return nil, &grpcStatus{grpcInvalidArgument, err.Error()}
+	}
+	if len(conditions) == 0 && !all {
+		return nil, &grpcStatus{grpcInvalidArgument, "conditions are required; set all to update every row"}
+	}
+	if len(values) == 0 {
+		return nil, &grpcStatus{grpcInvalidArgument, "values are required"}
+	}
+	result, err := client.UpdateWhere(table, conditions, values)
+	if err != nil {
+		return nil, err
+	}
+	return grpcResult(result)
+}
+
+func (s *GRPCServer) deleteRows(client *MenousDB, req []byte) ([]byte, error) {
+	var table string
+	var conditions map[string]interface{}
+	var all bool
+	err := decodeProto(req, func(f protoField) error {
+		var err error
+		switch f.num {
+		case 2:
+			table = string(f.data)
+		case 3:
+			conditions, err = decodeProtoStruct(f.data)
+		case 4:
+			all = f.varint != 0
+		}
+		return err
+	})
+	if err != nil {
+		return nil, &grpcStatus{grpcInvalidArgument, err.Error()}
+	}
+	if len(conditions) == 0 && !all {
+		return nil, &grpcStatus{grpcInvalidArgument, "conditions are required; set all to delete every row"}
+	}
+	result, err := client.DeleteWhere(table, conditions)
+	if err != nil {
+		return nil, err
+	}
+	return grpcResult(result)
+}
+
+// grpcResult encodes a server response as the JSON result field
+func grpcResult(result interface{}) ([]byte, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var e protoEncoder
+	e.string(1, string(data))
+	return e.buf, nil
+}
+
+// sameConditions reports whether a page token's conditions, which went
+// through JSON, match a request's
+func sameConditions(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		w, ok := b[k]
+		if !ok || compareValues(v, w) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// writeGRPCStatus ends a call with the status err maps to, in a
+// trailers-only response
+func writeGRPCStatus(w http.ResponseWriter, err error) {
+	code := grpcUnavailable
+	var status *grpcStatus
+	var validation *ValidationError
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &status):
+		code = status.code
+	case errors.As(err, &validation), errors.Is(err, ErrInvalidIdentifier):
+		code = grpcInvalidArgument
+	case errors.Is(err, ErrReadOnly), errors.Is(err, ErrAccessDenied):
+		code = grpcPermissionDenied
+	case errors.As(err, &apiErr):
+		switch {
+		case apiErr.HTTPStatus == http.StatusNotFound:
+			code = grpcNotFound
+		case apiErr.HTTPStatus == http.StatusUnauthorized, apiErr.HTTPStatus == http.StatusForbidden:
+			code = grpcPermissionDenied
+		case apiErr.HTTPStatus >= 400 && apiErr.HTTPStatus < 500:
+			code = grpcInvalidArgument
+		}
+	case errors.Is(err, ErrUnexpectedResponse):
+		code = grpcInternal
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", url.PathEscape(err.Error()))
+	w.WriteHeader(http.StatusOK)
+}
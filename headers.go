@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"unicode/utf8"
+)
+
+// identifierHeaders are the headers carrying database and table names, which
+// are percent-encoded when WithPercentEncodedIdentifiers is set
+var identifierHeaders = map[string]bool{
+	"database": true,
+	"table":    true,
+}
+
+// encodingHeader tells the server identifier headers are percent-encoded
+const encodingHeader = "X-Menousdb-Identifier-Encoding"
+
+// WithPercentEncodedIdentifiers percent-encodes database and table headers
+// and flags the request with X-Menousdb-Identifier-Encoding: percent, so
+// names outside printable ASCII survive proxies intact
+func WithPercentEncodedIdentifiers() Option {
+	return func(m *MenousDB) {
+		m.percentEncode = true
+	}
+}
+
+// encodeHeader validates a header value and encodes it if configured. Values
+// that cannot be sent faithfully are rejected rather than mangled in transit.
+func (m *MenousDB) encodeHeader(name, value string) (string, error) {
+	if !utf8.ValidString(value) {
+		return "", fmt.Errorf("%w: %s is not valid UTF-8", ErrInvalidHeaderValue, name)
+	}
+	if m.percentEncode && identifierHeaders[name] {
+		return url.PathEscape(value), nil
+	}
+	for _, r := range value {
+		if r < 0x20 || r == 0x7f {
+			return "", fmt.Errorf("%w: %s contains control character %q", ErrInvalidHeaderValue, name, r)
+		}
+		if r > 0x7e {
+			return "", fmt.Errorf("%w: %s contains non-ASCII character %q (see WithPercentEncodedIdentifiers)", ErrInvalidHeaderValue, name, r)
+		}
+	}
+	return value, nil
+}
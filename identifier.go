@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// MaxIdentifierLength is the longest database, table or column name accepted
+const MaxIdentifierLength = 128
+
+// ValidateIdentifier reports whether name is a valid database, table or
+// column name: 1 to MaxIdentifierLength bytes of letters, digits, '_' and
+// '-', not starting with '-'
+func ValidateIdentifier(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidIdentifier)
+	}
+	if len(name) > MaxIdentifierLength {
+		return fmt.Errorf("%w: %q is longer than %d bytes", ErrInvalidIdentifier, name, MaxIdentifierLength)
+	}
+	if name[0] == '-' {
+		return fmt.Errorf("%w: %q starts with '-'", ErrInvalidIdentifier, name)
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return fmt.Errorf("%w: %q contains %q", ErrInvalidIdentifier, name, r)
+		}
+	}
+	return nil
+}
+
+// WithoutIdentifierValidation disables client-side identifier checks, for
+// servers that accept names outside the default character set
+func WithoutIdentifierValidation() Option {
+	return func(m *MenousDB) {
+		m.skipIdentifiers = true
+	}
+}
+
+// validateIdentifiers checks the database and table headers and the column
+// names carried in a request body
+func (m *MenousDB) validateIdentifiers(headers map[string]string, body interface{}) error {
+	if m.skipIdentifiers {
+		return nil
+	}
+	for _, h := range []string{"database", "table"} {
+		if name, ok := headers[h]; ok {
+			if err := ValidateIdentifier(name); err != nil {
+				return fmt.Errorf("%s: %w", h, err)
+			}
+		}
+	}
+
+	fields, ok := body.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for _, f := range []string{"attributes", "columns"} {
+		names, _ := fields[f].([]string)
+		for _, name := range names {
+			if err := ValidateIdentifier(name); err != nil {
+				return fmt.Errorf("%s: %w", f, err)
+			}
+		}
+	}
+	for _, f := range []string{"conditions", "values"} {
+		row, _ := fields[f].(map[string]interface{})
+		for name := range row {
+			if err := ValidateIdentifier(name); err != nil {
+				return fmt.Errorf("%s: %w", f, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ImportOptions configures ImportRows and ImportTable
+type ImportOptions struct {
+	// BatchSize is the number of rows inserted between progress reports and
+	// checkpoints; defaults to 100
+	BatchSize int
+
+	// Total is the expected row count reported to Progress by ImportTable,
+	// which cannot know it in advance; zero means unknown
+	Total int
+
+	// Progress, if set, is called after every batch
+	Progress ProgressFunc
+
+	// CheckpointPath, if set, names a local file recording the rows committed
+	// after every batch. An interrupted import rerun with the same path and
+	// input skips the rows already inserted. The file is removed once the
+	// import completes.
+	CheckpointPath string
+
+	// DedupKeys, if set, drops rows whose values for these columns repeat
+	// a recent row, protecting against at-least-once upstream delivery
+	DedupKeys []string
+
+	// DedupWindow is the number of distinct recent keys remembered across
+	// batches. Zero deduplicates within each batch only.
+	DedupWindow int
+}
+
+// batchSize returns the configured batch size or the default
+func (o ImportOptions) batchSize() int {
+	if o.BatchSize <= 0 {
+		return 100
+	}
+	return o.BatchSize
+}
+
+// importCheckpoint is the on-disk checkpoint format
+type importCheckpoint struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	Rows     int    `json:"rows"`
+}
+
+// loadCheckpoint returns the rows already committed for table, or 0
+func loadCheckpoint(path, database, table string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var cp importCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return 0, fmt.Errorf("reading checkpoint %s: %w", path, err)
+	}
+	if cp.Database != database || cp.Table != table {
+		return 0, fmt.Errorf("checkpoint %s belongs to %s/%s, not %s/%s", path, cp.Database, cp.Table, database, table)
+	}
+	return cp.Rows, nil
+}
+
+// saveCheckpoint atomically records the rows committed for table
+func saveCheckpoint(path string, cp importCheckpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces path with data via a temporary file and rename
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ImportRows inserts rows into table and returns the number of input rows
+// processed, including rows skipped because a checkpoint showed them
+// committed and rows dropped as duplicates
+func (m *MenousDB) ImportRows(table string, rows []Row, opts ImportOptions) (int, error) {
+	i := 0
+	next := func() (Row, error) {
+		if i >= len(rows) {
+			return nil, io.EOF
+		}
+		i++
+		return rows[i-1], nil
+	}
+	return m.importFrom(table, next, len(rows), opts)
+}
+
+// ImportTable inserts newline-delimited JSON rows from r, the format written
+// by DumpTable, and returns the number of input rows processed
+func (m *MenousDB) ImportTable(r io.Reader, table string, opts ImportOptions) (int, error) {
+	total := opts.Total
+	if total <= 0 {
+		total = -1
+	}
+
+	dec := json.NewDecoder(r)
+	next := func() (Row, error) {
+		var row Row
+		if err := dec.Decode(&row); err != nil {
+			return nil, err
+		}
+		return row, nil
+	}
+	return m.importFrom(table, next, total, opts)
+}
+
+// importFrom inserts rows produced by next until it returns io.EOF
+func (m *MenousDB) importFrom(table string, next func() (Row, error), total int, opts ImportOptions) (int, error) {
+	done := 0
+	if opts.CheckpointPath != "" {
+		skip, err := loadCheckpoint(opts.CheckpointPath, m.Database, table)
+		if err != nil {
+			return 0, err
+		}
+		for done < skip {
+			if _, err := next(); err != nil {
+				return done, fmt.Errorf("skipping checkpointed row %d: %w", done, err)
+			}
+			done++
+		}
+	}
+
+	checkpoint := func() error {
+		if opts.CheckpointPath == "" {
+			return nil
+		}
+		cp := importCheckpoint{Database: m.Database, Table: table, Rows: done}
+		if err := saveCheckpoint(opts.CheckpointPath, cp); err != nil {
+			return fmt.Errorf("writing checkpoint: %w", err)
+		}
+		return nil
+	}
+
+	dedup := newDeduper(opts.DedupKeys, opts.DedupWindow)
+	batch := make([]Row, 0, opts.batchSize())
+	flush := func() error {
+		defer dedup.batchDone()
+		for _, row := range batch {
+			if dedup.duplicate(row) {
+				done++
+				continue
+			}
+			if _, err := m.InsertIntoTable(table, row); err != nil {
+				// Record the rows that did land so a resume doesn't repeat them
+				checkpoint()
+				return fmt.Errorf("inserting row %d: %w", done, err)
+			}
+			done++
+		}
+		batch = batch[:0]
+		if err := checkpoint(); err != nil {
+			return err
+		}
+		opts.Progress.report(done, total)
+		return nil
+	}
+
+	for {
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return done, fmt.Errorf("reading row %d: %w", done+len(batch), err)
+		}
+		batch = append(batch, row)
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return done, err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return done, err
+		}
+	}
+
+	if opts.CheckpointPath != "" {
+		if err := os.Remove(opts.CheckpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return done, err
+		}
+	}
+	return done, nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// IndexKind selects the structure of a LocalIndex
+type IndexKind int
+
+const (
+	// HashIndex supports exact lookups only
+	HashIndex IndexKind = iota
+	// BTreeIndex keeps rows ordered by the indexed columns, supporting
+	// exact lookups and ranges. It is a sorted array rather than a tree:
+	// writes invalidate the whole index, so cheap inserts are not needed.
+	BTreeIndex
+)
+
+// LocalIndex indexes one table in memory by columns the server cannot look
+// up efficiently. It is populated from a full table fetch on first use and
+// dropped whenever a write through the client (or a clone) touches the
+// table, to be rebuilt on the next lookup.
+type LocalIndex struct {
+	client  *MenousDB
+	table   string
+	kind    IndexKind
+	columns []string
+
+	mu     sync.Mutex
+	built  bool
+	rows   []Row
+	hash   map[string][]int
+	sorted []int
+}
+
+// indexRegistry tracks a client's indexes so writes can invalidate them
+type indexRegistry struct {
+	mu      sync.Mutex
+	indexes []*LocalIndex
+}
+
+// NewIndex creates an index over columns of table in the client's database
+func (m *MenousDB) NewIndex(table string, kind IndexKind, columns ...string) *LocalIndex {
+	ix := &LocalIndex{client: m, table: table, kind: kind, columns: columns}
+	if m.indexes != nil {
+		m.indexes.mu.Lock()
+		m.indexes.indexes = append(m.indexes.indexes, ix)
+		m.indexes.mu.Unlock()
+	}
+	return ix
+}
+
+// invalidate drops indexes a write to database and table could affect; an
+// empty table means the whole database changed
+func (r *indexRegistry) invalidate(database, table string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ix := range r.indexes {
+		if ix.client.Database == database && (table == "" || ix.table == table) {
+			ix.Invalidate()
+		}
+	}
+}
+
+// Invalidate drops the index contents so the next lookup rebuilds them
+func (ix *LocalIndex) Invalidate() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.built = false
+	ix.rows, ix.hash, ix.sorted = nil, nil, nil
+}
+
+// Lookup returns the rows whose indexed columns equal values, in column order
+func (ix *LocalIndex) Lookup(values ...interface{}) ([]Row, error) {
+	if len(values) != len(ix.columns) {
+		return nil, errors.New("lookup needs one value per indexed column")
+	}
+	probe := make(Row, len(values))
+	for i, c := range ix.columns {
+		probe[c] = values[i]
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if err := ix.build(); err != nil {
+		return nil, err
+	}
+
+	if ix.kind == HashIndex {
+		return ix.collect(ix.hash[rowKey(probe, ix.columns)]), nil
+	}
+	lo := sort.Search(len(ix.sorted), func(i int) bool {
+		return ix.compare(ix.rows[ix.sorted[i]], probe, len(ix.columns)) >= 0
+	})
+	hi := sort.Search(len(ix.sorted), func(i int) bool {
+		return ix.compare(ix.rows[ix.sorted[i]], probe, len(ix.columns)) > 0
+	})
+	return ix.collect(ix.sorted[lo:hi]), nil
+}
+
+// Range returns the rows whose first indexed column lies between lo and hi
+// inclusive, in index order. A nil bound is open. Only BTreeIndex supports
+// ranges.
+func (ix *LocalIndex) Range(lo, hi interface{}) ([]Row, error) {
+	if ix.kind != BTreeIndex {
+		return nil, errors.New("range lookups need a BTreeIndex")
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if err := ix.build(); err != nil {
+		return nil, err
+	}
+
+	first := ix.columns[0]
+	start := 0
+	if lo != nil {
+		start = sort.Search(len(ix.sorted), func(i int) bool {
+			return compareValues(ix.rows[ix.sorted[i]][first], lo) >= 0
+		})
+	}
+	end := len(ix.sorted)
+	if hi != nil {
+		end = sort.Search(len(ix.sorted), func(i int) bool {
+			return compareValues(ix.rows[ix.sorted[i]][first], hi) > 0
+		})
+	}
+	if start > end {
+		return nil, nil
+	}
+	return ix.collect(ix.sorted[start:end]), nil
+}
+
+// build populates the index from a full fetch unless it is current. The
+// caller holds ix.mu.
+func (ix *LocalIndex) build() error {
+	if ix.built {
+		return nil
+	}
+	rows, err := ix.client.GetTableRows(ix.table)
+	if err != nil {
+		return err
+	}
+
+	ix.rows = rows
+	switch ix.kind {
+	case HashIndex:
+		ix.hash = make(map[string][]int, len(rows))
+		for i, row := range rows {
+			key := rowKey(row, ix.columns)
+			ix.hash[key] = append(ix.hash[key], i)
+		}
+	case BTreeIndex:
+		ix.sorted = make([]int, len(rows))
+		for i := range rows {
+			ix.sorted[i] = i
+		}
+		sort.SliceStable(ix.sorted, func(a, b int) bool {
+			return ix.compare(rows[ix.sorted[a]], rows[ix.sorted[b]], len(ix.columns)) < 0
+		})
+	}
+	ix.built = true
+	return nil
+}
+
+// compare orders two rows by their first n indexed columns
+func (ix *LocalIndex) compare(a, b Row, n int) int {
+	for _, c := range ix.columns[:n] {
+		if r := compareValues(a[c], b[c]); r != 0 {
+			return r
+		}
+	}
+	return 0
+}
+
+// collect returns the rows at positions
+func (ix *LocalIndex) collect(positions []int) []Row {
+	if len(positions) == 0 {
+		return nil
+	}
+	out := make([]Row, len(positions))
+	for i, p := range positions {
+		out[i] = ix.rows[p]
+	}
+	return out
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// KafkaProducer writes a message to a Kafka topic, returning once the
+// brokers acknowledged it. It is implemented in a few lines over any Kafka
+// client; with segmentio/kafka-go, for example:
+//
+//	func (p kafkaGo) Produce(ctx context.Context, topic string, key, value []byte) error {
+//		return p.w.WriteMessages(ctx, kafka.Message{Topic: topic, Key: key, Value: value})
+//	}
+type KafkaProducer interface {
+	Produce(ctx context.Context, topic string, key, value []byte) error
+}
+
+// KafkaSink tails tables with a ChangeTailer and produces their changes to
+// Kafka as JSON ChangeEvents keyed by the row's key columns, so one row's
+// changes stay in one partition and in order. Delivery is at least once: a
+// table's position advances only after every change of a poll was
+// acknowledged, and a failed poll is produced again from the previous
+// position.
+type KafkaSink struct {
+	Client   *MenousDB
+	Producer KafkaProducer
+	Tables   []SinkTable
+
+	// TopicPrefix starts default topic names; defaults to "menousdb."
+	TopicPrefix string
+
+	// CheckpointPath and SkipSnapshot configure the tailer; see
+	// ChangeTailer
+	CheckpointPath string
+	SkipSnapshot   bool
+
+	// OnError, if set, receives errors from scheduled polls; Run keeps going
+	// after reporting them
+	OnError func(error)
+
+	once   sync.Once
+	tailer *ChangeTailer
+}
+
+// Run calls PollOnce immediately and then every interval until ctx is done
+func (s *KafkaSink) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.PollOnce(ctx); err != nil && ctx.Err() == nil && s.OnError != nil {
+			s.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// PollOnce produces every table's changes since the last poll, returning the
+// number of messages produced
+func (s *KafkaSink) PollOnce(ctx context.Context) (int, error) {
+	s.once.Do(func() {
+		s.tailer = &ChangeTailer{
+			Client:         s.Client,
+			Tables:         s.Tables,
+			CheckpointPath: s.CheckpointPath,
+			SkipSnapshot:   s.SkipSnapshot,
+		}
+	})
+
+	prefix := s.TopicPrefix
+	if prefix == "" {
+		prefix = "menousdb."
+	}
+	topics := make(map[string]string, len(s.Tables))
+	for _, t := range s.Tables {
+		topics[t.Name] = t.Topic
+		if t.Topic == "" {
+			topics[t.Name] = prefix + t.Name
+		}
+	}
+
+	return s.tailer.Poll(ctx, func(ctx context.Context, e ChangeEvent) error {
+		key, err := json.Marshal(e.Key)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return s.Producer.Produce(ctx, topics[e.Table], key, value)
+	})
+}
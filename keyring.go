@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// KeyRing maps database names to API keys. It is safe for concurrent use.
+type KeyRing struct {
+	mu   sync.RWMutex
+	keys map[string]string
+}
+
+// NewKeyRing creates a key ring seeded with the given database keys
+func NewKeyRing(keys map[string]string) *KeyRing {
+	k := &KeyRing{keys: make(map[string]string, len(keys))}
+	for db, key := range keys {
+		k.keys[db] = key
+	}
+	return k
+}
+
+// Set stores the key for a database
+func (k *KeyRing) Set(database, key string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.keys == nil {
+		k.keys = make(map[string]string)
+	}
+	k.keys[database] = key
+}
+
+// Remove deletes the key for a database
+func (k *KeyRing) Remove(database string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.keys, database)
+}
+
+// Lookup returns the key for a database and whether one was found
+func (k *KeyRing) Lookup(database string) (string, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[database]
+	return key, ok
+}
+
+// WithKeyRing makes the client take per-database keys from ring, falling back
+// to the client key for databases the ring does not know
+func WithKeyRing(ring *KeyRing) Option {
+	return func(m *MenousDB) {
+		m.keyRing = ring
+	}
+}
+
+// ForDatabase returns a copy of the client targeting another database. The
+// copy shares the HTTP client and key ring, so one client can serve every
+// tenant.
+func (m *MenousDB) ForDatabase(database string) *MenousDB {
+	return m.Clone(WithDatabase(database))
+}
+
+// resolveKey returns the key to use for a database
+func (m *MenousDB) resolveKey(ctx context.Context, database string) (string, error) {
+	if m.keyRing != nil && database != "" {
+		if key, ok := m.keyRing.Lookup(database); ok {
+			return key, nil
+		}
+	}
+	if key, ok := m.refreshedKey(); ok {
+		return key, nil
+	}
+	if m.secrets != nil {
+		return m.secrets.get(ctx)
+	}
+	return m.Key, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LatencyDistribution draws the delays WithSimulatedLatency adds
+type LatencyDistribution interface {
+	Sample(r *rand.Rand) time.Duration
+}
+
+// FixedLatency delays every request by d
+func FixedLatency(d time.Duration) LatencyDistribution {
+	return fixedLatency(d)
+}
+
+type fixedLatency time.Duration
+
+func (d fixedLatency) Sample(*rand.Rand) time.Duration { return time.Duration(d) }
+
+// NormalLatency draws delays from a normal distribution, clamped at zero
+func NormalLatency(mean, stddev time.Duration) LatencyDistribution {
+	return normalLatency{mean: mean, stddev: stddev}
+}
+
+type normalLatency struct {
+	mean, stddev time.Duration
+}
+
+func (n normalLatency) Sample(r *rand.Rand) time.Duration {
+	d := time.Duration(r.NormFloat64()*float64(n.stddev)) + n.mean
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// ParetoLatency draws heavy-tailed delays of at least min from a Pareto
+// distribution. Smaller shapes give longer tails: with shape 1.16, one
+// request in five takes over four times min, the tail that trips timeouts
+// in production.
+func ParetoLatency(min time.Duration, shape float64) LatencyDistribution {
+	return paretoLatency{min: min, shape: shape}
+}
+
+type paretoLatency struct {
+	min   time.Duration
+	shape float64
+}
+
+func (p paretoLatency) Sample(r *rand.Rand) time.Duration {
+	// Inverse transform sampling; 1-Float64 is in (0, 1]
+	d := float64(p.min) / math.Pow(1-r.Float64(), 1/p.shape)
+	if d > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+
+// simulatedLatency is the delay source shared by a client and its clones
+type simulatedLatency struct {
+	dist LatencyDistribution
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// WithSimulatedLatency delays every request sent to the server by a delay
+// drawn from dist, for staging environments to surface timeout handling bugs
+// before production does. The delay counts towards the client's timeout
+// like real network time, and cached responses are not delayed.
+//
+//	db := NewMenousDB(url, key, "app",
+//		WithTimeout(2*time.Second),
+//		WithSimulatedLatency(ParetoLatency(50*time.Millisecond, 1.16)))
+func WithSimulatedLatency(dist LatencyDistribution) Option {
+	return func(m *MenousDB) {
+		if dist == nil {
+			m.latency = nil
+			return
+		}
+		m.latency = &simulatedLatency{dist: dist, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	}
+}
+
+// sample draws the next delay
+func (l *simulatedLatency) sample() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.dist.Sample(l.rand)
+}
+
+// latencyTransport delays requests before passing them on
+type latencyTransport struct {
+	base    http.RoundTripper
+	latency *simulatedLatency
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *latencyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if d := t.latency.sample(); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-req.Context().Done():
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// withLatency returns client with the simulated latency in its transport,
+// so the client's timeout covers the delay
+func (m *MenousDB) withLatency(client *http.Client) *http.Client {
+	if m.latency == nil {
+		return client
+	}
+	c := *client
+	c.Transport = &latencyTransport{base: client.Transport, latency: m.latency}
+	return &c
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Flusher is implemented by anything that buffers writes on a client's
+// behalf, such as batch writers and offline queues, so Close can drain it
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// lifecycle tracks in-flight requests and registered flushers. Each clone
+// has its own, under its parent's: requests and flushers count towards every
+// ancestor, so closing a client closes its clones but not its parent.
+type lifecycle struct {
+	parent *lifecycle
+
+	mu       sync.Mutex
+	closed   bool
+	inflight int
+	drained  chan struct{}
+	flushers []Flusher
+}
+
+// RegisterFlusher adds f to the buffers flushed by Close
+func (m *MenousDB) RegisterFlusher(f Flusher) {
+	if m.life == nil {
+		return
+	}
+	for l := m.life; l != nil; l = l.parent {
+		l.mu.Lock()
+		l.flushers = append(l.flushers, f)
+		l.mu.Unlock()
+	}
+}
+
+// begin records a request starting, failing once the client is closed
+func (l *lifecycle) begin() error {
+	if l == nil {
+		return nil
+	}
+	if err := l.parent.begin(); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		l.parent.end()
+		return ErrClientClosed
+	}
+	l.inflight++
+	return nil
+}
+
+// check fails once the client is closed, for requests served without
+// reaching the server
+func (l *lifecycle) check() error {
+	if l == nil {
+		return nil
+	}
+	if err := l.parent.check(); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ErrClientClosed
+	}
+	return nil
+}
+
+// end records a request finishing
+func (l *lifecycle) end() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.inflight--
+	if l.inflight == 0 && l.drained != nil {
+		close(l.drained)
+		l.drained = nil
+	}
+	l.mu.Unlock()
+	l.parent.end()
+}
+
+// trackedBody ends a request's in-flight period when its body is closed
+type trackedBody struct {
+	io.ReadCloser
+	once sync.Once
+	life *lifecycle
+}
+
+// Close closes the body and marks the request finished
+func (b *trackedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.life.end)
+	return err
+}
+
+// Close shuts the client down: it flushes registered writers and queues,
+// stops accepting new requests, waits for in-flight ones to finish (a
+// response counts until its body is closed) and closes idle connections.
+// If ctx ends first Close returns its error; flush errors are joined into
+// the result. Close applies to this client and the clones made from it,
+// not to the client it was cloned from.
+func (m *MenousDB) Close(ctx context.Context) error {
+	l := m.life
+	if l == nil {
+		m.httpClient().CloseIdleConnections()
+		return nil
+	}
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	flushers := l.flushers
+	l.mu.Unlock()
+
+	// Flush while requests are still accepted
+	var errs []error
+	for _, f := range flushers {
+		if err := f.Flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Stop new requests and wait for the rest
+	l.mu.Lock()
+	l.closed = true
+	var drained chan struct{}
+	if l.inflight > 0 {
+		if l.drained == nil {
+			l.drained = make(chan struct{})
+		}
+		drained = l.drained
+	}
+	l.mu.Unlock()
+
+	if drained != nil {
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			errs = append(errs, ctx.Err())
+		}
+	}
+
+	m.httpClient().CloseIdleConnections()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+)
+
+// command is a menousdb CLI subcommand
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists the available subcommands by name
+var commands = map[string]command{
+	"browse":      {"browse, filter and edit tables in a terminal UI", runBrowse},
+	"completion":  {"print a bash, zsh or fish completion script", runCompletion},
+	"conformance": {"check which endpoints and behaviours a server supports", runConformance},
+	"exporter":    {"serve Prometheus metrics about a server", runExporter},
+	"gen-data":    {"generate realistic fake rows and bulk-load them", runGenData},
+	"graphql":     {"serve a GraphQL API generated from a database's tables", runGraphQL},
+	"grpc":        {"serve the gRPC service backed by a server", runGRPC},
+	"mirror":      {"continuously replicate tables to another server", runMirror},
+	"openapi":     {"print, check or generate bindings from OpenAPI descriptions", runOpenAPI},
+	"probe":       {"exit non-zero unless the server is live or ready", runProbe},
+	"profile":     {"list, add, remove and switch connection profiles", runProfile},
+	"proxy":       {"serve a REST/JSON API backed by a server", runProxy},
+	"schema-diff": {"print or apply the changes that reconcile a schema spec", runSchemaDiff},
+	"shell":       {"run an interactive query shell", runShell},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "menousdb: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "menousdb:", err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the list of subcommands
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name, c := range commands {
+		// Commands without a summary are internal, such as __complete
+		if c.summary != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: menousdb <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].summary)
+	}
+}
+
+// connFlags holds the connection flags for one server
+type connFlags struct {
+	url, key, database, profile *string
+}
+
+// newConnFlags registers url, key, db and profile flags on fs under prefix.
+// Without a prefix they default to the MENOUSDB_URL, MENOUSDB_KEY,
+// MENOUSDB_DATABASE and MENOUSDB_PROFILE environment variables.
+func newConnFlags(fs *flag.FlagSet, prefix, what string) *connFlags {
+	env := func(name string) string {
+		if prefix != "" {
+			return ""
+		}
+		return os.Getenv(name)
+	}
+	return &connFlags{
+		url:      fs.String(prefix+"url", env("MENOUSDB_URL"), what+" server URL"),
+		key:      fs.String(prefix+"key", env("MENOUSDB_KEY"), what+" API key"),
+		database: fs.String(prefix+"db", env("MENOUSDB_DATABASE"), what+" database"),
+		profile:  fs.String(prefix+"profile", env("MENOUSDB_PROFILE"), what+" profile from "+DefaultProfilesPath()),
+	}
+}
+
+// client builds a client from the parsed flags. Settings left empty come
+// from the named profile, or from the current profile when no flag names one
+// and no URL is given.
+func (c *connFlags) client(opts ...Option) (*MenousDB, error) {
+	url, key, database := *c.url, *c.key, *c.database
+	if *c.profile != "" || url == "" {
+		profiles, err := LoadProfiles(DefaultProfilesPath())
+		if err != nil {
+			return nil, err
+		}
+		prof, err := profiles.Lookup(*c.profile)
+		if err != nil {
+			return nil, err
+		}
+		if url == "" {
+			url = prof.URL
+		}
+		if database == "" {
+			database = prof.Database
+		}
+		var profOpts []Option
+		if prof.Timeout > 0 {
+			profOpts = append(profOpts, WithTimeout(prof.Timeout))
+		}
+		switch {
+		case key != "":
+		case prof.KeyFile != "":
+			profOpts = append(profOpts, WithSecretProvider(FileSecret(expandHome(prof.KeyFile)), 0))
+		default:
+			key = prof.Key
+		}
+		opts = append(profOpts, opts...)
+	}
+	if url == "" {
+		return nil, fmt.Errorf("missing server URL; pass -url or set up a profile")
+	}
+	return NewMenousDB(url, key, database, opts...), nil
+}
+
+// serveFlags are the listening and access flags of the commands that serve
+// an API on the server's behalf
+type serveFlags struct {
+	listen *string
+	token  *string
+	open   *bool
+}
+
+// newServeFlags registers the serving flags, listening on localhost:port by
+// default
+func newServeFlags(fs *flag.FlagSet, port, what string) *serveFlags {
+	return &serveFlags{
+		listen: fs.String("listen", "127.0.0.1:"+port, "address to serve the "+what+" on"),
+		token:  fs.String("token", os.Getenv("MENOUSDB_SERVE_TOKEN"), "bearer token clients must send; required unless -allow-unauthenticated"),
+		open:   fs.Bool("allow-unauthenticated", false, "serve without a token, letting anyone who reaches -listen act with the server API key"),
+	}
+}
+
+// authorize returns the Authorize hook checking the -token, or nil when
+// -allow-unauthenticated is set
+func (s *serveFlags) authorize() (func(r *http.Request, database string, write bool) error, error) {
+	switch {
+	case *s.token != "":
+		want := []byte("Bearer " + *s.token)
+		return func(r *http.Request, database string, write bool) error {
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+				return errors.New("missing or invalid bearer token")
+			}
+			return nil
+		}, nil
+	case *s.open:
+		return nil, nil
+	}
+	return nil, errors.New("-token (or MENOUSDB_SERVE_TOKEN) is required; pass -allow-unauthenticated to serve without one")
+}
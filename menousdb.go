@@ -2,11 +2,14 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
 )
 
 // MenousDB represents the database client
@@ -14,20 +17,150 @@ type MenousDB struct {
 	URL      string
 	Key      string
 	Database string
+
+	client     *http.Client
+	socketPath string
+	authScheme AuthScheme
+	basicUser  string
+	basicPass  string
+
+	tokenSource oauth2.TokenSource
+	signer      Signer
+	keyRing     *KeyRing
+	readOnly    bool
+	policy      AccessPolicy
+
+	queryMode       QueryMode
+	queryNegotiated int32
+
+	timeout time.Duration
+
+	replicaURLs []string
+	endpoints   *endpointSet
+	refresher   *endpointRefresher
+
+	life        *lifecycle
+	authRefresh *authRefresh
+	secrets     *secretCache
+	features    *featureSet
+	apiVersion  APIVersion
+	cache       *readCache
+	indexes     *indexRegistry
+	schemas     *schemaRegistry
+	coalescer   *updateCoalescer
+	events      *EventBus
+	nats        *natsBridge
+	latency     *simulatedLatency
+
+	limiter         *RateLimiter
+	throttleRetries int
+	throttleMaxWait time.Duration
+
+	percentEncode   bool
+	skipIdentifiers bool
+	strictDecode    bool
+	codec           Codec
 }
 
 // NewMenousDB creates a new MenousDB client
-func NewMenousDB(url, key, database string) *MenousDB {
+func NewMenousDB(url, key, database string, opts ...Option) *MenousDB {
 	// Ensure URL ends with a slash
 	if !strings.HasSuffix(url, "/") {
 		url += "/"
 	}
 
-	return &MenousDB{
+	m := &MenousDB{
 		URL:      url,
 		Key:      key,
 		Database: database,
+		life:     &lifecycle{},
+		features: newFeatureSet(),
+		indexes:  &indexRegistry{},
+		schemas:  &schemaRegistry{},
+
+		throttleRetries: DefaultThrottleRetries,
+		throttleMaxWait: DefaultThrottleMaxWait,
+	}
+
+	// Route unix:// URLs through the socket instead of TCP
+	if strings.HasPrefix(url, unixScheme) {
+		m.socketPath = strings.TrimSuffix(strings.TrimPrefix(url, unixScheme), "/")
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.client == nil {
+		m.client = &http.Client{}
 	}
+	discovery := m.refresher != nil && m.refresher.discover != nil
+	if (len(m.replicaURLs) > 0 || discovery) && m.socketPath == "" {
+		m.endpoints = newEndpointSet(m.URL, m.replicaURLs)
+	}
+	if m.socketPath != "" && m.client.Transport == nil {
+		// Copy rather than change a client passed in with WithHTTPClient,
+		// which others, such as a ConfigWatcher's later clients, may share
+		client := *m.client
+		client.Transport = unixTransport(m.socketPath)
+		m.client = &client
+	}
+	m.applyTimeout()
+
+	return m
+}
+
+// httpClient returns the client used to execute requests
+func (m *MenousDB) httpClient() *http.Client {
+	if m.client == nil {
+		return http.DefaultClient
+	}
+	return m.client
+}
+
+// requestURL builds the full URL for an endpoint
+func (m *MenousDB) requestURL(endpoint string) string {
+	path := m.versionPrefix() + endpoint
+	if m.socketPath != "" {
+		return unixBaseURL + path
+	}
+	if m.endpoints != nil {
+		if mutatingEndpoints[endpoint] {
+			return m.endpoints.primaryURL() + path
+		}
+		return m.endpoints.pick(time.Now()) + path
+	}
+	return m.URL + path
+}
+
+// bufferPool recycles the buffers request bodies are encoded into
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// maxPooledBuffer is the largest buffer bufferPool keeps; bigger ones, from
+// occasional large writes, are left to the garbage collector
+const maxPooledBuffer = 64 << 10
+
+// encodeBody encodes body through a pooled buffer and returns a copy of the
+// bytes, which the transport may hold, rewind and resend for as long as it
+// likes
+func (m *MenousDB) encodeBody(body interface{}) ([]byte, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			buf.Reset()
+			bufferPool.Put(buf)
+		}
+	}()
+	if err := m.encode(buf, body); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, buf.Len())
+	copy(payload, buf.Bytes())
+	return payload, nil
 }
 
 // validateDatabase checks if database is set
@@ -40,17 +173,197 @@ func (m *MenousDB) validateDatabase() error {
 
 // makeRequest handles common HTTP request logic
 func (m *MenousDB) makeRequest(method, endpoint string, headers map[string]string, body interface{}) (*http.Response, error) {
+	return m.request(method, endpoint, headers, body, false)
+}
+
+// request runs a request through the client's checks, cache and transport.
+// With params set the body travels as JSON query parameters instead, as
+// QueryModeParams sends it; everything before newRequest still sees it as
+// the body, so checks, cache keys and events are the same either way.
+func (m *MenousDB) request(method, endpoint string, headers map[string]string, body interface{}, params bool) (*http.Response, error) {
+	if err := m.checkWritable(endpoint); err != nil {
+		return nil, err
+	}
+	if err := m.checkPolicy(endpoint, headers); err != nil {
+		return nil, err
+	}
+	if err := m.validateIdentifiers(headers, body); err != nil {
+		return nil, err
+	}
+	body, err := m.checkWrite(endpoint, headers, body)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkFeature(endpoint); err != nil {
+		return nil, err
+	}
+
+	if m.cache != nil {
+		if rule, ok := m.cache.rule(endpoint); ok {
+			if err := m.life.check(); err != nil {
+				return nil, err
+			}
+			return m.cachedRequest(rule, method, endpoint, headers, body, params)
+		}
+	}
+	resp, err := m.roundTrip(method, endpoint, headers, body, params)
+	if err == nil && mutatingEndpoints[endpoint] {
+		m.afterWrite(endpoint, headers, body)
+	}
+	return resp, err
+}
+
+// afterWrite drops cached reads and local indexes a successful write could
+// have made stale, or with write-through caching updates the cached reads,
+// then emits the write's event
+func (m *MenousDB) afterWrite(endpoint string, headers map[string]string, body interface{}) {
+	database, table := headers["database"], headers["table"]
+	if m.cache != nil {
+		if m.cache.opts.WriteThrough && (endpoint == "insert-into-table" || endpoint == "update-table") {
+			m.cache.writeThrough(endpoint, database, table, body)
+		} else {
+			m.cache.invalidate(endpoint, database, table)
+		}
+		if m.cache.shared != nil {
+			m.cache.shared.publish(endpoint, database, table)
+		}
+	}
+	switch endpoint {
+	case "insert-into-table", "update-table", "delete-where", "delete-table":
+		m.indexes.invalidate(database, table)
+	default:
+		m.indexes.invalidate(database, "")
+	}
+	if m.events != nil || m.nats != nil {
+		m.emitEvent(endpoint, headers, body)
+	}
+}
+
+// roundTrip sends a checked request, adapting it to the client's API version
+// and tracking it until its body is closed
+func (m *MenousDB) roundTrip(method, endpoint string, headers map[string]string, body interface{}, params bool) (*http.Response, error) {
+	adapter := m.versionAdapter()
+	if adapter.Request != nil {
+		headers, body = adapter.Request(endpoint, headers, body)
+	}
+
+	if err := m.life.begin(); err != nil {
+		return nil, err
+	}
+	resp, err := m.sendRequest(method, endpoint, headers, body, params)
+	if err != nil {
+		m.life.end()
+		m.observeFeature(endpoint, err)
+		return nil, err
+	}
+	if adapter.Response != nil {
+		adapted, err := adapter.Response(endpoint, resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			m.life.end()
+			return nil, err
+		}
+		resp.Body = adapted
+	}
+	if m.life != nil {
+		resp.Body = &trackedBody{ReadCloser: resp.Body, life: m.life}
+	}
+	return resp, nil
+}
+
+// sendRequest builds and executes a request, backing off while the server
+// throttles it
+func (m *MenousDB) sendRequest(method, endpoint string, headers map[string]string, body interface{}, params bool) (*http.Response, error) {
+	m.maybeRefresh()
+
+	reauthed := false
+	for attempt := 0; ; attempt++ {
+		req, err := m.newRequest(method, endpoint, headers, body, params)
+		if err != nil {
+			return nil, err
+		}
+		if m.limiter != nil {
+			if err := m.limiter.Wait(req.Context()); err != nil {
+				if req.Body != nil {
+					req.Body.Close()
+				}
+				return nil, err
+			}
+		}
+
+		// Execute request
+		start := time.Now()
+		resp, err := m.withLatency(m.httpClient()).Do(req)
+		if m.endpoints != nil {
+			failed := err != nil || resp.StatusCode >= 500
+			m.endpoints.observe(req.URL.String(), time.Since(start), failed, time.Now())
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		// Back off and retry when the server is throttling us
+		if throttled(resp) && attempt < m.throttleRetries {
+			d := m.throttleDelay(resp, attempt)
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+			resp.Body.Close()
+			if m.limiter != nil {
+				m.limiter.Pause(d)
+			} else {
+				time.Sleep(d)
+			}
+			continue
+		}
+
+		apiErr := newAPIError(endpoint, resp)
+		resp.Body.Close()
+
+		// Let the application replace rejected credentials, then retry once
+		if resp.StatusCode == http.StatusUnauthorized && !reauthed && (m.authRefresh != nil || m.secrets != nil) {
+			reauthed = true
+			if m.secrets != nil {
+				m.secrets.invalidate()
+			}
+			if m.authRefresh != nil {
+				if err := m.refreshAuth(req.Context(), headers["database"], apiErr); err != nil {
+					return nil, fmt.Errorf("refreshing credentials: %w", err)
+				}
+			}
+			continue
+		}
+		return nil, apiErr
+	}
+}
+
+// newRequest builds an authenticated request. With params set body is sent
+// as query parameters.
+func (m *MenousDB) newRequest(method, endpoint string, headers map[string]string, body interface{}, params bool) (*http.Request, error) {
 	// Prepare URL
-	url := m.URL + endpoint
+	url := m.requestURL(endpoint)
+	if params {
+		query, err := queryParams(body)
+		if err != nil {
+			return nil, err
+		}
+		if len(query) > 0 {
+			url += "?" + query.Encode()
+		}
+		body = nil
+	}
 
-	// Prepare body
+	// Prepare body; a *bytes.Reader lets http.NewRequest set GetBody, so
+	// redirects and transport retries can resend it
 	var bodyReader io.Reader
+	var payload []byte
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
-		if err != nil {
+		var err error
+		if payload, err = m.encodeBody(body); err != nil {
 			return nil, err
 		}
-		bodyReader = bytes.NewBuffer(jsonBody)
+		bodyReader = bytes.NewReader(payload)
 	}
 
 	// Create request
@@ -62,16 +375,44 @@ func (m *MenousDB) makeRequest(method, endpoint string, headers map[string]strin
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	for k, v := range headers {
-		req.Header.Set(k, v)
+		value, err := m.encodeHeader(k, v)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(k, value)
+	}
+	if m.percentEncode {
+		req.Header.Set(encodingHeader, "percent")
+	}
+	key, err := m.resolveKey(req.Context(), headers["database"])
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := headers["key"]; ok {
+		value, err := m.encodeHeader("key", key)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("key", value)
+	}
+	if err := m.applyAuth(req, key); err != nil {
+		return nil, err
+	}
+
+	// Sign last so the signature covers every header set above
+	if m.signer != nil {
+		if err := m.signer.Sign(req, payload); err != nil {
+			return nil, fmt.Errorf("signing request: %w", err)
+		}
 	}
 
-	// Execute request
-	client := &http.Client{}
-	return client.Do(req)
+	return req, nil
 }
 
 // ReadDB retrieves database contents
-func (m *MenousDB) ReadDB() (map[string]interface{}, error) {
+func (m *MenousDB) ReadDB() (_ map[string]interface{}, err error) {
+	defer m.annotate(&err, "ReadDB", "read-db", "")
+
 	if err := m.validateDatabase(); err != nil {
 		return nil, err
 	}
@@ -88,7 +429,7 @@ func (m *MenousDB) ReadDB() (map[string]interface{}, error) {
 	defer resp.Body.Close()
 
 	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := m.decode(resp.Body, &result); err != nil {
 		return nil, err
 	}
 
@@ -96,7 +437,9 @@ func (m *MenousDB) ReadDB() (map[string]interface{}, error) {
 }
 
 // CreateDB creates a new database
-func (m *MenousDB) CreateDB() (string, error) {
+func (m *MenousDB) CreateDB() (_ string, err error) {
+	defer m.annotate(&err, "CreateDB", "create-db", "")
+
 	if err := m.validateDatabase(); err != nil {
 		return "", err
 	}
@@ -121,7 +464,9 @@ func (m *MenousDB) CreateDB() (string, error) {
 }
 
 // DeleteDB deletes the current database
-func (m *MenousDB) DeleteDB() (string, error) {
+func (m *MenousDB) DeleteDB() (_ string, err error) {
+	defer m.annotate(&err, "DeleteDB", "del-database", "")
+
 	if err := m.validateDatabase(); err != nil {
 		return "", err
 	}
@@ -146,7 +491,9 @@ func (m *MenousDB) DeleteDB() (string, error) {
 }
 
 // CheckDBExists checks if the database exists
-func (m *MenousDB) CheckDBExists() (string, error) {
+func (m *MenousDB) CheckDBExists() (_ string, err error) {
+	defer m.annotate(&err, "CheckDBExists", "check-db-exists", "")
+
 	if err := m.validateDatabase(); err != nil {
 		return "", err
 	}
@@ -171,7 +518,9 @@ func (m *MenousDB) CheckDBExists() (string, error) {
 }
 
 // CreateTable creates a new table in the database
-func (m *MenousDB) CreateTable(table string, attributes []string) (string, error) {
+func (m *MenousDB) CreateTable(table string, attributes []string) (_ string, err error) {
+	defer m.annotate(&err, "CreateTable", "create-table", table)
+
 	if err := m.validateDatabase(); err != nil {
 		return "", err
 	}
@@ -201,7 +550,9 @@ func (m *MenousDB) CreateTable(table string, attributes []string) (string, error
 }
 
 // CheckTableExists checks if a table exists in the database
-func (m *MenousDB) CheckTableExists(table string) (string, error) {
+func (m *MenousDB) CheckTableExists(table string) (_ string, err error) {
+	defer m.annotate(&err, "CheckTableExists", "check-table-exists", table)
+
 	if err := m.validateDatabase(); err != nil {
 		return "", err
 	}
@@ -227,7 +578,9 @@ func (m *MenousDB) CheckTableExists(table string) (string, error) {
 }
 
 // InsertIntoTable inserts values into a table
-func (m *MenousDB) InsertIntoTable(table string, values interface{}) (string, error) {
+func (m *MenousDB) InsertIntoTable(table string, values interface{}) (_ string, err error) {
+	defer m.annotate(&err, "InsertIntoTable", "insert-into-table", table)
+
 	if err := m.validateDatabase(); err != nil {
 		return "", err
 	}
@@ -257,7 +610,9 @@ func (m *MenousDB) InsertIntoTable(table string, values interface{}) (string, er
 }
 
 // GetTable retrieves a table's contents
-func (m *MenousDB) GetTable(table string) (interface{}, error) {
+func (m *MenousDB) GetTable(table string) (_ interface{}, err error) {
+	defer m.annotate(&err, "GetTable", "get-table", table)
+
 	if err := m.validateDatabase(); err != nil {
 		return nil, err
 	}
@@ -275,7 +630,7 @@ func (m *MenousDB) GetTable(table string) (interface{}, error) {
 	defer resp.Body.Close()
 
 	var result interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := m.decode(resp.Body, &result); err != nil {
 		// If JSON decoding fails, return raw text
 		body, readErr := io.ReadAll(resp.Body)
 		if readErr != nil {
@@ -284,11 +639,13 @@ func (m *MenousDB) GetTable(table string) (interface{}, error) {
 		return string(body), nil
 	}
 
-	return result, nil
+	return decompressRecords(result), nil
 }
 
 // SelectWhere retrieves records matching conditions
-func (m *MenousDB) SelectWhere(table string, conditions map[string]interface{}) (interface{}, error) {
+func (m *MenousDB) SelectWhere(table string, conditions map[string]interface{}) (_ interface{}, err error) {
+	defer m.annotate(&err, "SelectWhere", "select-where", table)
+
 	if err := m.validateDatabase(); err != nil {
 		return nil, err
 	}
@@ -303,14 +660,14 @@ func (m *MenousDB) SelectWhere(table string, conditions map[string]interface{})
 		"conditions": conditions,
 	}
 
-	resp, err := m.makeRequest("GET", "select-where", headers, body)
+	resp, err := m.doQuery("select-where", headers, body)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var result interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := m.decode(resp.Body, &result); err != nil {
 		// If JSON decoding fails, return raw text
 		body, readErr := io.ReadAll(resp.Body)
 		if readErr != nil {
@@ -319,11 +676,13 @@ func (m *MenousDB) SelectWhere(table string, conditions map[string]interface{})
 		return string(body), nil
 	}
 
-	return result, nil
+	return decompressRecords(result), nil
 }
 
 // SelectColumns retrieves specific columns from a table
-func (m *MenousDB) SelectColumns(table string, columns []string) (interface{}, error) {
+func (m *MenousDB) SelectColumns(table string, columns []string) (_ interface{}, err error) {
+	defer m.annotate(&err, "SelectColumns", "select-columns", table)
+
 	if err := m.validateDatabase(); err != nil {
 		return nil, err
 	}
@@ -338,14 +697,14 @@ func (m *MenousDB) SelectColumns(table string, columns []string) (interface{}, e
 		"columns": columns,
 	}
 
-	resp, err := m.makeRequest("GET", "select-columns", headers, body)
+	resp, err := m.doQuery("select-columns", headers, body)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var result interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := m.decode(resp.Body, &result); err != nil {
 		// If JSON decoding fails, return raw text
 		body, readErr := io.ReadAll(resp.Body)
 		if readErr != nil {
@@ -354,11 +713,13 @@ func (m *MenousDB) SelectColumns(table string, columns []string) (interface{}, e
 		return string(body), nil
 	}
 
-	return result, nil
+	return decompressRecords(result), nil
 }
 
 // SelectColumnsWhere retrieves specific columns matching conditions
-func (m *MenousDB) SelectColumnsWhere(table string, columns []string, conditions map[string]interface{}) (interface{}, error) {
+func (m *MenousDB) SelectColumnsWhere(table string, columns []string, conditions map[string]interface{}) (_ interface{}, err error) {
+	defer m.annotate(&err, "SelectColumnsWhere", "select-columns-where", table)
+
 	if err := m.validateDatabase(); err != nil {
 		return nil, err
 	}
@@ -374,14 +735,14 @@ func (m *MenousDB) SelectColumnsWhere(table string, columns []string, conditions
 		"conditions": conditions,
 	}
 
-	resp, err := m.makeRequest("GET", "select-columns-where", headers, body)
+	resp, err := m.doQuery("select-columns-where", headers, body)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var result interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := m.decode(resp.Body, &result); err != nil {
 		// If JSON decoding fails, return raw text
 		body, readErr := io.ReadAll(resp.Body)
 		if readErr != nil {
@@ -390,11 +751,13 @@ func (m *MenousDB) SelectColumnsWhere(table string, columns []string, conditions
 		return string(body), nil
 	}
 
-	return result, nil
+	return decompressRecords(result), nil
 }
 
 // DeleteWhere removes records matching conditions
-func (m *MenousDB) DeleteWhere(table string, conditions map[string]interface{}) (interface{}, error) {
+func (m *MenousDB) DeleteWhere(table string, conditions map[string]interface{}) (_ interface{}, err error) {
+	defer m.annotate(&err, "DeleteWhere", "delete-where", table)
+
 	if err := m.validateDatabase(); err != nil {
 		return nil, err
 	}
@@ -416,7 +779,7 @@ func (m *MenousDB) DeleteWhere(table string, conditions map[string]interface{})
 	defer resp.Body.Close()
 
 	var result interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := m.decode(resp.Body, &result); err != nil {
 		// If JSON decoding fails, return raw text
 		body, readErr := io.ReadAll(resp.Body)
 		if readErr != nil {
@@ -429,7 +792,9 @@ func (m *MenousDB) DeleteWhere(table string, conditions map[string]interface{})
 }
 
 // DeleteTable removes an entire table
-func (m *MenousDB) DeleteTable(table string) (interface{}, error) {
+func (m *MenousDB) DeleteTable(table string) (_ interface{}, err error) {
+	defer m.annotate(&err, "DeleteTable", "delete-table", table)
+
 	if err := m.validateDatabase(); err != nil {
 		return nil, err
 	}
@@ -447,7 +812,7 @@ func (m *MenousDB) DeleteTable(table string) (interface{}, error) {
 	defer resp.Body.Close()
 
 	var result interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := m.decode(resp.Body, &result); err != nil {
 		// If JSON decoding fails, return raw text
 		body, readErr := io.ReadAll(resp.Body)
 		if readErr != nil {
@@ -461,6 +826,16 @@ func (m *MenousDB) DeleteTable(table string) (interface{}, error) {
 
 // UpdateWhere updates records matching conditions
 func (m *MenousDB) UpdateWhere(table string, conditions, values map[string]interface{}) (interface{}, error) {
+	if m.coalescer != nil {
+		return m.coalescer.update(m, table, conditions, values)
+	}
+	return m.updateWhere(table, conditions, values)
+}
+
+// updateWhere sends one update-table request
+func (m *MenousDB) updateWhere(table string, conditions, values map[string]interface{}) (_ interface{}, err error) {
+	defer m.annotate(&err, "UpdateWhere", "update-table", table)
+
 	if err := m.validateDatabase(); err != nil {
 		return nil, err
 	}
@@ -483,7 +858,7 @@ func (m *MenousDB) UpdateWhere(table string, conditions, values map[string]inter
 	defer resp.Body.Close()
 
 	var result interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := m.decode(resp.Body, &result); err != nil {
 		// If JSON decoding fails, return raw text
 		body, readErr := io.ReadAll(resp.Body)
 		if readErr != nil {
@@ -496,7 +871,9 @@ func (m *MenousDB) UpdateWhere(table string, conditions, values map[string]inter
 }
 
 // GetDatabases retrieves list of databases
-func (m *MenousDB) GetDatabases() (interface{}, error) {
+func (m *MenousDB) GetDatabases() (_ interface{}, err error) {
+	defer m.annotate(&err, "GetDatabases", "get-databases", "")
+
 	headers := map[string]string{
 		"key": m.Key,
 	}
@@ -508,7 +885,7 @@ func (m *MenousDB) GetDatabases() (interface{}, error) {
 	defer resp.Body.Close()
 
 	var result interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := m.decode(resp.Body, &result); err != nil {
 		// If JSON decoding fails, return raw text
 		body, readErr := io.ReadAll(resp.Body)
 		if readErr != nil {
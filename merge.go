@@ -0,0 +1,101 @@
+package main
+
+import "fmt"
+
+// ConflictResolver decides what to store when an incoming row's key already
+// exists in the destination. It returns the row to write and whether to
+// write it at all.
+type ConflictResolver func(existing, incoming Row) (Row, bool)
+
+// KeepExisting leaves destination rows untouched on conflict
+func KeepExisting() ConflictResolver {
+	return func(existing, incoming Row) (Row, bool) {
+		return nil, false
+	}
+}
+
+// KeepIncoming overwrites destination rows with the source row on conflict
+func KeepIncoming() ConflictResolver {
+	return func(existing, incoming Row) (Row, bool) {
+		return incoming, true
+	}
+}
+
+// KeepNewest keeps whichever row has the greater value in column, such as an
+// updated_at timestamp or version number. Ties keep the existing row.
+func KeepNewest(column string) ConflictResolver {
+	return func(existing, incoming Row) (Row, bool) {
+		if compareValues(incoming[column], existing[column]) > 0 {
+			return incoming, true
+		}
+		return nil, false
+	}
+}
+
+// MergeResult summarizes a MergeTables run
+type MergeResult struct {
+	Inserted int
+	Updated  int
+	Skipped  int
+}
+
+// MergeTables upserts every row of src into dst, matching rows on
+// keyColumns. Rows missing from dst are inserted; conflicts are settled by
+// resolve, which defaults to KeepIncoming.
+func (m *MenousDB) MergeTables(src, dst string, keyColumns []string, resolve ConflictResolver) (MergeResult, error) {
+	var result MergeResult
+	if len(keyColumns) == 0 {
+		return result, fmt.Errorf("merge requires at least one key column")
+	}
+	if resolve == nil {
+		resolve = KeepIncoming()
+	}
+
+	srcRows, err := m.GetTableRows(src)
+	if err != nil {
+		return result, fmt.Errorf("reading %s: %w", src, err)
+	}
+	dstRows, err := m.GetTableRows(dst)
+	if err != nil {
+		return result, fmt.Errorf("reading %s: %w", dst, err)
+	}
+
+	existing := make(map[string]Row, len(dstRows))
+	for _, row := range dstRows {
+		existing[rowKey(row, keyColumns)] = row
+	}
+
+	for _, row := range srcRows {
+		key := rowKey(row, keyColumns)
+		current, ok := existing[key]
+		if !ok {
+			if _, err := m.InsertIntoTable(dst, row); err != nil {
+				return result, fmt.Errorf("inserting into %s: %w", dst, err)
+			}
+			existing[key] = row
+			result.Inserted++
+			continue
+		}
+
+		merged, write := resolve(current, row)
+		if !write {
+			result.Skipped++
+			continue
+		}
+		if _, err := m.UpdateWhere(dst, keyConditions(current, keyColumns), merged); err != nil {
+			return result, fmt.Errorf("updating %s: %w", dst, err)
+		}
+		existing[key] = merged
+		result.Updated++
+	}
+	return result, nil
+}
+
+// keyConditions builds equality conditions matching row on keyColumns
+func keyConditions(row Row, keyColumns []string) map[string]interface{} {
+	conditions := make(map[string]interface{}, len(keyColumns))
+	for _, c := range keyColumns {
+		conditions[c] = row[c]
+	}
+	return conditions
+}
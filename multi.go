@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// SelectMulti runs one select per table concurrently and returns the rows
+// keyed by table. Tables with no conditions are fetched whole. Results for
+// tables that succeeded are returned even when others fail; the error joins
+// every per-table failure.
+func (m *MenousDB) SelectMulti(queries map[string]map[string]interface{}) (map[string][]Row, error) {
+	type result struct {
+		table string
+		rows  []Row
+		err   error
+	}
+
+	results := make(chan result, len(queries))
+	var wg sync.WaitGroup
+	for table, conditions := range queries {
+		wg.Add(1)
+		go func(table string, conditions map[string]interface{}) {
+			defer wg.Done()
+			var rows []Row
+			var err error
+			if len(conditions) == 0 {
+				rows, err = m.GetTableRows(table)
+			} else {
+				rows, err = m.SelectWhereRows(table, conditions)
+			}
+			results <- result{table: table, rows: rows, err: err}
+		}(table, conditions)
+	}
+	wg.Wait()
+	close(results)
+
+	out := make(map[string][]Row, len(queries))
+	var errs []error
+	for res := range results {
+		if res.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", res.table, res.err))
+			continue
+		}
+		out[res.table] = res.rows
+	}
+	return out, errors.Join(errs...)
+}
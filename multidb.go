@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DefaultSourceColumn is the attribute MultiDB adds to each row to name the
+// database it came from
+const DefaultSourceColumn = "_database"
+
+// MultiDB runs the same query across several databases, such as one per
+// tenant, and merges the results
+type MultiDB struct {
+	Client    *MenousDB
+	Databases []string
+
+	// Concurrency bounds the databases queried at once; defaults to 8
+	Concurrency int
+
+	// SourceColumn names the attribute recording each row's database;
+	// defaults to DefaultSourceColumn
+	SourceColumn string
+}
+
+// NewMultiDB creates a MultiDB querying databases through client, which
+// supplies the URL, credentials and other settings
+func NewMultiDB(client *MenousDB, databases ...string) *MultiDB {
+	return &MultiDB{Client: client, Databases: databases}
+}
+
+// GetTable retrieves table from every database
+func (d *MultiDB) GetTable(table string) ([]Row, error) {
+	return d.Query(func(m *MenousDB) ([]Row, error) {
+		return m.GetTableRows(table)
+	})
+}
+
+// SelectWhere retrieves records matching conditions from table in every
+// database
+func (d *MultiDB) SelectWhere(table string, conditions map[string]interface{}) ([]Row, error) {
+	return d.Query(func(m *MenousDB) ([]Row, error) {
+		return m.SelectWhereRows(table, conditions)
+	})
+}
+
+// Query calls fn with a client for each database concurrently and merges the
+// rows in database order, annotating each with its source. Rows from
+// databases that succeeded are returned even when others fail; the error
+// joins every per-database failure.
+func (d *MultiDB) Query(fn func(*MenousDB) ([]Row, error)) ([]Row, error) {
+	concurrency := d.Concurrency
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+	column := d.SourceColumn
+	if column == "" {
+		column = DefaultSourceColumn
+	}
+
+	results := make([][]Row, len(d.Databases))
+	errs := make([]error, len(d.Databases))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, db := range d.Databases {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, db string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			rows, err := fn(d.Client.ForDatabase(db))
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", db, err)
+				return
+			}
+			for _, row := range rows {
+				row[column] = db
+			}
+			results[i] = rows
+		}(i, db)
+	}
+	wg.Wait()
+
+	var merged []Row
+	for _, rows := range results {
+		merged = append(merged, rows...)
+	}
+	return merged, errors.Join(errs...)
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSConn publishes to a NATS server. It speaks the NATS protocol directly
+// over one connection, answering the server's keepalive pings in the
+// background, so the module needs no NATS client dependency. A failed
+// connection is redialled by the next Publish.
+type NATSConn struct {
+	Addr string
+
+	// Token, or User and Password, authenticate the connection
+	Token    string
+	User     string
+	Password string
+
+	// DialTimeout bounds connecting; defaults to 5 seconds
+	DialTimeout time.Duration
+
+	mu      sync.Mutex
+	session *natsSession
+}
+
+// natsSession is one connection and the state its reader keeps
+type natsSession struct {
+	conn  net.Conn
+	pongs chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewNATSConn returns a publisher for the NATS server at addr (host:port)
+func NewNATSConn(addr string) *NATSConn {
+	return &NATSConn{Addr: addr}
+}
+
+// Publish sends data to subject. Like NATS publishing generally it does not
+// wait for the server; use Flush for that.
+func (c *NATSConn) Publish(ctx context.Context, subject string, data []byte) error {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("nats: invalid subject %q", subject)
+	}
+	buf := make([]byte, 0, len(subject)+len(data)+32)
+	buf = append(buf, "PUB "...)
+	buf = append(buf, subject...)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, int64(len(data)), 10)
+	buf = append(buf, "\r\n"...)
+	buf = append(buf, data...)
+	buf = append(buf, "\r\n"...)
+	_, err := c.write(ctx, buf)
+	return err
+}
+
+// Flush waits until the server has processed everything published so far,
+// returning any error it reported
+func (c *NATSConn) Flush(ctx context.Context) error {
+	s, err := c.write(ctx, []byte("PING\r\n"))
+	if err != nil {
+		return err
+	}
+	select {
+	case <-s.pongs:
+		return s.failure()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close closes the connection
+func (c *NATSConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.session == nil {
+		return nil
+	}
+	err := c.session.conn.Close()
+	c.session = nil
+	return err
+}
+
+// write sends data on the current session, dialling one if needed
+func (c *NATSConn) write(ctx context.Context, data []byte) (*natsSession, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.session != nil && c.session.failure() != nil {
+		c.session.conn.Close()
+		c.session = nil
+	}
+	if c.session == nil {
+		s, err := c.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.session = s
+	}
+
+	s := c.session
+	deadline, _ := ctx.Deadline()
+	s.conn.SetWriteDeadline(deadline)
+	if _, err := s.conn.Write(data); err != nil {
+		s.conn.Close()
+		c.session = nil
+		return nil, err
+	}
+	return s, nil
+}
+
+// dial connects, authenticates and starts the session's reader
+func (c *NATSConn) dial(ctx context.Context) (*natsSession, error) {
+	timeout := c.DialTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	r := bufio.NewReader(conn)
+
+	line, err := readNATSLine(r)
+	if err == nil && !strings.HasPrefix(line, "INFO ") {
+		err = fmt.Errorf("nats: expected INFO, got %q", line)
+	}
+	if err == nil {
+		var info struct {
+			TLSRequired bool `json:"tls_required"`
+		}
+		json.Unmarshal([]byte(line[len("INFO "):]), &info)
+		if info.TLSRequired {
+			err = errors.New("nats: server requires TLS")
+		}
+	}
+	if err == nil {
+		err = c.handshake(conn, r)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	s := &natsSession{conn: conn, pongs: make(chan struct{}, 16)}
+	go s.read(r)
+	return s, nil
+}
+
+// handshake sends CONNECT and waits for the server to accept it
+func (c *NATSConn) handshake(conn net.Conn, r *bufio.Reader) error {
+	connect := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "menousdb",
+		"lang":     "go",
+		"protocol": 1,
+	}
+	if c.Token != "" {
+		connect["auth_token"] = c.Token
+	}
+	if c.User != "" {
+		connect["user"] = c.User
+		connect["pass"] = c.Password
+	}
+	opts, err := json.Marshal(connect)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", opts); err != nil {
+		return err
+	}
+	for {
+		line, err := readNATSLine(r)
+		switch {
+		case err != nil:
+			return err
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", strings.TrimSpace(line[len("-ERR"):]))
+		}
+	}
+}
+
+// read answers pings, collects pongs and records errors until the
+// connection closes
+func (s *natsSession) read(r *bufio.Reader) {
+	for {
+		line, err := readNATSLine(r)
+		if err != nil {
+			s.fail(err)
+			close(s.pongs)
+			return
+		}
+		switch {
+		case line == "PING":
+			if _, err := s.conn.Write([]byte("PONG\r\n")); err != nil {
+				s.fail(err)
+			}
+		case line == "PONG":
+			select {
+			case s.pongs <- struct{}{}:
+			default:
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			s.fail(fmt.Errorf("nats: %s", strings.TrimSpace(line[len("-ERR"):])))
+		}
+	}
+}
+
+// fail records the session's first error
+func (s *natsSession) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// failure returns the session's error, if any
+func (s *natsSession) failure() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// readNATSLine reads one protocol line without its CRLF
+func readNATSLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// NATSOptions configures WithNATS
+type NATSOptions struct {
+	// SubjectPrefix starts every subject; defaults to "menousdb."
+	SubjectPrefix string
+
+	// Timeout bounds each publish; defaults to one second
+	Timeout time.Duration
+
+	// QueueSize bounds the events waiting to be published; defaults to
+	// 1024. Events written while the queue is full are dropped.
+	QueueSize int
+
+	// OnError, if set, receives publish failures and ErrNATSQueueFull for
+	// dropped events, which never fail the write that caused them. It is
+	// called from the publishing goroutine as well as from writers.
+	OnError func(error)
+}
+
+// natsBridge publishes a client's write events from a queue, so writes
+// never wait on the NATS server
+type natsBridge struct {
+	conn  *NATSConn
+	opts  NATSOptions
+	queue chan natsItem
+	once  sync.Once
+}
+
+// natsItem is a queued event, or with done set a Flush waiting for the
+// events queued before it
+type natsItem struct {
+	event Event
+	done  chan struct{}
+}
+
+// WithNATS publishes the event for each successful write, as JSON, to the
+// NATS subject for its table: the prefix followed by database.table, such as
+// menousdb.shop.orders. Subscribers can take a whole database with a
+// wildcard, as in menousdb.shop.>. Events are queued and published in order
+// by one background goroutine, so a slow or unreachable server delays no
+// write; when the queue is full events are dropped. Publishing is fire and
+// forget, so failures and drops reach OnError rather than the caller. Close
+// waits for the queue to drain.
+func WithNATS(conn *NATSConn, opts NATSOptions) Option {
+	if opts.SubjectPrefix == "" {
+		opts.SubjectPrefix = "menousdb."
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+	return func(m *MenousDB) {
+		m.nats = &natsBridge{conn: conn, opts: opts, queue: make(chan natsItem, opts.QueueSize)}
+		m.RegisterFlusher(m.nats)
+	}
+}
+
+// publish queues e for its table's subject, dropping it if the queue is
+// full
+func (b *natsBridge) publish(e Event) {
+	b.once.Do(func() { go b.run() })
+	select {
+	case b.queue <- natsItem{event: e}:
+	default:
+		if b.opts.OnError != nil {
+			b.opts.OnError(ErrNATSQueueFull)
+		}
+	}
+}
+
+// Flush waits until the events queued so far have been published
+func (b *natsBridge) Flush(ctx context.Context) error {
+	b.once.Do(func() { go b.run() })
+	done := make(chan struct{})
+	select {
+	case b.queue <- natsItem{done: done}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run publishes queued events for the life of the process
+func (b *natsBridge) run() {
+	for item := range b.queue {
+		if item.done != nil {
+			close(item.done)
+			continue
+		}
+		b.send(item.event)
+	}
+}
+
+// send publishes e to its table's subject
+func (b *natsBridge) send(e Event) {
+	database, table := e.Scope()
+	data, err := marshalEvent(e)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), b.opts.Timeout)
+		err = b.conn.Publish(ctx, b.opts.SubjectPrefix+database+"."+table, data)
+		cancel()
+	}
+	if err != nil && b.opts.OnError != nil {
+		b.opts.OnError(err)
+	}
+}
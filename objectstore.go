@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CompletedPart identifies an uploaded part of a multipart upload
+type CompletedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// MultipartUploader is the subset of an object store's multipart API needed
+// to stream snapshots. S3Uploader implements it for S3 and S3-compatible
+// stores; other stores need an adapter, such as one mapping it to Azure
+// Blob's Put Block/Put Block List. The data passed to UploadPart is not
+// modified afterwards, so uploaders may keep it, for instance to upload in
+// the background.
+type MultipartUploader interface {
+	CreateMultipartUpload(ctx context.Context, key string) (uploadID string, err error)
+	UploadPart(ctx context.Context, key, uploadID string, partNumber int, data []byte) (etag string, err error)
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []CompletedPart) error
+	AbortMultipartUpload(ctx context.Context, key, uploadID string) error
+}
+
+// ObjectPutter uploads a whole object in one request. ObjectWriter uses it,
+// when the uploader implements it, for objects smaller than one part.
+type ObjectPutter interface {
+	PutObject(ctx context.Context, key string, data []byte) error
+}
+
+// DefaultPartSize is the multipart chunk size, above S3's 5 MiB minimum
+const DefaultPartSize = 8 << 20
+
+// ObjectWriter is an io.WriteCloser that streams to object storage, uploading
+// a part each time PartSize bytes are buffered. Close completes the upload;
+// if any write fails the upload is aborted.
+type ObjectWriter struct {
+	ctx      context.Context
+	uploader MultipartUploader
+	key      string
+	partSize int
+
+	buf      bytes.Buffer
+	uploadID string
+	parts    []CompletedPart
+	err      error
+	closed   bool
+}
+
+// NewObjectWriter starts a streaming upload of key. A partSize of zero uses
+// DefaultPartSize.
+func NewObjectWriter(ctx context.Context, uploader MultipartUploader, key string, partSize int) *ObjectWriter {
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	return &ObjectWriter{ctx: ctx, uploader: uploader, key: key, partSize: partSize}
+}
+
+// Write buffers p, uploading full parts as they fill
+func (w *ObjectWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.closed {
+		return 0, errors.New("menousdb: write to closed ObjectWriter")
+	}
+	n, _ := w.buf.Write(p)
+	for w.buf.Len() >= w.partSize {
+		// Next aliases the buffer, which later writes reuse
+		part := make([]byte, w.partSize)
+		copy(part, w.buf.Next(w.partSize))
+		if err := w.uploadPart(part); err != nil {
+			return n, w.fail(err)
+		}
+	}
+	return n, nil
+}
+
+// uploadPart sends one part, starting the multipart upload on first use
+func (w *ObjectWriter) uploadPart(data []byte) error {
+	if w.uploadID == "" {
+		id, err := w.uploader.CreateMultipartUpload(w.ctx, w.key)
+		if err != nil {
+			return fmt.Errorf("starting upload of %s: %w", w.key, err)
+		}
+		w.uploadID = id
+	}
+	number := len(w.parts) + 1
+	etag, err := w.uploader.UploadPart(w.ctx, w.key, w.uploadID, number, data)
+	if err != nil {
+		return fmt.Errorf("uploading part %d of %s: %w", number, w.key, err)
+	}
+	w.parts = append(w.parts, CompletedPart{PartNumber: number, ETag: etag})
+	return nil
+}
+
+// fail records err and aborts any multipart upload in progress
+func (w *ObjectWriter) fail(err error) error {
+	w.err = err
+	if w.uploadID != "" {
+		w.uploader.AbortMultipartUpload(w.ctx, w.key, w.uploadID)
+	}
+	return err
+}
+
+// Close uploads any buffered data and completes the object
+func (w *ObjectWriter) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+
+	// Small objects skip the multipart round trips when possible
+	if putter, ok := w.uploader.(ObjectPutter); ok && w.uploadID == "" {
+		if err := putter.PutObject(w.ctx, w.key, w.buf.Bytes()); err != nil {
+			w.err = fmt.Errorf("uploading %s: %w", w.key, err)
+		}
+		return w.err
+	}
+
+	if w.buf.Len() > 0 || w.uploadID == "" {
+		if err := w.uploadPart(w.buf.Bytes()); err != nil {
+			return w.fail(err)
+		}
+	}
+	if err := w.uploader.CompleteMultipartUpload(w.ctx, w.key, w.uploadID, w.parts); err != nil {
+		return w.fail(fmt.Errorf("completing upload of %s: %w", w.key, err))
+	}
+	return nil
+}
+
+// BackupToObjectStore streams a database snapshot to key in object storage
+func (m *MenousDB) BackupToObjectStore(ctx context.Context, uploader MultipartUploader, key string) error {
+	w := NewObjectWriter(ctx, uploader, key, 0)
+	if err := m.Backup(w); err != nil {
+		w.fail(err)
+		return err
+	}
+	return w.Close()
+}
+
+// DumpTableToObjectStore streams a table export to key in object storage
+func (m *MenousDB) DumpTableToObjectStore(ctx context.Context, uploader MultipartUploader, key, table string, opts DumpOptions) (int, error) {
+	w := NewObjectWriter(ctx, uploader, key, 0)
+	n, err := m.DumpTable(w, table, opts)
+	if err != nil {
+		w.fail(err)
+		return n, err
+	}
+	return n, w.Close()
+}
+
+var _ io.WriteCloser = (*ObjectWriter)(nil)
@@ -0,0 +1,533 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIDoc is the subset of an OpenAPI 3.0 description the client
+// produces and reads: paths, operations, header parameters, JSON bodies and
+// component schemas. It decodes from YAML or JSON.
+type OpenAPIDoc struct {
+	OpenAPI    string                      `yaml:"openapi" json:"openapi"`
+	Info       OpenAPIInfo                 `yaml:"info" json:"info"`
+	Paths      map[string]*OpenAPIPathItem `yaml:"paths" json:"paths"`
+	Components OpenAPIComponents           `yaml:"components,omitempty" json:"components,omitempty"`
+}
+
+// OpenAPIInfo is a description's title and version
+type OpenAPIInfo struct {
+	Title   string `yaml:"title" json:"title"`
+	Version string `yaml:"version" json:"version"`
+}
+
+// OpenAPIComponents holds the reusable schemas
+type OpenAPIComponents struct {
+	Schemas map[string]*OpenAPISchema `yaml:"schemas,omitempty" json:"schemas,omitempty"`
+}
+
+// OpenAPIPathItem is the operations on one path
+type OpenAPIPathItem struct {
+	Get        *OpenAPIOperation  `yaml:"get,omitempty" json:"get,omitempty"`
+	Put        *OpenAPIOperation  `yaml:"put,omitempty" json:"put,omitempty"`
+	Post       *OpenAPIOperation  `yaml:"post,omitempty" json:"post,omitempty"`
+	Delete     *OpenAPIOperation  `yaml:"delete,omitempty" json:"delete,omitempty"`
+	Patch      *OpenAPIOperation  `yaml:"patch,omitempty" json:"patch,omitempty"`
+	Parameters []OpenAPIParameter `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+}
+
+// operation returns the operation for an HTTP method, or nil
+func (p *OpenAPIPathItem) operation(method string) *OpenAPIOperation {
+	switch method {
+	case "GET":
+		return p.Get
+	case "PUT":
+		return p.Put
+	case "POST":
+		return p.Post
+	case "DELETE":
+		return p.Delete
+	case "PATCH":
+		return p.Patch
+	}
+	return nil
+}
+
+// setOperation sets the operation for an HTTP method
+func (p *OpenAPIPathItem) setOperation(method string, op *OpenAPIOperation) {
+	switch method {
+	case "GET":
+		p.Get = op
+	case "PUT":
+		p.Put = op
+	case "POST":
+		p.Post = op
+	case "DELETE":
+		p.Delete = op
+	case "PATCH":
+		p.Patch = op
+	}
+}
+
+// OpenAPIOperation is one method on a path
+type OpenAPIOperation struct {
+	OperationID string                      `yaml:"operationId,omitempty" json:"operationId,omitempty"`
+	Summary     string                      `yaml:"summary,omitempty" json:"summary,omitempty"`
+	Parameters  []OpenAPIParameter          `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `yaml:"requestBody,omitempty" json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `yaml:"responses" json:"responses"`
+}
+
+// OpenAPIParameter is a path, query or header parameter
+type OpenAPIParameter struct {
+	Name        string         `yaml:"name" json:"name"`
+	In          string         `yaml:"in" json:"in"`
+	Description string         `yaml:"description,omitempty" json:"description,omitempty"`
+	Required    bool           `yaml:"required,omitempty" json:"required,omitempty"`
+	Schema      *OpenAPISchema `yaml:"schema,omitempty" json:"schema,omitempty"`
+}
+
+// OpenAPIRequestBody is an operation's body, by media type
+type OpenAPIRequestBody struct {
+	Required bool                         `yaml:"required,omitempty" json:"required,omitempty"`
+	Content  map[string]*OpenAPIMediaType `yaml:"content" json:"content"`
+}
+
+// OpenAPIResponse is one response, by media type
+type OpenAPIResponse struct {
+	Description string                       `yaml:"description" json:"description"`
+	Content     map[string]*OpenAPIMediaType `yaml:"content,omitempty" json:"content,omitempty"`
+}
+
+// OpenAPIMediaType holds the schema of one media type
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `yaml:"schema,omitempty" json:"schema,omitempty"`
+}
+
+// OpenAPISchema is a JSON schema as OpenAPI 3.0 uses them. An empty schema
+// allows any value. AdditionalProperties is a bool or a schema.
+type OpenAPISchema struct {
+	Ref                  string                    `yaml:"$ref,omitempty" json:"$ref,omitempty"`
+	Type                 string                    `yaml:"type,omitempty" json:"type,omitempty"`
+	Format               string                    `yaml:"format,omitempty" json:"format,omitempty"`
+	Description          string                    `yaml:"description,omitempty" json:"description,omitempty"`
+	Enum                 []string                  `yaml:"enum,omitempty" json:"enum,omitempty"`
+	Nullable             bool                      `yaml:"nullable,omitempty" json:"nullable,omitempty"`
+	Items                *OpenAPISchema            `yaml:"items,omitempty" json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `yaml:"properties,omitempty" json:"properties,omitempty"`
+	Required             []string                  `yaml:"required,omitempty" json:"required,omitempty"`
+	AdditionalProperties interface{}               `yaml:"additionalProperties,omitempty" json:"additionalProperties,omitempty"`
+}
+
+// additional returns the schema of properties not listed in Properties:
+// nil when they are not allowed, an empty schema when anything goes
+func (s *OpenAPISchema) additional() *OpenAPISchema {
+	switch v := s.AdditionalProperties.(type) {
+	case nil:
+		return &OpenAPISchema{}
+	case bool:
+		if v {
+			return &OpenAPISchema{}
+		}
+		return nil
+	case *OpenAPISchema:
+		return v
+	}
+	var out OpenAPISchema
+	data, err := yaml.Marshal(s.AdditionalProperties)
+	if err != nil || yaml.Unmarshal(data, &out) != nil {
+		return &OpenAPISchema{}
+	}
+	return &out
+}
+
+// resolve follows a local $ref, returning the schema itself otherwise
+func (d *OpenAPIDoc) resolve(s *OpenAPISchema) *OpenAPISchema {
+	for i := 0; s != nil && s.Ref != "" && i < 16; i++ {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// LoadOpenAPI reads an OpenAPI description from a YAML or JSON file, or
+// fetches it when path is an http or https URL
+func LoadOpenAPI(path string) (*OpenAPIDoc, error) {
+	data, err := readOpenAPI(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc OpenAPIDoc
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("%s is not an OpenAPI 3 description", path)
+	}
+	return &doc, nil
+}
+
+// readOpenAPI reads a file or fetches a URL
+func readOpenAPI(path string) ([]byte, error) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		return os.ReadFile(path)
+	}
+	resp, err := http.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", path, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// apiEndpoint describes one server endpoint as the client calls it
+type apiEndpoint struct {
+	name    string
+	summary string
+	// methods are the methods the client may use, the usual one first;
+	// reads also go out as POST or GET with query parameters, per QueryMode
+	methods []string
+	headers []string
+	// request names the body schema in apiSchemas, if there is a body
+	request string
+	// response is the JSON response schema, or nil for a text response
+	response *OpenAPISchema
+}
+
+// Response schemas shared by several endpoints
+var (
+	apiAnyResponse  = &OpenAPISchema{}
+	apiRowsResponse = &OpenAPISchema{Type: "array", Items: &OpenAPISchema{Ref: "#/components/schemas/Row"}}
+)
+
+// apiEndpoints lists the endpoints the client uses. ClientOpenAPI describes
+// them and OpenAPIDrift checks a server's description against them, so add
+// new endpoints here as the client starts calling them.
+var apiEndpoints = []apiEndpoint{
+	{name: "get-databases", summary: "List databases", methods: []string{"GET"}, headers: []string{"key"},
+		response: &OpenAPISchema{Type: "array", Items: &OpenAPISchema{Type: "string"}}},
+	{name: "read-db", summary: "Read a database's tables", methods: []string{"GET"}, headers: []string{"key", "database"},
+		response: &OpenAPISchema{Type: "object", AdditionalProperties: &OpenAPISchema{}}},
+	{name: "create-db", summary: "Create a database", methods: []string{"POST"}, headers: []string{"key", "database"}},
+	{name: "del-database", summary: "Delete a database", methods: []string{"DELETE"}, headers: []string{"key", "database"}},
+	{name: "check-db-exists", summary: "Check that a database exists", methods: []string{"GET"}, headers: []string{"key", "database"}},
+	{name: "create-table", summary: "Create a table", methods: []string{"POST"}, headers: []string{"key", "database", "table"},
+		request: "CreateTableRequest"},
+	{name: "check-table-exists", summary: "Check that a table exists", methods: []string{"GET"}, headers: []string{"key", "database", "table"}},
+	{name: "delete-table", summary: "Delete a table", methods: []string{"DELETE"}, headers: []string{"key", "database", "table"}},
+	{name: "get-table", summary: "Read a table's rows", methods: []string{"GET"}, headers: []string{"key", "database", "table"},
+		response: apiAnyResponse},
+	{name: "insert-into-table", summary: "Insert a row", methods: []string{"POST"}, headers: []string{"key", "database", "table"},
+		request: "InsertIntoTableRequest"},
+	{name: "select-where", summary: "Select rows matching conditions", methods: []string{"GET", "POST"}, headers: []string{"key", "database", "table"},
+		request: "SelectWhereRequest", response: apiRowsResponse},
+	{name: "select-columns", summary: "Select columns of every row", methods: []string{"GET", "POST"}, headers: []string{"key", "database", "table"},
+		request: "SelectColumnsRequest", response: apiRowsResponse},
+	{name: "select-columns-where", summary: "Select columns of rows matching conditions", methods: []string{"GET", "POST"}, headers: []string{"key", "database", "table"},
+		request: "SelectColumnsWhereRequest", response: apiRowsResponse},
+	{name: "update-table", summary: "Update rows matching conditions", methods: []string{"POST"}, headers: []string{"key", "database", "table"},
+		request: "UpdateTableRequest", response: apiAnyResponse},
+	{name: "delete-where", summary: "Delete rows matching conditions", methods: []string{"DELETE"}, headers: []string{"key", "database", "table"},
+		request: "DeleteWhereRequest", response: apiAnyResponse},
+	{name: "create-key", summary: "Create an API key", methods: []string{"POST"}, headers: []string{"key"},
+		request: "CreateKeyRequest"},
+	{name: "get-keys", summary: "List API keys", methods: []string{"GET"}, headers: []string{"key"},
+		response: apiAnyResponse},
+	{name: "revoke-key", summary: "Revoke an API key", methods: []string{"DELETE"}, headers: []string{"key"},
+		request: "RevokeKeyRequest"},
+}
+
+// apiHeaderDescriptions documents the headers endpoints take
+var apiHeaderDescriptions = map[string]string{
+	"key":      "API key",
+	"database": "Database name",
+	"table":    "Table name",
+}
+
+// apiSchemas are the component schemas of request bodies and rows
+func apiSchemas() map[string]*OpenAPISchema {
+	ref := func(name string) *OpenAPISchema {
+		return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+	}
+	names := &OpenAPISchema{Type: "array", Items: &OpenAPISchema{Type: "string"}}
+	object := func(description string, required []string, props map[string]*OpenAPISchema) *OpenAPISchema {
+		return &OpenAPISchema{Type: "object", Description: description, Required: required, Properties: props}
+	}
+	return map[string]*OpenAPISchema{
+		"Row": {Type: "object", Description: "A row, keyed by column name", AdditionalProperties: &OpenAPISchema{}},
+		"Conditions": {Type: "object", Description: "Column values a row must equal to match",
+			AdditionalProperties: &OpenAPISchema{}},
+		"ColumnDefinition": object("A typed column declaration", []string{"name"}, map[string]*OpenAPISchema{
+			"name":     {Type: "string"},
+			"type":     {Type: "string", Enum: []string{"string", "int", "float", "bool", "time", "json"}},
+			"nullable": {Type: "boolean"},
+			"required": {Type: "boolean"},
+			"default":  {},
+			"validate": {Type: "string"},
+		}),
+		"CreateTableRequest": object("The body of create-table", []string{"attributes"}, map[string]*OpenAPISchema{
+			"attributes": names,
+			"columns":    {Type: "array", Items: ref("ColumnDefinition")},
+		}),
+		"InsertIntoTableRequest": object("The body of insert-into-table", []string{"values"}, map[string]*OpenAPISchema{
+			"values": ref("Row"),
+		}),
+		"SelectWhereRequest": object("The body of select-where", []string{"conditions"}, map[string]*OpenAPISchema{
+			"conditions": ref("Conditions"),
+		}),
+		"SelectColumnsRequest": object("The body of select-columns", []string{"columns"}, map[string]*OpenAPISchema{
+			"columns": names,
+		}),
+		"SelectColumnsWhereRequest": object("The body of select-columns-where", []string{"columns", "conditions"}, map[string]*OpenAPISchema{
+			"columns":    names,
+			"conditions": ref("Conditions"),
+		}),
+		"UpdateTableRequest": object("The body of update-table", []string{"conditions", "values"}, map[string]*OpenAPISchema{
+			"conditions": ref("Conditions"),
+			"values":     ref("Row"),
+		}),
+		"DeleteWhereRequest": object("The body of delete-where", []string{"conditions"}, map[string]*OpenAPISchema{
+			"conditions": ref("Conditions"),
+		}),
+		"CreateKeyRequest": object("The body of create-key", []string{"database"}, map[string]*OpenAPISchema{
+			"database": {Type: "string"},
+		}),
+		"RevokeKeyRequest": object("The body of revoke-key", []string{"api_key"}, map[string]*OpenAPISchema{
+			"api_key": {Type: "string"},
+		}),
+	}
+}
+
+// ClientOpenAPI describes the endpoints the client calls, the headers and
+// bodies it sends and the responses it expects. Served or compared with a
+// server's own description, it shows what the client depends on.
+func ClientOpenAPI() *OpenAPIDoc {
+	doc := &OpenAPIDoc{
+		OpenAPI:    "3.0.3",
+		Info:       OpenAPIInfo{Title: "MenousDB", Version: "1.0.0"},
+		Paths:      make(map[string]*OpenAPIPathItem),
+		Components: OpenAPIComponents{Schemas: apiSchemas()},
+	}
+	for _, e := range apiEndpoints {
+		item := &OpenAPIPathItem{}
+		for _, method := range e.methods {
+			op := &OpenAPIOperation{
+				OperationID: apiOperationID(e.name, method, e.methods),
+				Summary:     e.summary,
+				Responses:   map[string]*OpenAPIResponse{"200": e.responseSpec()},
+			}
+			for _, h := range e.headers {
+				op.Parameters = append(op.Parameters, OpenAPIParameter{
+					Name: h, In: "header", Required: true,
+					Description: apiHeaderDescriptions[h], Schema: &OpenAPISchema{Type: "string"},
+				})
+			}
+			if e.request != "" {
+				op.RequestBody = &OpenAPIRequestBody{Required: true, Content: map[string]*OpenAPIMediaType{
+					"application/json": {Schema: &OpenAPISchema{Ref: "#/components/schemas/" + e.request}},
+				}}
+			}
+			item.setOperation(method, op)
+		}
+		doc.Paths["/"+e.name] = item
+	}
+	return doc
+}
+
+// responseSpec returns the endpoint's success response
+func (e apiEndpoint) responseSpec() *OpenAPIResponse {
+	if e.response == nil {
+		return &OpenAPIResponse{Description: "A status message", Content: map[string]*OpenAPIMediaType{
+			"text/plain": {Schema: &OpenAPISchema{Type: "string"}},
+		}}
+	}
+	return &OpenAPIResponse{Description: "OK", Content: map[string]*OpenAPIMediaType{
+		"application/json": {Schema: e.response},
+	}}
+}
+
+// apiOperationID names an operation after its endpoint in camelCase,
+// suffixing the method for endpoints with several
+func apiOperationID(endpoint, method string, methods []string) string {
+	id := graphQLTypeName(strings.ReplaceAll(endpoint, "-", "_"))
+	id = strings.ToLower(id[:1]) + id[1:]
+	if len(methods) > 1 && method != methods[0] {
+		id += strings.ToUpper(method[:1]) + strings.ToLower(method[1:])
+	}
+	return id
+}
+
+// APIDrift is one difference between a server's OpenAPI description and
+// what the client expects. Breaking drift makes some client call fail;
+// the rest, such as endpoints the client does not use, is informational.
+type APIDrift struct {
+	Endpoint string
+	Problem  string
+	Breaking bool
+}
+
+// String describes the drift for reports
+func (d APIDrift) String() string {
+	kind := "note"
+	if d.Breaking {
+		kind = "breaking"
+	}
+	return fmt.Sprintf("%s: %s: %s", kind, d.Endpoint, d.Problem)
+}
+
+// OpenAPIDrift compares a server's description with the endpoints the
+// client calls: endpoints or methods it lacks, headers and body properties
+// it requires that the client does not send, properties the client sends
+// that it does not accept, and mismatched property and response types.
+// Paths may carry a version prefix such as /v1/. The result is sorted by
+// endpoint, breaking drift first.
+func OpenAPIDrift(server *OpenAPIDoc) []APIDrift {
+	paths := make(map[string]*OpenAPIPathItem)
+	for path, item := range server.Paths {
+		name := strings.Trim(path, "/")
+		if i := strings.IndexByte(name, '/'); i >= 0 && strings.HasPrefix(name, "v") {
+			name = name[i+1:]
+		}
+		paths[name] = item
+	}
+
+	client := ClientOpenAPI()
+	var drift []APIDrift
+	used := make(map[string]bool)
+	for _, e := range apiEndpoints {
+		used[e.name] = true
+		add := func(breaking bool, format string, args ...interface{}) {
+			drift = append(drift, APIDrift{Endpoint: e.name, Problem: fmt.Sprintf(format, args...), Breaking: breaking})
+		}
+		item := paths[e.name]
+		if item == nil {
+			add(true, "not described")
+			continue
+		}
+		var op *OpenAPIOperation
+		for _, method := range e.methods {
+			if op = item.operation(method); op != nil {
+				if method != e.methods[0] {
+					add(false, "no %s operation; only clients using %s work", e.methods[0], method)
+				}
+				break
+			}
+		}
+		if op == nil {
+			add(true, "no %s operation", strings.Join(e.methods, " or "))
+			continue
+		}
+
+		sent := make(map[string]bool)
+		for _, h := range e.headers {
+			sent[h] = true
+		}
+		for _, p := range append(append([]OpenAPIParameter(nil), item.Parameters...), op.Parameters...) {
+			switch {
+			case p.In == "header" && p.Required && !sent[p.Name]:
+				add(true, "requires header %s, which the client does not send", p.Name)
+			case p.In == "query" && p.Required:
+				add(true, "requires query parameter %s, which the client does not send", p.Name)
+			}
+		}
+
+		clientOp := client.Paths["/"+e.name].operation(e.methods[0])
+		drift = append(drift, bodyDrift(e.name, client, clientOp, server, op)...)
+		drift = append(drift, responseDrift(e, server, op)...)
+	}
+	for name := range paths {
+		if !used[name] {
+			drift = append(drift, APIDrift{Endpoint: name, Problem: "not used by the client"})
+		}
+	}
+	sort.SliceStable(drift, func(i, j int) bool {
+		if drift[i].Breaking != drift[j].Breaking {
+			return drift[i].Breaking
+		}
+		return drift[i].Endpoint < drift[j].Endpoint
+	})
+	return drift
+}
+
+// bodyDrift compares the body the client sends with the one the server
+// describes
+func bodyDrift(endpoint string, client *OpenAPIDoc, clientOp *OpenAPIOperation, server *OpenAPIDoc, op *OpenAPIOperation) []APIDrift {
+	var drift []APIDrift
+	add := func(breaking bool, format string, args ...interface{}) {
+		drift = append(drift, APIDrift{Endpoint: endpoint, Problem: fmt.Sprintf(format, args...), Breaking: breaking})
+	}
+	var want *OpenAPISchema
+	if op.RequestBody != nil {
+		if mt := op.RequestBody.Content["application/json"]; mt != nil {
+			want = server.resolve(mt.Schema)
+		}
+	}
+	if clientOp.RequestBody == nil {
+		if op.RequestBody != nil && op.RequestBody.Required {
+			add(true, "requires a request body, which the client does not send")
+		}
+		return drift
+	}
+	have := client.resolve(clientOp.RequestBody.Content["application/json"].Schema)
+	if want == nil {
+		if op.RequestBody != nil {
+			add(true, "does not accept a JSON body")
+		} else {
+			add(false, "describes no request body; the client sends one")
+		}
+		return drift
+	}
+
+	for _, name := range want.Required {
+		if _, ok := have.Properties[name]; !ok {
+			add(true, "requires body property %s, which the client does not send", name)
+		}
+	}
+	for name, prop := range have.Properties {
+		theirs, ok := want.Properties[name]
+		if !ok {
+			switch {
+			case want.additional() == nil:
+				add(true, "does not accept body property %s", name)
+			case want.AdditionalProperties == nil:
+				add(false, "does not describe body property %s, which the client sends", name)
+			}
+			continue
+		}
+		if a, b := client.resolve(prop).Type, server.resolve(theirs).Type; a != "" && b != "" && a != b {
+			add(true, "body property %s is %s, the client sends %s", name, b, a)
+		}
+	}
+	return drift
+}
+
+// responseDrift compares the response the client decodes with the one the
+// server describes
+func responseDrift(e apiEndpoint, server *OpenAPIDoc, op *OpenAPIOperation) []APIDrift {
+	if e.response == nil || e.response.Type == "" {
+		// Text and untyped responses are read as whatever arrives
+		return nil
+	}
+	for code, resp := range op.Responses {
+		if !strings.HasPrefix(code, "2") || resp == nil {
+			continue
+		}
+		mt := resp.Content["application/json"]
+		if mt == nil {
+			continue
+		}
+		if got := server.resolve(mt.Schema); got != nil && got.Type != "" && got.Type != e.response.Type {
+			return []APIDrift{{Endpoint: e.name, Breaking: true,
+				Problem: fmt.Sprintf("responds with %s, the client expects %s", got.Type, e.response.Type)}}
+		}
+	}
+	return nil
+}
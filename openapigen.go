@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+//go:generate go run . openapi gen -spec openapi/menousdb.yaml -o apitypes_gen.go
+
+// GenerateBindings writes Go source declaring a struct for every object
+// schema with properties in doc's components, so request and response types
+// can be regenerated when the server's description changes. Other schemas,
+// such as maps and arrays, are inlined where they are used.
+func GenerateBindings(doc *OpenAPIDoc, pkg string) ([]byte, error) {
+	structs := make(map[string]bool)
+	for name, s := range doc.Components.Schemas {
+		if s != nil && s.Ref == "" && len(s.Properties) > 0 {
+			if !isGoIdentifier(name) {
+				return nil, fmt.Errorf("schema name %q is not a Go identifier", name)
+			}
+			structs[name] = true
+		}
+	}
+	names := make([]string, 0, len(structs))
+	for name := range structs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by \"menousdb openapi gen\" from %s %s; DO NOT EDIT.\n\n", doc.Info.Title, doc.Info.Version)
+	fmt.Fprintf(&b, "package %s\n", pkg)
+	for _, name := range names {
+		s := doc.Components.Schemas[name]
+		b.WriteByte('\n')
+		if s.Description != "" {
+			fmt.Fprintf(&b, "// %s is %s\n", name, lowerFirst(strings.TrimSuffix(s.Description, ".")))
+		} else {
+			fmt.Fprintf(&b, "// %s is the %s schema\n", name, name)
+		}
+		fmt.Fprintf(&b, "type %s struct {\n", name)
+		required := make(map[string]bool, len(s.Required))
+		for _, r := range s.Required {
+			required[r] = true
+		}
+		props := make([]string, 0, len(s.Properties))
+		for p := range s.Properties {
+			props = append(props, p)
+		}
+		sort.Strings(props)
+		for _, p := range props {
+			typ, err := goTypeOf(doc, s.Properties[p], structs, 0)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", name, p, err)
+			}
+			tag := p
+			if !required[p] {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&b, "\t%s %s `json:%q`\n", goFieldName(p), typ, tag)
+		}
+		b.WriteString("}\n")
+	}
+	return format.Source(b.Bytes())
+}
+
+// goTypeOf returns the Go type for a schema
+func goTypeOf(doc *OpenAPIDoc, s *OpenAPISchema, structs map[string]bool, depth int) (string, error) {
+	if s == nil {
+		return "interface{}", nil
+	}
+	if depth > 16 {
+		return "", fmt.Errorf("schema nests too deeply")
+	}
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		if structs[name] {
+			return name, nil
+		}
+		target, ok := doc.Components.Schemas[name]
+		if !ok || name == s.Ref {
+			return "", fmt.Errorf("unresolved reference %s", s.Ref)
+		}
+		return goTypeOf(doc, target, structs, depth+1)
+	}
+
+	var typ string
+	switch s.Type {
+	case "string":
+		typ = "string"
+	case "integer":
+		typ = "int64"
+	case "number":
+		typ = "float64"
+	case "boolean":
+		typ = "bool"
+	case "array":
+		elem, err := goTypeOf(doc, s.Items, structs, depth+1)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	case "object":
+		elem := "interface{}"
+		if extra := s.additional(); extra != nil && len(s.Properties) == 0 {
+			var err error
+			if elem, err = goTypeOf(doc, extra, structs, depth+1); err != nil {
+				return "", err
+			}
+		}
+		return "map[string]" + elem, nil
+	default:
+		return "interface{}", nil
+	}
+	if s.Nullable {
+		typ = "*" + typ
+	}
+	return typ, nil
+}
+
+// goInitialisms are kept upper-case in field names, as golint expects
+var goInitialisms = map[string]bool{
+	"api": true, "id": true, "url": true, "uri": true, "http": true, "json": true,
+	"sql": true, "ttl": true, "uuid": true, "ip": true, "db": true,
+}
+
+// goFieldName turns a property name such as api_key into APIKey
+func goFieldName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		if goInitialisms[strings.ToLower(part)] {
+			b.WriteString(strings.ToUpper(part))
+		} else {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	out := b.String()
+	if out == "" || out[0] >= '0' && out[0] <= '9' {
+		out = "X" + out
+	}
+	return out
+}
+
+// isGoIdentifier reports whether s can name an exported Go type
+func isGoIdentifier(s string) bool {
+	if s == "" || s[0] < 'A' || s[0] > 'Z' {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// lowerFirst lower-cases a sentence's first letter so it reads after "is"
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
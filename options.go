@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures a MenousDB client
+type Option func(*MenousDB)
+
+// WithHTTPClient sets the HTTP client used to execute requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(m *MenousDB) {
+		m.client = client
+	}
+}
+
+// WithKey overrides the API key
+func WithKey(key string) Option {
+	return func(m *MenousDB) {
+		m.Key = key
+	}
+}
+
+// WithDatabase overrides the target database
+func WithDatabase(database string) Option {
+	return func(m *MenousDB) {
+		m.Database = database
+	}
+}
+
+// WithTimeout sets the per-request timeout. The HTTP transport, and with it
+// the connection pool, stays shared with any client this one was cloned from.
+func WithTimeout(d time.Duration) Option {
+	return func(m *MenousDB) {
+		m.timeout = d
+	}
+}
+
+// applyTimeout gives the client its own http.Client when its timeout differs
+// from the one it holds, keeping the transport
+func (m *MenousDB) applyTimeout() {
+	client := m.httpClient()
+	if m.timeout == 0 || client.Timeout == m.timeout {
+		return
+	}
+	c := *client
+	c.Timeout = m.timeout
+	m.client = &c
+}
+
+// Clone returns an independent copy of the client with opts applied on top
+// of its current settings. The copy shares the underlying transport, so
+// per-request variations (a different key, timeout or database) reuse the
+// same connections.
+//
+// The copy also shares, unless opts replace them, the read cache (whose
+// entries are scoped by credentials), index and schema registries, rate
+// limiter, event bus and endpoint set with its refresher. It has its own
+// lifecycle: closing the copy stops only it and its own clones, while
+// closing m stops the copy too. WithEndpoints or WithEndpointRefresh give
+// the copy its own endpoint set, and a client set with WithHTTPClient on a
+// unix socket client keeps routing through the socket.
+func (m *MenousDB) Clone(opts ...Option) *MenousDB {
+	c := *m
+	c.features = m.features.clone()
+	c.life = &lifecycle{parent: m.life}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.socketPath == "" && (len(c.replicaURLs) != len(m.replicaURLs) || c.refresher != m.refresher) {
+		c.endpoints = newEndpointSet(c.URL, c.replicaURLs)
+	}
+	if c.socketPath != "" && c.client != m.client && c.client != nil && c.client.Transport == nil {
+		client := *c.client
+		client.Transport = unixTransport(c.socketPath)
+		c.client = &client
+	}
+	c.applyTimeout()
+	return &c
+}
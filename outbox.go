@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// OutboxAttributes are the attributes of an outbox table. An event's state
+// is pending while the writes it describes run, ready once they succeeded,
+// and published once a relay delivered it.
+var OutboxAttributes = []string{
+	"id", "batch", "topic", "key", "payload", "state", "created", "published",
+}
+
+// Outbox event states
+const (
+	OutboxPending   = "pending"
+	OutboxReady     = "ready"
+	OutboxPublished = "published"
+)
+
+// OutboxEvent is a domain event recorded in an outbox
+type OutboxEvent struct {
+	// ID identifies the event; Emit generates one when it is empty.
+	// Consumers should deduplicate on it, as delivery is at least once.
+	ID      string
+	Topic   string
+	Key     string
+	Payload json.RawMessage
+
+	// Created is set by Emit when zero
+	Created time.Time
+}
+
+// NewOutboxEvent builds an event with v encoded as its payload
+func NewOutboxEvent(topic, key string, v interface{}) (OutboxEvent, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return OutboxEvent{}, err
+	}
+	return OutboxEvent{Topic: topic, Key: key, Payload: payload}, nil
+}
+
+// Outbox records events in a table alongside data writes, for an
+// OutboxRelay to publish
+type Outbox struct {
+	client *MenousDB
+	table  string
+}
+
+// Outbox returns the outbox kept in table
+func (m *MenousDB) Outbox(table string) *Outbox {
+	return &Outbox{client: m, table: table}
+}
+
+// Ensure creates the outbox table if it does not exist
+func (o *Outbox) Ensure() (bool, error) {
+	return o.client.EnsureTable(TableSpec{Name: o.table, Attributes: OutboxAttributes})
+}
+
+// Emit records events and runs write, the data writes they describe.
+// MenousDB has no transactions, so the events are written first as pending
+// and made ready with one update once write succeeds; if write fails they
+// are deleted. An event is therefore never published for a failed write.
+// If the update fails, or the process stops before it, the events stay
+// pending, listed by Pending for an operator to Release or Discard.
+func (o *Outbox) Emit(write func() error, events ...OutboxEvent) error {
+	batch := newOutboxID()
+	now := time.Now().UTC()
+	for i := range events {
+		e := &events[i]
+		if e.ID == "" {
+			e.ID = newOutboxID()
+		}
+		if e.Created.IsZero() {
+			e.Created = now
+		}
+		row := Row{
+			"id":        e.ID,
+			"batch":     batch,
+			"topic":     e.Topic,
+			"key":       e.Key,
+			"payload":   string(e.Payload),
+			"state":     OutboxPending,
+			"created":   e.Created.Format(time.RFC3339Nano),
+			"published": "",
+		}
+		if _, err := o.client.InsertIntoTable(o.table, row); err != nil {
+			o.discard(batch)
+			return fmt.Errorf("recording event %d of %d: %w", i+1, len(events), err)
+		}
+	}
+
+	if write != nil {
+		if err := write(); err != nil {
+			if derr := o.discard(batch); derr != nil {
+				return fmt.Errorf("%w (discarding events: %v)", err, derr)
+			}
+			return err
+		}
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	_, err := o.client.UpdateWhere(o.table, map[string]interface{}{"batch": batch}, map[string]interface{}{"state": OutboxReady})
+	return err
+}
+
+// Pending returns events whose writes may or may not have completed,
+// oldest first
+func (o *Outbox) Pending() ([]OutboxEvent, error) {
+	return o.events(OutboxPending)
+}
+
+// Release makes a pending event ready for publishing
+func (o *Outbox) Release(id string) error {
+	_, err := o.client.UpdateWhere(o.table, map[string]interface{}{"id": id, "state": OutboxPending}, map[string]interface{}{"state": OutboxReady})
+	return err
+}
+
+// Discard deletes an event without publishing it
+func (o *Outbox) Discard(id string) error {
+	_, err := o.client.DeleteWhere(o.table, map[string]interface{}{"id": id})
+	return err
+}
+
+// PurgePublished deletes every published event
+func (o *Outbox) PurgePublished() error {
+	_, err := o.client.DeleteWhere(o.table, map[string]interface{}{"state": OutboxPublished})
+	return err
+}
+
+// discard deletes a batch's events
+func (o *Outbox) discard(batch string) error {
+	_, err := o.client.DeleteWhere(o.table, map[string]interface{}{"batch": batch})
+	return err
+}
+
+// events returns the events in state, oldest first, reading past any cache
+// so a relay never sees events it already published
+func (o *Outbox) events(state string) ([]OutboxEvent, error) {
+	rows, err := o.client.uncached().SelectWhereRows(o.table, map[string]interface{}{"state": state})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]OutboxEvent, 0, len(rows))
+	for _, row := range rows {
+		e := OutboxEvent{
+			ID:      fmt.Sprint(row["id"]),
+			Topic:   fmt.Sprint(row["topic"]),
+			Key:     fmt.Sprint(row["key"]),
+			Payload: json.RawMessage(fmt.Sprint(row["payload"])),
+		}
+		if t, err := toTime(row["created"]); err == nil {
+			e.Created = t
+		}
+		events = append(events, e)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Created.Before(events[j].Created)
+	})
+	return events, nil
+}
+
+// OutboxPublisher delivers an event to a broker or other consumer
+type OutboxPublisher func(ctx context.Context, e OutboxEvent) error
+
+// OutboxRelay publishes ready events from an outbox in creation order and
+// marks them published. An event published but not yet marked when the
+// relay stops is published again by the next run.
+type OutboxRelay struct {
+	// Interval is the time between polls of the outbox; defaults to one
+	// second
+	Interval time.Duration
+
+	// BatchSize bounds the events published per poll; defaults to 100
+	BatchSize int
+
+	// OnError is called when a poll fails; the failed event and those after
+	// it are retried on the next poll
+	OnError func(error)
+
+	outbox  *Outbox
+	publish OutboxPublisher
+}
+
+// NewOutboxRelay creates a relay delivering o's events through publish
+func NewOutboxRelay(o *Outbox, publish OutboxPublisher) *OutboxRelay {
+	return &OutboxRelay{outbox: o, publish: publish}
+}
+
+// Run polls and publishes until ctx ends
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RelayOnce(ctx); err != nil && ctx.Err() == nil && r.OnError != nil {
+			r.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce publishes up to BatchSize ready events, stopping at the first
+// failure so later events are not delivered ahead of it. It returns the
+// number published.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.outbox.events(OutboxReady)
+	if err != nil {
+		return 0, err
+	}
+	size := r.BatchSize
+	if size <= 0 {
+		size = 100
+	}
+	if len(events) > size {
+		events = events[:size]
+	}
+
+	for i, e := range events {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+		if err := r.publish(ctx, e); err != nil {
+			return i, fmt.Errorf("publishing event %s: %w", e.ID, err)
+		}
+		values := map[string]interface{}{
+			"state":     OutboxPublished,
+			"published": time.Now().UTC().Format(time.RFC3339Nano),
+		}
+		if _, err := r.outbox.client.UpdateWhere(r.outbox.table, map[string]interface{}{"id": e.ID}, values); err != nil {
+			return i, fmt.Errorf("marking event %s published: %w", e.ID, err)
+		}
+	}
+	return len(events), nil
+}
+
+// newOutboxID returns a random event or batch id
+func newOutboxID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
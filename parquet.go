@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Parquet physical types used by the writer
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+)
+
+// Parquet encodings, repetition and converted type constants
+const (
+	parquetPlain        = 0
+	parquetRLE          = 3
+	parquetOptional     = 1
+	parquetUTF8         = 0
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+)
+
+// parquetColumn is one inferred column
+type parquetColumn struct {
+	name     string
+	physical int32
+}
+
+// ExportParquet writes table to w as a Parquet file and returns the number of
+// rows written
+func (m *MenousDB) ExportParquet(w io.Writer, table string) (int, error) {
+	rows, err := m.GetTableRows(table)
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), WriteParquet(w, rows)
+}
+
+// WriteParquet writes rows to w as an uncompressed Parquet file with one row
+// group. Column types are inferred from the values: booleans become BOOLEAN,
+// integral numbers INT64, other numbers DOUBLE and everything else a UTF-8
+// BYTE_ARRAY (objects and arrays as JSON). Columns holding mixed kinds fall
+// back to strings. Every column is OPTIONAL so nulls and missing attributes
+// survive.
+func WriteParquet(w io.Writer, rows []Row) error {
+	names := inferColumns(rows)
+	columns := make([]parquetColumn, len(names))
+	for i, name := range names {
+		columns[i] = parquetColumn{name: name, physical: inferParquetType(rows, name)}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("PAR1")
+
+	chunks := make([][]byte, len(columns))
+	var totalSize int64
+	for i, col := range columns {
+		offset := int64(out.Len())
+		page, err := encodeParquetPage(rows, col)
+		if err != nil {
+			return fmt.Errorf("column %q: %w", col.name, err)
+		}
+		out.Write(page)
+		totalSize += int64(len(page))
+		chunks[i] = encodeColumnChunk(col, int64(len(rows)), offset, int64(len(page)))
+	}
+
+	meta := encodeFileMetaData(columns, chunks, int64(len(rows)), totalSize)
+	out.Write(meta)
+	binary.Write(&out, binary.LittleEndian, uint32(len(meta)))
+	out.WriteString("PAR1")
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// inferParquetType picks the physical type for column
+func inferParquetType(rows []Row, column string) int32 {
+	var t int32 = -1
+	for _, row := range rows {
+		var vt int32
+		switch v := row[column].(type) {
+		case nil:
+			continue
+		case bool:
+			vt = parquetBoolean
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+				vt = parquetInt64
+			} else {
+				vt = parquetDouble
+			}
+		default:
+			vt = parquetByteArray
+		}
+		switch {
+		case t == -1:
+			t = vt
+		case t == vt:
+		case (t == parquetInt64 && vt == parquetDouble) || (t == parquetDouble && vt == parquetInt64):
+			t = parquetDouble
+		default:
+			return parquetByteArray
+		}
+	}
+	if t == -1 {
+		return parquetByteArray
+	}
+	return t
+}
+
+// encodeParquetPage builds a v1 data page (header and body) for one column
+func encodeParquetPage(rows []Row, col parquetColumn) ([]byte, error) {
+	var values bytes.Buffer
+	defined := make([]bool, len(rows))
+	var bits []bool
+	for i, row := range rows {
+		v := row[col.name]
+		if v == nil {
+			continue
+		}
+		defined[i] = true
+		switch col.physical {
+		case parquetBoolean:
+			bits = append(bits, v.(bool))
+		case parquetInt64:
+			binary.Write(&values, binary.LittleEndian, int64(v.(float64)))
+		case parquetDouble:
+			binary.Write(&values, binary.LittleEndian, v.(float64))
+		case parquetByteArray:
+			s, err := parquetString(v)
+			if err != nil {
+				return nil, err
+			}
+			binary.Write(&values, binary.LittleEndian, uint32(len(s)))
+			values.WriteString(s)
+		}
+	}
+	if col.physical == parquetBoolean {
+		values.Write(packBits(bits))
+	}
+
+	// Definition levels: RLE/bit-packed hybrid with bit width 1, length prefixed
+	levels := encodeBitPackedRun(defined)
+	var body bytes.Buffer
+	binary.Write(&body, binary.LittleEndian, uint32(len(levels)))
+	body.Write(levels)
+	body.Write(values.Bytes())
+
+	var dataHeader thriftWriter
+	dataHeader.i32Field(1, int32(len(rows)))
+	dataHeader.i32Field(2, parquetPlain)
+	dataHeader.i32Field(3, parquetRLE)
+	dataHeader.i32Field(4, parquetRLE)
+	dataHeader.stop()
+
+	var header thriftWriter
+	header.i32Field(1, parquetDataPage)
+	header.i32Field(2, int32(body.Len()))
+	header.i32Field(3, int32(body.Len()))
+	header.structField(5, dataHeader.bytes())
+	header.stop()
+
+	return append(header.bytes(), body.Bytes()...), nil
+}
+
+// parquetString renders a value as a UTF-8 column value
+func parquetString(v interface{}) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	}
+	raw, err := json.Marshal(v)
+	return string(raw), err
+}
+
+// packBits packs booleans LSB first, as PLAIN BOOLEAN and bit-packed runs do
+func packBits(bits []bool) []byte {
+	out := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		if b {
+			out[i/8] |= 1 << (i % 8)
+		}
+	}
+	return out
+}
+
+// encodeBitPackedRun encodes bit-width-1 levels as one bit-packed run
+func encodeBitPackedRun(levels []bool) []byte {
+	if len(levels) == 0 {
+		return nil
+	}
+	groups := (len(levels) + 7) / 8
+	var out []byte
+	out = binary.AppendUvarint(out, uint64(groups<<1|1))
+	return append(out, packBits(levels)...)
+}
+
+// encodeColumnChunk builds the ColumnChunk metadata for one column
+func encodeColumnChunk(col parquetColumn, numRows, offset, size int64) []byte {
+	var meta thriftWriter
+	meta.i32Field(1, col.physical)
+	meta.i32ListField(2, []int32{parquetPlain, parquetRLE})
+	meta.stringListField(3, []string{col.name})
+	meta.i32Field(4, parquetUncompressed)
+	meta.i64Field(5, numRows)
+	meta.i64Field(6, size)
+	meta.i64Field(7, size)
+	meta.i64Field(9, offset)
+	meta.stop()
+
+	var chunk thriftWriter
+	chunk.i64Field(2, offset)
+	chunk.structField(3, meta.bytes())
+	chunk.stop()
+	return chunk.bytes()
+}
+
+// encodeFileMetaData builds the footer for a single row group file
+func encodeFileMetaData(columns []parquetColumn, chunks [][]byte, numRows, totalSize int64) []byte {
+	schema := make([][]byte, 0, len(columns)+1)
+
+	var root thriftWriter
+	root.stringField(4, "schema")
+	root.i32Field(5, int32(len(columns)))
+	root.stop()
+	schema = append(schema, root.bytes())
+
+	for _, col := range columns {
+		var el thriftWriter
+		el.i32Field(1, col.physical)
+		el.i32Field(3, parquetOptional)
+		el.stringField(4, col.name)
+		if col.physical == parquetByteArray {
+			el.i32Field(6, parquetUTF8)
+		}
+		el.stop()
+		schema = append(schema, el.bytes())
+	}
+
+	var group thriftWriter
+	group.structListField(1, chunks)
+	group.i64Field(2, totalSize)
+	group.i64Field(3, numRows)
+	group.stop()
+
+	var meta thriftWriter
+	meta.i32Field(1, 1)
+	meta.structListField(2, schema)
+	meta.i64Field(3, numRows)
+	meta.structListField(4, [][]byte{group.bytes()})
+	meta.stringField(6, "menousdb-go")
+	meta.stop()
+	return meta.bytes()
+}
+
+// Thrift compact protocol type ids
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes one struct with the Thrift compact protocol, which
+// Parquet uses for its page headers and footer
+type thriftWriter struct {
+	buf    []byte
+	lastID int16
+}
+
+// fieldHeader writes a field header using the short delta form when possible
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	delta := id - t.lastID
+	if delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.buf = binary.AppendVarint(t.buf, int64(id))
+	}
+	t.lastID = id
+}
+
+// i32Field writes an i32 field
+func (t *thriftWriter) i32Field(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+// i64Field writes an i64 field
+func (t *thriftWriter) i64Field(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+// stringField writes a binary field
+func (t *thriftWriter) stringField(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+// structField writes an already encoded struct field
+func (t *thriftWriter) structField(id int16, encoded []byte) {
+	t.fieldHeader(id, thriftStruct)
+	t.buf = append(t.buf, encoded...)
+}
+
+// listHeader writes a compact list header
+func (t *thriftWriter) listHeader(size int, elem byte) {
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|elem)
+		return
+	}
+	t.buf = append(t.buf, 0xf0|elem)
+	t.buf = binary.AppendUvarint(t.buf, uint64(size))
+}
+
+// i32ListField writes a list<i32> field
+func (t *thriftWriter) i32ListField(id int16, values []int32) {
+	t.fieldHeader(id, thriftList)
+	t.listHeader(len(values), thriftI32)
+	for _, v := range values {
+		t.buf = binary.AppendVarint(t.buf, int64(v))
+	}
+}
+
+// stringListField writes a list<binary> field
+func (t *thriftWriter) stringListField(id int16, values []string) {
+	t.fieldHeader(id, thriftList)
+	t.listHeader(len(values), thriftBinary)
+	for _, s := range values {
+		t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+		t.buf = append(t.buf, s...)
+	}
+}
+
+// structListField writes a list of already encoded structs
+func (t *thriftWriter) structListField(id int16, encoded [][]byte) {
+	t.fieldHeader(id, thriftList)
+	t.listHeader(len(encoded), thriftStruct)
+	for _, e := range encoded {
+		t.buf = append(t.buf, e...)
+	}
+}
+
+// stop terminates the struct
+func (t *thriftWriter) stop() {
+	t.buf = append(t.buf, 0)
+}
+
+// bytes returns the encoded struct
+func (t *thriftWriter) bytes() []byte {
+	return t.buf
+}
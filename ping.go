@@ -0,0 +1,32 @@
+package main
+
+import (
+	"io"
+	"time"
+)
+
+// Ping checks that the server is reachable and returns the round-trip time.
+// It calls check-db-exists when a database is set, get-databases otherwise.
+func (m *MenousDB) Ping() (_ time.Duration, err error) {
+	headers := map[string]string{
+		"key": m.Key,
+	}
+	endpoint := "get-databases"
+	if m.Database != "" {
+		headers["database"] = m.Database
+		endpoint = "check-db-exists"
+	}
+	defer m.annotate(&err, "Ping", endpoint, "")
+
+	start := time.Now()
+	resp, err := m.uncached().makeRequest("GET", endpoint, headers, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
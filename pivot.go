@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PivotTable is a cross-tabulation: one row per distinct value of RowKey,
+// one column per distinct value of ColumnKey, and in each cell the
+// aggregate of the records with that pair of values
+type PivotTable struct {
+	RowKey    string
+	ColumnKey string
+	// Rows and Columns are the distinct key values, in the order the filter
+	// predicates sort them
+	Rows    []interface{}
+	Columns []interface{}
+	// Values[i][j] is the aggregate for Rows[i] and Columns[j], nil where
+	// no record has that pair
+	Values [][]interface{}
+}
+
+// Pivot cross-tabulates the records of table matching conditions (all when
+// empty) by rowKey and columnKey, folding each cell's records with agg:
+//
+//	sales, err := db.Pivot("orders", "region", "month", SumOf("total"), nil)
+//
+// Records are aggregated as they stream, so memory grows with the number of
+// cells rather than records.
+func (m *MenousDB) Pivot(table, rowKey, columnKey string, agg Aggregate, conditions map[string]interface{}) (*PivotTable, error) {
+	rows := newKeySet()
+	columns := newKeySet()
+	cells := make(map[[2]string]Aggregator)
+
+	err := m.streamSelect("Pivot", table, nil, conditions, false, m.columnDecoder(table, func(r Row) error {
+		rk := rows.add(r[rowKey])
+		ck := columns.add(r[columnKey])
+		cell := cells[[2]string{rk, ck}]
+		if cell == nil {
+			cell = agg()
+			cells[[2]string{rk, ck}] = cell
+		}
+		cell.Add(r)
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PivotTable{RowKey: rowKey, ColumnKey: columnKey}
+	rowKeys := rows.sorted()
+	columnKeys := columns.sorted()
+	for _, rk := range rowKeys {
+		line := make([]interface{}, len(columnKeys))
+		for j, ck := range columnKeys {
+			if cell := cells[[2]string{rk, ck}]; cell != nil {
+				line[j] = cell.Result()
+			}
+		}
+		p.Rows = append(p.Rows, rows.values[rk])
+		p.Values = append(p.Values, line)
+	}
+	for _, ck := range columnKeys {
+		p.Columns = append(p.Columns, columns.values[ck])
+	}
+	return p, nil
+}
+
+// ToRows flattens the table into rows for exporting: each holds the row key
+// under RowKey and each cell under its column value's text
+func (p *PivotTable) ToRows() []Row {
+	out := make([]Row, len(p.Rows))
+	for i, rv := range p.Rows {
+		row := Row{p.RowKey: rv}
+		for j, cv := range p.Columns {
+			row[fmt.Sprint(cv)] = p.Values[i][j]
+		}
+		out[i] = row
+	}
+	return out
+}
+
+// keySet collects distinct values by valueKey
+type keySet struct {
+	values map[string]interface{}
+}
+
+func newKeySet() *keySet {
+	return &keySet{values: make(map[string]interface{})}
+}
+
+// add records v and returns its key
+func (s *keySet) add(v interface{}) string {
+	k := valueKey(v)
+	if _, ok := s.values[k]; !ok {
+		s.values[k] = v
+	}
+	return k
+}
+
+// sorted returns the keys ordered by their values
+func (s *keySet) sorted() []string {
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if c := compareValues(s.values[keys[i]], s.values[keys[j]]); c != 0 {
+			return c < 0
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,96 @@
+package main
+
+import "fmt"
+
+// Operation describes a call about to be sent to the server
+type Operation struct {
+	Endpoint string
+	Database string
+	Table    string
+	Mutating bool
+}
+
+// AccessPolicy decides client-side whether an operation may proceed. A
+// non-nil error rejects the call; it is wrapped with ErrAccessDenied.
+type AccessPolicy interface {
+	Check(op Operation) error
+}
+
+// AccessPolicyFunc adapts an ordinary function to the AccessPolicy interface
+type AccessPolicyFunc func(op Operation) error
+
+// Check calls f(op)
+func (f AccessPolicyFunc) Check(op Operation) error {
+	return f(op)
+}
+
+// TablePolicy grants per-table access. Tables missing from both maps are
+// denied unless AllowOthers is set. Operations on no table are allowed when
+// they only read, such as listing tables; mutating ones, such as dropping
+// the database or creating API keys, are denied unless AllowDatabaseWrites
+// is set.
+type TablePolicy struct {
+	// Read lists tables that may be queried
+	Read map[string]bool
+	// Write lists tables that may be mutated
+	Write map[string]bool
+	// AllowOthers permits tables not mentioned in either map
+	AllowOthers bool
+	// AllowDatabaseWrites permits mutating operations on no table, which
+	// act on whole databases or on API keys
+	AllowDatabaseWrites bool
+}
+
+// Check implements AccessPolicy
+func (p *TablePolicy) Check(op Operation) error {
+	if op.Table == "" {
+		if op.Mutating && !p.AllowDatabaseWrites {
+			return fmt.Errorf("%s is not permitted without a table", op.Endpoint)
+		}
+		return nil
+	}
+	_, known := p.Read[op.Table]
+	if _, ok := p.Write[op.Table]; ok {
+		known = true
+	}
+	if !known {
+		if p.AllowOthers {
+			return nil
+		}
+		return fmt.Errorf("table %q is not permitted", op.Table)
+	}
+	if op.Mutating {
+		if !p.Write[op.Table] {
+			return fmt.Errorf("write access to table %q is not permitted", op.Table)
+		}
+		return nil
+	}
+	if !p.Read[op.Table] && !p.Write[op.Table] {
+		return fmt.Errorf("read access to table %q is not permitted", op.Table)
+	}
+	return nil
+}
+
+// WithAccessPolicy sets the policy consulted before every request
+func WithAccessPolicy(p AccessPolicy) Option {
+	return func(m *MenousDB) {
+		m.policy = p
+	}
+}
+
+// checkPolicy runs the access policy for an endpoint call
+func (m *MenousDB) checkPolicy(endpoint string, headers map[string]string) error {
+	if m.policy == nil {
+		return nil
+	}
+	op := Operation{
+		Endpoint: endpoint,
+		Database: headers["database"],
+		Table:    headers["table"],
+		Mutating: mutatingEndpoints[endpoint],
+	}
+	if err := m.policy.Check(op); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrAccessDenied, endpoint, err)
+	}
+	return nil
+}
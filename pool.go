@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pool hands out pre-configured clients, each typically with its own key or
+// database, to one goroutine at a time. Clients reported unhealthy are
+// skipped until a cooldown passes and a health check succeeds again.
+type Pool struct {
+	// HealthCheck probes a client after its cooldown; defaults to Ping
+	HealthCheck func(*MenousDB) error
+
+	// Cooldown is how long an unhealthy client is skipped; defaults to 30s
+	Cooldown time.Duration
+
+	mu        sync.Mutex
+	idle      []*MenousDB
+	out       map[*MenousDB]bool // pool members, true while checked out
+	busy      int
+	returned  chan struct{} // closed and replaced whenever a client is put back
+	unhealthy map[*MenousDB]time.Time
+}
+
+// NewPool creates a pool over clients
+func NewPool(clients ...*MenousDB) *Pool {
+	p := &Pool{
+		idle:      append([]*MenousDB(nil), clients...),
+		out:       make(map[*MenousDB]bool, len(clients)),
+		returned:  make(chan struct{}),
+		unhealthy: make(map[*MenousDB]time.Time),
+	}
+	for _, c := range clients {
+		p.out[c] = false
+	}
+	return p
+}
+
+// Get checks out a healthy client. While any client is checked out it waits
+// for one to be put back or for an idle client's cooldown to pass; when none
+// is and every idle client is cooling down or fails its health check, it
+// fails with ErrNoHealthyClient. It fails with ctx's error if ctx ends first.
+func (p *Pool) Get(ctx context.Context) (*MenousDB, error) {
+	for {
+		p.mu.Lock()
+		c, probe, next := p.pick()
+		if c != nil {
+			p.out[c] = true
+			p.busy++
+			p.mu.Unlock()
+			if !probe || p.probe(c) {
+				return c, nil
+			}
+			// probe marked c unhealthy again; put it back and look further
+			p.Put(c)
+			continue
+		}
+		if p.busy == 0 {
+			p.mu.Unlock()
+			return nil, ErrNoHealthyClient
+		}
+		returned := p.returned
+		p.mu.Unlock()
+
+		var cooled <-chan time.Time
+		var timer *time.Timer
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			cooled = timer.C
+		}
+		select {
+		case <-returned:
+		case <-cooled:
+		case <-ctx.Done():
+			err := ctx.Err()
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil, err
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// pick removes and returns the first idle client that is healthy or whose
+// cooldown has passed, reporting whether it needs a health check first.
+// Finding none, it returns when the earliest cooldown among idle clients
+// ends. p.mu must be held.
+func (p *Pool) pick() (c *MenousDB, probe bool, next time.Time) {
+	now := time.Now()
+	for i, c := range p.idle {
+		until, bad := p.unhealthy[c]
+		if bad && now.Before(until) {
+			if next.IsZero() || until.Before(next) {
+				next = until
+			}
+			continue
+		}
+		p.idle = append(p.idle[:i], p.idle[i+1:]...)
+		return c, bad, time.Time{}
+	}
+	return nil, false, next
+}
+
+// probe health checks c once its cooldown has passed, returning it to
+// rotation on success and restarting the cooldown on failure
+func (p *Pool) probe(c *MenousDB) bool {
+	check := p.HealthCheck
+	if check == nil {
+		check = func(c *MenousDB) error {
+			_, err := c.Ping()
+			return err
+		}
+	}
+	if err := check(c); err != nil {
+		p.MarkUnhealthy(c)
+		return false
+	}
+	p.mu.Lock()
+	delete(p.unhealthy, c)
+	p.mu.Unlock()
+	return true
+}
+
+// Put returns a client checked out with Get to the pool. Clients the pool
+// does not hold, or that are already back, are refused with
+// ErrNotCheckedOut.
+func (p *Pool) Put(c *MenousDB) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.out[c] {
+		return ErrNotCheckedOut
+	}
+	p.out[c] = false
+	p.busy--
+	p.idle = append(p.idle, c)
+	close(p.returned)
+	p.returned = make(chan struct{})
+	return nil
+}
+
+// MarkUnhealthy takes c out of rotation for the cooldown period, typically
+// after a call through it failed at the transport level
+func (p *Pool) MarkUnhealthy(c *MenousDB) {
+	cooldown := p.Cooldown
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	p.mu.Lock()
+	p.unhealthy[c] = time.Now().Add(cooldown)
+	p.mu.Unlock()
+}
+
+// Do checks out a client, runs fn with it and returns it to the pool,
+// marking it unhealthy if fn fails with a transport error
+func (p *Pool) Do(ctx context.Context, fn func(*MenousDB) error) error {
+	c, err := p.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.Put(c)
+
+	err = fn(c)
+	if isTransportError(err) {
+		p.MarkUnhealthy(c)
+	}
+	return err
+}
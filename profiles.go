@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is a named connection in the profiles file
+type Profile struct {
+	URL      string        `yaml:"url" json:"url"`
+	Key      string        `yaml:"key,omitempty" json:"key,omitempty"`
+	Database string        `yaml:"database,omitempty" json:"database,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// KeyFile, if set, names a file the API key is read from instead of Key,
+	// as FileSecret reads it
+	KeyFile string `yaml:"key_file,omitempty" json:"key_file,omitempty"`
+}
+
+// Profiles is the CLI's profiles file, ~/.menousdb/config by default:
+//
+//	current: staging
+//	profiles:
+//	  local:
+//	    url: http://localhost:5555
+//	    key: dev-key
+//	    database: scratch
+//	  staging:
+//	    url: https://db.staging.example.com
+//	    key_file: ~/.menousdb/staging.key
+//	    database: shop
+//	    timeout: 10s
+type Profiles struct {
+	Current  string             `yaml:"current,omitempty" json:"current,omitempty"`
+	Profiles map[string]Profile `yaml:"profiles" json:"profiles"`
+}
+
+// DefaultProfilesPath returns MENOUSDB_CONFIG, or ~/.menousdb/config
+func DefaultProfilesPath() string {
+	if path := os.Getenv("MENOUSDB_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".menousdb", "config")
+}
+
+// LoadProfiles reads a profiles file; a missing file has no profiles
+func LoadProfiles(path string) (*Profiles, error) {
+	p := &Profiles{}
+	if path == "" {
+		return p, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Save writes the profiles to path, readable only by the user since
+// profiles hold API keys
+func (p *Profiles) Save(path string) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(p); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// Names returns the profile names, sorted
+func (p *Profiles) Names() []string {
+	names := make([]string, 0, len(p.Profiles))
+	for name := range p.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the named profile, or the current one when name is empty.
+// No name and no current profile return an empty profile.
+func (p *Profiles) Lookup(name string) (Profile, error) {
+	if name == "" {
+		name = p.Current
+		if name == "" {
+			return Profile{}, nil
+		}
+	}
+	prof, ok := p.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("no profile %q", name)
+	}
+	return prof, nil
+}
+
+// expandHome replaces a leading ~/ with the home directory
+func expandHome(path string) string {
+	if len(path) > 1 && path[0] == '~' && path[1] == '/' {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
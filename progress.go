@@ -0,0 +1,13 @@
+package main
+
+// ProgressFunc receives progress updates from long-running bulk operations.
+// done counts rows processed so far; total is the expected number of rows,
+// or -1 when it is not known up front.
+type ProgressFunc func(done, total int)
+
+// report calls p if it is set
+func (p ProgressFunc) report(done, total int) {
+	if p != nil {
+		p(done, total)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Protocol buffer wire types
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// errProtoTruncated is returned for messages that end mid-field
+var errProtoTruncated = errors.New("protobuf: truncated message")
+
+// protoEncoder appends protocol buffer fields to a message. It covers the
+// scalar, string, message and google.protobuf.Struct fields GRPCServer
+// needs, not protobuf in general.
+type protoEncoder struct {
+	buf []byte
+}
+
+// tag appends a field key
+func (e *protoEncoder) tag(field, wireType int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wireType))
+}
+
+// varint appends a varint field, omitting the zero value
+func (e *protoEncoder) varint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, protoVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+// bytes appends a length-delimited field
+func (e *protoEncoder) bytes(field int, b []byte) {
+	e.tag(field, protoBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// string appends a string field, omitting the empty string
+func (e *protoEncoder) string(field int, s string) {
+	if s != "" {
+		e.bytes(field, []byte(s))
+	}
+}
+
+// double appends a double field even when zero, as Value's oneof needs
+func (e *protoEncoder) double(field int, f float64) {
+	e.tag(field, protoFixed64)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(f))
+}
+
+// structValue appends m as a google.protobuf.Struct field
+func (e *protoEncoder) structValue(field int, m map[string]interface{}) {
+	e.bytes(field, encodeProtoStruct(m))
+}
+
+// encodeProtoStruct encodes a google.protobuf.Struct: map<string, Value>
+// fields = 1, in key order so output is deterministic
+func encodeProtoStruct(m map[string]interface{}) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var s protoEncoder
+	for _, k := range keys {
+		var entry protoEncoder
+		entry.string(1, k)
+		entry.bytes(2, encodeProtoValue(m[k]))
+		s.bytes(1, entry.buf)
+	}
+	return s.buf
+}
+
+// encodeProtoValue encodes a google.protobuf.Value: null_value = 1,
+// number_value = 2, string_value = 3, bool_value = 4, struct_value = 5,
+// list_value = 6
+func encodeProtoValue(v interface{}) []byte {
+	var e protoEncoder
+	switch v := v.(type) {
+	case nil:
+		e.tag(1, protoVarint)
+		e.buf = append(e.buf, 0)
+	case bool:
+		e.tag(4, protoVarint)
+		if v {
+			e.buf = append(e.buf, 1)
+		} else {
+			e.buf = append(e.buf, 0)
+		}
+	case string:
+		e.bytes(3, []byte(v))
+	case map[string]interface{}:
+		e.bytes(5, encodeProtoStruct(v))
+	case []interface{}:
+		var list protoEncoder
+		for _, item := range v {
+			list.bytes(1, encodeProtoValue(item))
+		}
+		e.bytes(6, list.buf)
+	default:
+		if f, err := toFloat(v); err == nil {
+			e.double(2, f)
+		} else {
+			e.bytes(3, []byte(fmt.Sprint(v)))
+		}
+	}
+	return e.buf
+}
+
+// protoField is one decoded field
+type protoField struct {
+	num      int
+	wireType int
+	varint   uint64
+	data     []byte
+}
+
+// decodeProto calls fn with each field of a message in order
+func decodeProto(msg []byte, fn func(f protoField) error) error {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		msg = msg[n:]
+		f := protoField{num: int(key >> 3), wireType: int(key & 7)}
+		switch f.wireType {
+		case protoVarint:
+			f.varint, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return errProtoTruncated
+			}
+			msg = msg[n:]
+		case protoFixed64:
+			if len(msg) < 8 {
+				return errProtoTruncated
+			}
+			f.varint = binary.LittleEndian.Uint64(msg)
+			msg = msg[8:]
+		case protoFixed32:
+			if len(msg) < 4 {
+				return errProtoTruncated
+			}
+			f.varint = uint64(binary.LittleEndian.Uint32(msg))
+			msg = msg[4:]
+		case protoBytes:
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < size {
+				return errProtoTruncated
+			}
+			f.data = msg[n : n+int(size)]
+			msg = msg[n+int(size):]
+		default:
+			return fmt.Errorf("protobuf: unsupported wire type %d", f.wireType)
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeProtoStruct decodes a google.protobuf.Struct into a map
+func decodeProtoStruct(msg []byte) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	err := decodeProto(msg, func(f protoField) error {
+		if f.num != 1 || f.wireType != protoBytes {
+			return nil
+		}
+		var key string
+		var value interface{}
+		err := decodeProto(f.data, func(e protoField) error {
+			var err error
+			switch {
+			case e.num == 1 && e.wireType == protoBytes:
+				key = string(e.data)
+			case e.num == 2 && e.wireType == protoBytes:
+				value, err = decodeProtoValue(e.data)
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		m[key] = value
+		return nil
+	})
+	return m, err
+}
+
+// decodeProtoValue decodes a google.protobuf.Value; an empty Value is null
+func decodeProtoValue(msg []byte) (interface{}, error) {
+	var v interface{}
+	err := decodeProto(msg, func(f protoField) error {
+		var err error
+		switch f.num {
+		case 1:
+			v = nil
+		case 2:
+			v = math.Float64frombits(f.varint)
+		case 3:
+			v = string(f.data)
+		case 4:
+			v = f.varint != 0
+		case 5:
+			v, err = decodeProtoStruct(f.data)
+		case 6:
+			list := []interface{}{}
+			err = decodeProto(f.data, func(item protoField) error {
+				if item.num != 1 {
+					return nil
+				}
+				iv, err := decodeProtoValue(item.data)
+				list = append(list, iv)
+				return err
+			})
+			v = list
+		}
+		return err
+	})
+	return v, err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+)
+
+// QueryMode controls how query payloads (conditions, columns) are sent for
+// read endpoints that historically took a JSON body on GET
+type QueryMode int
+
+const (
+	// QueryModeBody sends a JSON body with GET, the original protocol
+	QueryModeBody QueryMode = iota
+	// QueryModeParams encodes each payload field as a JSON query parameter
+	QueryModeParams
+	// QueryModePost sends the JSON body with POST instead of GET
+	QueryModePost
+	// QueryModeAuto tries POST first and falls back to GET with a body if
+	// the server answers 405 Method Not Allowed, remembering the result
+	QueryModeAuto
+)
+
+// WithQueryMode selects how query payloads are transported
+func WithQueryMode(mode QueryMode) Option {
+	return func(m *MenousDB) {
+		m.queryMode = mode
+	}
+}
+
+// Negotiation states for QueryModeAuto
+const (
+	queryUnknown int32 = iota
+	queryUsePost
+	queryUseBody
+)
+
+// effectiveQueryMode resolves QueryModeAuto to a concrete mode
+func (m *MenousDB) effectiveQueryMode() QueryMode {
+	if m.queryMode != QueryModeAuto {
+		return m.queryMode
+	}
+	if atomic.LoadInt32(&m.queryNegotiated) == queryUseBody {
+		return QueryModeBody
+	}
+	return QueryModePost
+}
+
+// queryParams encodes body's fields as JSON query parameters
+func queryParams(body interface{}) (url.Values, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	values := url.Values{}
+	for k, v := range fields {
+		values.Set(k, string(v))
+	}
+	return values, nil
+}
+
+// doQuery sends a read request whose payload travels according to the
+// configured QueryMode
+func (m *MenousDB) doQuery(endpoint string, headers map[string]string, body interface{}) (*http.Response, error) {
+	switch m.effectiveQueryMode() {
+	case QueryModeParams:
+		return m.request("GET", endpoint, headers, body, true)
+	case QueryModePost:
+		resp, err := m.makeRequest("POST", endpoint, headers, body)
+		if m.queryMode != QueryModeAuto {
+			return resp, err
+		}
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.HTTPStatus == http.StatusMethodNotAllowed {
+			atomic.StoreInt32(&m.queryNegotiated, queryUseBody)
+			return m.makeRequest("GET", endpoint, headers, body)
+		}
+		if err != nil {
+			return nil, err
+		}
+		atomic.StoreInt32(&m.queryNegotiated, queryUsePost)
+		return resp, nil
+	default:
+		return m.makeRequest("GET", endpoint, headers, body)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults for backing off throttled requests
+const (
+	DefaultThrottleRetries = 3
+	DefaultThrottleMaxWait = 30 * time.Second
+)
+
+// throttleBaseDelay is the first backoff when a throttled response carries
+// no Retry-After header; it doubles on each further attempt
+const throttleBaseDelay = 500 * time.Millisecond
+
+// RateLimiter is a token bucket shared by every client it is attached to.
+// Besides pacing requests it can be paused, which the client does when the
+// server asks it to back off, so concurrent callers wait too instead of
+// piling onto a throttled server.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	paused time.Time
+}
+
+// NewRateLimiter allows rate requests per second on average with bursts of
+// up to burst requests. A rate of zero or less disables pacing, leaving only
+// server-requested pauses.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Wait blocks until a request may be sent or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		d := l.reserve(time.Now())
+		if d <= 0 {
+			return nil
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// reserve takes a token if one is available, otherwise it returns how long
+// to wait before trying again
+func (l *RateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Before(l.paused) {
+		return l.paused.Sub(now)
+	}
+	if l.rate <= 0 {
+		return 0
+	}
+
+	// Refill
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// Pause holds every caller of Wait for d, extending any pause in effect
+func (l *RateLimiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.paused) {
+		l.paused = until
+	}
+}
+
+// WithRateLimiter paces requests through l. Share one limiter between clones
+// to pace them together.
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(m *MenousDB) {
+		m.limiter = l
+	}
+}
+
+// WithThrottleRetries sets how often a request answered with 429, or 503 with
+// a Retry-After header, is retried, and caps each wait at maxWait. A zero
+// retries disables the retry; the APIError is then returned at once.
+func WithThrottleRetries(retries int, maxWait time.Duration) Option {
+	return func(m *MenousDB) {
+		m.throttleRetries = retries
+		m.throttleMaxWait = maxWait
+	}
+}
+
+// throttled reports whether a response asks the client to slow down
+func throttled(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") != "")
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date, returning zero when it is absent or invalid
+func parseRetryAfter(h http.Header, now time.Time) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// throttleDelay picks how long to back off before retry attempt (counting
+// from zero), honouring Retry-After and the configured cap
+func (m *MenousDB) throttleDelay(resp *http.Response, attempt int) time.Duration {
+	d := parseRetryAfter(resp.Header, time.Now())
+	if d == 0 {
+		d = throttleBaseDelay << attempt
+	}
+	if m.throttleMaxWait > 0 && d > m.throttleMaxWait {
+		d = m.throttleMaxWait
+	}
+	return d
+}
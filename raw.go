@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// RawResponse is an undecoded server response with its status metadata
+type RawResponse struct {
+	Body       json.RawMessage
+	StatusCode int
+	Header     http.Header
+}
+
+// readRaw drains resp into a RawResponse
+func readRaw(resp *http.Response) (*RawResponse, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &RawResponse{
+		Body:       body,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}, nil
+}
+
+// GetTableRaw retrieves a table's contents without decoding them
+func (m *MenousDB) GetTableRaw(table string) (_ *RawResponse, err error) {
+	defer m.annotate(&err, "GetTableRaw", "get-table", table)
+
+	if err := m.validateDatabase(); err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{
+		"key":      m.Key,
+		"database": m.Database,
+		"table":    table,
+	}
+
+	resp, err := m.makeRequest("GET", "get-table", headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return readRaw(resp)
+}
+
+// SelectWhereRaw retrieves records matching conditions without decoding them
+func (m *MenousDB) SelectWhereRaw(table string, conditions map[string]interface{}) (_ *RawResponse, err error) {
+	defer m.annotate(&err, "SelectWhereRaw", "select-where", table)
+
+	if err := m.validateDatabase(); err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{
+		"key":      m.Key,
+		"database": m.Database,
+		"table":    table,
+	}
+
+	body := map[string]interface{}{
+		"conditions": conditions,
+	}
+
+	resp, err := m.doQuery("select-where", headers, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return readRaw(resp)
+}
@@ -0,0 +1,32 @@
+package main
+
+import "fmt"
+
+// mutatingEndpoints lists the endpoints that change server state
+var mutatingEndpoints = map[string]bool{
+	"create-db":         true,
+	"del-database":      true,
+	"create-table":      true,
+	"insert-into-table": true,
+	"delete-where":      true,
+	"delete-table":      true,
+	"update-table":      true,
+	"create-key":        true,
+	"revoke-key":        true,
+}
+
+// WithReadOnly rejects every mutating call client-side with ErrReadOnly
+func WithReadOnly() Option {
+	return func(m *MenousDB) {
+		m.readOnly = true
+	}
+}
+
+// checkWritable returns ErrReadOnly if endpoint mutates data on a read-only
+// client
+func (m *MenousDB) checkWritable(endpoint string) error {
+	if m.readOnly && mutatingEndpoints[endpoint] {
+		return fmt.Errorf("%w: refusing %s", ErrReadOnly, endpoint)
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisBackend is a CacheBackend on a Redis server. It speaks the Redis
+// protocol directly over one connection, serializing commands, so the module
+// needs no Redis client dependency; Subscribe uses a connection of its own.
+type RedisBackend struct {
+	Addr     string
+	Password string
+	DB       int
+
+	// DialTimeout bounds connecting; defaults to 5 seconds
+	DialTimeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewRedisBackend returns a backend for the Redis server at addr
+// (host:port)
+func NewRedisBackend(addr string) *RedisBackend {
+	return &RedisBackend{Addr: addr}
+}
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// Get returns the value under key, reporting false when there is none
+func (b *RedisBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := b.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	data, ok := reply.([]byte)
+	return data, ok, nil
+}
+
+// Set stores value under key for ttl
+func (b *RedisBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := b.do(ctx, args...)
+	return err
+}
+
+// Incr increments the counter under key and returns its new value
+func (b *RedisBackend) Incr(ctx context.Context, key string) (int64, error) {
+	reply, err := b.do(ctx, "INCR", key)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: INCR returned %T", reply)
+	}
+	return n, nil
+}
+
+// Publish sends message to the subscribers of channel
+func (b *RedisBackend) Publish(ctx context.Context, channel string, message []byte) error {
+	_, err := b.do(ctx, "PUBLISH", channel, string(message))
+	return err
+}
+
+// Subscribe calls fn with each message published to channel until ctx ends
+// or the connection fails
+func (b *RedisBackend) Subscribe(ctx context.Context, channel string, fn func(message []byte)) error {
+	conn, r, err := b.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := writeRedisCommand(conn, "SUBSCRIBE", channel); err != nil {
+		return err
+	}
+	for {
+		reply, err := readRedisReply(r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 3 {
+			continue
+		}
+		if kind, _ := parts[0].([]byte); string(kind) != "message" {
+			continue
+		}
+		if payload, ok := parts[2].([]byte); ok {
+			fn(payload)
+		}
+	}
+}
+
+// Close closes the command connection
+func (b *RedisBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.Close()
+	b.conn, b.r = nil, nil
+	return err
+}
+
+// do sends one command on the shared connection and reads its reply,
+// reconnecting on the next call after a connection failure
+func (b *RedisBackend) do(ctx context.Context, args ...string) (interface{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil {
+		conn, r, err := b.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b.conn, b.r = conn, r
+	}
+
+	deadline, _ := ctx.Deadline()
+	b.conn.SetDeadline(deadline)
+	err := writeRedisCommand(b.conn, args...)
+	var reply interface{}
+	if err == nil {
+		reply, err = readRedisReply(b.r)
+	}
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		b.conn.Close()
+		b.conn, b.r = nil, nil
+	}
+	return reply, err
+}
+
+// dial opens a connection, authenticating and selecting the database
+func (b *RedisBackend) dial(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	timeout := b.DialTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", b.Addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := bufio.NewReader(conn)
+
+	var setup [][]string
+	if b.Password != "" {
+		setup = append(setup, []string{"AUTH", b.Password})
+	}
+	if b.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(b.DB)})
+	}
+	for _, args := range setup {
+		err := writeRedisCommand(conn, args...)
+		if err == nil {
+			_, err = readRedisReply(r)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("redis %s: %w", args[0], err)
+		}
+	}
+	return conn, r, nil
+}
+
+// writeRedisCommand encodes args as a RESP array of bulk strings
+func writeRedisCommand(w io.Writer, args ...string) error {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, a := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(a)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, a...)
+		buf = append(buf, '\r', '\n')
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// readRedisReply decodes one RESP reply: simple strings as string, errors
+// as redisError, integers as int64, bulk strings as []byte (nil for null)
+// and arrays as []interface{}
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, text := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return text, nil
+	case '-':
+		return nil, redisError(text)
+	case ':':
+		return strconv.ParseInt(text, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readRedisReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DiscoverFunc returns the current server URLs. The first one is the primary
+// that receives writes; the rest serve reads alongside it.
+type DiscoverFunc func(ctx context.Context) ([]string, error)
+
+// endpointRefresher re-resolves or rediscovers the server at most once per
+// interval, in the background of whichever request notices it is due
+type endpointRefresher struct {
+	interval time.Duration
+	discover DiscoverFunc
+
+	mu      sync.Mutex
+	last    time.Time
+	running bool
+	addrs   []string
+	err     error
+}
+
+// WithEndpointRefresh keeps long-lived clients pointed at the right servers.
+// Every interval the client either calls discover, replacing its endpoints
+// with the URLs returned, or, when discover is nil, re-resolves the server
+// hostname. In both cases pooled connections are dropped when the answer
+// changes so new requests reach the new addresses. Refresh runs in the
+// background; requests never wait for it.
+func WithEndpointRefresh(interval time.Duration, discover DiscoverFunc) Option {
+	return func(m *MenousDB) {
+		m.refresher = &endpointRefresher{interval: interval, discover: discover}
+	}
+}
+
+// maybeRefresh starts a background refresh when one is due
+func (m *MenousDB) maybeRefresh() {
+	r := m.refresher
+	if r == nil || m.socketPath != "" {
+		return
+	}
+	r.mu.Lock()
+	due := !r.running && time.Since(r.last) >= r.interval
+	if due {
+		r.running = true
+	}
+	r.mu.Unlock()
+	if due {
+		go m.refresh(context.Background())
+	}
+}
+
+// RefreshEndpoints re-resolves or rediscovers the server now
+func (m *MenousDB) RefreshEndpoints(ctx context.Context) error {
+	r := m.refresher
+	if r == nil {
+		return errors.New("endpoint refresh is not configured")
+	}
+	r.mu.Lock()
+	r.running = true
+	r.mu.Unlock()
+	return m.refresh(ctx)
+}
+
+// LastRefreshError returns the error of the most recent refresh, if any
+func (m *MenousDB) LastRefreshError() error {
+	if m.refresher == nil {
+		return nil
+	}
+	m.refresher.mu.Lock()
+	defer m.refresher.mu.Unlock()
+	return m.refresher.err
+}
+
+// refresh performs one refresh and records its outcome
+func (m *MenousDB) refresh(ctx context.Context) error {
+	r := m.refresher
+	var changed bool
+	var err error
+	if r.discover != nil {
+		changed, err = m.rediscover(ctx)
+	} else {
+		changed, err = m.reresolve(ctx)
+	}
+	if changed {
+		m.httpClient().CloseIdleConnections()
+	}
+
+	r.mu.Lock()
+	r.last = time.Now()
+	r.running = false
+	r.err = err
+	r.mu.Unlock()
+	return err
+}
+
+// rediscover replaces the endpoint set with the discovery hook's answer
+func (m *MenousDB) rediscover(ctx context.Context) (bool, error) {
+	urls, err := m.refresher.discover(ctx)
+	if err != nil {
+		return false, err
+	}
+	if len(urls) == 0 {
+		return false, errors.New("discovery returned no endpoints")
+	}
+	return m.endpoints.replace(urls), nil
+}
+
+// reresolve looks up the server hostname, reporting whether its addresses
+// changed since the last lookup
+func (m *MenousDB) reresolve(ctx context.Context) (bool, error) {
+	u, err := url.Parse(m.URL)
+	if err != nil {
+		return false, err
+	}
+	host := u.Hostname()
+	if net.ParseIP(host) != nil {
+		return false, nil
+	}
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return false, err
+	}
+	sort.Strings(addrs)
+
+	r := m.refresher
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changed := r.addrs != nil && !equalStrings(r.addrs, addrs)
+	r.addrs = addrs
+	return changed, nil
+}
+
+// equalStrings reports whether a and b hold the same strings in order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RESTProxy serves a conventional REST/JSON API and forwards each request to
+// MenousDB through Client, so frontends need not deal with the header-based
+// protocol:
+//
+//	GET    /databases                               list databases
+//	PUT    /databases/{db}                          create a database
+//	DELETE /databases/{db}                          delete a database
+//	GET    /databases/{db}/tables                   list tables
+//	POST   /databases/{db}/tables                   create {"name", "attributes"}
+//	GET    /databases/{db}/tables/{table}           describe a table
+//	DELETE /databases/{db}/tables/{table}           delete a table
+//	GET    /databases/{db}/tables/{table}/rows      select rows
+//	POST   /databases/{db}/tables/{table}/rows      insert a row or an array of rows
+//	PATCH  /databases/{db}/tables/{table}/rows      update matching rows
+//	DELETE /databases/{db}/tables/{table}/rows      delete matching rows
+//	POST   /databases/{db}/tables/{table}/query     select with a JSON body
+//
+// Row routes take equality conditions as query parameters, each value parsed
+// as JSON when it can be, so ?age=30 matches a number and ?age="30" a
+// string. The parameters columns (comma-separated), limit and offset are
+// reserved. PATCH and DELETE refuse to touch every row unless all=true is
+// given. The query route takes {"conditions", "columns", "limit",
+// "offset"}. Errors are JSON objects with an "error" message, and
+// "fields" for rows failing validation.
+type RESTProxy struct {
+	Client *MenousDB
+
+	// Authorize, if set, vets each request before it is forwarded; an error
+	// answers 403 with its message
+	Authorize func(r *http.Request, database string, write bool) error
+
+	// MaxBodyBytes bounds request bodies; defaults to 1 MiB
+	MaxBodyBytes int64
+
+	once sync.Once
+	mux  *http.ServeMux
+}
+
+// restQuery is the body of the query route
+type restQuery struct {
+	Conditions map[string]interface{} `json:"conditions"`
+	Columns    []string               `json:"columns"`
+	Limit      int                    `json:"limit"`
+	Offset     int                    `json:"offset"`
+}
+
+// restStatusError is an error answered with a specific status
+type restStatusError struct {
+	status int
+	msg    string
+}
+
+func (e *restStatusError) Error() string { return e.msg }
+
+// ServeHTTP routes a request to its handler
+func (p *RESTProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.once.Do(p.routes)
+	p.mux.ServeHTTP(w, r)
+}
+
+// routes builds the route table
+func (p *RESTProxy) routes() {
+	p.mux = http.NewServeMux()
+	p.handle("GET /databases", false, p.listDatabases)
+	p.handle("PUT /databases/{db}", true, p.createDatabase)
+	p.handle("DELETE /databases/{db}", true, p.deleteDatabase)
+	p.handle("GET /databases/{db}/tables", false, p.listTables)
+	p.handle("POST /databases/{db}/tables", true, p.createTable)
+	p.handle("GET /databases/{db}/tables/{table}", false, p.describeTable)
+	p.handle("DELETE /databases/{db}/tables/{table}", true, p.deleteTable)
+	p.handle("GET /databases/{db}/tables/{table}/rows", false, p.selectRows)
+	p.handle("POST /databases/{db}/tables/{table}/rows", true, p.insertRows)
+	p.handle("PATCH /databases/{db}/tables/{table}/rows", true, p.updateRows)
+	p.handle("DELETE /databases/{db}/tables/{table}/rows", true, p.deleteRows)
+	p.handle("POST /databases/{db}/tables/{table}/query", false, p.queryRows)
+}
+
+// handle registers fn for pattern, authorizing the request and writing its
+// result or error
+func (p *RESTProxy) handle(pattern string, write bool, fn func(r *http.Request, client *MenousDB) (int, interface{}, error)) {
+	p.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		db := r.PathValue("db")
+		if p.Authorize != nil {
+			if err := p.Authorize(r, db, write); err != nil {
+				writeRESTError(w, &restStatusError{http.StatusForbidden, err.Error()})
+				return
+			}
+		}
+		limit := p.MaxBodyBytes
+		if limit <= 0 {
+			limit = 1 << 20
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+		client := p.Client
+		if db != "" {
+			client = client.ForDatabase(db)
+		}
+		status, result, err := fn(r, client)
+		if err != nil {
+			writeRESTError(w, err)
+			return
+		}
+		writeRESTJSON(w, status, result)
+	})
+}
+
+func (p *RESTProxy) listDatabases(r *http.Request, client *MenousDB) (int, interface{}, error) {
+	result, err := client.GetDatabases()
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]interface{}{"databases": databaseNames(result)}, nil
+}
+
+func (p *RESTProxy) createDatabase(r *http.Request, client *MenousDB) (int, interface{}, error) {
+	exists, err := client.DatabaseExists()
+	if err != nil {
+		return 0, nil, err
+	}
+	if exists {
+		return http.StatusOK, map[string]interface{}{"database": client.Database, "created": false}, nil
+	}
+	if _, err := client.CreateDB(); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, map[string]interface{}{"database": client.Database, "created": true}, nil
+}
+
+func (p *RESTProxy) deleteDatabase(r *http.Request, client *MenousDB) (int, interface{}, error) {
+	if _, err := client.DeleteDB(); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func (p *RESTProxy) listTables(r *http.Request, client *MenousDB) (int, interface{}, error) {
+	tables, err := client.ListTables()
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]interface{}{"tables": tables}, nil
+}
+
+func (p *RESTProxy) createTable(r *http.Request, client *MenousDB) (int, interface{}, error) {
+	var spec struct {
+		Name       string   `json:"name"`
+		Attributes []string `json:"attributes"`
+	}
+	if err := decodeRESTBody(r, &spec); err != nil {
+		return 0, nil, err
+	}
+	if spec.Name == "" || len(spec.Attributes) == 0 {
+		return 0, nil, &restStatusError{http.StatusBadRequest, "name and attributes are required"}
+	}
+	created, err := client.EnsureTable(TableSpec{Name: spec.Name, Attributes: spec.Attributes})
+	if err != nil {
+		return 0, nil, err
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	return status, map[string]interface{}{"table": spec.Name, "created": created}, nil
+}
+
+func (p *RESTProxy) describeTable(r *http.Request, client *MenousDB) (int, interface{}, error) {
+	table := r.PathValue("table")
+	if err := requireTable(client, table); err != nil {
+		return 0, nil, err
+	}
+	spec, err := client.DescribeTable(table)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]interface{}{"table": spec.Name, "attributes": spec.Attributes}, nil
+}
+
+func (p *RESTProxy) deleteTable(r *http.Request, client *MenousDB) (int, interface{}, error) {
+	table := r.PathValue("table")
+	if err := requireTable(client, table); err != nil {
+		return 0, nil, err
+	}
+	if _, err := client.DeleteTable(table); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func (p *RESTProxy) selectRows(r *http.Request, client *MenousDB) (int, interface{}, error) {
+	q, err := restQueryOf(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return selectREST(client, r.PathValue("table"), q)
+}
+
+func (p *RESTProxy) queryRows(r *http.Request, client *MenousDB) (int, interface{}, error) {
+	var q restQuery
+	if err := decodeRESTBody(r, &q); err != nil {
+		return 0, nil, err
+	}
+	return selectREST(client, r.PathValue("table"), q)
+}
+
+func (p *RESTProxy) insertRows(r *http.Request, client *MenousDB) (int, interface{}, error) {
+	var body interface{}
+	if err := decodeRESTBody(r, &body); err != nil {
+		return 0, nil, err
+	}
+	var rows []interface{}
+	switch v := body.(type) {
+	case map[string]interface{}:
+		rows = []interface{}{v}
+	case []interface{}:
+		rows = v
+	default:
+		return 0, nil, &restStatusError{http.StatusBadRequest, "body must be a row object or an array of rows"}
+	}
+
+	table := r.PathValue("table")
+	for i, row := range rows {
+		if _, ok := row.(map[string]interface{}); !ok {
+			return 0, nil, &restStatusError{http.StatusBadRequest, fmt.Sprintf("row %d is not an object", i)}
+		}
+		if _, err := client.InsertIntoTable(table, row); err != nil {
+			if i > 0 {
+				return 0, nil, fmt.Errorf("after inserting %d of %d rows: %w", i, len(rows), err)
+			}
+			return 0, nil, err
+		}
+	}
+	return http.StatusCreated, map[string]interface{}{"inserted": len(rows)}, nil
+}
+
+func (p *RESTProxy) updateRows(r *http.Request, client *MenousDB) (int, interface{}, error) {
+	conditions, err := restConditions(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	var values map[string]interface{}
+	if err := decodeRESTBody(r, &values); err != nil {
+		return 0, nil, err
+	}
+	if len(values) == 0 {
+		return 0, nil, &restStatusError{http.StatusBadRequest, "body must be an object of values to set"}
+	}
+	result, err := client.UpdateWhere(r.PathValue("table"), conditions, values)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]interface{}{"result": result}, nil
+}
+
+func (p *RESTProxy) deleteRows(r *http.Request, client *MenousDB) (int, interface{}, error) {
+	conditions, err := restConditions(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	result, err := client.DeleteWhere(r.PathValue("table"), conditions)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]interface{}{"result": result}, nil
+}
+
+// selectREST runs a select and pages its rows
+func selectREST(client *MenousDB, table string, q restQuery) (int, interface{}, error) {
+	if q.Limit < 0 || q.Offset < 0 {
+		return 0, nil, &restStatusError{http.StatusBadRequest, "limit and offset must not be negative"}
+	}
+	if err := requireTable(client, table); err != nil {
+		return 0, nil, err
+	}
+	rows, err := client.Select(table, q.Columns...).Where(q.Conditions).Rows()
+	if err != nil {
+		return 0, nil, err
+	}
+	total := len(rows)
+	if q.Offset > len(rows) {
+		q.Offset = len(rows)
+	}
+	rows = rows[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(rows) {
+		rows = rows[:q.Limit]
+	}
+	if rows == nil {
+		rows = []Row{}
+	}
+	return http.StatusOK, map[string]interface{}{"rows": rows, "total": total}, nil
+}
+
+// requireTable answers 404 for a missing table
+func requireTable(client *MenousDB, table string) error {
+	exists, err := client.TableExists(table)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &restStatusError{http.StatusNotFound, fmt.Sprintf("table %q does not exist", table)}
+	}
+	return nil
+}
+
+// restQueryOf reads a select from query parameters
+func restQueryOf(r *http.Request) (restQuery, error) {
+	var q restQuery
+	params := r.URL.Query()
+	if v := params.Get("columns"); v != "" {
+		q.Columns = strings.Split(v, ",")
+	}
+	for name, dst := range map[string]*int{"limit": &q.Limit, "offset": &q.Offset} {
+		if v := params.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return q, &restStatusError{http.StatusBadRequest, fmt.Sprintf("%s must be an integer", name)}
+			}
+			*dst = n
+		}
+	}
+	q.Conditions = restParams(params)
+	return q, nil
+}
+
+// restConditions reads the conditions of a bulk update or delete, refusing
+// none unless all=true
+func restConditions(r *http.Request) (map[string]interface{}, error) {
+	params := r.URL.Query()
+	conditions := restParams(params)
+	if len(conditions) == 0 && params.Get("all") != "true" {
+		return nil, &restStatusError{http.StatusBadRequest, "conditions are required; pass all=true to affect every row"}
+	}
+	return conditions, nil
+}
+
+// restParams returns the query parameters that are not reserved as
+// conditions, parsing JSON values
+func restParams(params map[string][]string) map[string]interface{} {
+	var conditions map[string]interface{}
+	for name, values := range params {
+		switch name {
+		case "columns", "limit", "offset", "all":
+			continue
+		}
+		if conditions == nil {
+			conditions = make(map[string]interface{})
+		}
+		raw := values[0]
+		var v interface{}
+		if !decodeCached([]byte(raw), &v) {
+			v = raw
+		}
+		conditions[name] = v
+	}
+	return conditions
+}
+
+// decodeRESTBody decodes a JSON request body, keeping numbers exact
+func decodeRESTBody(r *http.Request, dst interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(dst); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return &restStatusError{http.StatusRequestEntityTooLarge, "request body too large"}
+		}
+		return &restStatusError{http.StatusBadRequest, "invalid JSON body: " + err.Error()}
+	}
+	return nil
+}
+
+// writeRESTJSON writes v as the JSON response body
+func writeRESTJSON(w http.ResponseWriter, status int, v interface{}) {
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		status = http.StatusInternalServerError
+		buf.Reset()
+		json.NewEncoder(&buf).Encode(map[string]string{"error": err.Error()})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// writeRESTError answers err with the status it maps to
+func writeRESTError(w http.ResponseWriter, err error) {
+	body := map[string]interface{}{"error": err.Error()}
+	status := http.StatusBadGateway
+
+	var statusErr *restStatusError
+	var validation *ValidationError
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &statusErr):
+		status = statusErr.status
+	case errors.As(err, &validation):
+		status = http.StatusUnprocessableEntity
+		body["fields"] = validation.Fields
+	case errors.Is(err, ErrInvalidIdentifier):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrReadOnly), errors.Is(err, ErrAccessDenied):
+		status = http.StatusForbidden
+	case errors.As(err, &apiErr):
+		// Client errors pass through; server failures are the upstream's
+		if apiErr.HTTPStatus >= 400 && apiErr.HTTPStatus < 500 {
+			status = apiErr.HTTPStatus
+		}
+	}
+	writeRESTJSON(w, status, body)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Row is a single table record keyed by attribute name
+type Row = map[string]interface{}
+
+// toRows converts a decoded select response into rows. The server returns
+// either a JSON array of records or an object of records keyed by row id; the
+// latter are returned in row id order.
+func toRows(result interface{}) ([]Row, error) {
+	switch v := result.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		rows := make([]Row, 0, len(v))
+		for i, item := range v {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%w: element %d is %T, not an object", ErrUnexpectedResponse, i, item)
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	case map[string]interface{}:
+		ids := make([]string, 0, len(v))
+		for id := range v {
+			ids = append(ids, id)
+		}
+		sortRowIDs(ids)
+		rows := make([]Row, 0, len(v))
+		for _, id := range ids {
+			row, ok := v[id].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%w: record %q is %T, not an object", ErrUnexpectedResponse, id, v[id])
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	case string:
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedResponse, v)
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnexpectedResponse, result)
+	}
+}
+
+// sortRowIDs orders row ids numerically where possible, then lexically
+func sortRowIDs(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		a, errA := strconv.ParseInt(ids[i], 10, 64)
+		b, errB := strconv.ParseInt(ids[j], 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			return a < b
+		case errA == nil:
+			return true
+		case errB == nil:
+			return false
+		}
+		return ids[i] < ids[j]
+	})
+}
+
+// GetTableRows retrieves a table's contents as rows
+func (m *MenousDB) GetTableRows(table string) (_ []Row, err error) {
+	defer m.annotate(&err, "GetTableRows", "get-table", table)
+
+	result, err := m.GetTable(table)
+	if err != nil {
+		return nil, err
+	}
+	return toRows(result)
+}
+
+// SelectWhereRows retrieves records matching conditions as rows
+func (m *MenousDB) SelectWhereRows(table string, conditions map[string]interface{}) (_ []Row, err error) {
+	defer m.annotate(&err, "SelectWhereRows", "select-where", table)
+
+	result, err := m.SelectWhere(table, conditions)
+	if err != nil {
+		return nil, err
+	}
+	return toRows(result)
+}
+
+// SelectColumnsRows retrieves specific columns from a table as rows
+func (m *MenousDB) SelectColumnsRows(table string, columns []string) (_ []Row, err error) {
+	defer m.annotate(&err, "SelectColumnsRows", "select-columns", table)
+
+	result, err := m.SelectColumns(table, columns)
+	if err != nil {
+		return nil, err
+	}
+	return toRows(result)
+}
+
+// SelectColumnsWhereRows retrieves specific columns matching conditions as rows
+func (m *MenousDB) SelectColumnsWhereRows(table string, columns []string, conditions map[string]interface{}) (_ []Row, err error) {
+	defer m.annotate(&err, "SelectColumnsWhereRows", "select-columns-where", table)
+
+	result, err := m.SelectColumnsWhere(table, columns, conditions)
+	if err != nil {
+		return nil, err
+	}
+	return toRows(result)
+}
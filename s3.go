@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Uploader uploads to Amazon S3 or an S3-compatible store such as MinIO,
+// implementing MultipartUploader and ObjectPutter. It signs requests with
+// AWS Signature Version 4 itself, so the module needs no AWS SDK; it does
+// not read credentials from the environment or instance metadata.
+type S3Uploader struct {
+	Bucket string
+	Region string
+
+	// Endpoint, if set, is the base URL of an S3-compatible service,
+	// addressed path-style as Endpoint/Bucket/key. When empty, AWS is
+	// addressed as https://Bucket.s3.Region.amazonaws.com/key.
+	Endpoint string
+
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// SessionToken is sent with temporary credentials
+	SessionToken string
+
+	// HTTPClient sends requests; defaults to http.DefaultClient
+	HTTPClient *http.Client
+
+	// Now returns the signing time; defaults to time.Now
+	Now func() time.Time
+}
+
+// s3Error is the error document S3 answers with
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// CreateMultipartUpload implements MultipartUploader
+func (u *S3Uploader) CreateMultipartUpload(ctx context.Context, key string) (string, error) {
+	body, _, err := u.do(ctx, "POST", key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("s3: no upload ID in response: %s", body)
+	}
+	return result.UploadID, nil
+}
+
+// UploadPart implements MultipartUploader
+func (u *S3Uploader) UploadPart(ctx context.Context, key, uploadID string, partNumber int, data []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+	_, header, err := u.do(ctx, "PUT", key, query, data)
+	if err != nil {
+		return "", err
+	}
+	return header.Get("ETag"), nil
+}
+
+// CompleteMultipartUpload implements MultipartUploader
+func (u *S3Uploader) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []CompletedPart) error {
+	type part struct {
+		PartNumber int
+		ETag       string
+	}
+	doc := struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []part   `xml:"Part"`
+	}{}
+	for _, p := range parts {
+		doc.Parts = append(doc.Parts, part{p.PartNumber, p.ETag})
+	}
+	data, err := xml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	body, _, err := u.do(ctx, "POST", key, url.Values{"uploadId": {uploadID}}, data)
+	if err != nil {
+		return err
+	}
+	// S3 can report a failed completion in a 200 response
+	var e s3Error
+	if xml.Unmarshal(body, &e) == nil {
+		return fmt.Errorf("s3: completing %s: %s: %s", key, e.Code, e.Message)
+	}
+	return nil
+}
+
+// AbortMultipartUpload implements MultipartUploader
+func (u *S3Uploader) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	_, _, err := u.do(ctx, "DELETE", key, url.Values{"uploadId": {uploadID}}, nil)
+	return err
+}
+
+// PutObject implements ObjectPutter
+func (u *S3Uploader) PutObject(ctx context.Context, key string, data []byte) error {
+	_, _, err := u.do(ctx, "PUT", key, nil, data)
+	return err
+}
+
+// do sends a signed request for key, returning the response body and
+// headers, or an error for any non-2xx answer
+func (u *S3Uploader) do(ctx context.Context, method, key string, query url.Values, data []byte) ([]byte, http.Header, error) {
+	var target string
+	if u.Endpoint != "" {
+		target = strings.TrimRight(u.Endpoint, "/") + "/" + s3Escape(u.Bucket, false) + "/" + s3Escape(key, true)
+	} else {
+		target = "https://" + u.Bucket + ".s3." + u.Region + ".amazonaws.com/" + s3Escape(key, true)
+	}
+	if len(query) > 0 {
+		target += "?" + s3Query(query)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	u.sign(req, data)
+
+	client := u.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e s3Error
+		if xml.Unmarshal(body, &e) == nil {
+			return nil, nil, fmt.Errorf("s3: %s %s: %s: %s", method, key, e.Code, e.Message)
+		}
+		return nil, nil, fmt.Errorf("s3: %s %s: %s", method, key, resp.Status)
+	}
+	return body, resp.Header, nil
+}
+
+// sign adds Signature Version 4 headers covering req's host, its x-amz-*
+// headers and any others already set
+func (u *S3Uploader) sign(req *http.Request, payload []byte) {
+	now := time.Now
+	if u.Now != nil {
+		now = u.Now
+	}
+	t := now().UTC()
+	stamp := t.Format("20060102T150405Z")
+	day := stamp[:8]
+	payloadHash := sha256.Sum256(payload)
+
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if u.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", u.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3Query(req.URL.Query()),
+		canonicalHeaders.String(),
+		signed,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	scope := day + "/" + u.Region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + u.SecretAccessKey)
+	for _, part := range []string{day, u.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+u.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+signature)
+}
+
+// hmacSHA256 returns HMAC-SHA256(key, data)
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Query encodes query parameters sorted and escaped as Signature Version
+// 4 requires
+func s3Query(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k, false)+"="+s3Escape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes every byte except unreserved characters, and
+// slashes when keepSlash is set
+func s3Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && keepSlash {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+var (
+	_ MultipartUploader = (*S3Uploader)(nil)
+	_ ObjectPutter      = (*S3Uploader)(nil)
+)
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Rows iterates over query results with database/sql style scanning
+//
+//	rows, err := db.Query("users", []string{"name", "age"}, nil)
+//	for rows.Next() {
+//		var name string
+//		var age int
+//		if err := rows.Scan(&name, &age); err != nil { ... }
+//	}
+type Rows struct {
+	rows    []Row
+	columns []string
+	pos     int
+}
+
+// NewRows wraps rows for scanning. When no columns are given, every attribute
+// present in rows is used, in sorted order.
+func NewRows(rows []Row, columns ...string) *Rows {
+	if len(columns) == 0 {
+		columns = inferColumns(rows)
+	}
+	return &Rows{rows: rows, columns: columns}
+}
+
+// inferColumns returns the sorted union of attribute names in rows
+func inferColumns(rows []Row) []string {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range rows {
+		for k := range row {
+			if !seen[k] {
+				seen[k] = true
+				columns = append(columns, k)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// Query selects columns from table matching conditions and returns a *Rows
+// positioned before the first record. Empty columns selects every
+// attribute; nil conditions selects every record.
+func (m *MenousDB) Query(table string, columns []string, conditions map[string]interface{}) (*Rows, error) {
+	var rows []Row
+	var err error
+	switch {
+	case len(columns) == 0 && conditions == nil:
+		rows, err = m.GetTableRows(table)
+	case len(columns) == 0:
+		rows, err = m.SelectWhereRows(table, conditions)
+	case conditions == nil:
+		rows, err = m.SelectColumnsRows(table, columns)
+	default:
+		rows, err = m.SelectColumnsWhereRows(table, columns, conditions)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewRows(rows, columns...), nil
+}
+
+// Columns returns the column names in scan order
+func (r *Rows) Columns() []string {
+	return r.columns
+}
+
+// Len returns the total number of rows
+func (r *Rows) Len() int {
+	return len(r.rows)
+}
+
+// Next advances to the next row, reporting whether one exists
+func (r *Rows) Next() bool {
+	if r.pos >= len(r.rows) {
+		return false
+	}
+	r.pos++
+	return true
+}
+
+// Row returns the current row
+func (r *Rows) Row() Row {
+	if r.pos == 0 || r.pos > len(r.rows) {
+		return nil
+	}
+	return r.rows[r.pos-1]
+}
+
+// Scan copies the current row's columns, in Columns order, into dest. Each
+// destination is converted from the JSON value: numeric kinds, strings,
+// bools, time.Time (RFC 3339 strings or Unix seconds), []byte, sql.Scanner
+// and *interface{}. Pointer-to-pointer destinations are set to nil for
+// missing or null values.
+func (r *Rows) Scan(dest ...interface{}) error {
+	row := r.Row()
+	if row == nil {
+		return errors.New("menousdb: Scan called without a current row")
+	}
+	if len(dest) != len(r.columns) {
+		return fmt.Errorf("menousdb: expected %d destination arguments in Scan, not %d", len(r.columns), len(dest))
+	}
+	for i, d := range dest {
+		if err := convertAssign(d, row[r.columns[i]]); err != nil {
+			return fmt.Errorf("menousdb: scanning column %q: %w", r.columns[i], err)
+		}
+	}
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// convertAssign stores src in the value dest points to
+func convertAssign(dest, src interface{}) error {
+	if scanner, ok := dest.(sql.Scanner); ok {
+		return scanner.Scan(src)
+	}
+	if p, ok := dest.(*interface{}); ok {
+		*p = src
+		return nil
+	}
+
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return fmt.Errorf("destination %T is not a non-nil pointer", dest)
+	}
+	return assignValue(dv.Elem(), src)
+}
+
+// assignValue converts src into the settable value v
+func assignValue(v reflect.Value, src interface{}) error {
+	if v.Kind() == reflect.Ptr {
+		if src == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		elem := reflect.New(v.Type().Elem())
+		if err := assignValue(elem.Elem(), src); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if src == nil {
+		return fmt.Errorf("cannot store null in %s", v.Type())
+	}
+
+	if v.Type() == timeType {
+		t, err := toTime(src)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		switch s := src.(type) {
+		case string:
+			v.SetString(s)
+		case float64:
+			v.SetString(strconv.FormatFloat(s, 'f', -1, 64))
+		case bool:
+			v.SetString(strconv.FormatBool(s))
+		default:
+			raw, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			v.SetString(string(raw))
+		}
+		return nil
+	case reflect.Bool:
+		switch b := src.(type) {
+		case bool:
+			v.SetBool(b)
+			return nil
+		case string:
+			parsed, err := strconv.ParseBool(b)
+			if err != nil {
+				return err
+			}
+			v.SetBool(parsed)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, err := toFloat(src)
+		if err != nil {
+			return err
+		}
+		if f != math.Trunc(f) {
+			return fmt.Errorf("cannot store %v in %s without truncation", f, v.Type())
+		}
+		if !isInt64(f) || v.OverflowInt(int64(f)) {
+			return fmt.Errorf("%v overflows %s", f, v.Type())
+		}
+		v.SetInt(int64(f))
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, err := toFloat(src)
+		if err != nil {
+			return err
+		}
+		if f < 0 || f != math.Trunc(f) {
+			return fmt.Errorf("cannot store %v in %s", f, v.Type())
+		}
+		if f >= math.MaxUint64 || v.OverflowUint(uint64(f)) {
+			return fmt.Errorf("%v overflows %s", f, v.Type())
+		}
+		v.SetUint(uint64(f))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(src)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if s, ok := src.(string); ok {
+				v.SetBytes([]byte(s))
+				return nil
+			}
+		}
+	}
+
+	// Fall back to a JSON round trip for structs, maps and slices
+	raw, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, v.Addr().Interface()); err != nil {
+		return fmt.Errorf("cannot store %T in %s: %w", src, v.Type(), err)
+	}
+	return nil
+}
+
+// toFloat converts a JSON number or numeric string to float64
+func toFloat(src interface{}) (float64, error) {
+	switch n := src.(type) {
+	case float64:
+		return n, nil
+	case json.Number:
+		return n.Float64()
+	case float32:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case string:
+		return strconv.ParseFloat(n, 64)
+	case bool:
+		if n {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("cannot convert %T to a number", src)
+}
+
+// toTime converts an RFC 3339 string or Unix seconds to time.Time
+func toTime(src interface{}) (time.Time, error) {
+	switch t := src.(type) {
+	case time.Time:
+		return t, nil
+	case int64:
+		return time.Unix(t, 0).UTC(), nil
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	case float64:
+		sec, frac := math.Modf(t)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("cannot convert %T to time.Time", src)
+}
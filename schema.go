@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaSpec declares the databases and tables that should exist
+type SchemaSpec struct {
+	Databases []DatabaseSpec `yaml:"databases" json:"databases"`
+}
+
+// DatabaseSpec declares one database and its tables
+type DatabaseSpec struct {
+	Name   string      `yaml:"name" json:"name"`
+	Tables []TableSpec `yaml:"tables" json:"tables"`
+}
+
+// TableSpec declares one table and its attributes. Columns, when given,
+// add types and defaults; attributes missing from Attributes are taken from
+// them.
+type TableSpec struct {
+	Name       string      `yaml:"name" json:"name"`
+	Attributes []string    `yaml:"attributes" json:"attributes"`
+	Columns    []ColumnDef `yaml:"columns,omitempty" json:"columns,omitempty"`
+}
+
+// attributes returns the table's attribute names, including columns not
+// listed in Attributes
+func (t TableSpec) attributes() []string {
+	out := append([]string(nil), t.Attributes...)
+	seen := make(map[string]bool, len(out))
+	for _, a := range out {
+		seen[a] = true
+	}
+	for _, c := range t.Columns {
+		if !seen[c.Name] {
+			out = append(out, c.Name)
+			seen[c.Name] = true
+		}
+	}
+	return out
+}
+
+// createTable creates the table described by spec, with typed columns when
+// it declares them
+func (m *MenousDB) createTable(spec TableSpec) (string, error) {
+	if len(spec.Columns) == 0 {
+		return m.CreateTable(spec.Name, spec.Attributes)
+	}
+	return m.CreateTableWithColumns(spec.Name, spec.columnDefs())
+}
+
+// columnDefs returns a definition for every attribute in attributes order,
+// untyped where Columns has none
+func (t TableSpec) columnDefs() []ColumnDef {
+	byName := make(map[string]ColumnDef, len(t.Columns))
+	for _, c := range t.Columns {
+		byName[c.Name] = c
+	}
+	var defs []ColumnDef
+	for _, a := range t.attributes() {
+		c, ok := byName[a]
+		if !ok {
+			c = ColumnDef{Name: a, Nullable: true}
+		}
+		defs = append(defs, c)
+	}
+	return defs
+}
+
+// LoadSchemaSpec reads a schema spec from a YAML or JSON file
+func LoadSchemaSpec(path string) (*SchemaSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec SchemaSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &spec, nil
+}
+
+// EnsureDatabase creates the client's database if it does not exist and
+// reports whether it was created
+func (m *MenousDB) EnsureDatabase() (bool, error) {
+	exists, err := m.DatabaseExists()
+	if err != nil || exists {
+		return false, err
+	}
+	if _, err := m.CreateDB(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// EnsureTable creates the table if it does not exist and reports whether it
+// was created. Existing tables are left as they are; use ApplySchema to find
+// attribute drift.
+func (m *MenousDB) EnsureTable(spec TableSpec) (bool, error) {
+	if len(spec.Columns) > 0 {
+		m.DeclareColumns(spec.Name, spec.columnDefs())
+	}
+	exists, err := m.TableExists(spec.Name)
+	if err != nil || exists {
+		return false, err
+	}
+	if _, err := m.createTable(spec); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DescribeTable returns the attributes of an existing table. They come from
+// the table's attribute list in the database contents when the server
+// includes one, and otherwise from the union of attributes in its rows.
+func (m *MenousDB) DescribeTable(table string) (*TableSpec, error) {
+	data, err := m.ReadDB()
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := data[table]
+	if !ok {
+		return nil, fmt.Errorf("table %q does not exist", table)
+	}
+	if fields, ok := entry.(map[string]interface{}); ok {
+		if list, ok := fields["attributes"].([]interface{}); ok {
+			spec := &TableSpec{Name: table}
+			for _, a := range list {
+				if s, ok := a.(string); ok {
+					spec.Attributes = append(spec.Attributes, s)
+				}
+			}
+			return spec, nil
+		}
+	}
+
+	rows, err := m.GetTableRows(table)
+	if err != nil {
+		return nil, err
+	}
+	return &TableSpec{Name: table, Attributes: inferColumns(rows)}, nil
+}
+
+// SchemaChange is one action taken (or needed) by ApplySchema
+type SchemaChange struct {
+	Database string
+	Table    string
+	Action   string
+	Detail   string
+	Applied  bool
+}
+
+// String describes the change for logs and dry runs
+func (c SchemaChange) String() string {
+	target := c.Database
+	if c.Table != "" {
+		target += "." + c.Table
+	}
+	s := c.Action + " " + target
+	if c.Detail != "" {
+		s += " (" + c.Detail + ")"
+	}
+	return s
+}
+
+// ApplySchema reconciles spec against the server, creating missing
+// databases and tables, and declares typed columns client-side. With dryRun
+// it only reports what it would do.
+// Attributes declared for an existing table but absent from it are reported
+// as unapplied changes, since the server cannot alter tables.
+func (m *MenousDB) ApplySchema(spec *SchemaSpec, dryRun bool) ([]SchemaChange, error) {
+	var changes []SchemaChange
+	for _, dbSpec := range spec.Databases {
+		db := m.ForDatabase(dbSpec.Name)
+
+		exists, err := db.DatabaseExists()
+		if err != nil {
+			return changes, fmt.Errorf("checking database %s: %w", dbSpec.Name, err)
+		}
+		if !exists {
+			change := SchemaChange{Database: dbSpec.Name, Action: "create-database"}
+			if !dryRun {
+				if _, err := db.CreateDB(); err != nil {
+					return changes, fmt.Errorf("creating database %s: %w", dbSpec.Name, err)
+				}
+				change.Applied = true
+			}
+			changes = append(changes, change)
+		}
+
+		for _, t := range dbSpec.Tables {
+			if len(t.Columns) > 0 {
+				db.DeclareColumns(t.Name, t.columnDefs())
+			}
+			tableExists := false
+			if exists {
+				if tableExists, err = db.TableExists(t.Name); err != nil {
+					return changes, fmt.Errorf("checking table %s.%s: %w", dbSpec.Name, t.Name, err)
+				}
+			}
+			if !tableExists {
+				change := SchemaChange{Database: dbSpec.Name, Table: t.Name, Action: "create-table"}
+				if !dryRun {
+					if _, err := db.createTable(t); err != nil {
+						return changes, fmt.Errorf("creating table %s.%s: %w", dbSpec.Name, t.Name, err)
+					}
+					change.Applied = true
+				}
+				changes = append(changes, change)
+				continue
+			}
+
+			actual, err := db.DescribeTable(t.Name)
+			if err != nil {
+				return changes, fmt.Errorf("describing %s.%s: %w", dbSpec.Name, t.Name, err)
+			}
+			have := make(map[string]bool, len(actual.Attributes))
+			for _, a := range actual.Attributes {
+				have[a] = true
+			}
+			for _, a := range t.attributes() {
+				if !have[a] {
+					changes = append(changes, SchemaChange{
+						Database: dbSpec.Name, Table: t.Name, Action: "add-attribute", Detail: a,
+					})
+				}
+			}
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import "fmt"
+
+// DescribeSchema reads the current schema of the named databases from the
+// server. Databases that do not exist are left out of the result.
+func (m *MenousDB) DescribeSchema(databases ...string) (*SchemaSpec, error) {
+	spec := &SchemaSpec{}
+	for _, name := range databases {
+		db := m.ForDatabase(name)
+		exists, err := db.DatabaseExists()
+		if err != nil {
+			return nil, fmt.Errorf("checking database %s: %w", name, err)
+		}
+		if !exists {
+			continue
+		}
+		tables, err := db.ListTables()
+		if err != nil {
+			return nil, fmt.Errorf("listing tables of %s: %w", name, err)
+		}
+		dbSpec := DatabaseSpec{Name: name}
+		for _, table := range tables {
+			t, err := db.DescribeTable(table)
+			if err != nil {
+				return nil, fmt.Errorf("describing %s.%s: %w", name, table, err)
+			}
+			dbSpec.Tables = append(dbSpec.Tables, *t)
+		}
+		spec.Databases = append(spec.Databases, dbSpec)
+	}
+	return spec, nil
+}
+
+// SchemaDiff returns the changes that turn actual into desired: databases
+// and tables to create, and attributes to add to or drop from tables both
+// declare. Tables and databases only actual has are left alone. The changes
+// follow desired's order and none are marked applied.
+func SchemaDiff(desired, actual *SchemaSpec) []SchemaChange {
+	have := make(map[string]map[string]TableSpec)
+	for _, db := range actual.Databases {
+		tables := make(map[string]TableSpec, len(db.Tables))
+		for _, t := range db.Tables {
+			tables[t.Name] = t
+		}
+		have[db.Name] = tables
+	}
+
+	var changes []SchemaChange
+	for _, db := range desired.Databases {
+		tables, exists := have[db.Name]
+		if !exists {
+			changes = append(changes, SchemaChange{Database: db.Name, Action: "create-database"})
+		}
+		for _, t := range db.Tables {
+			current, ok := tables[t.Name]
+			if !ok {
+				changes = append(changes, SchemaChange{Database: db.Name, Table: t.Name, Action: "create-table"})
+				continue
+			}
+			want := t.attributes()
+			got := current.attributes()
+			for _, a := range missingFrom(want, got) {
+				changes = append(changes, SchemaChange{Database: db.Name, Table: t.Name, Action: "add-attribute", Detail: a})
+			}
+			for _, a := range missingFrom(got, want) {
+				changes = append(changes, SchemaChange{Database: db.Name, Table: t.Name, Action: "drop-attribute", Detail: a})
+			}
+		}
+	}
+	return changes
+}
+
+// missingFrom returns the names in a that b lacks, in a's order
+func missingFrom(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, s := range b {
+		in[s] = true
+	}
+	var out []string
+	for _, s := range a {
+		if !in[s] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ApplySchemaChanges carries out changes computed by SchemaDiff against
+// desired, returning them with Applied set on those it made. Databases and
+// tables are created; attribute changes are left unapplied, since the server
+// cannot alter tables. It stops at the first failure.
+func (m *MenousDB) ApplySchemaChanges(desired *SchemaSpec, changes []SchemaChange) ([]SchemaChange, error) {
+	specs := make(map[string]TableSpec)
+	for _, db := range desired.Databases {
+		for _, t := range db.Tables {
+			specs[db.Name+"\x00"+t.Name] = t
+		}
+	}
+
+	out := append([]SchemaChange(nil), changes...)
+	for i, c := range out {
+		db := m.ForDatabase(c.Database)
+		switch c.Action {
+		case "create-database":
+			if _, err := db.CreateDB(); err != nil {
+				return out, fmt.Errorf("creating database %s: %w", c.Database, err)
+			}
+		case "create-table":
+			spec, ok := specs[c.Database+"\x00"+c.Table]
+			if !ok {
+				return out, fmt.Errorf("table %s.%s is not in the desired schema", c.Database, c.Table)
+			}
+			if _, err := db.createTable(spec); err != nil {
+				return out, fmt.Errorf("creating table %s.%s: %w", c.Database, c.Table, err)
+			}
+			if len(spec.Columns) > 0 {
+				db.DeclareColumns(spec.Name, spec.columnDefs())
+			}
+		default:
+			continue
+		}
+		out[i].Applied = true
+	}
+	return out, nil
+}
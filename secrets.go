@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretProvider fetches the API key from a secrets manager such as Vault or
+// AWS Secrets Manager, so it need not live in the process environment
+type SecretProvider interface {
+	Secret(ctx context.Context) (string, error)
+}
+
+// SecretProviderFunc adapts a function to SecretProvider
+type SecretProviderFunc func(ctx context.Context) (string, error)
+
+// Secret calls f
+func (f SecretProviderFunc) Secret(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// FileSecret reads the key from path on every fetch, trimming surrounding
+// whitespace. It suits secrets rendered to disk by Vault Agent or mounted by
+// Kubernetes, which rewrite the file on rotation.
+func FileSecret(path string) SecretProvider {
+	return SecretProviderFunc(func(ctx context.Context) (string, error) {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(raw)), nil
+	})
+}
+
+// secretCache holds the last key fetched from a provider, shared by clones
+type secretCache struct {
+	provider SecretProvider
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	key     string
+	fetched time.Time
+}
+
+// WithSecretProvider fetches the API key from p on first use instead of
+// taking it from the constructor. The key is cached for ttl (forever when
+// ttl is zero) and fetched again when it expires or when the server rejects
+// it with 401, so rotated keys are picked up without a restart. KeyRing
+// entries still take precedence for their databases.
+func WithSecretProvider(p SecretProvider, ttl time.Duration) Option {
+	return func(m *MenousDB) {
+		m.secrets = &secretCache{provider: p, ttl: ttl}
+	}
+}
+
+// get returns the cached key, fetching it when missing or expired
+func (c *secretCache) get(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.key != "" && (c.ttl == 0 || time.Since(c.fetched) < c.ttl) {
+		return c.key, nil
+	}
+	key, err := c.provider.Secret(ctx)
+	if err != nil {
+		return "", fmt.Errorf("fetching API key: %w", err)
+	}
+	if key == "" {
+		return "", fmt.Errorf("fetching API key: provider returned an empty key")
+	}
+	c.key, c.fetched = key, time.Now()
+	return key, nil
+}
+
+// invalidate drops the cached key so the next request fetches it again
+func (c *secretCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.key = ""
+}
@@ -0,0 +1,118 @@
+package main
+
+import "errors"
+
+// SelectQuery builds a select. Columns and conditions are sent to the
+// server; computed columns and client-side predicates run on each record as
+// it is decoded, in the order they were added, so later stages see the
+// fields earlier ones produced.
+type SelectQuery struct {
+	client     *MenousDB
+	table      string
+	columns    []string
+	conditions map[string]interface{}
+	filter     *Filter
+	less       func(a, b Row) bool
+}
+
+// Select starts a query over table, returning columns (all when none given)
+func (m *MenousDB) Select(table string, columns ...string) *SelectQuery {
+	return &SelectQuery{client: m, table: table, columns: columns, filter: NewFilter()}
+}
+
+// Where adds equality conditions evaluated by the server
+func (q *SelectQuery) Where(conditions map[string]interface{}) *SelectQuery {
+	if q.conditions == nil {
+		q.conditions = make(map[string]interface{}, len(conditions))
+	}
+	for k, v := range conditions {
+		q.conditions[k] = v
+	}
+	return q
+}
+
+// Filter adds predicates evaluated client-side, for conditions the server
+// cannot express
+func (q *SelectQuery) Filter(ps ...Predicate) *SelectQuery {
+	q.filter.Where(ps...)
+	return q
+}
+
+// Compute adds a virtual column name holding fn's result for each record
+func (q *SelectQuery) Compute(name string, fn func(r Row) interface{}) *SelectQuery {
+	q.filter.Map(func(r Row) Row {
+		r[name] = fn(r)
+		return r
+	})
+	return q
+}
+
+// As returns column under key instead, so results can be passed straight to
+// callers expecting their own field names. Stages added after As see the
+// new key.
+func (q *SelectQuery) As(column, key string) *SelectQuery {
+	q.filter.Rename(column, key)
+	return q
+}
+
+// Limit stops after n records; zero means no limit
+func (q *SelectQuery) Limit(n int) *SelectQuery {
+	q.filter.Limit(n)
+	return q
+}
+
+// Each runs the query and calls fn for each resulting record as it is
+// decoded, or once all are sorted when SortBy is set. Returning ErrStopFetch
+// from fn stops early without an error.
+func (q *SelectQuery) Each(fn func(Row) error) error {
+	if q.less != nil {
+		return q.eachSorted(fn)
+	}
+	err := q.client.streamSelect("Select", q.table, q.columns, q.conditions, false, q.client.columnDecoder(q.table, q.filter.Stream(fn)))
+	if errors.Is(err, ErrStopFetch) {
+		return nil
+	}
+	return err
+}
+
+// eachSorted collects the filtered records, sorts them and calls fn for up
+// to the limit
+func (q *SelectQuery) eachSorted(fn func(Row) error) error {
+	var rows []Row
+	unlimited := *q.filter
+	unlimited.limit = 0
+	collect := unlimited.Stream(func(r Row) error {
+		rows = append(rows, r)
+		return nil
+	})
+	err := q.client.streamSelect("Select", q.table, q.columns, q.conditions, false, q.client.columnDecoder(q.table, collect))
+	if err != nil {
+		return err
+	}
+	SortRows(rows, q.less)
+	if n := q.filter.limit; n > 0 && len(rows) > n {
+		rows = rows[:n]
+	}
+	for _, r := range rows {
+		if err := fn(r); err != nil {
+			if errors.Is(err, ErrStopFetch) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Rows runs the query and returns the resulting records
+func (q *SelectQuery) Rows() ([]Row, error) {
+	var out []Row
+	err := q.Each(func(r Row) error {
+		out = append(out, r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CacheBackend is a cache shared between application instances, such as
+// RedisBackend. Get reports false for missing keys; Subscribe blocks,
+// calling fn for each message, until ctx ends or it fails.
+type CacheBackend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Incr(ctx context.Context, key string) (int64, error)
+	Publish(ctx context.Context, channel string, message []byte) error
+	Subscribe(ctx context.Context, channel string, fn func(message []byte)) error
+}
+
+// SharedCacheOptions configures a SharedCache
+type SharedCacheOptions struct {
+	// Prefix starts every key the cache writes; defaults to "menousdb:"
+	Prefix string
+
+	// Channel carries invalidations between instances; defaults to the
+	// prefix followed by "invalidate"
+	Channel string
+
+	// Timeout bounds each backend call, after which the cache is skipped;
+	// defaults to 500ms
+	Timeout time.Duration
+
+	// OnError, if set, is called with backend failures, which otherwise
+	// only make the shared tier miss
+	OnError func(error)
+}
+
+// SharedCache is a second cache tier behind the in-memory one, kept in a
+// CacheBackend so application instances share warm reads. Keys carry
+// generation counters kept in the backend: a write bumps the counters of
+// the table or database it touched, so every instance stops seeing entries
+// from before it, and publishes the change so other instances drop their
+// in-memory entries too. Call Run to receive those messages.
+//
+// Entry keys start with a hash of the credentials and API version the read
+// was made with, so instances or tenants using different API keys never
+// serve each other's responses. The backend itself is the trust boundary:
+// anyone who can read it can read every cached response, whichever key
+// fetched it, so it must be trusted as far as the most privileged key that
+// uses it. Tenants that must not share data at rest need separate backends,
+// or at least separate Prefixes under backend-side access control.
+type SharedCache struct {
+	backend CacheBackend
+	opts    SharedCacheOptions
+	origin  string
+
+	mu     sync.Mutex
+	gens   map[string]int64
+	caches []*readCache
+}
+
+// NewSharedCache creates a shared cache tier on backend
+func NewSharedCache(backend CacheBackend, opts SharedCacheOptions) *SharedCache {
+	if opts.Prefix == "" {
+		opts.Prefix = "menousdb:"
+	}
+	if opts.Channel == "" {
+		opts.Channel = opts.Prefix + "invalidate"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 500 * time.Millisecond
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	return &SharedCache{
+		backend: backend,
+		opts:    opts,
+		origin:  hex.EncodeToString(id),
+		gens:    make(map[string]int64),
+	}
+}
+
+// WithSharedCache puts s behind the client's in-memory cache, enabling
+// WithCache's defaults when no cache is configured yet
+func WithSharedCache(s *SharedCache) Option {
+	return func(m *MenousDB) {
+		if m.cache == nil {
+			WithCache(CacheOptions{})(m)
+		}
+		c := newReadCache(m.cache.opts)
+		for e, r := range m.cache.rules {
+			c.rules[e] = r
+		}
+		c.shared = s
+		s.mu.Lock()
+		s.caches = append(s.caches, c)
+		s.mu.Unlock()
+		m.cache = c
+	}
+}
+
+// invalidation is the message published for each write
+type invalidation struct {
+	Origin   string           `json:"origin"`
+	Endpoint string           `json:"endpoint"`
+	Database string           `json:"database"`
+	Table    string           `json:"table"`
+	Gens     map[string]int64 `json:"gens"`
+}
+
+// Run applies invalidations published by other instances until ctx ends
+// or the backend subscription fails
+func (s *SharedCache) Run(ctx context.Context) error {
+	return s.backend.Subscribe(ctx, s.opts.Channel, func(message []byte) {
+		var inv invalidation
+		if err := json.Unmarshal(message, &inv); err != nil || inv.Origin == s.origin {
+			return
+		}
+		s.mu.Lock()
+		for k, v := range inv.Gens {
+			if v > s.gens[k] {
+				s.gens[k] = v
+			}
+		}
+		caches := s.caches
+		s.mu.Unlock()
+		for _, c := range caches {
+			c.invalidate(inv.Endpoint, inv.Database, inv.Table)
+		}
+	})
+}
+
+// genKeys returns the generation counters an entry for database and table
+// depends on
+func (s *SharedCache) genKeys(database, table string) []string {
+	return []string{
+		s.opts.Prefix + "gen:*",
+		s.opts.Prefix + "gen:db:" + database,
+		s.opts.Prefix + "gen:table:" + database + "\x00" + table,
+	}
+}
+
+// key returns the backend key for a request's cache key in the current
+// generations. cacheKey begins with the client's cacheScope, keeping the
+// entries of different credentials apart in the backend too.
+func (s *SharedCache) key(ctx context.Context, cacheKey, database, table string) (string, error) {
+	key := s.opts.Prefix + "entry:" + cacheKey
+	for _, g := range s.genKeys(database, table) {
+		n, err := s.gen(ctx, g)
+		if err != nil {
+			return "", err
+		}
+		key += ":" + strconv.FormatInt(n, 10)
+	}
+	return key, nil
+}
+
+// gen returns a generation counter, reading it from the backend the first
+// time
+func (s *SharedCache) gen(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	n, ok := s.gens[key]
+	s.mu.Unlock()
+	if ok {
+		return n, nil
+	}
+	data, found, err := s.backend.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		if n, err = strconv.ParseInt(string(data), 10, 64); err != nil {
+			return 0, err
+		}
+	}
+	s.mu.Lock()
+	if n > s.gens[key] {
+		s.gens[key] = n
+	}
+	n = s.gens[key]
+	s.mu.Unlock()
+	return n, nil
+}
+
+// sharedEntry is the stored form of a cached response
+type sharedEntry struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// load returns the shared entry for a request, or nil on a miss or failure
+func (s *SharedCache) load(cacheKey string, rule cacheRule, endpoint string, headers map[string]string, body interface{}) *cacheEntry {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.Timeout)
+	defer cancel()
+	key, err := s.key(ctx, cacheKey, headers["database"], headers["table"])
+	if err != nil {
+		s.fail(err)
+		return nil
+	}
+	data, found, err := s.backend.Get(ctx, key)
+	if err != nil {
+		s.fail(err)
+		return nil
+	}
+	var se sharedEntry
+	if !found || json.Unmarshal(data, &se) != nil {
+		return nil
+	}
+	return &cacheEntry{
+		key:      cacheKey,
+		rule:     rule,
+		endpoint: endpoint,
+		request:  body,
+		database: headers["database"],
+		table:    headers["table"],
+		status:   se.Status,
+		header:   se.Header,
+		body:     se.Body,
+	}
+}
+
+// save stores e in the backend for as long as it may be served
+func (s *SharedCache) save(e *cacheEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.Timeout)
+	defer cancel()
+	key, err := s.key(ctx, e.key, e.database, e.table)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+	data, err := json.Marshal(sharedEntry{Status: e.status, Header: e.header, Body: e.body})
+	if err != nil {
+		return
+	}
+	if err := s.backend.Set(ctx, key, data, e.rule.ttl+e.rule.stale); err != nil {
+		s.fail(err)
+	}
+}
+
+// publish bumps the generations a write to endpoint affects, with the same
+// scopes as readCache.invalidate, and tells the other instances
+func (s *SharedCache) publish(endpoint, database, table string) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.Timeout)
+	defer cancel()
+
+	keys := s.genKeys(database, table)
+	var bump []string
+	tableOnly := table != "" && (endpoint == "insert-into-table" || endpoint == "update-table" || endpoint == "delete-where")
+	switch {
+	case tableOnly:
+		bump = keys[2:]
+	case database != "":
+		bump = []string{keys[1], s.genKeys("", "")[1]}
+	default:
+		bump = keys[:1]
+	}
+
+	inv := invalidation{Origin: s.origin, Endpoint: endpoint, Database: database, Table: table, Gens: make(map[string]int64)}
+	for _, k := range bump {
+		n, err := s.backend.Incr(ctx, k)
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		inv.Gens[k] = n
+	}
+	s.mu.Lock()
+	for k, n := range inv.Gens {
+		if n > s.gens[k] {
+			s.gens[k] = n
+		}
+	}
+	s.mu.Unlock()
+
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return
+	}
+	if err := s.backend.Publish(ctx, s.opts.Channel, data); err != nil {
+		s.fail(err)
+	}
+}
+
+// fail reports a backend error
+func (s *SharedCache) fail(err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryBackend is a CacheBackend in a map
+type memoryBackend struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (b *memoryBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, ok := b.data[key]
+	return v, ok, nil
+}
+
+func (b *memoryBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data[key] = value
+	return nil
+}
+
+func (b *memoryBackend) Incr(ctx context.Context, key string) (int64, error) {
+	return 1, nil
+}
+
+func (b *memoryBackend) Publish(ctx context.Context, channel string, message []byte) error {
+	return nil
+}
+
+func (b *memoryBackend) Subscribe(ctx context.Context, channel string, fn func(message []byte)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestSharedCacheScopedByKey(t *testing.T) {
+	var requests int
+	srv := keyedServer(t, &requests)
+	backend := &memoryBackend{data: map[string][]byte{}}
+
+	// Two instances sharing one backend, as separate processes would
+	admin := NewMenousDB(srv.URL, "admin", "db", WithSharedCache(NewSharedCache(backend, SharedCacheOptions{})))
+	guest := NewMenousDB(srv.URL, "guest", "db", WithSharedCache(NewSharedCache(backend, SharedCacheOptions{})))
+
+	if _, err := admin.GetTable("t"); err != nil {
+		t.Fatalf("admin GetTable: %v", err)
+	}
+	var entries int
+	for k := range backend.data {
+		if strings.Contains(k, "entry:") {
+			entries++
+		}
+	}
+	if entries != 1 {
+		t.Fatalf("backend holds %d entries after one read, want 1", entries)
+	}
+	if rows, err := guest.GetTable("t"); err == nil {
+		t.Fatalf("guest GetTable served %v from the admin's shared entry", rows)
+	}
+}
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Shell is an interactive prompt over a client, speaking a small SQL-like
+// language:
+//
+//	use shop;
+//	show tables;
+//	select name, age from users where age > 30 and city = 'Lyon' order by age desc limit 10;
+//	select count(*) from users where email like '%@example.com';
+//	insert into users (name, age) values ('Ada', 36), ('Alan', 41);
+//	insert into users {"name": "Grace", "age": 45};
+//	update users set age = 37 where name = 'Ada';
+//	delete from users where name = 'Alan';
+//
+// Statements end with a semicolon and may span lines. Equality conditions
+// and-ed at the top of a where clause are sent to the server and the rest
+// of the clause is evaluated client-side; update and delete accept only
+// equality conditions, since the server applies them.
+//
+// Lines starting with a backslash are shell commands; \? lists them.
+// Executed statements are kept in HistoryPath and can be rerun with !n or
+// !!. Line editing comes from the terminal, or from rlwrap when it wraps the
+// shell; \e edits the current statement in $EDITOR.
+type Shell struct {
+	Client *MenousDB
+	In     io.Reader
+	Out    io.Writer
+
+	// Interactive prints prompts and keeps going after errors; otherwise the
+	// first failing statement ends Run with its error
+	Interactive bool
+
+	// HistoryPath, if set, names the file history is loaded from and
+	// appended to
+	HistoryPath string
+
+	// JSON prints results as JSON instead of aligned columns
+	JSON bool
+
+	// Timing prints how long each statement took
+	Timing bool
+
+	history []string
+	buf     []string
+}
+
+// maxShellHistory bounds the history kept in memory and on disk
+const maxShellHistory = 1000
+
+// errShellQuit ends Run without an error
+var errShellQuit = errors.New("quit")
+
+// Run reads and executes statements until the input ends or \q
+func (s *Shell) Run() error {
+	s.loadHistory()
+	if s.Interactive {
+		fmt.Fprintln(s.Out, `menousdb shell; end statements with ";", type help or \? for help, \q to quit`)
+	}
+	sc := bufio.NewScanner(s.In)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for {
+		s.prompt()
+		if !sc.Scan() {
+			if s.Interactive {
+				fmt.Fprintln(s.Out)
+			}
+			if rest := strings.TrimSpace(strings.Join(s.buf, "\n")); rest != "" {
+				s.buf = nil
+				return s.run(rest)
+			}
+			return sc.Err()
+		}
+		err := s.line(sc.Text())
+		if errors.Is(err, errShellQuit) {
+			return nil
+		}
+		if err != nil {
+			if !s.Interactive {
+				return err
+			}
+			fmt.Fprintln(s.Out, "error:", err)
+		}
+	}
+}
+
+// prompt prints the prompt, showing the database and whether a statement
+// is being continued
+func (s *Shell) prompt() {
+	if !s.Interactive {
+		return
+	}
+	db := s.Client.Database
+	if db == "" {
+		db = "menousdb"
+	}
+	if len(s.buf) > 0 {
+		fmt.Fprintf(s.Out, "%s-> ", db)
+		return
+	}
+	fmt.Fprintf(s.Out, "%s=> ", db)
+}
+
+// line handles one line of input
+func (s *Shell) line(line string) error {
+	trimmed := strings.TrimSpace(line)
+	if len(s.buf) == 0 {
+		switch {
+		case trimmed == "":
+			return nil
+		case strings.HasPrefix(trimmed, `\`):
+			return s.command(trimmed)
+		case trimmed == "!!" || strings.HasPrefix(trimmed, "!") && len(trimmed) > 1:
+			stmt, err := s.recall(trimmed[1:])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(s.Out, stmt)
+			return s.run(stmt)
+		}
+		switch strings.ToLower(strings.TrimSuffix(trimmed, ";")) {
+		case "help":
+			return s.command(`\h`)
+		case "exit", "quit":
+			return errShellQuit
+		}
+	}
+	s.buf = append(s.buf, line)
+	if !strings.HasSuffix(trimmed, ";") {
+		return nil
+	}
+	stmt := strings.TrimSpace(strings.Join(s.buf, "\n"))
+	s.buf = nil
+	return s.run(stmt)
+}
+
+// run records and executes a complete statement
+func (s *Shell) run(stmt string) error {
+	s.remember(stmt)
+	start := time.Now()
+	err := s.Exec(stmt)
+	if s.Timing && err == nil {
+		fmt.Fprintf(s.Out, "Time: %s\n", time.Since(start).Round(time.Microsecond))
+	}
+	return err
+}
+
+// command runs a backslash command
+func (s *Shell) command(line string) error {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case `\q`:
+		return errShellQuit
+	case `\?`, `\h`:
+		fmt.Fprint(s.Out, shellHelp)
+	case `\c`:
+		if len(fields) != 2 {
+			return fmt.Errorf(`\c takes a database name`)
+		}
+		return s.Exec("use " + fields[1])
+	case `\d`:
+		if len(fields) == 1 {
+			return s.Exec("show tables")
+		}
+		return s.Exec("describe " + fields[1])
+	case `\l`:
+		return s.Exec("show databases")
+	case `\json`:
+		s.JSON = !s.JSON
+		fmt.Fprintf(s.Out, "JSON output is %s\n", onOff(s.JSON))
+	case `\timing`:
+		s.Timing = !s.Timing
+		fmt.Fprintf(s.Out, "Timing is %s\n", onOff(s.Timing))
+	case `\history`, `\s`:
+		for i, h := range s.history {
+			fmt.Fprintf(s.Out, "%5d  %s\n", i+1, h)
+		}
+	case `\r`:
+		s.buf = nil
+		fmt.Fprintln(s.Out, "Statement buffer reset")
+	case `\e`:
+		return s.edit()
+	default:
+		return fmt.Errorf(`unknown command %s; \? lists commands`, fields[0])
+	}
+	return nil
+}
+
+// edit opens the current statement, or the last one run, in $EDITOR and
+// runs the result when it ends with a semicolon, keeping it as the current
+// statement otherwise
+func (s *Shell) edit() error {
+	text := strings.Join(s.buf, "\n")
+	if text == "" && len(s.history) > 0 {
+		text = s.history[len(s.history)-1]
+	}
+	f, err := os.CreateTemp("", "menousdb-*.sql")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(text + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor: %w", err)
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return err
+	}
+
+	s.buf = nil
+	edited := strings.TrimSpace(string(data))
+	if edited == "" {
+		return nil
+	}
+	fmt.Fprintln(s.Out, edited)
+	if strings.HasSuffix(edited, ";") {
+		return s.run(edited)
+	}
+	s.buf = strings.Split(edited, "\n")
+	return nil
+}
+
+// recall returns the history entry !ref names: ! for the last, a number
+// for that entry, or a prefix for the latest entry starting with it
+func (s *Shell) recall(ref string) (string, error) {
+	if len(s.history) == 0 {
+		return "", fmt.Errorf("history is empty")
+	}
+	if ref == "!" {
+		return s.history[len(s.history)-1], nil
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 0 {
+			n += len(s.history) + 1
+		}
+		if n < 1 || n > len(s.history) {
+			return "", fmt.Errorf("no history entry %s", ref)
+		}
+		return s.history[n-1], nil
+	}
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if strings.HasPrefix(s.history[i], ref) {
+			return s.history[i], nil
+		}
+	}
+	return "", fmt.Errorf("no history entry starts with %q", ref)
+}
+
+// loadHistory reads HistoryPath; a missing or unreadable file starts an
+// empty history
+func (s *Shell) loadHistory() {
+	if s.HistoryPath == "" {
+		return
+	}
+	data, err := os.ReadFile(s.HistoryPath)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			s.history = append(s.history, strings.ReplaceAll(line, `\n`, "\n"))
+		}
+	}
+	if len(s.history) > maxShellHistory {
+		s.history = s.history[len(s.history)-maxShellHistory:]
+	}
+}
+
+// remember appends stmt to the history, skipping repeats of the last entry
+func (s *Shell) remember(stmt string) {
+	if n := len(s.history); n > 0 && s.history[n-1] == stmt {
+		return
+	}
+	s.history = append(s.history, stmt)
+	if len(s.history) > maxShellHistory {
+		s.history = s.history[len(s.history)-maxShellHistory:]
+	}
+	if s.HistoryPath == "" {
+		return
+	}
+	// Entries are one per line, with newlines inside statements escaped
+	f, err := os.OpenFile(s.HistoryPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, strings.ReplaceAll(stmt, "\n", `\n`))
+}
+
+// Exec parses and executes one statement, printing its result to Out
+func (s *Shell) Exec(stmt string) error {
+	st, err := parseShell(stmt)
+	if err != nil {
+		return err
+	}
+	m := s.Client
+	switch st.kind {
+	case "use":
+		s.Client = m.ForDatabase(st.name)
+		fmt.Fprintf(s.Out, "Using database %s\n", st.name)
+		return nil
+	case "show":
+		var names []string
+		if st.target == "databases" {
+			result, err := m.GetDatabases()
+			if err != nil {
+				return err
+			}
+			names = databaseNames(result)
+		} else if names, err = m.ListTables(); err != nil {
+			return err
+		}
+		for _, n := range names {
+			fmt.Fprintln(s.Out, n)
+		}
+		return nil
+	case "describe":
+		t, err := m.DescribeTable(st.name)
+		if err != nil {
+			return err
+		}
+		for _, c := range t.columnDefs() {
+			typ := string(c.Type)
+			if typ == "" {
+				typ = "any"
+			}
+			fmt.Fprintf(s.Out, "%s\t%s\n", c.Name, typ)
+		}
+		return nil
+	case "create":
+		if st.database {
+			return s.result(m.ForDatabase(st.name).CreateDB())
+		}
+		return s.result(m.CreateTable(st.name, st.columns))
+	case "drop":
+		if st.database {
+			return s.result(m.ForDatabase(st.name).DeleteDB())
+		}
+		return s.result(m.DeleteTable(st.name))
+	case "select", "count":
+		return s.query(st)
+	case "insert":
+		for i, r := range st.rows {
+			if _, err := m.InsertIntoTable(st.name, r); err != nil {
+				return fmt.Errorf("row %d: %w (%d inserted)", i+1, err, i)
+			}
+		}
+		fmt.Fprintf(s.Out, "INSERT %d\n", len(st.rows))
+		return nil
+	case "update", "delete":
+		conds, exact := st.where.equalities()
+		if !exact {
+			return fmt.Errorf("%s supports only column = value conditions joined by and", st.kind)
+		}
+		if len(conds) == 0 {
+			return fmt.Errorf("%s needs a where clause", st.kind)
+		}
+		if st.kind == "update" {
+			return s.result(m.UpdateWhere(st.name, conds, st.values))
+		}
+		return s.result(m.DeleteWhere(st.name, conds))
+	}
+	return fmt.Errorf("unsupported statement %s", st.kind)
+}
+
+// query runs a select or count
+func (s *Shell) query(st *shellStatement) error {
+	conds, exact := st.where.equalities()
+	// Client-side conditions and sorting may use columns the select does
+	// not return, so those fetch whole rows and project afterwards
+	columns := st.columns
+	if !exact || len(st.order) > 0 {
+		columns = nil
+	}
+	q := s.Client.Select(st.name, columns...).Where(conds)
+	if !exact {
+		q.Filter(st.where.predicate())
+	}
+	if st.kind == "count" {
+		n := 0
+		err := q.Each(func(Row) error {
+			n++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return s.print([]Row{{"count": n}}, []string{"count"})
+	}
+	if len(st.order) > 0 {
+		q.SortBy(OrderBy(st.order...))
+	}
+	if st.limit > 0 {
+		q.Limit(st.limit)
+	}
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	if columns == nil && st.columns != nil {
+		for i, r := range rows {
+			rows[i] = project(r, st.columns)
+		}
+	}
+	return s.print(rows, st.columns)
+}
+
+// result prints a write's server response
+func (s *Shell) result(v interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case string:
+		fmt.Fprintln(s.Out, v)
+	case nil:
+		fmt.Fprintln(s.Out, "OK")
+	default:
+		data, _ := json.Marshal(v)
+		fmt.Fprintln(s.Out, string(data))
+	}
+	return nil
+}
+
+// print writes rows as JSON lines or aligned columns. Without columns, the
+// union of the rows' keys is shown, sorted.
+func (s *Shell) print(rows []Row, columns []string) error {
+	if s.JSON {
+		enc := json.NewEncoder(s.Out)
+		for _, r := range rows {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(columns) == 0 {
+		columns = inferColumns(rows)
+	}
+	tw := tabwriter.NewWriter(s.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	rule := make([]string, len(columns))
+	for i, c := range columns {
+		rule[i] = strings.Repeat("-", len(c))
+	}
+	fmt.Fprintln(tw, strings.Join(rule, "\t"))
+	cells := make([]string, len(columns))
+	for _, r := range rows {
+		for i, c := range columns {
+			cells[i] = shellCell(r[c])
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(rows) == 1 {
+		fmt.Fprintln(s.Out, "(1 row)")
+	} else {
+		fmt.Fprintf(s.Out, "(%d rows)\n", len(rows))
+	}
+	return nil
+}
+
+// shellCell formats a value for a table cell on one line
+func shellCell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return strings.NewReplacer("\n", `\n`, "\t", `\t`).Replace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
+
+// onOff names a toggle's state
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+const shellHelp = `Statements, ended by ";":
+  use DB                                    switch database
+  show tables | show databases              list names
+  describe TABLE                            list a table's columns
+  create table TABLE (COL, ...)             create a table
+  create database DB | drop database DB
+  drop table TABLE
+  select COLS|*|count(*) from TABLE [where COND] [order by COL [asc|desc], ...] [limit N]
+  insert into TABLE (COL, ...) values (V, ...), ...
+  insert into TABLE {JSON object} | [JSON objects]
+  update TABLE set COL = V, ... where COL = V [and ...]
+  delete from TABLE where COL = V [and ...]
+
+Conditions: COL = != <> < <= > >= V, COL in (V, ...), COL like 'a%',
+COL contains 'x', COL is [not] null, combined with and, or, not and ( ).
+Values: numbers, 'strings', "strings", true, false, null.
+
+Commands:
+  \q              quit (also exit, quit, end of input)
+  \c DB           use DB
+  \d [TABLE]      show tables, or describe TABLE
+  \l              show databases
+  \json           toggle JSON output
+  \timing         toggle statement timing
+  \history, \s    list history; !N, !-N, !! or !PREFIX reruns an entry
+  \e              edit the current or last statement in $EDITOR
+  \r              reset the statement being typed
+  \?, \h, help    this help
+`
@@ -0,0 +1,677 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// shellStatement is one parsed statement of the shell's query language
+type shellStatement struct {
+	kind     string // use, show, describe, create, drop, select, count, insert, update, delete
+	target   string // tables or databases, for show
+	name     string
+	columns  []string
+	where    *shellCond
+	order    []Comparator
+	limit    int
+	rows     []Row
+	values   map[string]interface{}
+	database bool // create and drop name a database rather than a table
+}
+
+// shellCond is a where clause: a comparison, or and, or and not over
+// nested conditions
+type shellCond struct {
+	op     string // and, or, not, =, !=, <, <=, >, >=, in, like, contains, null, notnull
+	column string
+	value  interface{}
+	values []interface{}
+	terms  []*shellCond
+}
+
+// predicate returns the client-side predicate matching c
+func (c *shellCond) predicate() Predicate {
+	switch c.op {
+	case "and", "or":
+		ps := make([]Predicate, len(c.terms))
+		for i, t := range c.terms {
+			ps[i] = t.predicate()
+		}
+		if c.op == "and" {
+			return And(ps...)
+		}
+		return Or(ps...)
+	case "not":
+		return Not(c.terms[0].predicate())
+	case "=":
+		return Eq(c.column, c.value)
+	case "!=":
+		return Ne(c.column, c.value)
+	case "<":
+		return Lt(c.column, c.value)
+	case "<=":
+		return Lte(c.column, c.value)
+	case ">":
+		return Gt(c.column, c.value)
+	case ">=":
+		return Gte(c.column, c.value)
+	case "in":
+		return In(c.column, c.values...)
+	case "contains":
+		return Contains(c.column, fmt.Sprint(c.value))
+	case "like":
+		re := likePattern(fmt.Sprint(c.value))
+		column := c.column
+		return func(r Row) bool {
+			s, ok := r[column].(string)
+			return ok && re.MatchString(s)
+		}
+	case "null":
+		return IsNull(c.column)
+	case "notnull":
+		return Not(IsNull(c.column))
+	}
+	return func(Row) bool { return false }
+}
+
+// equalities returns the equality comparisons and-ed at the top of c, which
+// the server can evaluate, and whether they are the whole condition
+func (c *shellCond) equalities() (map[string]interface{}, bool) {
+	conds := map[string]interface{}{}
+	if c == nil {
+		return conds, true
+	}
+	terms := []*shellCond{c}
+	if c.op == "and" {
+		terms = c.terms
+	}
+	exact := true
+	for _, t := range terms {
+		if t.op != "=" {
+			exact = false
+			continue
+		}
+		if prev, dup := conds[t.column]; dup && compareValues(prev, t.value) != 0 {
+			exact = false
+			continue
+		}
+		conds[t.column] = t.value
+	}
+	return conds, exact
+}
+
+// likePattern compiles a SQL LIKE pattern, where % matches any run of
+// characters and _ any one
+func likePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^(?s)")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// shellToken is a lexical token of the query language
+type shellToken struct {
+	kind string // ident, number, string, punct, json, eof
+	text string
+	pos  int
+}
+
+// lexShell splits src into tokens
+func lexShell(src string) ([]shellToken, error) {
+	var toks []shellToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '-' && strings.HasPrefix(src[i:], "--"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '\'' || c == '"':
+			var b strings.Builder
+			j := i + 1
+			for {
+				if j >= len(src) {
+					return nil, fmt.Errorf("unterminated string at %d", i)
+				}
+				if src[j] == '\\' && j+1 < len(src) {
+					b.WriteByte(src[j+1])
+					j += 2
+					continue
+				}
+				if src[j] == c {
+					// A doubled quote is a literal quote
+					if j+1 < len(src) && src[j+1] == c {
+						b.WriteByte(c)
+						j += 2
+						continue
+					}
+					break
+				}
+				b.WriteByte(src[j])
+				j++
+			}
+			toks = append(toks, shellToken{"string", b.String(), i})
+			i = j + 1
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			j := i + 1
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.' || src[j] == 'e' || src[j] == 'E' ||
+				(src[j] == '-' || src[j] == '+') && (src[j-1] == 'e' || src[j-1] == 'E')) {
+				j++
+			}
+			toks = append(toks, shellToken{"number", src[i:j], i})
+			i = j
+		case c == '_' || c == '`' || unicode.IsLetter(rune(c)) || c >= 0x80:
+			if c == '`' {
+				j := strings.IndexByte(src[i+1:], '`')
+				if j < 0 {
+					return nil, fmt.Errorf("unterminated identifier at %d", i)
+				}
+				toks = append(toks, shellToken{"ident", src[i+1 : i+1+j], i})
+				i += j + 2
+				continue
+			}
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] == '.' || src[j] == '-' || src[j] >= '0' && src[j] <= '9' ||
+				unicode.IsLetter(rune(src[j])) || src[j] >= 0x80) {
+				j++
+			}
+			toks = append(toks, shellToken{"ident", src[i:j], i})
+			i = j
+		case c == '{' || c == '[':
+			// JSON runs to the end of the statement; only insert takes it
+			text := strings.TrimSpace(src[i:])
+			text = strings.TrimSpace(strings.TrimSuffix(text, ";"))
+			toks = append(toks, shellToken{"json", text, i})
+			i = len(src)
+		default:
+			for _, op := range []string{"!=", "<>", "<=", ">="} {
+				if strings.HasPrefix(src[i:], op) {
+					toks = append(toks, shellToken{"punct", op, i})
+					i += len(op)
+					goto next
+				}
+			}
+			if !strings.ContainsRune("()*,;=<>", rune(c)) {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			toks = append(toks, shellToken{"punct", string(c), i})
+			i++
+		next:
+		}
+	}
+	return append(toks, shellToken{"eof", "", len(src)}), nil
+}
+
+// shellParser parses one statement
+type shellParser struct {
+	toks []shellToken
+	pos  int
+}
+
+// parseShell parses a statement, with or without its trailing semicolon
+func parseShell(src string) (*shellStatement, error) {
+	toks, err := lexShell(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &shellParser{toks: toks}
+	st, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	p.accept(";")
+	if t := p.peek(); t.kind != "eof" {
+		return nil, fmt.Errorf("unexpected %q after statement", t.text)
+	}
+	return st, nil
+}
+
+func (p *shellParser) peek() shellToken { return p.toks[p.pos] }
+
+func (p *shellParser) next() shellToken {
+	t := p.toks[p.pos]
+	if t.kind != "eof" {
+		p.pos++
+	}
+	return t
+}
+
+// is reports whether t is the keyword or punctuation kw
+func (t shellToken) is(kw string) bool {
+	return (t.kind == "ident" || t.kind == "punct") && strings.EqualFold(t.text, kw)
+}
+
+// accept consumes the next token if it is kw
+func (p *shellParser) accept(kw string) bool {
+	if p.peek().is(kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect consumes kw or fails
+func (p *shellParser) expect(kw string) error {
+	if !p.accept(kw) {
+		return p.unexpected(kw)
+	}
+	return nil
+}
+
+// back un-reads t, the token next just returned
+func (p *shellParser) back(t shellToken) {
+	if t.kind != "eof" {
+		p.pos--
+	}
+}
+
+func (p *shellParser) unexpected(want string) error {
+	t := p.peek()
+	if t.kind == "eof" {
+		return fmt.Errorf("expected %s at end of statement", want)
+	}
+	return fmt.Errorf("expected %s, found %q", want, t.text)
+}
+
+// name reads an identifier; quoted strings are accepted for names the
+// lexer would split
+func (p *shellParser) name(what string) (string, error) {
+	t := p.peek()
+	if t.kind != "ident" && t.kind != "string" {
+		return "", p.unexpected(what)
+	}
+	p.pos++
+	return t.text, nil
+}
+
+// statement parses any statement
+func (p *shellParser) statement() (*shellStatement, error) {
+	t := p.next()
+	switch strings.ToLower(t.text) {
+	case "use":
+		db, err := p.name("database name")
+		return &shellStatement{kind: "use", name: db}, err
+	case "show":
+		what := strings.ToLower(p.next().text)
+		if what != "tables" && what != "databases" {
+			return nil, fmt.Errorf("show tables or show databases")
+		}
+		return &shellStatement{kind: "show", target: what}, nil
+	case "describe", "desc":
+		table, err := p.name("table name")
+		return &shellStatement{kind: "describe", name: table}, err
+	case "create", "drop":
+		return p.createDrop(strings.ToLower(t.text))
+	case "select":
+		return p.selectStmt()
+	case "insert":
+		return p.insert()
+	case "update":
+		return p.update()
+	case "delete":
+		return p.delete()
+	case "":
+		return nil, fmt.Errorf("empty statement")
+	}
+	return nil, fmt.Errorf("unknown statement %q; type help for the syntax", t.text)
+}
+
+// createDrop parses create and drop of tables and databases
+func (p *shellParser) createDrop(kind string) (*shellStatement, error) {
+	st := &shellStatement{kind: kind}
+	switch {
+	case p.accept("table"):
+	case p.accept("database"):
+		st.database = true
+	default:
+		return nil, p.unexpected("table or database")
+	}
+	name, err := p.name("name")
+	if err != nil {
+		return nil, err
+	}
+	st.name = name
+	if kind == "create" && !st.database {
+		if st.columns, err = p.nameList(); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// nameList parses (a, b, c)
+func (p *shellParser) nameList() ([]string, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var names []string
+	for {
+		n, err := p.name("column name")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+		if p.accept(")") {
+			return names, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// selectStmt parses select columns from table [where] [order by] [limit]
+func (p *shellParser) selectStmt() (*shellStatement, error) {
+	st := &shellStatement{kind: "select"}
+	switch {
+	case p.accept("*"):
+	case p.peek().is("count") && p.toks[p.pos+1].is("("):
+		p.pos += 2
+		if err := p.expect("*"); err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		st.kind = "count"
+	default:
+		for {
+			c, err := p.name("column name")
+			if err != nil {
+				return nil, err
+			}
+			st.columns = append(st.columns, c)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if err := p.expect("from"); err != nil {
+		return nil, err
+	}
+	table, err := p.name("table name")
+	if err != nil {
+		return nil, err
+	}
+	st.name = table
+	if err := p.whereClause(st); err != nil {
+		return nil, err
+	}
+	if p.accept("order") {
+		if err := p.expect("by"); err != nil {
+			return nil, err
+		}
+		for {
+			c, err := p.name("column name")
+			if err != nil {
+				return nil, err
+			}
+			if p.accept("desc") {
+				st.order = append(st.order, Desc(c))
+			} else {
+				p.accept("asc")
+				st.order = append(st.order, Asc(c))
+			}
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if p.accept("limit") {
+		t := p.next()
+		n, err := strconv.Atoi(t.text)
+		if t.kind != "number" || err != nil || n < 0 {
+			return nil, fmt.Errorf("limit takes a non-negative integer, found %q", t.text)
+		}
+		st.limit = n
+	}
+	return st, nil
+}
+
+// insert parses insert into table (a, b) values (1, 2), ... or
+// insert into table followed by a JSON object or array of objects
+func (p *shellParser) insert() (*shellStatement, error) {
+	if err := p.expect("into"); err != nil {
+		return nil, err
+	}
+	table, err := p.name("table name")
+	if err != nil {
+		return nil, err
+	}
+	st := &shellStatement{kind: "insert", name: table}
+
+	if t := p.peek(); t.kind == "json" {
+		p.pos++
+		text := t.text
+		if strings.HasPrefix(text, "{") {
+			text = "[" + text + "]"
+		}
+		if err := json.Unmarshal([]byte(text), &st.rows); err != nil {
+			return nil, fmt.Errorf("insert JSON: %w", err)
+		}
+		return st, nil
+	}
+
+	columns, err := p.nameList()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("values"); err != nil {
+		return nil, err
+	}
+	for {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		row := Row{}
+		for i := 0; ; i++ {
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if i >= len(columns) {
+				return nil, fmt.Errorf("more values than the %d columns", len(columns))
+			}
+			row[columns[i]] = v
+			if p.accept(")") {
+				if i+1 != len(columns) {
+					return nil, fmt.Errorf("%d values for %d columns", i+1, len(columns))
+				}
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		st.rows = append(st.rows, row)
+		if !p.accept(",") {
+			return st, nil
+		}
+	}
+}
+
+// update parses update table set a = 1, b = 2 where ...
+func (p *shellParser) update() (*shellStatement, error) {
+	table, err := p.name("table name")
+	if err != nil {
+		return nil, err
+	}
+	st := &shellStatement{kind: "update", name: table, values: map[string]interface{}{}}
+	if err := p.expect("set"); err != nil {
+		return nil, err
+	}
+	for {
+		c, err := p.name("column name")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		if st.values[c], err = p.value(); err != nil {
+			return nil, err
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	return st, p.whereClause(st)
+}
+
+// delete parses delete from table where ...
+func (p *shellParser) delete() (*shellStatement, error) {
+	if err := p.expect("from"); err != nil {
+		return nil, err
+	}
+	table, err := p.name("table name")
+	if err != nil {
+		return nil, err
+	}
+	st := &shellStatement{kind: "delete", name: table}
+	return st, p.whereClause(st)
+}
+
+// whereClause parses an optional where clause into st
+func (p *shellParser) whereClause(st *shellStatement) (err error) {
+	if p.accept("where") {
+		st.where, err = p.or()
+	}
+	return err
+}
+
+func (p *shellParser) or() (*shellCond, error) {
+	return p.chain("or", p.and)
+}
+
+func (p *shellParser) and() (*shellCond, error) {
+	return p.chain("and", p.unary)
+}
+
+// chain parses terms joined by op
+func (p *shellParser) chain(op string, term func() (*shellCond, error)) (*shellCond, error) {
+	c, err := term()
+	if err != nil {
+		return nil, err
+	}
+	if !p.peek().is(op) {
+		return c, nil
+	}
+	out := &shellCond{op: op, terms: []*shellCond{c}}
+	for p.accept(op) {
+		c, err := term()
+		if err != nil {
+			return nil, err
+		}
+		out.terms = append(out.terms, c)
+	}
+	return out, nil
+}
+
+func (p *shellParser) unary() (*shellCond, error) {
+	if p.accept("not") {
+		c, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &shellCond{op: "not", terms: []*shellCond{c}}, nil
+	}
+	if p.accept("(") {
+		c, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expect(")")
+	}
+	return p.comparison()
+}
+
+// comparison parses column op value, column in (...), column is [not] null,
+// and column like or contains a string
+func (p *shellParser) comparison() (*shellCond, error) {
+	column, err := p.name("column name")
+	if err != nil {
+		return nil, err
+	}
+	c := &shellCond{column: column}
+	t := p.next()
+	switch op := strings.ToLower(t.text); op {
+	case "=", "!=", "<>", "<", "<=", ">", ">=", "like", "contains":
+		if op == "<>" {
+			op = "!="
+		}
+		c.op = op
+		c.value, err = p.value()
+		return c, err
+	case "in":
+		c.op = "in"
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		for {
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			c.values = append(c.values, v)
+			if p.accept(")") {
+				return c, nil
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	case "is":
+		c.op = "null"
+		if p.accept("not") {
+			c.op = "notnull"
+		}
+		return c, p.expect("null")
+	}
+	p.back(t)
+	return nil, p.unexpected("comparison operator")
+}
+
+// value parses a literal: a number, string, true, false or null
+func (p *shellParser) value() (interface{}, error) {
+	t := p.next()
+	switch t.kind {
+	case "string":
+		return t.text, nil
+	case "number":
+		if n, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return n, nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return f, nil
+	case "ident":
+		switch strings.ToLower(t.text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+	p.back(t)
+	return nil, p.unexpected("value")
+}
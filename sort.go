@@ -0,0 +1,53 @@
+package main
+
+import "sort"
+
+// Comparator orders two rows, returning a negative number when a sorts
+// before b, zero when they tie and a positive number otherwise
+type Comparator func(a, b Row) int
+
+// Asc orders rows by column, nulls and missing values first, with the same
+// rules as the filter predicates
+func Asc(column string) Comparator {
+	return func(a, b Row) int { return compareValues(a[column], b[column]) }
+}
+
+// Desc orders rows by column, largest first and nulls last
+func Desc(column string) Comparator {
+	return func(a, b Row) int { return compareValues(b[column], a[column]) }
+}
+
+// OrderBy combines comparators into a less function: rows are ordered by the
+// first comparator, ties broken by the next, and so on
+//
+//	rows.SortBy(OrderBy(Asc("last_name"), Asc("first_name"), Desc("age")))
+func OrderBy(cmps ...Comparator) func(a, b Row) bool {
+	return func(a, b Row) bool {
+		for _, cmp := range cmps {
+			if c := cmp(a, b); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	}
+}
+
+// SortRows sorts rows by less, keeping rows that tie in their original
+// order
+func SortRows(rows []Row, less func(a, b Row) bool) {
+	sort.SliceStable(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+}
+
+// SortBy stably sorts the rows not yet read by less and returns r
+func (r *Rows) SortBy(less func(a, b Row) bool) *Rows {
+	SortRows(r.rows[r.pos:], less)
+	return r
+}
+
+// SortBy orders the results by less, stably. Sorting needs every matching
+// record, so they are collected before Each is called and Limit applies to
+// the sorted order.
+func (q *SelectQuery) SortBy(less func(a, b Row) bool) *SelectQuery {
+	q.less = less
+	return q
+}
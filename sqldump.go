@@ -0,0 +1,760 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// SQLImportOptions configures ImportSQLDump
+type SQLImportOptions struct {
+	// SkipCreate ignores CREATE TABLE statements, for loading rows into
+	// tables that already exist. Column lists are still learned from them.
+	SkipCreate bool
+
+	// Progress, if set, is called after every inserted row with the rows
+	// inserted so far; the total is unknown
+	Progress ProgressFunc
+}
+
+// SQLImportResult summarizes an ImportSQLDump run
+type SQLImportResult struct {
+	Tables  []string
+	Rows    int
+	Skipped int
+}
+
+// ImportSQLDump replays simple MySQL or Postgres dumps: CREATE TABLE becomes
+// CreateTable with the column names, and INSERT INTO ... VALUES and the data
+// of COPY ... FROM stdin become one InsertIntoTable per row. COPY values
+// arrive as strings, or nil for \N, since its text format carries no types.
+// Other statements (SET, LOCK, indexes) are counted as skipped. Backslash
+// escapes in strings follow MySQL unless the dump turns on
+// standard_conforming_strings, as pg_dump does; E'...' strings always
+// take them.
+func (m *MenousDB) ImportSQLDump(r io.Reader, opts SQLImportOptions) (SQLImportResult, error) {
+	var result SQLImportResult
+	reader := newStatementReader(r)
+	columns := make(map[string][]string)
+
+	for {
+		stmt, err := reader.next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+
+		tokens, err := lexSQL(stmt, reader.backslashEscapes)
+		if err != nil {
+			return result, fmt.Errorf("%w in %s", err, abbreviate(stmt))
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+
+		switch {
+		case tokens[0].is("CREATE") && len(tokens) > 1 && tokens[1].is("TABLE"):
+			table, cols, err := parseCreateTable(tokens)
+			if err != nil {
+				return result, fmt.Errorf("%w in %s", err, abbreviate(stmt))
+			}
+			columns[table] = cols
+			if !opts.SkipCreate {
+				if _, err := m.CreateTable(table, cols); err != nil {
+					return result, fmt.Errorf("creating %s: %w", table, err)
+				}
+			}
+			result.Tables = append(result.Tables, table)
+
+		case tokens[0].is("INSERT"):
+			table, rows, err := parseInsert(tokens, columns)
+			if err != nil {
+				return result, fmt.Errorf("%w in %s", err, abbreviate(stmt))
+			}
+			for _, row := range rows {
+				if _, err := m.InsertIntoTable(table, row); err != nil {
+					return result, fmt.Errorf("inserting into %s: %w", table, err)
+				}
+				result.Rows++
+				opts.Progress.report(result.Rows, -1)
+			}
+
+		case tokens[0].is("COPY") && isCopyFromStdin(tokens):
+			table, cols, err := parseCopy(tokens, columns)
+			if err != nil {
+				return result, fmt.Errorf("%w in %s", err, abbreviate(stmt))
+			}
+			lines, err := reader.copyData()
+			if err != nil {
+				return result, fmt.Errorf("%w for %s", err, abbreviate(stmt))
+			}
+			for _, line := range lines {
+				row, err := parseCopyRow(line, cols)
+				if err != nil {
+					return result, fmt.Errorf("%w in COPY data for %s", err, table)
+				}
+				if _, err := m.InsertIntoTable(table, row); err != nil {
+					return result, fmt.Errorf("inserting into %s: %w", table, err)
+				}
+				result.Rows++
+				opts.Progress.report(result.Rows, -1)
+			}
+
+		case tokens[0].is("SET") && isStandardStringsOn(tokens):
+			reader.backslashEscapes = false
+			result.Skipped++
+
+		default:
+			result.Skipped++
+		}
+	}
+}
+
+// abbreviate shortens a statement for error messages
+func abbreviate(stmt string) string {
+	stmt = strings.Join(strings.Fields(stmt), " ")
+	if len(stmt) > 60 {
+		return stmt[:57] + "..."
+	}
+	return stmt
+}
+
+// isStandardStringsOn matches SET standard_conforming_strings = on
+func isStandardStringsOn(tokens []sqlToken) bool {
+	return len(tokens) >= 4 && tokens[1].is("standard_conforming_strings") &&
+		(tokens[2].text == "=" || tokens[2].is("TO")) && tokens[3].is("on")
+}
+
+// isCopyFromStdin matches COPY ... FROM stdin, whose data follows the
+// statement in the dump
+func isCopyFromStdin(tokens []sqlToken) bool {
+	for i := 1; i+1 < len(tokens); i++ {
+		if tokens[i].is("FROM") {
+			return tokens[i+1].is("stdin")
+		}
+	}
+	return false
+}
+
+// statementReader splits a dump into statements at top-level semicolons,
+// dropping comments
+type statementReader struct {
+	r                *bufio.Reader
+	backslashEscapes bool
+}
+
+// newStatementReader reads statements from r
+func newStatementReader(r io.Reader) *statementReader {
+	return &statementReader{r: bufio.NewReader(r), backslashEscapes: true}
+}
+
+// next returns the next statement without its terminating semicolon
+func (s *statementReader) next() (string, error) {
+	var b strings.Builder
+	var quote rune
+	var escapes bool
+	for {
+		c, _, err := s.r.ReadRune()
+		if err == io.EOF {
+			if quote != 0 {
+				return "", errors.New("unterminated quoted text at end of dump")
+			}
+			if strings.TrimSpace(b.String()) == "" {
+				return "", io.EOF
+			}
+			return b.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if quote != 0 {
+			b.WriteRune(c)
+			switch {
+			case c == '\\' && quote == '\'' && escapes:
+				escaped, _, err := s.r.ReadRune()
+				if err != nil {
+					return "", errors.New("unterminated string at end of dump")
+				}
+				b.WriteRune(escaped)
+			case c == quote:
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+			escapes = s.backslashEscapes || isEscapePrefix(b.String())
+			b.WriteRune(c)
+		case ';':
+			if strings.TrimSpace(b.String()) != "" {
+				return b.String(), nil
+			}
+			b.Reset()
+		case '-':
+			if peek, _ := s.r.Peek(1); len(peek) == 1 && peek[0] == '-' {
+				s.skipLine()
+				b.WriteByte(' ')
+				continue
+			}
+			b.WriteRune(c)
+		case '#':
+			s.skipLine()
+			b.WriteByte(' ')
+		case '/':
+			if peek, _ := s.r.Peek(1); len(peek) == 1 && peek[0] == '*' {
+				s.r.ReadRune()
+				if err := s.skipBlockComment(); err != nil {
+					return "", err
+				}
+				b.WriteByte(' ')
+				continue
+			}
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+}
+
+// copyData reads the data lines of a COPY ... FROM stdin up to its \.
+// terminator, starting after the statement's semicolon
+func (s *statementReader) copyData() ([]string, error) {
+	// The rest of the COPY statement's line
+	if _, err := s.r.ReadString('\n'); err != nil {
+		return nil, errors.New("missing COPY data")
+	}
+	var lines []string
+	for {
+		line, err := s.r.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line == `\.` {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, errors.New("unterminated COPY data")
+		}
+		lines = append(lines, line)
+	}
+}
+
+// isEscapePrefix reports whether text ends in the E of an E'...' string
+func isEscapePrefix(text string) bool {
+	n := len(text)
+	if n == 0 || (text[n-1] != 'E' && text[n-1] != 'e') {
+		return false
+	}
+	if n == 1 {
+		return true
+	}
+	c := rune(text[n-2])
+	return !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '$'
+}
+
+// skipLine discards input up to the end of the line
+func (s *statementReader) skipLine() {
+	s.r.ReadString('\n')
+}
+
+// skipBlockComment discards input up to the closing */. MySQL conditional
+// comments (/*!40101 ... */) are dropped too; they only carry settings.
+func (s *statementReader) skipBlockComment() error {
+	prev := rune(0)
+	for {
+		c, _, err := s.r.ReadRune()
+		if err != nil {
+			return errors.New("unterminated comment at end of dump")
+		}
+		if prev == '*' && c == '/' {
+			return nil
+		}
+		prev = c
+	}
+}
+
+// sqlTokenKind classifies lexed tokens
+type sqlTokenKind int
+
+const (
+	sqlIdent sqlTokenKind = iota
+	sqlQuotedIdent
+	sqlString
+	sqlNumber
+	sqlPunct
+)
+
+// sqlToken is one lexical token of a statement
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+}
+
+// is reports whether t is the bare word w, ignoring case
+func (t sqlToken) is(w string) bool {
+	return t.kind == sqlIdent && strings.EqualFold(t.text, w)
+}
+
+// lexSQL tokenizes a statement
+func lexSQL(stmt string, backslashEscapes bool) ([]sqlToken, error) {
+	var tokens []sqlToken
+	runes := []rune(stmt)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '\'':
+			// E'...' right after the E takes backslash escapes
+			prefix := len(tokens) > 0 && tokens[len(tokens)-1].is("E") && (runes[i-1] == 'E' || runes[i-1] == 'e')
+			text, end, err := lexString(runes, i, backslashEscapes || prefix)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, sqlToken{sqlString, text})
+			i = end
+
+		case c == '"' || c == '`':
+			end := i + 1
+			var b strings.Builder
+			for {
+				if end >= len(runes) {
+					return nil, errors.New("unterminated quoted identifier")
+				}
+				if runes[end] == c {
+					if end+1 < len(runes) && runes[end+1] == c {
+						b.WriteRune(c)
+						end += 2
+						continue
+					}
+					break
+				}
+				b.WriteRune(runes[end])
+				end++
+			}
+			tokens = append(tokens, sqlToken{sqlQuotedIdent, b.String()})
+			i = end + 1
+
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E' ||
+				((runes[i] == '-' || runes[i] == '+') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{sqlNumber, string(runes[start:i])})
+
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{sqlIdent, string(runes[start:i])})
+
+		default:
+			tokens = append(tokens, sqlToken{sqlPunct, string(c)})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// lexString reads the quoted string starting at runes[i], returning its
+// text and the index after the closing quote
+func lexString(runes []rune, i int, backslashEscapes bool) (string, int, error) {
+	var b strings.Builder
+	i++
+	for {
+		if i >= len(runes) {
+			return "", 0, errors.New("unterminated string")
+		}
+		c := runes[i]
+		if c == '\\' && backslashEscapes && i+1 < len(runes) {
+			b.WriteRune(unescapeMySQL(runes[i+1]))
+			i += 2
+			continue
+		}
+		if c == '\'' {
+			if i+1 < len(runes) && runes[i+1] == '\'' {
+				b.WriteRune('\'')
+				i += 2
+				continue
+			}
+			return b.String(), i + 1, nil
+		}
+		b.WriteRune(c)
+		i++
+	}
+}
+
+// unescapeMySQL maps the character after a backslash to its value
+func unescapeMySQL(c rune) rune {
+	switch c {
+	case '0':
+		return 0
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'Z':
+		return 0x1a
+	case 'b':
+		return '\b'
+	}
+	return c
+}
+
+// sqlParser walks a token slice
+type sqlParser struct {
+	tokens []sqlToken
+	pos    int
+}
+
+// peek returns the current token, or a zero token at the end
+func (p *sqlParser) peek() sqlToken {
+	if p.pos >= len(p.tokens) {
+		return sqlToken{kind: sqlPunct}
+	}
+	return p.tokens[p.pos]
+}
+
+// take consumes and returns the current token
+func (p *sqlParser) take() sqlToken {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// punct consumes the punctuation s if present
+func (p *sqlParser) punct(s string) bool {
+	if t := p.peek(); t.kind == sqlPunct && t.text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// word consumes the bare word w if present
+func (p *sqlParser) word(w string) bool {
+	if p.peek().is(w) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// name parses a possibly schema-qualified name, returning its last part
+func (p *sqlParser) name() (string, error) {
+	t := p.take()
+	if t.kind != sqlIdent && t.kind != sqlQuotedIdent {
+		return "", fmt.Errorf("expected a name, found %q", t.text)
+	}
+	for p.punct(".") {
+		t = p.take()
+		if t.kind != sqlIdent && t.kind != sqlQuotedIdent {
+			return "", fmt.Errorf("expected a name after '.', found %q", t.text)
+		}
+	}
+	return t.text, nil
+}
+
+// skipGroup skips tokens up to the next top-level ',' or the ')' closing
+// the current list, leaving it unconsumed
+func (p *sqlParser) skipGroup() {
+	depth := 0
+	for p.pos < len(p.tokens) {
+		t := p.peek()
+		if t.kind == sqlPunct {
+			switch t.text {
+			case "(":
+				depth++
+			case ")":
+				if depth == 0 {
+					return
+				}
+				depth--
+			case ",":
+				if depth == 0 {
+					return
+				}
+			}
+		}
+		p.pos++
+	}
+}
+
+// tableConstraintWords start table-level definitions that are not columns
+var tableConstraintWords = []string{
+	"PRIMARY", "KEY", "UNIQUE", "CONSTRAINT", "INDEX", "FOREIGN", "CHECK", "FULLTEXT", "SPATIAL", "EXCLUDE",
+}
+
+// parseCreateTable extracts the table name and column names
+func parseCreateTable(tokens []sqlToken) (string, []string, error) {
+	p := &sqlParser{tokens: tokens, pos: 2}
+	if p.word("IF") {
+		if !p.word("NOT") || !p.word("EXISTS") {
+			return "", nil, errors.New("malformed IF NOT EXISTS")
+		}
+	}
+	table, err := p.name()
+	if err != nil {
+		return "", nil, err
+	}
+	if !p.punct("(") {
+		return "", nil, errors.New("expected column list")
+	}
+
+	var columns []string
+	for {
+		t := p.peek()
+		isConstraint := false
+		for _, w := range tableConstraintWords {
+			if t.is(w) {
+				isConstraint = true
+				break
+			}
+		}
+		if !isConstraint {
+			if t.kind != sqlIdent && t.kind != sqlQuotedIdent {
+				return "", nil, fmt.Errorf("expected a column name, found %q", t.text)
+			}
+			columns = append(columns, t.text)
+		}
+		p.skipGroup()
+		if p.punct(",") {
+			continue
+		}
+		if p.punct(")") {
+			return table, columns, nil
+		}
+		return "", nil, errors.New("unterminated column list")
+	}
+}
+
+// parseInsert extracts the target table and rows of an INSERT statement
+func parseInsert(tokens []sqlToken, known map[string][]string) (string, []Row, error) {
+	p := &sqlParser{tokens: tokens, pos: 1}
+	p.word("IGNORE")
+	if !p.word("INTO") {
+		return "", nil, errors.New("expected INTO")
+	}
+	table, err := p.name()
+	if err != nil {
+		return "", nil, err
+	}
+
+	columns := known[table]
+	if p.punct("(") {
+		columns = nil
+		for {
+			name, err := p.name()
+			if err != nil {
+				return "", nil, err
+			}
+			columns = append(columns, name)
+			if p.punct(")") {
+				break
+			}
+			if !p.punct(",") {
+				return "", nil, errors.New("malformed column list")
+			}
+		}
+	}
+	if len(columns) == 0 {
+		return "", nil, fmt.Errorf("no column list for %s and no CREATE TABLE seen", table)
+	}
+	if !p.word("VALUES") {
+		return "", nil, errors.New("only INSERT ... VALUES is supported")
+	}
+
+	var rows []Row
+	for {
+		if !p.punct("(") {
+			return "", nil, errors.New("expected '(' before values")
+		}
+		row := make(Row, len(columns))
+		for i := 0; ; i++ {
+			v, err := p.value()
+			if err != nil {
+				return "", nil, err
+			}
+			if i >= len(columns) {
+				return "", nil, fmt.Errorf("more values than the %d columns of %s", len(columns), table)
+			}
+			row[columns[i]] = v
+			if p.punct(")") {
+				if i+1 != len(columns) {
+					return "", nil, fmt.Errorf("%d values for the %d columns of %s", i+1, len(columns), table)
+				}
+				break
+			}
+			if !p.punct(",") {
+				return "", nil, errors.New("malformed values list")
+			}
+		}
+		rows = append(rows, row)
+		if !p.punct(",") {
+			// Trailing ON DUPLICATE KEY / ON CONFLICT / RETURNING clauses are ignored
+			return table, rows, nil
+		}
+	}
+}
+
+// parseCopy extracts the target table and columns of a COPY ... FROM stdin
+func parseCopy(tokens []sqlToken, known map[string][]string) (string, []string, error) {
+	p := &sqlParser{tokens: tokens, pos: 1}
+	table, err := p.name()
+	if err != nil {
+		return "", nil, err
+	}
+	columns := known[table]
+	if p.punct("(") {
+		columns = nil
+		for {
+			name, err := p.name()
+			if err != nil {
+				return "", nil, err
+			}
+			columns = append(columns, name)
+			if p.punct(")") {
+				break
+			}
+			if !p.punct(",") {
+				return "", nil, errors.New("malformed column list")
+			}
+		}
+	}
+	if len(columns) == 0 {
+		return "", nil, fmt.Errorf("no column list for %s and no CREATE TABLE seen", table)
+	}
+	return table, columns, nil
+}
+
+// parseCopyRow decodes one line of COPY text format: tab-separated values
+// with backslash escapes, and \N for NULL
+func parseCopyRow(line string, columns []string) (Row, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != len(columns) {
+		return nil, fmt.Errorf("%d values for %d columns", len(fields), len(columns))
+	}
+	row := make(Row, len(columns))
+	for i, field := range fields {
+		if field == `\N` {
+			row[columns[i]] = nil
+			continue
+		}
+		row[columns[i]] = unescapeCopy(field)
+	}
+	return row, nil
+}
+
+// unescapeCopy decodes the backslash escapes of a COPY text value
+func unescapeCopy(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c != '\\' || i+1 == len(field) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = field[i]; c {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case 'x':
+			n, j := 0, i+1
+			for ; j < len(field) && j <= i+2 && isHexDigit(field[j]); j++ {
+				n = n*16 + hexValue(field[j])
+			}
+			if j == i+1 {
+				b.WriteByte('x')
+				continue
+			}
+			b.WriteByte(byte(n))
+			i = j - 1
+		default:
+			if c >= '0' && c <= '7' {
+				n, j := 0, i
+				for ; j < len(field) && j < i+3 && field[j] >= '0' && field[j] <= '7'; j++ {
+					n = n*8 + int(field[j]-'0')
+				}
+				b.WriteByte(byte(n))
+				i = j - 1
+				continue
+			}
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// isHexDigit reports whether c is a hexadecimal digit
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// hexValue returns the value of hexadecimal digit c
+func hexValue(c byte) int {
+	switch {
+	case c >= 'a':
+		return int(c-'a') + 10
+	case c >= 'A':
+		return int(c-'A') + 10
+	}
+	return int(c - '0')
+}
+
+// value parses one literal
+func (p *sqlParser) value() (interface{}, error) {
+	negative := false
+	if p.punct("-") {
+		negative = true
+	} else {
+		p.punct("+")
+	}
+
+	t := p.take()
+	switch {
+	case t.kind == sqlNumber:
+		if negative {
+			return json.Number("-" + t.text), nil
+		}
+		return json.Number(t.text), nil
+	case negative:
+		return nil, fmt.Errorf("expected a number after '-', found %q", t.text)
+	case t.kind == sqlString:
+		return t.text, nil
+	case t.is("NULL"):
+		return nil, nil
+	case t.is("TRUE"):
+		return true, nil
+	case t.is("FALSE"):
+		return false, nil
+	case t.kind == sqlIdent && p.peek().kind == sqlString:
+		// Typed or prefixed literals such as E'..', N'..' or DATE '..'
+		return p.take().text, nil
+	}
+	return nil, fmt.Errorf("unsupported value %q", t.text)
+}
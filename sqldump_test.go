@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestImportSQLDumpPostgres(t *testing.T) {
+	var inserted []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/insert-into-table" {
+			var body struct {
+				Values map[string]interface{} `json:"values"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decoding insert: %v", err)
+			}
+			inserted = append(inserted, body.Values)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	f, err := os.Open("testdata/pg_dump.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m := NewMenousDB(srv.URL, "key", "db")
+	result, err := m.ImportSQLDump(f, SQLImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportSQLDump: %v", err)
+	}
+	if !reflect.DeepEqual(result.Tables, []string{"users"}) || result.Rows != 4 {
+		t.Fatalf("result = %+v, want table users and 4 rows", result)
+	}
+
+	want := []map[string]interface{}{
+		{"id": "1", "name": "Ada", "bio": "likes C:\\path and\ttabs"},
+		{"id": "2", "name": "Bob", "bio": nil},
+		{"id": "3", "name": "O'Brien; Ltd", "bio": "line one\nline two"},
+		{"id": float64(4), "name": `C:\dir`, "bio": "it's\n"},
+	}
+	if !reflect.DeepEqual(inserted, want) {
+		t.Fatalf("inserted\n%v\nwant\n%v", inserted, want)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// sqliteDrivers are the driver names registered by the common SQLite
+// packages: mattn/go-sqlite3 and modernc.org/sqlite
+var sqliteDrivers = []string{"sqlite3", "sqlite"}
+
+// ExportToSQLite materializes every table of the database into a SQLite
+// file at path, creating it if needed. The program must import a SQLite
+// database/sql driver (github.com/mattn/go-sqlite3 or modernc.org/sqlite).
+func (m *MenousDB) ExportToSQLite(path string) error {
+	driver := ""
+	for _, name := range sqliteDrivers {
+		for _, registered := range sql.Drivers() {
+			if registered == name {
+				driver = name
+				break
+			}
+		}
+		if driver != "" {
+			break
+		}
+	}
+	if driver == "" {
+		return fmt.Errorf("no SQLite driver registered; import github.com/mattn/go-sqlite3 or modernc.org/sqlite")
+	}
+
+	db, err := sql.Open(driver, path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return m.ExportToSQL(db)
+}
+
+// SQLDialect selects the identifier quoting, placeholders and column types
+// ExportToSQLDialect writes
+type SQLDialect int
+
+const (
+	// SQLDialectSQLite quotes with "" and uses ? placeholders
+	SQLDialectSQLite SQLDialect = iota
+	// SQLDialectPostgres quotes with "" and uses $1, $2, ... placeholders
+	SQLDialectPostgres
+	// SQLDialectMySQL quotes with `` and uses ? placeholders
+	SQLDialectMySQL
+)
+
+// placeholder returns the bind parameter for the i'th argument, from 0
+func (d SQLDialect) placeholder(i int) string {
+	if d == SQLDialectPostgres {
+		return fmt.Sprintf("$%d", i+1)
+	}
+	return "?"
+}
+
+// quote quotes an identifier
+func (d SQLDialect) quote(name string) string {
+	if d == SQLDialectMySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return quoteSQLIdent(name)
+}
+
+// columnType maps an inferred SQLite column type to the dialect's
+func (d SQLDialect) columnType(sqliteType string) string {
+	switch d {
+	case SQLDialectPostgres:
+		switch sqliteType {
+		case "INTEGER":
+			return "BIGINT"
+		case "REAL":
+			return "DOUBLE PRECISION"
+		}
+	case SQLDialectMySQL:
+		switch sqliteType {
+		case "INTEGER":
+			return "BIGINT"
+		case "REAL":
+			return "DOUBLE"
+		case "TEXT":
+			return "LONGTEXT"
+		}
+	}
+	return sqliteType
+}
+
+// ExportToSQL writes every table of the database into db, a SQLite database,
+// creating one SQL table per MenousDB table with column types inferred from
+// the data. A table of the same name already in db is dropped first, so
+// exporting again replaces the data rather than appending to it. Nested
+// objects and arrays, and every value in a column of mixed types, are stored
+// as JSON text. Use ExportToSQLDialect for other databases.
+func (m *MenousDB) ExportToSQL(db *sql.DB) error {
+	return m.ExportToSQLDialect(db, SQLDialectSQLite)
+}
+
+// ExportToSQLDialect is ExportToSQL for a database speaking dialect.
+// Booleans are stored as 0 and 1 in every dialect unless their column holds
+// mixed types.
+func (m *MenousDB) ExportToSQLDialect(db *sql.DB, dialect SQLDialect) error {
+	tables, err := m.ListTables()
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		rows, err := m.GetTableRows(table)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", table, err)
+		}
+		if err := exportTableSQL(db, dialect, table, rows); err != nil {
+			return fmt.Errorf("exporting %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// exportTableSQL recreates table in db and loads rows in one transaction
+func exportTableSQL(db *sql.DB, dialect SQLDialect, table string, rows []Row) error {
+	columns := inferColumns(rows)
+	if len(columns) == 0 {
+		return nil
+	}
+
+	types := make([]string, len(columns))
+	defs := make([]string, len(columns))
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, c := range columns {
+		types[i] = inferSQLType(rows, c)
+		quoted[i] = dialect.quote(c)
+		defs[i] = quoted[i] + " " + dialect.columnType(types[i])
+		placeholders[i] = dialect.placeholder(i)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DROP TABLE IF EXISTS " + dialect.quote(table)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", dialect.quote(table), strings.Join(defs, ", "))); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		dialect.quote(table), strings.Join(quoted, ", "), strings.Join(placeholders, ", ")))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	args := make([]interface{}, len(columns))
+	for _, row := range rows {
+		for i, c := range columns {
+			v, err := sqlValue(row[c], types[i])
+			if err != nil {
+				return err
+			}
+			args[i] = v
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// quoteSQLIdent quotes an identifier for SQL
+func quoteSQLIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// inferSQLType picks a SQLite column type from the values in column
+func inferSQLType(rows []Row, column string) string {
+	sqlType := ""
+	for _, row := range rows {
+		var t string
+		switch v := row[column].(type) {
+		case nil:
+			continue
+		case bool:
+			t = "INTEGER"
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				t = "INTEGER"
+			} else {
+				t = "REAL"
+			}
+		default:
+			t = "TEXT"
+		}
+		switch {
+		case sqlType == "":
+			sqlType = t
+		case sqlType == t:
+		case sqlType == "INTEGER" && t == "REAL", sqlType == "REAL" && t == "INTEGER":
+			sqlType = "REAL"
+		default:
+			return "TEXT"
+		}
+	}
+	if sqlType == "" {
+		return "TEXT"
+	}
+	return sqlType
+}
+
+// sqlValue converts a decoded JSON value into a database/sql argument for a
+// column of sqlType, as inferSQLType chose it. Values in TEXT columns other
+// than strings are bound as their JSON text.
+func sqlValue(v interface{}, sqlType string) (interface{}, error) {
+	switch x := v.(type) {
+	case nil, string:
+		return x, nil
+	case bool:
+		if sqlType == "TEXT" {
+			break
+		}
+		if x {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case float64:
+		switch sqlType {
+		case "REAL":
+			return x, nil
+		case "INTEGER":
+			return int64(x), nil
+		}
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// StatsOptions configures ColumnStats
+type StatsOptions struct {
+	// Conditions restricts the rows considered; nil considers every row
+	Conditions map[string]interface{}
+
+	// Buckets, if positive, adds an equal-width histogram of the column's
+	// numeric values with that many buckets
+	Buckets int
+}
+
+// ColumnSummary describes the values of one column
+type ColumnSummary struct {
+	Column string
+	// Rows is the number of rows considered, Nulls those where the column
+	// is null or missing
+	Rows  int
+	Nulls int
+	// Distinct is the number of distinct non-null values
+	Distinct int
+	// Min and Max are the extremes, ordered as the filter predicates order
+	// values; nil when every value is null
+	Min, Max interface{}
+	// Numeric counts the numeric values and Mean is their average
+	Numeric int
+	Mean    float64
+	// Histogram buckets the numeric values when StatsOptions.Buckets is set
+	Histogram []HistogramBucket
+}
+
+// HistogramBucket counts the values in [Lower, Upper); the last bucket
+// includes its upper bound
+type HistogramBucket struct {
+	Lower, Upper float64
+	Count        int
+}
+
+// ColumnStats summarizes column over the rows of table in one streaming
+// pass, for data-quality checks and dashboards. Memory grows with the
+// number of distinct values, which the exact cardinality needs, not with
+// the number of rows.
+func (m *MenousDB) ColumnStats(table, column string, opts StatsOptions) (*ColumnSummary, error) {
+	s := &ColumnSummary{Column: column}
+	distinct := make(map[string]bool)
+	numbers := make(map[float64]int)
+	sum := 0.0
+
+	err := m.streamSelect("ColumnStats", table, nil, opts.Conditions, false, m.columnDecoder(table, func(r Row) error {
+		s.Rows++
+		v := r[column]
+		if v == nil {
+			s.Nulls++
+			return nil
+		}
+		if s.Min == nil || compareValues(v, s.Min) < 0 {
+			s.Min = v
+		}
+		if s.Max == nil || compareValues(v, s.Max) > 0 {
+			s.Max = v
+		}
+		if kindRank(v) == kindRank(0.0) {
+			if f, err := toFloat(v); err == nil {
+				s.Numeric++
+				sum += f
+				numbers[f]++
+			}
+		}
+		distinct[valueKey(v)] = true
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	s.Distinct = len(distinct)
+	if s.Numeric > 0 {
+		s.Mean = sum / float64(s.Numeric)
+	}
+	if opts.Buckets > 0 && len(numbers) > 0 {
+		s.Histogram = histogram(numbers, opts.Buckets)
+	}
+	return s, nil
+}
+
+// valueKey identifies a value for grouping and counting distinct values.
+// Numbers of any Go type share a key when they are equal.
+func valueKey(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "0"
+	case string:
+		return "s" + x
+	}
+	if kindRank(v) == kindRank(0.0) {
+		if f, err := toFloat(v); err == nil {
+			return fmt.Sprintf("n%v", f)
+		}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("v%v", v)
+	}
+	return "j" + string(data)
+}
+
+// histogram spreads counted values over n equal-width buckets
+func histogram(counts map[float64]int, n int) []HistogramBucket {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for f := range counts {
+		lo = math.Min(lo, f)
+		hi = math.Max(hi, f)
+	}
+	width := (hi - lo) / float64(n)
+	buckets := make([]HistogramBucket, n)
+	for i := range buckets {
+		buckets[i].Lower = lo + float64(i)*width
+		buckets[i].Upper = lo + float64(i+1)*width
+	}
+	buckets[n-1].Upper = hi
+	for f, c := range counts {
+		i := n - 1
+		if width > 0 {
+			i = int((f - lo) / width)
+		}
+		if i >= n {
+			i = n - 1
+		}
+		buckets[i].Count += c
+	}
+	return buckets
+}
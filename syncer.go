@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SyncTable selects a table for replication and how conflicts are settled
+type SyncTable struct {
+	Name       string
+	KeyColumns []string
+
+	// Resolve settles rows present on both sides with different values;
+	// defaults to KeepIncoming, making the source authoritative
+	Resolve ConflictResolver
+
+	// Delete removes target rows that no longer exist on the source
+	Delete bool
+}
+
+// TableSyncReport describes the outcome of syncing one table
+type TableSyncReport struct {
+	Table    string
+	Skipped  bool
+	Inserted int
+	Updated  int
+	Deleted  int
+	Kept     int
+}
+
+// Syncer replicates selected tables from one MenousDB server to another,
+// either on demand with SyncOnce or on a schedule with Run
+type Syncer struct {
+	Source *MenousDB
+	Target *MenousDB
+	Tables []SyncTable
+
+	// CheckpointPath, if set, records a content hash per table after it is
+	// synced. Tables whose source content matches the checkpoint are skipped,
+	// so an interrupted run resumes where it stopped and idle tables cost a
+	// single read.
+	CheckpointPath string
+
+	// OnError, if set, receives errors from scheduled runs; Run keeps going
+	// after reporting them
+	OnError func(error)
+}
+
+// syncCheckpoint is the on-disk checkpoint format
+type syncCheckpoint struct {
+	Tables map[string]string `json:"tables"`
+}
+
+// Run calls SyncOnce immediately and then every interval until ctx is done
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.SyncOnce(); err != nil && s.OnError != nil {
+			s.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncOnce replicates every configured table once
+func (s *Syncer) SyncOnce() ([]TableSyncReport, error) {
+	cp, err := s.loadCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]TableSyncReport, 0, len(s.Tables))
+	for _, t := range s.Tables {
+		report, hash, err := s.syncTable(t, cp.Tables[t.Name])
+		reports = append(reports, report)
+		if err != nil {
+			return reports, fmt.Errorf("syncing %s: %w", t.Name, err)
+		}
+		cp.Tables[t.Name] = hash
+		if err := s.saveCheckpoint(cp); err != nil {
+			return reports, err
+		}
+	}
+	return reports, nil
+}
+
+// syncTable brings one target table in line with the source, returning the
+// source content hash for checkpointing
+func (s *Syncer) syncTable(t SyncTable, lastHash string) (TableSyncReport, string, error) {
+	report := TableSyncReport{Table: t.Name}
+
+	srcRows, err := s.Source.GetTableRows(t.Name)
+	if err != nil {
+		return report, "", err
+	}
+	hash, err := hashRows(srcRows)
+	if err != nil {
+		return report, "", err
+	}
+	if hash == lastHash {
+		report.Skipped = true
+		return report, hash, nil
+	}
+
+	dstRows, err := s.Target.GetTableRows(t.Name)
+	if err != nil {
+		return report, "", err
+	}
+
+	resolve := t.Resolve
+	if resolve == nil {
+		resolve = KeepIncoming()
+	}
+
+	_, err = diffRows(dstRows, t.KeyColumns, func(emit func(Row) error) error {
+		for _, row := range srcRows {
+			if err := emit(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(c RowChange) error {
+		switch c.Kind {
+		case RowAdded:
+			if _, err := s.Target.InsertIntoTable(t.Name, c.New); err != nil {
+				return err
+			}
+			report.Inserted++
+		case RowChanged:
+			merged, write := resolve(c.Old, c.New)
+			if !write {
+				report.Kept++
+				return nil
+			}
+			if _, err := s.Target.UpdateWhere(t.Name, c.Key, merged); err != nil {
+				return err
+			}
+			report.Updated++
+		case RowRemoved:
+			if !t.Delete {
+				report.Kept++
+				return nil
+			}
+			if _, err := s.Target.DeleteWhere(t.Name, c.Key); err != nil {
+				return err
+			}
+			report.Deleted++
+		}
+		return nil
+	})
+	return report, hash, err
+}
+
+// hashRows returns a content hash identifying a table snapshot
+func hashRows(rows []Row) (string, error) {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadCheckpoint reads the checkpoint file, if any
+func (s *Syncer) loadCheckpoint() (syncCheckpoint, error) {
+	cp := syncCheckpoint{Tables: make(map[string]string)}
+	if s.CheckpointPath == "" {
+		return cp, nil
+	}
+	data, err := os.ReadFile(s.CheckpointPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("reading checkpoint %s: %w", s.CheckpointPath, err)
+	}
+	if cp.Tables == nil {
+		cp.Tables = make(map[string]string)
+	}
+	return cp, nil
+}
+
+// saveCheckpoint persists the checkpoint file, if configured
+func (s *Syncer) saveCheckpoint(cp syncCheckpoint) error {
+	if s.CheckpointPath == "" {
+		return nil
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.CheckpointPath, data)
+}
@@ -0,0 +1,136 @@
+package main
+
+// Table is a handle on one table in the client's database. Declarations made
+// through it (column definitions, defaults, checks, compression) are shared
+// with every handle on the same table and apply to writes made through the
+// client directly too.
+type Table struct {
+	client *MenousDB
+	name   string
+	schema *tableSchema
+}
+
+// Table returns a handle on table in the client's database
+func (m *MenousDB) Table(name string) *Table {
+	if m.schemas == nil {
+		m.schemas = &schemaRegistry{}
+	}
+	return &Table{client: m, name: name, schema: m.schemas.table(m.Database, name, true)}
+}
+
+// Name returns the table name
+func (t *Table) Name() string {
+	return t.name
+}
+
+// Columns returns the table's column declarations
+func (t *Table) Columns() []ColumnDef {
+	t.schema.mu.RLock()
+	defer t.schema.mu.RUnlock()
+	return append([]ColumnDef(nil), t.schema.columns...)
+}
+
+// Declare replaces the table's column declarations, as DeclareColumns does
+func (t *Table) Declare(columns ...ColumnDef) *Table {
+	t.schema.mu.Lock()
+	defer t.schema.mu.Unlock()
+	t.schema.columns = append([]ColumnDef(nil), columns...)
+	return t
+}
+
+// Default sets the value inserted for column when an insert omits it,
+// declaring the column untyped if it is not declared yet
+func (t *Table) Default(column string, value interface{}) *Table {
+	t.schema.mu.Lock()
+	defer t.schema.mu.Unlock()
+	for i := range t.schema.columns {
+		if t.schema.columns[i].Name == column {
+			t.schema.columns[i].Default = value
+			return t
+		}
+	}
+	t.schema.columns = append(t.schema.columns, ColumnDef{Name: column, Nullable: true, Default: value})
+	return t
+}
+
+// Require marks columns as required, declaring any not declared yet
+func (t *Table) Require(columns ...string) *Table {
+	t.schema.mu.Lock()
+	defer t.schema.mu.Unlock()
+	for _, column := range columns {
+		found := false
+		for i := range t.schema.columns {
+			if t.schema.columns[i].Name == column {
+				t.schema.columns[i].Required = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.schema.columns = append(t.schema.columns, ColumnDef{Name: column, Nullable: true, Required: true})
+		}
+	}
+	return t
+}
+
+// Check adds a named constraint on columns: inserts and updates whose row
+// fails p are rejected before they are sent. A constraint with the name of
+// an existing one replaces it.
+//
+//	users.Check("age_positive", Gte("age", 0), "age")
+//	users.Check("role_known", In("role", "admin", "member"), "role")
+func (t *Table) Check(name string, p Predicate, columns ...string) *Table {
+	t.schema.mu.Lock()
+	defer t.schema.mu.Unlock()
+	c := CheckConstraint{Name: name, Columns: append([]string(nil), columns...), Predicate: p}
+	for i := range t.schema.checks {
+		if t.schema.checks[i].Name == name {
+			t.schema.checks[i] = c
+			return t
+		}
+	}
+	t.schema.checks = append(t.schema.checks, c)
+	return t
+}
+
+// Checks returns the table's constraints
+func (t *Table) Checks() []CheckConstraint {
+	t.schema.mu.RLock()
+	defer t.schema.mu.RUnlock()
+	return append([]CheckConstraint(nil), t.schema.checks...)
+}
+
+// Create creates the table on the server from its column declarations
+func (t *Table) Create() (string, error) {
+	return t.client.CreateTableWithColumns(t.name, t.Columns())
+}
+
+// Insert inserts one record, filling omitted columns with their defaults
+func (t *Table) Insert(values interface{}) (string, error) {
+	return t.client.InsertIntoTable(t.name, values)
+}
+
+// Update sets values on records matching conditions
+func (t *Table) Update(conditions, values map[string]interface{}) (interface{}, error) {
+	return t.client.UpdateWhere(t.name, conditions, values)
+}
+
+// Delete removes records matching conditions
+func (t *Table) Delete(conditions map[string]interface{}) (interface{}, error) {
+	return t.client.DeleteWhere(t.name, conditions)
+}
+
+// Rows retrieves every record as rows
+func (t *Table) Rows() ([]Row, error) {
+	return t.client.GetTableRows(t.name)
+}
+
+// Select starts a query over the table
+func (t *Table) Select(columns ...string) *SelectQuery {
+	return t.client.Select(t.name, columns...)
+}
+
+// Exists reports whether the table exists
+func (t *Table) Exists() (bool, error) {
+	return t.client.TableExists(t.name)
+}
@@ -0,0 +1,17 @@
+package main
+
+import "sort"
+
+// ListTables returns the names of the tables in the database, sorted
+func (m *MenousDB) ListTables() ([]string, error) {
+	data, err := m.ReadDB()
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]string, 0, len(data))
+	for name := range data {
+		tables = append(tables, name)
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+// makeRaw puts the terminal in into raw mode, without echo or line
+// buffering, and returns a function restoring its previous mode. It drives
+// stty, which every Unix terminal has, rather than the platform's ioctls.
+func makeRaw(in *os.File) (func(), error) {
+	saved, err := stty(in, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("not a terminal: %w", err)
+	}
+	if _, err := stty(in, "raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(in, strings.TrimSpace(saved)) }, nil
+}
+
+// terminalSize returns the terminal's width and height, or 80 by 24 when
+// they cannot be read
+func terminalSize(in *os.File) (width, height int) {
+	out, err := stty(in, "size")
+	if err == nil {
+		if _, err := fmt.Sscan(out, &height, &width); err == nil && width > 0 && height > 0 {
+			return width, height
+		}
+	}
+	return 80, 24
+}
+
+// stty runs stty with in as its terminal
+func stty(in *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = in
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// readKeys sends each key read from r to keys, until r fails. A character
+// split across reads is held back until the rest of it arrives.
+func readKeys(r io.Reader, keys chan<- string) error {
+	buf := make([]byte, 256)
+	var pending []byte
+	for {
+		n, err := r.Read(buf)
+		pending = append(pending, buf[:n]...)
+		whole := completeRunes(pending)
+		for _, key := range decodeKeys(pending[:whole]) {
+			keys <- key
+		}
+		pending = append(pending[:0], pending[whole:]...)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// completeRunes returns the length of data without a trailing incomplete
+// UTF-8 sequence
+func completeRunes(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+// escapeKeys names the escape sequences of special keys
+var escapeKeys = map[string]string{
+	"\x1b[A": "up", "\x1b[B": "down", "\x1b[C": "right", "\x1b[D": "left",
+	"\x1bOA": "up", "\x1bOB": "down", "\x1bOC": "right", "\x1bOD": "left",
+	"\x1b[H": "home", "\x1b[F": "end", "\x1bOH": "home", "\x1bOF": "end",
+	"\x1b[1~": "home", "\x1b[4~": "end", "\x1b[7~": "home", "\x1b[8~": "end",
+	"\x1b[5~": "pgup", "\x1b[6~": "pgdown", "\x1b[3~": "delete", "\x1b[2~": "insert",
+}
+
+// decodeKeys splits input into key names: special keys by name, such as up,
+// enter and ctrl+c, and everything else as the character typed
+func decodeKeys(data []byte) []string {
+	var keys []string
+	s := string(data)
+	for len(s) > 0 {
+		if s[0] == 0x1b {
+			if len(s) == 1 {
+				keys = append(keys, "esc")
+				break
+			}
+			matched := false
+			for seq, name := range escapeKeys {
+				if strings.HasPrefix(s, seq) {
+					keys = append(keys, name)
+					s = s[len(seq):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				// An unknown sequence: skip to its final byte
+				end := 1
+				if len(s) > 1 && (s[1] == '[' || s[1] == 'O') {
+					end = 2
+					for end < len(s) && (s[end] < 0x40 || s[end] > 0x7e) {
+						end++
+					}
+					end++
+				}
+				if end == 1 {
+					keys = append(keys, "esc")
+				}
+				if end > len(s) {
+					end = len(s)
+				}
+				s = s[end:]
+			}
+			continue
+		}
+		switch c := s[0]; c {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case '\t':
+			keys = append(keys, "tab")
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+		case 0x03:
+			keys = append(keys, "ctrl+c")
+		case 0x04:
+			keys = append(keys, "ctrl+d")
+		default:
+			if c < 0x20 {
+				keys = append(keys, fmt.Sprintf("ctrl+%c", c+'a'-1))
+				break
+			}
+			// Invalid bytes are dropped rather than typed
+			r, size := utf8.DecodeRuneInString(s)
+			if r != utf8.RuneError || size > 1 {
+				keys = append(keys, string(r))
+			}
+			s = s[size:]
+			continue
+		}
+		s = s[1:]
+	}
+	return keys
+}
@@ -0,0 +1,36 @@
+--
+-- PostgreSQL database dump
+--
+
+SET statement_timeout = 0;
+SET client_encoding = 'UTF8';
+SET standard_conforming_strings = on;
+SELECT pg_catalog.set_config('search_path', '', false);
+
+CREATE TABLE public.users (
+    id integer NOT NULL,
+    name text,
+    bio text
+);
+
+ALTER TABLE public.users OWNER TO postgres;
+
+--
+-- Data for Name: users; Type: TABLE DATA; Schema: public; Owner: postgres
+--
+
+COPY public.users (id, name, bio) FROM stdin;
+1	Ada	likes C:\\path and\ttabs
+2	Bob	\N
+3	O'Brien; Ltd	line one\nline two
+\.
+
+
+INSERT INTO public.users VALUES (4, 'C:\dir', E'it\'s\n');
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+
+--
+-- PostgreSQL database dump complete
+--
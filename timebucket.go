@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MaxTimeBuckets bounds the windows BucketByTime returns, so sparse data
+// over a long span with a short interval cannot fill memory with empty
+// windows
+const MaxTimeBuckets = 100000
+
+// TimeBucket is one window of a BucketByTime result
+type TimeBucket struct {
+	Start, End time.Time
+	// Values holds each aggregation's result for the window's records
+	Values map[string]interface{}
+}
+
+// BucketByTime groups the records of table matching conditions (all when
+// empty) into fixed windows of interval by timeColumn and aggregates each
+// window, for charting:
+//
+//	hourly, err := db.BucketByTime("events", "at", time.Hour,
+//		map[string]Aggregate{"events": CountOf(), "avg_ms": AvgOf("duration_ms")}, nil)
+//
+// Windows are aligned in UTC as time.Truncate aligns them, so days start at
+// midnight and weeks (7*24*time.Hour) on Monday. The result runs from the
+// first window with records to the last, empty windows included, so series
+// have no gaps, and spans of more than MaxTimeBuckets windows are refused.
+// Records whose time is missing or unparseable are skipped.
+func (m *MenousDB) BucketByTime(table, timeColumn string, interval time.Duration, aggregations map[string]Aggregate, conditions map[string]interface{}) ([]TimeBucket, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %s", interval)
+	}
+	windows := make(map[int64]map[string]Aggregator)
+
+	err := m.streamSelect("BucketByTime", table, nil, conditions, false, m.columnDecoder(table, func(r Row) error {
+		t, err := toTime(r[timeColumn])
+		if err != nil {
+			return nil
+		}
+		start := t.UTC().Truncate(interval).UnixNano()
+		w := windows[start]
+		if w == nil {
+			w = newAggregators(aggregations)
+			windows[start] = w
+		}
+		for _, a := range w {
+			a.Add(r)
+		}
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if len(windows) == 0 {
+		return nil, nil
+	}
+
+	starts := make([]int64, 0, len(windows))
+	for s := range windows {
+		starts = append(starts, s)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	count := (starts[len(starts)-1]-starts[0])/int64(interval) + 1
+	if count > MaxTimeBuckets {
+		return nil, fmt.Errorf("records span %d windows of %s, more than %d; use a longer interval or narrower conditions", count, interval, MaxTimeBuckets)
+	}
+
+	out := make([]TimeBucket, 0, count)
+	last := time.Unix(0, starts[len(starts)-1]).UTC()
+	for start := time.Unix(0, starts[0]).UTC(); !start.After(last); start = start.Add(interval) {
+		w := windows[start.UnixNano()]
+		if w == nil {
+			w = newAggregators(aggregations)
+		}
+		b := TimeBucket{Start: start, End: start.Add(interval), Values: make(map[string]interface{}, len(w))}
+		for name, a := range w {
+			b.Values[name] = a.Result()
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// newAggregators starts one aggregator per named aggregation
+func newAggregators(aggregations map[string]Aggregate) map[string]Aggregator {
+	out := make(map[string]Aggregator, len(aggregations))
+	for name, agg := range aggregations {
+		out[name] = agg()
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+)
+
+// TopN returns the n rows of table matching conditions (all rows when
+// empty) with the largest values in column, largest first. Records are
+// streamed through a heap of n rows, so memory stays bounded by n however
+// large the table is. Rows where column is null or missing are skipped, and
+// ties keep the row read first.
+func (m *MenousDB) TopN(table, column string, n int, conditions map[string]interface{}) ([]Row, error) {
+	return m.selectN("TopN", table, column, n, conditions, 1)
+}
+
+// BottomN is TopN for the smallest values, smallest first
+func (m *MenousDB) BottomN(table, column string, n int, conditions map[string]interface{}) ([]Row, error) {
+	return m.selectN("BottomN", table, column, n, conditions, -1)
+}
+
+// selectN keeps the n best rows by column, where sign 1 prefers large values
+// and -1 small ones
+func (m *MenousDB) selectN(op, table, column string, n int, conditions map[string]interface{}, sign int) ([]Row, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("n must be positive, got %d", n)
+	}
+	h := &rankHeap{column: column, sign: sign}
+	seq := 0
+	err := m.streamSelect(op, table, nil, conditions, false, m.columnDecoder(table, func(r Row) error {
+		if r[column] == nil {
+			return nil
+		}
+		item := rankedRow{row: r, seq: seq}
+		seq++
+		if h.Len() < n {
+			heap.Push(h, item)
+		} else if h.better(item, h.items[0]) {
+			h.items[0] = item
+			heap.Fix(h, 0)
+		}
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(h.items, func(i, j int) bool { return h.better(h.items[i], h.items[j]) })
+	out := make([]Row, len(h.items))
+	for i, item := range h.items {
+		out[i] = item.row
+	}
+	return out, nil
+}
+
+// rankedRow is a row with its position in the stream, for stable ties
+type rankedRow struct {
+	row Row
+	seq int
+}
+
+// rankHeap is a heap with the worst kept row on top, so a better row can
+// replace it
+type rankHeap struct {
+	items  []rankedRow
+	column string
+	sign   int
+}
+
+// better reports whether a ranks above b: a better value, or an equal one
+// read earlier
+func (h *rankHeap) better(a, b rankedRow) bool {
+	if c := compareValues(a.row[h.column], b.row[h.column]) * h.sign; c != 0 {
+		return c > 0
+	}
+	return a.seq < b.seq
+}
+
+func (h *rankHeap) Len() int           { return len(h.items) }
+func (h *rankHeap) Less(i, j int) bool { return h.better(h.items[j], h.items[i]) }
+func (h *rankHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *rankHeap) Push(x interface{}) { h.items = append(h.items, x.(rankedRow)) }
+func (h *rankHeap) Pop() interface{} {
+	item := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return item
+}
@@ -0,0 +1,180 @@
+package main
+
+import "fmt"
+
+// Relation is a foreign-key-like link: a row of From points at the rows of
+// To whose ToColumn equals its FromColumn
+type Relation struct {
+	Name       string
+	From       string
+	FromColumn string
+	To         string
+	ToColumn   string
+}
+
+// Reverse returns the relation followed the other way
+func (r Relation) Reverse() Relation {
+	return Relation{Name: r.Name, From: r.To, FromColumn: r.ToColumn, To: r.From, ToColumn: r.FromColumn}
+}
+
+// TraverseOptions configures Traverse
+type TraverseOptions struct {
+	// Relations are the links followed, each in its own direction only
+	Relations []Relation
+
+	// MaxDepth stops expanding rows this many links from a start row; zero
+	// means no limit
+	MaxDepth int
+
+	// MaxNodes stops the traversal once this many rows are reached; zero
+	// means no limit
+	MaxNodes int
+
+	// DepthFirst follows each path to its end before the next, instead of
+	// visiting rows level by level
+	DepthFirst bool
+
+	// Keys names the columns identifying a row of each table, so a row
+	// reached twice is one node; tables not listed use all their columns
+	Keys map[string][]string
+}
+
+// GraphNode is a row reached by Traverse
+type GraphNode struct {
+	Table string
+	Row   Row
+	Depth int
+}
+
+// GraphEdge links two nodes of a Subgraph by index
+type GraphEdge struct {
+	Relation string
+	From, To int
+}
+
+// Subgraph is the rows reachable from the start rows and the links between
+// them, nodes in visiting order
+type Subgraph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+// Traverse starts from the rows of table matching conditions and follows
+// opts.Relations outward, returning the subgraph reached:
+//
+//	g, err := db.Traverse("users", map[string]interface{}{"id": 1}, TraverseOptions{
+//		Relations: []Relation{
+//			{Name: "follows", From: "users", FromColumn: "id", To: "follows", ToColumn: "follower"},
+//			{Name: "followee", From: "follows", FromColumn: "followee", To: "users", ToColumn: "id"},
+//		},
+//		MaxDepth: 4,
+//	})
+//
+// Each lookup is one select-where request; lookups repeated during the
+// traversal are answered from memory.
+func (m *MenousDB) Traverse(table string, conditions map[string]interface{}, opts TraverseOptions) (*Subgraph, error) {
+	t := &traversal{
+		client:  m,
+		opts:    opts,
+		g:       &Subgraph{},
+		index:   make(map[string]int),
+		lookups: make(map[string][]Row),
+		edges:   make(map[GraphEdge]bool),
+	}
+
+	start, err := m.SelectWhereRows(table, conditions)
+	if err != nil {
+		return nil, err
+	}
+	var pending []int
+	for _, row := range start {
+		if i, added := t.node(table, row, 0); added {
+			pending = append(pending, i)
+		}
+	}
+
+	for len(pending) > 0 && !t.full() {
+		var i int
+		if opts.DepthFirst {
+			i, pending = pending[len(pending)-1], pending[:len(pending)-1]
+		} else {
+			i, pending = pending[0], pending[1:]
+		}
+		n := t.g.Nodes[i]
+		if opts.MaxDepth > 0 && n.Depth >= opts.MaxDepth {
+			continue
+		}
+		for _, rel := range opts.Relations {
+			if rel.From != n.Table || n.Row[rel.FromColumn] == nil {
+				continue
+			}
+			rows, err := t.lookup(rel.To, rel.ToColumn, n.Row[rel.FromColumn])
+			if err != nil {
+				return t.g, fmt.Errorf("following %s from %s: %w", rel.Name, n.Table, err)
+			}
+			for _, row := range rows {
+				if t.full() {
+					break
+				}
+				j, added := t.node(rel.To, row, n.Depth+1)
+				t.edge(GraphEdge{Relation: rel.Name, From: i, To: j})
+				if added {
+					pending = append(pending, j)
+				}
+			}
+		}
+	}
+	return t.g, nil
+}
+
+// traversal is the state of one Traverse call
+type traversal struct {
+	client  *MenousDB
+	opts    TraverseOptions
+	g       *Subgraph
+	index   map[string]int
+	lookups map[string][]Row
+	edges   map[GraphEdge]bool
+}
+
+// node returns the index of row's node, adding it if it is new
+func (t *traversal) node(table string, row Row, depth int) (int, bool) {
+	columns := t.opts.Keys[table]
+	if len(columns) == 0 {
+		columns = inferColumns([]Row{row})
+	}
+	key := table + "\x00" + rowKey(row, columns)
+	if i, ok := t.index[key]; ok {
+		return i, false
+	}
+	t.g.Nodes = append(t.g.Nodes, GraphNode{Table: table, Row: row, Depth: depth})
+	t.index[key] = len(t.g.Nodes) - 1
+	return len(t.g.Nodes) - 1, true
+}
+
+// edge records e once
+func (t *traversal) edge(e GraphEdge) {
+	if !t.edges[e] {
+		t.edges[e] = true
+		t.g.Edges = append(t.g.Edges, e)
+	}
+}
+
+// full reports whether MaxNodes is reached
+func (t *traversal) full() bool {
+	return t.opts.MaxNodes > 0 && len(t.g.Nodes) >= t.opts.MaxNodes
+}
+
+// lookup returns the rows of table whose column equals v
+func (t *traversal) lookup(table, column string, v interface{}) ([]Row, error) {
+	key := table + "\x00" + column + "\x00" + valueKey(v)
+	if rows, ok := t.lookups[key]; ok {
+		return rows, nil
+	}
+	rows, err := t.client.SelectWhereRows(table, map[string]interface{}{column: v})
+	if err != nil {
+		return nil, err
+	}
+	t.lookups[key] = rows
+	return rows, nil
+}
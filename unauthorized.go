@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// UnauthorizedFunc is called when the server rejects a request's credentials
+// with 401. It receives the request's database (empty for server-wide calls)
+// and returns a fresh key, with which the request is retried once. Returning
+// an empty key retries with the credentials the client already has, for
+// hooks that refresh a token source out of band. The hook may run
+// concurrently when several requests fail at once.
+type UnauthorizedFunc func(ctx context.Context, database string, err *APIError) (string, error)
+
+// authRefresh holds the hook and the keys it has handed out, shared by clones
+type authRefresh struct {
+	fn UnauthorizedFunc
+
+	mu  sync.Mutex
+	key string
+}
+
+// WithOnUnauthorized installs fn to recover from rejected credentials. A
+// fresh key replaces the database's KeyRing entry when it has one, and the
+// client's default key otherwise, for every later request.
+func WithOnUnauthorized(fn UnauthorizedFunc) Option {
+	return func(m *MenousDB) {
+		m.authRefresh = &authRefresh{fn: fn}
+	}
+}
+
+// refreshedKey returns the default key last handed out by the hook, if any
+func (m *MenousDB) refreshedKey() (string, bool) {
+	if m.authRefresh == nil {
+		return "", false
+	}
+	m.authRefresh.mu.Lock()
+	defer m.authRefresh.mu.Unlock()
+	return m.authRefresh.key, m.authRefresh.key != ""
+}
+
+// refreshAuth asks the hook for fresh credentials after apiErr and stores them
+func (m *MenousDB) refreshAuth(ctx context.Context, database string, apiErr *APIError) error {
+	key, err := m.authRefresh.fn(ctx, database, apiErr)
+	if err != nil || key == "" {
+		return err
+	}
+	if m.keyRing != nil && database != "" {
+		if _, ok := m.keyRing.Lookup(database); ok {
+			m.keyRing.Set(database, key)
+			return nil
+		}
+	}
+	m.authRefresh.mu.Lock()
+	m.authRefresh.key = key
+	m.authRefresh.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+const (
+	// unixScheme is the URL prefix selecting a unix domain socket
+	unixScheme = "unix://"
+
+	// unixBaseURL is the placeholder HTTP URL used for socket requests
+	unixBaseURL = "http://unix/"
+)
+
+// unixTransport returns a transport that dials the given socket path
+func unixTransport(socketPath string) *http.Transport {
+	dialer := &net.Dialer{}
+	return &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}
+}
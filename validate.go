@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Validator checks one non-null value against a rule. param is the text
+// after "=" in the rule, empty when there is none. The error's message
+// becomes the FieldError message, so it should read after the field name,
+// such as "must be a valid IBAN".
+type Validator func(value interface{}, param string) error
+
+// validators maps rule names usable in menousdb tags and ColumnDef.Validate
+// to their checks. The built-in rules are:
+//
+//	min=N, max=N   string length in characters, array length, or number
+//	format=F       string format: email, url or uuid
+var validators = struct {
+	sync.RWMutex
+	m map[string]Validator
+}{m: map[string]Validator{
+	"min":    ruleMin,
+	"max":    ruleMax,
+	"format": ruleFormat,
+}}
+
+// RegisterValidator makes a rule available to menousdb tags and
+// ColumnDef.Validate under name:
+//
+//	RegisterValidator("iban", func(v interface{}, _ string) error {
+//		if s, _ := v.(string); !validIBAN(s) {
+//			return errors.New("must be a valid IBAN")
+//		}
+//		return nil
+//	})
+//
+//	Account string `menousdb:"account,required,iban"`
+//
+// It panics if name is empty, contains "=" or ",", or is already registered,
+// or if fn is nil.
+func RegisterValidator(name string, fn Validator) {
+	if name == "" || strings.ContainsAny(name, "=,") {
+		panic("menousdb: invalid validator name " + strconv.Quote(name))
+	}
+	if fn == nil {
+		panic("menousdb: RegisterValidator fn is nil")
+	}
+	validators.Lock()
+	defer validators.Unlock()
+	if _, dup := validators.m[name]; dup {
+		panic("menousdb: RegisterValidator called twice for " + name)
+	}
+	validators.m[name] = fn
+}
+
+// validator returns the rule registered under name
+func validator(name string) (Validator, bool) {
+	validators.RLock()
+	defer validators.RUnlock()
+	fn, ok := validators.m[name]
+	return fn, ok
+}
+
+// checkRules runs the column's Validate rules on v, a non-null value
+func (c ColumnDef) checkRules(v interface{}) []FieldError {
+	if c.Validate == "" {
+		return nil
+	}
+	var fields []FieldError
+	for _, rule := range strings.Split(c.Validate, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		fn, ok := validator(name)
+		if !ok {
+			fields = append(fields, FieldError{Field: c.Name, Rule: name, Message: "has unknown rule " + name})
+			continue
+		}
+		if err := fn(v, arg); err != nil {
+			fields = append(fields, FieldError{Field: c.Name, Rule: name, Message: err.Error()})
+		}
+	}
+	return fields
+}
+
+// taggedStruct is what checkWrite needs from a tagged struct type: its
+// columns, and the JSON names of fields the menousdb tag renames
+type taggedStruct struct {
+	columns []ColumnDef
+	renames map[string]string
+}
+
+// taggedTypes caches taggedStruct by struct type
+var taggedTypes sync.Map
+
+// taggedColumns returns the columns of a struct value, as AutoMigrate derives
+// them, when any field carries a menousdb tag; nil otherwise
+func taggedColumns(v interface{}) *taggedStruct {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	if cached, ok := taggedTypes.Load(t); ok {
+		return cached.(*taggedStruct)
+	}
+	var ts *taggedStruct
+	if hasMenousTag(t) {
+		ts = &taggedStruct{columns: structColumns(t), renames: make(map[string]string)}
+		tagRenames(t, ts.renames)
+	}
+	taggedTypes.Store(t, ts)
+	return ts
+}
+
+// tagRenames records, for fields of t whose menousdb tag names them
+// differently from encoding/json, the JSON name and the column name
+func tagRenames(t reflect.Type, renames map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, skip := fieldColumn(f)
+		if skip {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			tagRenames(ft, renames)
+			continue
+		}
+		jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+		if name != "" && name != jsonName {
+			renames[jsonName] = name
+		}
+	}
+}
+
+// rename moves values encoded under a field's JSON name to its column name
+func (ts *taggedStruct) rename(values Row) Row {
+	if ts == nil || len(ts.renames) == 0 {
+		return values
+	}
+	out := make(Row, len(values))
+	for k, v := range values {
+		if name, ok := ts.renames[k]; ok {
+			k = name
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// hasMenousTag reports whether t or a struct it embeds tags a field
+func hasMenousTag(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if _, ok := f.Tag.Lookup("menousdb"); ok {
+			return true
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct && hasMenousTag(ft) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeTagged adds struct tag columns to declared ones. A column in both
+// keeps its declaration and gains the tag's required flag and rules.
+func mergeTagged(declared []ColumnDef, tagged *taggedStruct) []ColumnDef {
+	if tagged == nil {
+		return declared
+	}
+	for _, t := range tagged.columns {
+		found := false
+		for i := range declared {
+			if declared[i].Name != t.Name {
+				continue
+			}
+			found = true
+			declared[i].Required = declared[i].Required || t.Required
+			if t.Validate != "" {
+				if declared[i].Validate != "" {
+					declared[i].Validate += ","
+				}
+				declared[i].Validate += t.Validate
+			}
+		}
+		if !found {
+			declared = append(declared, t)
+		}
+	}
+	return declared
+}
+
+// ruleMin fails values below arg
+func ruleMin(v interface{}, arg string) error {
+	n, limit, err := ruleMeasure(v, arg)
+	if err != nil {
+		return err
+	}
+	if n < limit {
+		return fmt.Errorf("must be at least %s%s", arg, ruleUnit(v))
+	}
+	return nil
+}
+
+// ruleMax fails values above arg
+func ruleMax(v interface{}, arg string) error {
+	n, limit, err := ruleMeasure(v, arg)
+	if err != nil {
+		return err
+	}
+	if n > limit {
+		return fmt.Errorf("must be at most %s%s", arg, ruleUnit(v))
+	}
+	return nil
+}
+
+// ruleMeasure returns the size min and max compare for v: the length of
+// strings and arrays, the value of numbers
+func ruleMeasure(v interface{}, arg string) (float64, float64, error) {
+	limit, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("has invalid limit %q", arg)
+	}
+	switch x := v.(type) {
+	case string:
+		return float64(utf8.RuneCountInString(x)), limit, nil
+	case []interface{}:
+		return float64(len(x)), limit, nil
+	case json.Number:
+		f, err := x.Float64()
+		return f, limit, err
+	}
+	f, err := toFloat(v)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot be measured, got %T", v)
+	}
+	return f, limit, nil
+}
+
+// ruleUnit names what min and max counted for v
+func ruleUnit(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return " characters"
+	case []interface{}:
+		return " items"
+	}
+	return ""
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ruleFormat fails strings not in the format arg
+func ruleFormat(v interface{}, arg string) error {
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("must be a string, got %T", v)
+	}
+	valid := false
+	switch arg {
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		valid = err == nil && addr.Address == s
+	case "url":
+		u, err := url.ParseRequestURI(s)
+		valid = err == nil && u.Scheme != "" && u.Host != ""
+	case "uuid":
+		valid = uuidPattern.MatchString(s)
+	default:
+		return fmt.Errorf("has unknown format %q", arg)
+	}
+	if !valid {
+		return fmt.Errorf("must be a valid %s", arg)
+	}
+	return nil
+}
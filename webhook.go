@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Webhook is an endpoint notified of table changes
+type Webhook struct {
+	URL string
+
+	// Secret signs each notification; see VerifyWebhookSignature
+	Secret string
+
+	// Tables limits the webhook to some of the dispatcher's tables; empty
+	// means all of them
+	Tables []string
+}
+
+// WebhookDeadLetterAttributes are the attributes of a dead-letter table:
+// the webhook URL, the undelivered ChangeEvent as JSON, the last error, the
+// number of attempts and when delivery was abandoned
+var WebhookDeadLetterAttributes = []string{"url", "event", "error", "attempts", "failed"}
+
+// WebhookDispatcher POSTs table changes to webhooks as JSON ChangeEvents.
+// Each webhook has its own ChangeTailer, and so its own queue and
+// checkpoint, and is delivered to on its own goroutine: a slow or failing
+// endpoint holds back only its own notifications. Requests carry:
+//
+//	X-MenousDB-Event      the change kind: added, removed or changed
+//	X-MenousDB-Delivery   table:offset, the same each time that webhook is
+//	                      sent that change
+//	X-MenousDB-Timestamp  Unix seconds when the request was signed
+//	X-MenousDB-Signature  sha256=HMAC-SHA256(secret, timestamp + "." + body)
+//
+// A 2xx response delivers the change. Network errors, 408, 429 and 5xx are
+// retried with doubling backoff; other responses, and changes still failing
+// after MaxAttempts, are dead-lettered so the webhook moves on. Each
+// webhook gets a table's changes in order and at least once, so endpoints
+// should deduplicate on X-MenousDB-Delivery. Offsets are per webhook: two
+// webhooks may number the same change differently.
+type WebhookDispatcher struct {
+	Client *MenousDB
+	Tables []SinkTable
+	Hooks  []Webhook
+
+	// HTTPClient sends notifications; defaults to a client with a 10 second
+	// timeout
+	HTTPClient *http.Client
+
+	// MaxAttempts bounds deliveries of one change to one webhook; defaults
+	// to 5
+	MaxAttempts int
+
+	// Backoff is the wait before the first retry, doubling after each;
+	// defaults to one second
+	Backoff time.Duration
+
+	// DeadLetterTable, if set, receives a row with
+	// WebhookDeadLetterAttributes for each abandoned delivery
+	DeadLetterTable string
+
+	// CheckpointPath and SkipSnapshot configure the tailers; see
+	// ChangeTailer. Each webhook checkpoints to CheckpointPath followed by
+	// a dot and a hash of its URL.
+	CheckpointPath string
+	SkipSnapshot   bool
+
+	// OnError, if set, receives errors from scheduled polls and abandoned
+	// deliveries
+	OnError func(error)
+
+	once    sync.Once
+	tailers []*ChangeTailer
+}
+
+// Run polls each webhook on its own goroutine, immediately and then every
+// interval, until ctx is done
+func (d *WebhookDispatcher) Run(ctx context.Context, interval time.Duration) error {
+	d.init()
+	var wg sync.WaitGroup
+	for i := range d.Hooks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				if _, err := d.pollHook(ctx, i); err != nil && ctx.Err() == nil && d.OnError != nil {
+					d.OnError(err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// PollOnce dispatches every webhook's changes since its last poll, the
+// webhooks concurrently, returning the number of notifications delivered
+func (d *WebhookDispatcher) PollOnce(ctx context.Context) (int, error) {
+	d.init()
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		delivered int
+		errs      []error
+	)
+	for i := range d.Hooks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n, err := d.pollHook(ctx, i)
+			mu.Lock()
+			defer mu.Unlock()
+			delivered += n
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return delivered, errors.Join(errs...)
+}
+
+// init gives each webhook a tailer over the tables it wants
+func (d *WebhookDispatcher) init() {
+	d.once.Do(func() {
+		d.tailers = make([]*ChangeTailer, len(d.Hooks))
+		for i, hook := range d.Hooks {
+			var tables []SinkTable
+			for _, table := range d.Tables {
+				if hook.wants(table.Name) {
+					tables = append(tables, table)
+				}
+			}
+			checkpoint := d.CheckpointPath
+			if checkpoint != "" {
+				sum := sha256.Sum256([]byte(hook.URL))
+				checkpoint += "." + hex.EncodeToString(sum[:8])
+			}
+			d.tailers[i] = &ChangeTailer{
+				Client:         d.Client,
+				Tables:         tables,
+				CheckpointPath: checkpoint,
+				SkipSnapshot:   d.SkipSnapshot,
+			}
+		}
+	})
+}
+
+// pollHook delivers the changes for the i'th webhook since its last poll
+func (d *WebhookDispatcher) pollHook(ctx context.Context, i int) (int, error) {
+	hook := d.Hooks[i]
+	delivered := 0
+	_, err := d.tailers[i].Poll(ctx, func(ctx context.Context, e ChangeEvent) error {
+		ok, err := d.deliver(ctx, hook, e)
+		if ok {
+			delivered++
+		}
+		return err
+	})
+	if err != nil {
+		err = fmt.Errorf("webhook %s: %w", hook.URL, err)
+	}
+	return delivered, err
+}
+
+// wants reports whether the webhook is notified of table's changes
+func (h Webhook) wants(table string) bool {
+	if len(h.Tables) == 0 {
+		return true
+	}
+	for _, t := range h.Tables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver sends e to hook, retrying and dead-lettering as configured. It
+// reports whether e was delivered, and fails only when ctx ends or the
+// dead letter cannot be recorded.
+func (d *WebhookDispatcher) deliver(ctx context.Context, hook Webhook, e ChangeEvent) (bool, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return false, err
+	}
+	attempts := d.MaxAttempts
+	if attempts <= 0 {
+		attempts = 5
+	}
+	backoff := d.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	var lastErr error
+	attempt := 0
+	for attempt < attempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return false, ctx.Err()
+			case <-time.After(backoff << (attempt - 1)):
+			}
+		}
+		attempt++
+		retry, err := d.post(ctx, hook, e, body)
+		if err == nil {
+			return true, nil
+		}
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return false, d.deadLetter(hook, body, lastErr, attempt)
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth
+// retrying
+func (d *WebhookDispatcher) post(ctx context.Context, hook Webhook, e ChangeEvent, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-MenousDB-Event", e.Kind)
+	req.Header.Set("X-MenousDB-Delivery", e.Table+":"+strconv.FormatInt(e.Offset, 10))
+	req.Header.Set("X-MenousDB-Timestamp", timestamp)
+	if hook.Secret != "" {
+		req.Header.Set("X-MenousDB-Signature", signWebhook(hook.Secret, timestamp, body))
+	}
+
+	client := d.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook %s answered %s", hook.URL, resp.Status)
+}
+
+// deadLetter records an abandoned delivery
+func (d *WebhookDispatcher) deadLetter(hook Webhook, body []byte, cause error, attempts int) error {
+	if d.OnError != nil {
+		d.OnError(fmt.Errorf("dead-lettered after %d attempts: %w", attempts, cause))
+	}
+	if d.DeadLetterTable == "" {
+		return nil
+	}
+	row := Row{
+		"url":      hook.URL,
+		"event":    string(body),
+		"error":    cause.Error(),
+		"attempts": attempts,
+		"failed":   time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if _, err := d.Client.InsertIntoTable(d.DeadLetterTable, row); err != nil {
+		return fmt.Errorf("recording dead letter for %s: %w", hook.URL, err)
+	}
+	return nil
+}
+
+// signWebhook returns the signature header value for a notification
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a notification's X-MenousDB-Signature
+// against its timestamp and raw body, rejecting timestamps more than
+// tolerance from now to stop replays; a zero tolerance skips that check
+func VerifyWebhookSignature(secret, timestamp, signature string, body []byte, tolerance time.Duration) bool {
+	if tolerance > 0 {
+		secs, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return false
+		}
+		skew := time.Since(time.Unix(secs, 0))
+		if skew < -tolerance || skew > tolerance {
+			return false
+		}
+	}
+	return hmac.Equal([]byte(signature), []byte(signWebhook(secret, timestamp, body)))
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// writeThrough applies a successful insert or update to the cached reads of
+// its table instead of dropping them. The patches use the values sent, not
+// the rows stored, which the server does not return; see
+// CacheOptions.WriteThrough. Entries the write cannot be applied to are
+// dropped as invalidate would.
+func (c *readCache) writeThrough(endpoint, database, table string, body interface{}) {
+	var req struct {
+		Values     Row                    `json:"values"`
+		Conditions map[string]interface{} `json:"conditions"`
+	}
+	if !remarshal(body, &req) || req.Values == nil {
+		c.invalidate(endpoint, database, table)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+
+	for key, e := range c.entries {
+		if e.database != database || e.table != table || isExistsEndpoint(e.endpoint) {
+			continue
+		}
+		var patched []byte
+		var ok bool
+		if endpoint == "insert-into-table" {
+			patched, ok = e.patchInsert(req.Values)
+		} else {
+			patched, ok = e.patchUpdate(req.Conditions, req.Values)
+		}
+		if !ok {
+			c.lru.Remove(e.elem)
+			delete(c.entries, key)
+			continue
+		}
+		// Replace rather than modify the entry, which may still be in use
+		// outside the lock
+		ne := *e
+		ne.body = patched
+		ne.elem.Value = &ne
+		c.entries[key] = &ne
+	}
+}
+
+// patchInsert returns the entry's body with row added if the entry's query
+// selects it
+func (e *cacheEntry) patchInsert(row Row) ([]byte, bool) {
+	conditions, columns, ok := e.query()
+	if !ok {
+		return nil, false
+	}
+	if !Matches(conditions)(row) {
+		return e.body, true
+	}
+	var records []Row
+	if !decodeCached(e.body, &records) {
+		// Records keyed by row id, which the insert response does not give
+		return nil, false
+	}
+	return marshalCached(append(records, project(row, columns)))
+}
+
+// patchUpdate returns the entry's body with values applied to the records
+// matching where. Updates to a column the entry's query filters on could
+// move rows into the result, so they drop the entry.
+func (e *cacheEntry) patchUpdate(where map[string]interface{}, values Row) ([]byte, bool) {
+	conditions, columns, ok := e.query()
+	if !ok {
+		return nil, false
+	}
+	for c := range values {
+		if _, filtered := conditions[c]; filtered {
+			return nil, false
+		}
+	}
+	if columns != nil {
+		selected := make(map[string]bool, len(columns))
+		for _, c := range columns {
+			selected[c] = true
+		}
+		for c := range where {
+			if !selected[c] {
+				return nil, false
+			}
+		}
+	}
+
+	var records interface{}
+	if !decodeCached(e.body, &records) {
+		return nil, false
+	}
+	var rows []interface{}
+	switch v := records.(type) {
+	case []interface{}:
+		rows = v
+	case map[string]interface{}:
+		for _, r := range v {
+			rows = append(rows, r)
+		}
+	default:
+		return nil, false
+	}
+
+	match := Matches(where)
+	changed := false
+	for _, r := range rows {
+		row, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if !match(row) {
+			continue
+		}
+		for c, v := range project(values, columns) {
+			row[c] = v
+		}
+		changed = true
+	}
+	if !changed {
+		return e.body, true
+	}
+	return marshalCached(records)
+}
+
+// query returns the conditions and columns of the cached request; columns
+// is nil when every column is selected
+func (e *cacheEntry) query() (map[string]interface{}, []string, bool) {
+	var q struct {
+		Conditions map[string]interface{} `json:"conditions"`
+		Columns    []string               `json:"columns"`
+	}
+	if e.request != nil && !remarshal(e.request, &q) {
+		return nil, nil, false
+	}
+	return q.Conditions, q.Columns, true
+}
+
+// project returns the columns of row, or row itself when columns is nil
+func project(row Row, columns []string) Row {
+	if columns == nil {
+		return row
+	}
+	out := make(Row, len(columns))
+	for _, c := range columns {
+		if v, ok := row[c]; ok {
+			out[c] = v
+		}
+	}
+	return out
+}
+
+// isExistsEndpoint reports whether endpoint is an existence check, which row
+// writes cannot change
+func isExistsEndpoint(endpoint string) bool {
+	for _, e := range existsEndpoints {
+		if e == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// remarshal converts v to dst through its JSON encoding, keeping numbers
+// exact
+func remarshal(v, dst interface{}) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	return decodeCached(data, dst)
+}
+
+// decodeCached decodes a cached body, keeping numbers exact so re-encoding
+// does not alter them
+func decodeCached(data []byte, dst interface{}) bool {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(dst) == nil
+}
+
+// marshalCached encodes a patched body
+func marshalCached(v interface{}) ([]byte, bool) {
+	data, err := json.Marshal(v)
+	return data, err == nil
+}